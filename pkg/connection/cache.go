@@ -0,0 +1,286 @@
+package connection
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/logging"
+)
+
+const defaultCacheCapacity = 1000
+
+// CachedResponse represents a cached GET response
+type CachedResponse struct {
+	Resource     string
+	StatusCode   int
+	Header       http.Header
+	Body         []byte
+	ETag         string
+	LastModified string
+	Expires      time.Time
+}
+
+// CacheStore is a store for cached responses, keyed by composed URI and credentials
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+	Keys() []string
+}
+
+// CacheRule defines the TTL for GET responses of resources beginning with Prefix,
+// e.g. /ecloud/v2/regions
+type CacheRule struct {
+	Prefix string
+	TTL    time.Duration
+}
+
+// ResponseCache caches GET responses for an APIConnection. Responses are cached for the TTL of the
+// longest matching rule, falling back to DefaultTTL. Responses aren't cached where the resulting
+// TTL is zero. Expired responses returned with an ETag or Last-Modified header are revalidated
+// with a conditional request. Mutating requests invalidate cached responses for the same resource
+// path, its descendants and its ancestors
+type ResponseCache struct {
+	Store      CacheStore
+	Rules      []CacheRule
+	DefaultTTL time.Duration
+
+	now func() time.Time
+}
+
+// NewResponseCache returns a ResponseCache with an in-memory LRU store
+func NewResponseCache(rules ...CacheRule) *ResponseCache {
+	return &ResponseCache{
+		Store: NewLRUCacheStore(defaultCacheCapacity),
+		Rules: rules,
+		now:   time.Now,
+	}
+}
+
+// ttl returns the TTL for given resource
+func (c *ResponseCache) ttl(resource string) time.Duration {
+	ttl := c.DefaultTTL
+	matched := -1
+	for _, rule := range c.Rules {
+		if strings.HasPrefix(resource, rule.Prefix) && len(rule.Prefix) > matched {
+			ttl = rule.TTL
+			matched = len(rule.Prefix)
+		}
+	}
+	return ttl
+}
+
+func (c *ResponseCache) currentTime() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+// invalidate removes cached responses for resource, its descendants and its ancestors
+func (c *ResponseCache) invalidate(resource string) {
+	resource = normaliseResource(resource)
+	ancestors := make(map[string]bool)
+	for p := resource; p != ""; p = p[:strings.LastIndex(p, "/")] {
+		ancestors[p] = true
+	}
+
+	for _, key := range c.Store.Keys() {
+		cached, ok := c.Store.Get(key)
+		if !ok {
+			continue
+		}
+		if ancestors[cached.Resource] || strings.HasPrefix(cached.Resource, resource+"/") {
+			logging.Debugf("Invalidating cached response for %s", cached.Resource)
+			c.Store.Delete(key)
+		}
+	}
+}
+
+// response returns a new APIResponse for cached
+func (cached *CachedResponse) response() *APIResponse {
+	return &APIResponse{
+		Response: &http.Response{
+			StatusCode: cached.StatusCode,
+			Header:     cached.Header.Clone(),
+			Body:       io.NopCloser(bytes.NewReader(cached.Body)),
+		},
+	}
+}
+
+func normaliseResource(resource string) string {
+	return "/" + strings.Trim(resource, "/")
+}
+
+// cacheKey returns the cache key for given URI and credentials. Credentials are hashed to avoid
+// retaining them in the store
+func cacheKey(uri string, credentials Credentials) string {
+	h := sha256.New()
+	if credentials != nil {
+		headers := credentials.GetAuthHeaders()
+		keys := make([]string, 0, len(headers))
+		for k := range headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			h.Write([]byte(k + "=" + headers[k] + "\n"))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16] + " " + uri
+}
+
+// invokeCached invokes a GET request, returning a cached response where available
+func (c *APIConnection) invokeCached(request APIRequest) (*APIResponse, error) {
+	cache := c.Cache
+	resource := normaliseResource(request.Resource)
+	ttl := cache.ttl(resource)
+	if ttl <= 0 {
+		return c.invoke(request)
+	}
+
+	key := cacheKey(c.composeURI(request), c.Credentials)
+	cached, ok := cache.Store.Get(key)
+	if ok && cache.currentTime().Before(cached.Expires) {
+		logging.Debugf("Using cached response for %s", resource)
+		return cached.response(), nil
+	}
+
+	revalidate := ok && (cached.ETag != "" || cached.LastModified != "")
+	if revalidate {
+		request.Headers = request.Headers.Clone()
+		if request.Headers == nil {
+			request.Headers = http.Header{}
+		}
+		if cached.ETag != "" {
+			request.Headers.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			request.Headers.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := c.invoke(request)
+	if err != nil {
+		return resp, err
+	}
+
+	if revalidate && resp.StatusCode == http.StatusNotModified {
+		logging.Debugf("Revalidated cached response for %s", resource)
+		resp.Body.Close()
+		// Store an updated copy, as the cached response may be read concurrently
+		revalidated := *cached
+		revalidated.Expires = cache.currentTime().Add(ttl)
+		cache.Store.Set(key, &revalidated)
+		return revalidated.response(), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return resp, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	cache.Store.Set(key, &CachedResponse{
+		Resource:     resource,
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Expires:      cache.currentTime().Add(ttl),
+	})
+
+	return resp, nil
+}
+
+// LRUCacheStore is an in-memory CacheStore which evicts the least recently used response once
+// capacity is reached
+type LRUCacheStore struct {
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+	mutex    sync.Mutex
+}
+
+type lruCacheEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewLRUCacheStore returns a new LRUCacheStore holding at most capacity responses
+func NewLRUCacheStore(capacity int) *LRUCacheStore {
+	return &LRUCacheStore{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the cached response for key
+func (s *LRUCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*lruCacheEntry).resp, true
+}
+
+// Set stores resp for key, evicting the least recently used response where at capacity
+func (s *LRUCacheStore) Set(key string, resp *CachedResponse) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		elem.Value.(*lruCacheEntry).resp = resp
+		s.order.MoveToFront(elem)
+		return
+	}
+
+	s.items[key] = s.order.PushFront(&lruCacheEntry{key: key, resp: resp})
+	for s.capacity > 0 && s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*lruCacheEntry).key)
+	}
+}
+
+// Delete removes the cached response for key
+func (s *LRUCacheStore) Delete(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		s.order.Remove(elem)
+		delete(s.items, key)
+	}
+}
+
+// Keys returns the keys of all cached responses
+func (s *LRUCacheStore) Keys() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keys := make([]string, 0, len(s.items))
+	for key := range s.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
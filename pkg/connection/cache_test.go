@@ -0,0 +1,323 @@
+package connection
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ans-group/sdk-go/test"
+	"github.com/stretchr/testify/assert"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newCachedTestConnection(handler test.RoundTripFunc, rules ...CacheRule) (*APIConnection, *testClock) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := NewAPIKeyCredentialsAPIConnection("testkey")
+	c.HTTPClient = test.NewTestClient(handler)
+	c.Cache = NewResponseCache(rules...)
+	c.Cache.now = clock.Now
+	return c, clock
+}
+
+func testResponse(statusCode int, body string, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+	}
+}
+
+func readBody(t *testing.T, resp *APIResponse) string {
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	return string(body)
+}
+
+func TestAPIConnection_Cache(t *testing.T) {
+	t.Run("WithinTTL_ReturnsCachedResponse", func(t *testing.T) {
+		calls := 0
+		c, _ := newCachedTestConnection(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return testResponse(200, `{"data":[]}`, nil), nil
+		}, CacheRule{Prefix: "/ecloud/v2/regions", TTL: time.Minute})
+
+		resp1, err := c.Get("/ecloud/v2/regions", APIRequestParameters{})
+		assert.Nil(t, err)
+		resp2, err := c.Get("/ecloud/v2/regions", APIRequestParameters{})
+		assert.Nil(t, err)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, `{"data":[]}`, readBody(t, resp1))
+		assert.Equal(t, `{"data":[]}`, readBody(t, resp2))
+	})
+
+	t.Run("NoMatchingRule_NotCached", func(t *testing.T) {
+		calls := 0
+		c, _ := newCachedTestConnection(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return testResponse(200, `{}`, nil), nil
+		}, CacheRule{Prefix: "/ecloud/v2/regions", TTL: time.Minute})
+
+		c.Get("/ecloud/v2/instances", APIRequestParameters{})
+		c.Get("/ecloud/v2/instances", APIRequestParameters{})
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("DifferentParameters_CachedSeparately", func(t *testing.T) {
+		calls := 0
+		c, _ := newCachedTestConnection(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return testResponse(200, `{}`, nil), nil
+		}, CacheRule{Prefix: "/ecloud/v2/regions", TTL: time.Minute})
+
+		c.Get("/ecloud/v2/regions", APIRequestParameters{})
+		c.Get("/ecloud/v2/regions", APIRequestParameters{Pagination: APIRequestPagination{Page: 2}})
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("DifferentCredentials_CachedSeparately", func(t *testing.T) {
+		calls := 0
+		c, _ := newCachedTestConnection(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return testResponse(200, `{}`, nil), nil
+		}, CacheRule{Prefix: "/", TTL: time.Minute})
+
+		c.Get("/ecloud/v2/regions", APIRequestParameters{})
+		c.Credentials = &APIKeyCredentials{APIKey: "otherkey"}
+		c.Get("/ecloud/v2/regions", APIRequestParameters{})
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("NonOKResponse_NotCached", func(t *testing.T) {
+		calls := 0
+		c, _ := newCachedTestConnection(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return testResponse(500, `{}`, nil), nil
+		}, CacheRule{Prefix: "/", TTL: time.Minute})
+
+		c.Get("/ecloud/v2/regions", APIRequestParameters{})
+		c.Get("/ecloud/v2/regions", APIRequestParameters{})
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Expired_WithoutValidators_Refetches", func(t *testing.T) {
+		calls := 0
+		c, clock := newCachedTestConnection(func(req *http.Request) (*http.Response, error) {
+			calls++
+			assert.Empty(t, req.Header.Get("If-None-Match"))
+			return testResponse(200, `{}`, nil), nil
+		}, CacheRule{Prefix: "/", TTL: time.Minute})
+
+		c.Get("/ecloud/v2/regions", APIRequestParameters{})
+		clock.now = clock.now.Add(2 * time.Minute)
+		c.Get("/ecloud/v2/regions", APIRequestParameters{})
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Expired_WithETag_RevalidatesWithIfNoneMatch", func(t *testing.T) {
+		calls := 0
+		c, clock := newCachedTestConnection(func(req *http.Request) (*http.Response, error) {
+			calls++
+			if calls == 1 {
+				return testResponse(200, `{"data":"original"}`, http.Header{"Etag": []string{`"abc"`}}), nil
+			}
+			assert.Equal(t, `"abc"`, req.Header.Get("If-None-Match"))
+			return testResponse(304, "", nil), nil
+		}, CacheRule{Prefix: "/", TTL: time.Minute})
+
+		c.Get("/ecloud/v2/regions", APIRequestParameters{})
+		clock.now = clock.now.Add(2 * time.Minute)
+		resp, err := c.Get("/ecloud/v2/regions", APIRequestParameters{})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, `{"data":"original"}`, readBody(t, resp))
+
+		// Revalidation should extend expiry
+		c.Get("/ecloud/v2/regions", APIRequestParameters{})
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Expired_ConcurrentRevalidation", func(t *testing.T) {
+		const n = 10
+		var calls, revalidating atomic.Int32
+		// Hold each revalidation until all are in flight, so the cached entry is updated
+		// concurrently
+		release := make(chan struct{})
+		c, clock := newCachedTestConnection(func(req *http.Request) (*http.Response, error) {
+			if calls.Add(1) == 1 {
+				return testResponse(200, `{"data":"original"}`, http.Header{"Etag": []string{`"abc"`}}), nil
+			}
+			if revalidating.Add(1) == n {
+				close(release)
+			}
+			<-release
+			return testResponse(304, "", nil), nil
+		}, CacheRule{Prefix: "/", TTL: time.Minute})
+
+		c.Get("/ecloud/v2/regions", APIRequestParameters{})
+		clock.now = clock.now.Add(2 * time.Minute)
+
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := c.Get("/ecloud/v2/regions", APIRequestParameters{})
+				assert.Nil(t, err)
+				assert.Equal(t, `{"data":"original"}`, readBody(t, resp))
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(n+1), calls.Load())
+	})
+
+	t.Run("Expired_WithLastModified_RevalidatesWithIfModifiedSince", func(t *testing.T) {
+		calls := 0
+		c, clock := newCachedTestConnection(func(req *http.Request) (*http.Response, error) {
+			calls++
+			if calls == 1 {
+				return testResponse(200, `{}`, http.Header{"Last-Modified": []string{"Mon, 01 Jan 2024 00:00:00 GMT"}}), nil
+			}
+			assert.Equal(t, "Mon, 01 Jan 2024 00:00:00 GMT", req.Header.Get("If-Modified-Since"))
+			return testResponse(200, `{"data":"updated"}`, nil), nil
+		}, CacheRule{Prefix: "/", TTL: time.Minute})
+
+		c.Get("/ecloud/v2/regions", APIRequestParameters{})
+		clock.now = clock.now.Add(2 * time.Minute)
+		resp, err := c.Get("/ecloud/v2/regions", APIRequestParameters{})
+
+		assert.Nil(t, err)
+		assert.Equal(t, `{"data":"updated"}`, readBody(t, resp))
+	})
+
+	t.Run("LongestPrefixRuleApplies", func(t *testing.T) {
+		c, _ := newCachedTestConnection(nil,
+			CacheRule{Prefix: "/ecloud", TTL: time.Minute},
+			CacheRule{Prefix: "/ecloud/v2/instances", TTL: 0},
+		)
+
+		assert.Equal(t, time.Minute, c.Cache.ttl("/ecloud/v2/regions"))
+		assert.Equal(t, time.Duration(0), c.Cache.ttl("/ecloud/v2/instances/i-abcdef12"))
+	})
+}
+
+func TestAPIConnection_Cache_Invalidation(t *testing.T) {
+	paths := []string{
+		"/ecloud/v2/instances",
+		"/ecloud/v2/instances/i-abcdef12",
+		"/ecloud/v2/instances/i-abcdef12/volumes",
+		"/ecloud/v2/instances/i-98765432",
+		"/ecloud/v2/regions",
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		resource    string
+		invalidated []string
+	}{
+		{
+			name:        "PatchItem_InvalidatesItemDescendantsAndCollection",
+			method:      "PATCH",
+			resource:    "/ecloud/v2/instances/i-abcdef12",
+			invalidated: []string{"/ecloud/v2/instances", "/ecloud/v2/instances/i-abcdef12", "/ecloud/v2/instances/i-abcdef12/volumes"},
+		},
+		{
+			name:        "PostCollection_InvalidatesCollectionAndItems",
+			method:      "POST",
+			resource:    "/ecloud/v2/instances",
+			invalidated: []string{"/ecloud/v2/instances", "/ecloud/v2/instances/i-abcdef12", "/ecloud/v2/instances/i-abcdef12/volumes", "/ecloud/v2/instances/i-98765432"},
+		},
+		{
+			name:        "PostAction_InvalidatesAncestors",
+			method:      "PUT",
+			resource:    "/ecloud/v2/instances/i-abcdef12/power-on",
+			invalidated: []string{"/ecloud/v2/instances", "/ecloud/v2/instances/i-abcdef12"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make(map[string]int)
+			c, _ := newCachedTestConnection(func(req *http.Request) (*http.Response, error) {
+				if req.Method == "GET" {
+					calls[req.URL.Path]++
+				}
+				return testResponse(200, `{}`, nil), nil
+			}, CacheRule{Prefix: "/", TTL: time.Minute})
+
+			for _, p := range paths {
+				c.Get(p, APIRequestParameters{})
+			}
+
+			_, err := c.Invoke(APIRequest{Method: tt.method, Resource: tt.resource})
+			assert.Nil(t, err)
+
+			for _, p := range paths {
+				c.Get(p, APIRequestParameters{})
+			}
+
+			for _, p := range paths {
+				expected := 1
+				for _, inv := range tt.invalidated {
+					if inv == p {
+						expected = 2
+					}
+				}
+				assert.Equal(t, expected, calls[p], p)
+			}
+		})
+	}
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	t.Run("EvictsLeastRecentlyUsed", func(t *testing.T) {
+		s := NewLRUCacheStore(2)
+		s.Set("a", &CachedResponse{Resource: "/a"})
+		s.Set("b", &CachedResponse{Resource: "/b"})
+		s.Get("a")
+		s.Set("c", &CachedResponse{Resource: "/c"})
+
+		_, okA := s.Get("a")
+		_, okB := s.Get("b")
+		_, okC := s.Get("c")
+
+		assert.True(t, okA)
+		assert.False(t, okB)
+		assert.True(t, okC)
+		assert.Len(t, s.Keys(), 2)
+	})
+
+	t.Run("Delete_RemovesEntry", func(t *testing.T) {
+		s := NewLRUCacheStore(2)
+		s.Set("a", &CachedResponse{Resource: "/a"})
+		s.Delete("a")
+
+		_, ok := s.Get("a")
+
+		assert.False(t, ok)
+		assert.Empty(t, s.Keys())
+	})
+}
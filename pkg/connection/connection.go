@@ -37,6 +37,8 @@ type APIConnection struct {
 	APIScheme   string
 	Headers     http.Header
 	UserAgent   string
+	// Cache optionally caches GET responses, see ResponseCache
+	Cache *ResponseCache
 }
 
 type RequestSerializer interface {
@@ -156,6 +158,21 @@ func (c *APIConnection) Delete(resource string, body interface{}) (*APIResponse,
 
 // Invoke invokes a request, returning an APIResponse
 func (c *APIConnection) Invoke(request APIRequest) (*APIResponse, error) {
+	if c.Cache == nil || c.Cache.Store == nil {
+		return c.invoke(request)
+	}
+
+	if strings.EqualFold(request.Method, "GET") {
+		return c.invokeCached(request)
+	}
+
+	resp, err := c.invoke(request)
+	c.Cache.invalidate(request.Resource)
+	return resp, err
+}
+
+// invoke invokes a request without caching
+func (c *APIConnection) invoke(request APIRequest) (*APIResponse, error) {
 	req, err := c.NewRequest(request)
 	if err != nil {
 		return nil, err
@@ -164,36 +181,46 @@ func (c *APIConnection) Invoke(request APIRequest) (*APIResponse, error) {
 	return c.InvokeRequest(req)
 }
 
-// NewRequest generates a new Request from given parameters
+// getBody returns the encoded body for given request
 func (c *APIConnection) getBody(request APIRequest) (io.Reader, error) {
+	return encodeBody(request.Body)
+}
+
+// encodeBody validates and encodes given request body. io.Reader bodies are returned as-is
+func encodeBody(body interface{}) (io.Reader, error) {
 	buf := new(bytes.Buffer)
-	if request.Body != nil {
-		if reader, ok := request.Body.(io.Reader); ok {
+	if body != nil {
+		if reader, ok := body.(io.Reader); ok {
 			return reader, nil
 		}
 
-		if v, ok := request.Body.(Validatable); ok {
+		if v, ok := body.(Validatable); ok {
 			valErr := v.Validate()
 			if valErr != nil {
 				return nil, valErr
 			}
 		}
 
-		if serializer, ok := request.Body.(RequestSerializer); ok {
-			body, err := serializer.Serialize()
+		if serializer, ok := body.(RequestSerializer); ok {
+			encoded, err := serializer.Serialize()
 			if err != nil {
 				return nil, err
 			}
 
-			buf.Write(body)
+			buf.Write(encoded)
 		} else {
-			err := json.NewEncoder(buf).Encode(request.Body)
+			err := json.NewEncoder(buf).Encode(body)
 			if err != nil {
 				return nil, err
 			}
 		}
 
-		logging.Tracef("Encoded body: %s", buf)
+		if r, ok := body.(Redactable); ok {
+			redacted, _ := json.Marshal(r.Redacted())
+			logging.Tracef("Encoded body: %s", redacted)
+		} else {
+			logging.Tracef("Encoded body: %s", buf)
+		}
 	}
 
 	return buf, nil
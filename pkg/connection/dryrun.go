@@ -0,0 +1,178 @@
+package connection
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// PlannedOperation represents a mutating request captured by DryRunConnection
+type PlannedOperation struct {
+	Method   string          `json:"method"`
+	Resource string          `json:"resource"`
+	Body     json.RawMessage `json:"body,omitempty"`
+}
+
+// DryRunResponseFunc returns the synthesised response for a planned operation. index is the
+// zero-based position of the operation within the plan
+type DryRunResponseFunc func(op PlannedOperation, index int) *APIResponse
+
+// DryRunConnection wraps a Connection, passing GET requests through to the wrapped connection and
+// recording all other requests as planned operations without executing them. Recorded requests
+// are answered with a synthesised success response, by default containing a placeholder task ID
+type DryRunConnection struct {
+	Connection Connection
+	// ResponseFunc optionally overrides the synthesised response for planned operations
+	ResponseFunc DryRunResponseFunc
+
+	plan  []PlannedOperation
+	mutex sync.Mutex
+}
+
+// NewDryRunConnection returns a new DryRunConnection wrapping conn
+func NewDryRunConnection(conn Connection) *DryRunConnection {
+	return &DryRunConnection{Connection: conn}
+}
+
+// DryRunTaskID returns the placeholder task ID returned for the planned operation at index
+func DryRunTaskID(index int) string {
+	return fmt.Sprintf("task-dryrun%d", index+1)
+}
+
+// DefaultDryRunResponse returns a 202 response containing a placeholder task reference. The
+// resource ID is omitted so that the response decodes into models with either int or string IDs
+func DefaultDryRunResponse(op PlannedOperation, index int) *APIResponse {
+	return &APIResponse{
+		Response: &http.Response{
+			StatusCode: http.StatusAccepted,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"data":{"task_id":"%s"},"meta":{}}`, DryRunTaskID(index)))),
+		},
+	}
+}
+
+// Get invokes a GET request against the wrapped connection
+func (c *DryRunConnection) Get(resource string, parameters APIRequestParameters) (*APIResponse, error) {
+	return c.Invoke(APIRequest{
+		Method:     "GET",
+		Resource:   resource,
+		Parameters: parameters,
+	})
+}
+
+// Post records a planned POST request
+func (c *DryRunConnection) Post(resource string, body interface{}) (*APIResponse, error) {
+	return c.Invoke(APIRequest{
+		Method:   "POST",
+		Resource: resource,
+		Body:     body,
+	})
+}
+
+// Put records a planned PUT request
+func (c *DryRunConnection) Put(resource string, body interface{}) (*APIResponse, error) {
+	return c.Invoke(APIRequest{
+		Method:   "PUT",
+		Resource: resource,
+		Body:     body,
+	})
+}
+
+// Patch records a planned PATCH request
+func (c *DryRunConnection) Patch(resource string, body interface{}) (*APIResponse, error) {
+	return c.Invoke(APIRequest{
+		Method:   "PATCH",
+		Resource: resource,
+		Body:     body,
+	})
+}
+
+// Delete records a planned DELETE request
+func (c *DryRunConnection) Delete(resource string, body interface{}) (*APIResponse, error) {
+	return c.Invoke(APIRequest{
+		Method:   "DELETE",
+		Resource: resource,
+		Body:     body,
+	})
+}
+
+// Invoke passes GET requests through to the wrapped connection, recording all other requests as
+// planned operations. Request bodies are validated and encoded as they would be for a real request,
+// with Redactable bodies recorded in redacted form
+func (c *DryRunConnection) Invoke(request APIRequest) (*APIResponse, error) {
+	if strings.EqualFold(request.Method, "GET") {
+		return c.Connection.Invoke(request)
+	}
+
+	op := PlannedOperation{
+		Method:   strings.ToUpper(request.Method),
+		Resource: "/" + strings.Trim(request.Resource, "/"),
+	}
+
+	if request.Body != nil {
+		reader, err := encodeBody(request.Body)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.TrimSpace(body)
+		if r, ok := request.Body.(Redactable); ok {
+			body, err = json.Marshal(r.Redacted())
+			if err != nil {
+				return nil, err
+			}
+		}
+		if json.Valid(body) {
+			op.Body = body
+		} else {
+			op.Body, _ = json.Marshal(string(body))
+		}
+	}
+
+	c.mutex.Lock()
+	index := len(c.plan)
+	c.plan = append(c.plan, op)
+	c.mutex.Unlock()
+
+	responseFunc := c.ResponseFunc
+	if responseFunc == nil {
+		responseFunc = DefaultDryRunResponse
+	}
+
+	return responseFunc(op, index), nil
+}
+
+// Plan returns a copy of the operations planned so far
+func (c *DryRunConnection) Plan() []PlannedOperation {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return append([]PlannedOperation{}, c.plan...)
+}
+
+// Reset clears all planned operations
+func (c *DryRunConnection) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.plan = nil
+}
+
+// WritePlan writes the planned operations to w as an indented JSON array
+func (c *DryRunConnection) WritePlan(w io.Writer) error {
+	plan := c.Plan()
+	if plan == nil {
+		plan = []PlannedOperation{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(plan)
+}
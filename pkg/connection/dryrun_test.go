@@ -0,0 +1,185 @@
+package connection
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testConnection struct {
+	invoked []APIRequest
+}
+
+func (c *testConnection) Get(resource string, parameters APIRequestParameters) (*APIResponse, error) {
+	return c.Invoke(APIRequest{Method: "GET", Resource: resource, Parameters: parameters})
+}
+
+func (c *testConnection) Post(resource string, body interface{}) (*APIResponse, error) {
+	return c.Invoke(APIRequest{Method: "POST", Resource: resource, Body: body})
+}
+
+func (c *testConnection) Put(resource string, body interface{}) (*APIResponse, error) {
+	return c.Invoke(APIRequest{Method: "PUT", Resource: resource, Body: body})
+}
+
+func (c *testConnection) Patch(resource string, body interface{}) (*APIResponse, error) {
+	return c.Invoke(APIRequest{Method: "PATCH", Resource: resource, Body: body})
+}
+
+func (c *testConnection) Delete(resource string, body interface{}) (*APIResponse, error) {
+	return c.Invoke(APIRequest{Method: "DELETE", Resource: resource, Body: body})
+}
+
+func (c *testConnection) Invoke(request APIRequest) (*APIResponse, error) {
+	c.invoked = append(c.invoked, request)
+	return &APIResponse{
+		Response: &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"data":{"id":"i-abcdef12"}}`))),
+		},
+	}, nil
+}
+
+type testDryRunRequest struct {
+	Name string `json:"name"`
+}
+
+func (r *testDryRunRequest) Validate() *ValidationError {
+	if r.Name == "" {
+		return NewValidationError("name is required")
+	}
+	return nil
+}
+
+type testRedactableRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (r testRedactableRequest) Redacted() interface{} {
+	r.Password = "REDACTED"
+	return r
+}
+
+func TestDryRunConnection(t *testing.T) {
+	t.Run("Get_PassesThrough", func(t *testing.T) {
+		inner := &testConnection{}
+		c := NewDryRunConnection(inner)
+
+		resp, err := c.Get("/ecloud/v2/instances/i-abcdef12", APIRequestParameters{})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Len(t, inner.invoked, 1)
+		assert.Empty(t, c.Plan())
+	})
+
+	t.Run("Mutations_RecordedNotExecuted", func(t *testing.T) {
+		inner := &testConnection{}
+		c := NewDryRunConnection(inner)
+
+		c.Post("/ecloud/v2/instances", &testDryRunRequest{Name: "test"})
+		c.Patch("ecloud/v2/instances/i-abcdef12/", &testDryRunRequest{Name: "renamed"})
+		c.Put("/ecloud/v2/instances/i-abcdef12/power-on", nil)
+		c.Delete("/ecloud/v2/instances/i-abcdef12", nil)
+
+		assert.Empty(t, inner.invoked)
+		assert.Equal(t, []PlannedOperation{
+			{Method: "POST", Resource: "/ecloud/v2/instances", Body: []byte(`{"name":"test"}`)},
+			{Method: "PATCH", Resource: "/ecloud/v2/instances/i-abcdef12", Body: []byte(`{"name":"renamed"}`)},
+			{Method: "PUT", Resource: "/ecloud/v2/instances/i-abcdef12/power-on"},
+			{Method: "DELETE", Resource: "/ecloud/v2/instances/i-abcdef12"},
+		}, c.Plan())
+	})
+
+	t.Run("RedactableBody_RecordedRedacted", func(t *testing.T) {
+		c := NewDryRunConnection(&testConnection{})
+
+		_, err := c.Post("/ecloud/v2/instances/i-abcdef12/user-script", testRedactableRequest{Username: "root", Password: "s3cr3t"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, `{"username":"root","password":"REDACTED"}`, string(c.Plan()[0].Body))
+	})
+
+	t.Run("InvalidBody_ReturnsValidationError", func(t *testing.T) {
+		c := NewDryRunConnection(&testConnection{})
+
+		_, err := c.Post("/ecloud/v2/instances", &testDryRunRequest{})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "name is required", err.Error())
+		assert.Empty(t, c.Plan())
+	})
+
+	t.Run("DefaultResponse_ContainsPlaceholderTask", func(t *testing.T) {
+		c := NewDryRunConnection(&testConnection{})
+		c.Post("/ecloud/v2/volumes", nil)
+
+		body := &APIResponseBodyData[struct {
+			TaskID string `json:"task_id"`
+			ID     int    `json:"id"`
+		}]{}
+		resp, err := c.Post("/ecloud/v2/instances", nil)
+		assert.Nil(t, err)
+		err = resp.HandleResponse(body)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "task-dryrun2", body.Data.TaskID)
+	})
+
+	t.Run("CustomResponseFunc_Used", func(t *testing.T) {
+		c := NewDryRunConnection(&testConnection{})
+		c.ResponseFunc = func(op PlannedOperation, index int) *APIResponse {
+			return &APIResponse{Response: &http.Response{StatusCode: 204, Body: io.NopCloser(bytes.NewReader(nil))}}
+		}
+
+		resp, err := c.Delete("/ecloud/v2/instances/i-abcdef12", nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+	})
+
+	t.Run("WritePlan_WritesJSON", func(t *testing.T) {
+		c := NewDryRunConnection(&testConnection{})
+		c.Delete("/ecloud/v2/instances/i-abcdef12", nil)
+		buf := &bytes.Buffer{}
+
+		err := c.WritePlan(buf)
+
+		assert.Nil(t, err)
+		assert.JSONEq(t, `[{"method":"DELETE","resource":"/ecloud/v2/instances/i-abcdef12"}]`, buf.String())
+	})
+
+	t.Run("WritePlan_Empty_WritesEmptyArray", func(t *testing.T) {
+		c := NewDryRunConnection(&testConnection{})
+		buf := &bytes.Buffer{}
+
+		err := c.WritePlan(buf)
+
+		assert.Nil(t, err)
+		assert.JSONEq(t, `[]`, buf.String())
+	})
+
+	t.Run("Reset_ClearsPlan", func(t *testing.T) {
+		c := NewDryRunConnection(&testConnection{})
+		c.Delete("/ecloud/v2/instances/i-abcdef12", nil)
+
+		c.Reset()
+
+		assert.Empty(t, c.Plan())
+	})
+
+	t.Run("ReaderBody_RecordedAsString", func(t *testing.T) {
+		c := NewDryRunConnection(&testConnection{})
+
+		_, err := c.Post("/ecloud/v2/instances", bytes.NewReader([]byte("not json")))
+
+		assert.Nil(t, err)
+		assert.Equal(t, `"not json"`, string(c.Plan()[0].Body))
+	})
+}
+
+var _ Connection = &DryRunConnection{}
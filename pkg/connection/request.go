@@ -11,6 +11,12 @@ type Validatable interface {
 	Validate() *ValidationError
 }
 
+// Redactable is implemented by request bodies containing secrets. Redacted returns a copy of the
+// body with secrets masked, which is logged and recorded in dry-run plans in place of the body
+type Redactable interface {
+	Redacted() interface{}
+}
+
 type ValidationError struct {
 	Message string
 }
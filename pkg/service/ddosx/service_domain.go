@@ -51,65 +51,41 @@ func (s *Service) DeployDomain(domainName string) error {
 	return connection.PostRaw(s.connection, fmt.Sprintf("/ddosx/v1/domains/%s/deploy", domainName), nil, &connection.APIResponseBody{}, connection.NotFoundResponseHandler(&DomainNotFoundError{Name: domainName}))
 }
 
+func (s *Service) domainRecordRes(domainName string) *resource.Resource[Record, string] {
+	return resource.NewStringResourceWithIdentifier[Record](s.connection, "records", "record", "ID",
+		func(id string) error { return &DomainRecordNotFoundError{DomainName: domainName, ID: id} }).
+		WithParent(resource.StringParentWithIdentifier("/ddosx/v1/domains", "domain", "name", domainName, &DomainNotFoundError{Name: domainName}))
+}
+
 // GetDomainRecords retrieves a list of records
 func (s *Service) GetDomainRecords(domainName string, parameters connection.APIRequestParameters) ([]Record, error) {
-	return connection.InvokeRequestAll(func(p connection.APIRequestParameters) (*connection.Paginated[Record], error) {
-		return s.GetDomainRecordsPaginated(domainName, p)
-	}, parameters)
+	return s.domainRecordRes(domainName).List(parameters)
 }
 
 // GetDomainRecordsPaginated retrieves a paginated list of domains
 func (s *Service) GetDomainRecordsPaginated(domainName string, parameters connection.APIRequestParameters) (*connection.Paginated[Record], error) {
-	if domainName == "" {
-		return nil, fmt.Errorf("invalid domain name")
-	}
-	body, err := connection.Get[[]Record](s.connection, fmt.Sprintf("/ddosx/v1/domains/%s/records", domainName), parameters, connection.NotFoundResponseHandler(&DomainNotFoundError{Name: domainName}))
-	return connection.NewPaginated(body, parameters, func(p connection.APIRequestParameters) (*connection.Paginated[Record], error) {
-		return s.GetDomainRecordsPaginated(domainName, p)
-	}), err
+	return s.domainRecordRes(domainName).ListPaginated(parameters)
 }
 
 // GetDomainRecord retrieves a single domain record by ID
 func (s *Service) GetDomainRecord(domainName string, recordID string) (Record, error) {
-	if domainName == "" {
-		return Record{}, fmt.Errorf("invalid domain name")
-	}
-	if recordID == "" {
-		return Record{}, fmt.Errorf("invalid record ID")
-	}
-	body, err := connection.Get[Record](s.connection, fmt.Sprintf("/ddosx/v1/domains/%s/records/%s", domainName, recordID), connection.APIRequestParameters{}, connection.NotFoundResponseHandler(&DomainRecordNotFoundError{DomainName: domainName, ID: recordID}))
-	return body.Data, err
+	return s.domainRecordRes(domainName).Get(recordID)
 }
 
 // CreateDomainRecord creates a new record for a domain
 func (s *Service) CreateDomainRecord(domainName string, req CreateRecordRequest) (string, error) {
-	if domainName == "" {
-		return "", fmt.Errorf("invalid domain name")
-	}
-	body, err := connection.Post[Record](s.connection, fmt.Sprintf("/ddosx/v1/domains/%s/records", domainName), &req, connection.NotFoundResponseHandler(&DomainNotFoundError{Name: domainName}))
-	return body.Data.ID, err
+	record, err := s.domainRecordRes(domainName).Create(&req)
+	return record.ID, err
 }
 
 // PatchDomainRecord patches a single domain record by ID
 func (s *Service) PatchDomainRecord(domainName string, recordID string, req PatchRecordRequest) error {
-	if domainName == "" {
-		return fmt.Errorf("invalid domain name")
-	}
-	if recordID == "" {
-		return fmt.Errorf("invalid record ID")
-	}
-	return connection.PatchRaw(s.connection, fmt.Sprintf("/ddosx/v1/domains/%s/records/%s", domainName, recordID), &req, &connection.APIResponseBody{}, connection.NotFoundResponseHandler(&DomainRecordNotFoundError{ID: recordID}))
+	return s.domainRecordRes(domainName).Patch(recordID, &req)
 }
 
 // DeleteDomainRecord deletes a single domain record by ID
 func (s *Service) DeleteDomainRecord(domainName string, recordID string) error {
-	if domainName == "" {
-		return fmt.Errorf("invalid domain name")
-	}
-	if recordID == "" {
-		return fmt.Errorf("invalid record ID")
-	}
-	return connection.DeleteRaw(s.connection, fmt.Sprintf("/ddosx/v1/domains/%s/records/%s", domainName, recordID), nil, &connection.APIResponseBody{}, connection.NotFoundResponseHandler(&DomainRecordNotFoundError{ID: recordID}))
+	return s.domainRecordRes(domainName).Delete(recordID)
 }
 
 // GetDomainProperties retrieves a list of domain properties
@@ -0,0 +1,486 @@
+package ecloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"gopkg.in/yaml.v3"
+)
+
+// AffinityRuleSet is a snapshot of affinity rules, their members, and the instances and hosts they
+// relate to. It can be retrieved with GetAffinityRuleSet, or decoded from JSON with
+// ReadAffinityRuleSet for offline use
+type AffinityRuleSet struct {
+	Rules     []AffinityRule       `json:"rules"`
+	Members   []AffinityRuleMember `json:"members"`
+	Instances []Instance           `json:"instances"`
+	Hosts     []Host               `json:"hosts"`
+}
+
+// GetAffinityRuleSet retrieves the affinity rules of the VPC with ID vpcID along with their members,
+// instances and hosts
+func GetAffinityRuleSet(svc ECloudService, vpcID string) (AffinityRuleSet, error) {
+	var set AffinityRuleSet
+	if vpcID == "" {
+		return set, fmt.Errorf("invalid vpc id")
+	}
+
+	rules, err := svc.GetAffinityRules(eqFilter("vpc_id", vpcID))
+	if err != nil {
+		return set, fmt.Errorf("failed to retrieve affinity rules for vpc [%s]: %w", vpcID, err)
+	}
+	set.Rules = rules
+
+	for _, rule := range rules {
+		members, err := svc.GetAffinityRuleMembers(rule.ID, connection.APIRequestParameters{})
+		if err != nil {
+			return set, fmt.Errorf("failed to retrieve members for affinity rule [%s]: %w", rule.ID, err)
+		}
+		set.Members = append(set.Members, members...)
+	}
+
+	// Instances aren't filtered by VPC so that members in other VPCs are reported as mismatched
+	// rather than missing
+	set.Instances, err = svc.GetInstances(connection.APIRequestParameters{})
+	if err != nil {
+		return set, fmt.Errorf("failed to retrieve instances: %w", err)
+	}
+	set.Hosts, err = svc.GetHosts(connection.APIRequestParameters{})
+	if err != nil {
+		return set, fmt.Errorf("failed to retrieve hosts: %w", err)
+	}
+
+	return set, nil
+}
+
+// ReadAffinityRuleSet decodes a JSON encoded AffinityRuleSet from r
+func ReadAffinityRuleSet(r io.Reader) (AffinityRuleSet, error) {
+	var set AffinityRuleSet
+	err := json.NewDecoder(r).Decode(&set)
+	return set, err
+}
+
+// AffinityIssueKind classifies an AffinityIssue
+type AffinityIssueKind string
+
+func (k AffinityIssueKind) String() string {
+	return string(k)
+}
+
+const (
+	// AffinityIssueMemberNotFound indicates a member instance doesn't exist
+	AffinityIssueMemberNotFound AffinityIssueKind = "member_not_found"
+	// AffinityIssueVPCMismatch indicates a member instance is in a different VPC to its rule
+	AffinityIssueVPCMismatch AffinityIssueKind = "vpc_mismatch"
+	// AffinityIssueAvailabilityZoneMismatch indicates a member instance is in a different
+	// availability zone to its rule
+	AffinityIssueAvailabilityZoneMismatch AffinityIssueKind = "availability_zone_mismatch"
+	// AffinityIssueInsufficientHosts indicates more anti-affinity members share a host group than
+	// it has hosts
+	AffinityIssueInsufficientHosts AffinityIssueKind = "insufficient_hosts"
+	// AffinityIssueSplitHostGroups indicates affinity members are placed on different host groups,
+	// or a mix of host groups and shared capacity, so can't share a host
+	AffinityIssueSplitHostGroups AffinityIssueKind = "split_host_groups"
+	// AffinityIssueConflictingRules indicates a pair of instances is required both to share a host
+	// and to be kept apart
+	AffinityIssueConflictingRules AffinityIssueKind = "conflicting_rules"
+	// AffinityIssueTooFewMembers indicates a rule has fewer than two members, so has no effect
+	AffinityIssueTooFewMembers AffinityIssueKind = "too_few_members"
+)
+
+// AffinityIssue is a problem found with an affinity rule by ValidateAffinityRules
+type AffinityIssue struct {
+	Kind   AffinityIssueKind `json:"kind"`
+	RuleID string            `json:"rule_id"`
+	// InstanceIDs lists the member instances involved, where applicable
+	InstanceIDs []string `json:"instance_ids,omitempty"`
+	// Unsatisfiable is true where the rule can't be honoured. Other issues are warnings
+	Unsatisfiable bool   `json:"unsatisfiable"`
+	Detail        string `json:"detail"`
+}
+
+func (i AffinityIssue) String() string {
+	return fmt.Sprintf("%s: affinity rule [%s]: %s", i.Kind, i.RuleID, i.Detail)
+}
+
+// ValidateAffinityRules checks each rule in set against its members, returning issues ordered by
+// rule ID followed by conflicts between rules. Anti-affinity members on a host group need a host
+// each, so a host group with fewer hosts than members is unsatisfiable. Affinity members must share
+// a host, so must all be on the same host group
+func ValidateAffinityRules(set AffinityRuleSet) []AffinityIssue {
+	instances := make(map[string]Instance)
+	for _, instance := range set.Instances {
+		instances[instance.ID] = instance
+	}
+	hosts := make(map[string]int)
+	for _, host := range set.Hosts {
+		hosts[host.HostGroupID]++
+	}
+	members := make(map[string][]string)
+	for _, member := range set.Members {
+		members[member.AffinityRuleID] = append(members[member.AffinityRuleID], member.InstanceID)
+	}
+
+	rules := append([]AffinityRule{}, set.Rules...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	var issues []AffinityIssue
+	for _, rule := range rules {
+		instanceIDs := append([]string{}, members[rule.ID]...)
+		sort.Strings(instanceIDs)
+
+		if len(instanceIDs) < 2 {
+			issues = append(issues, AffinityIssue{Kind: AffinityIssueTooFewMembers, RuleID: rule.ID, InstanceIDs: instanceIDs,
+				Detail: fmt.Sprintf("rule has %d members", len(instanceIDs))})
+		}
+
+		byHostGroup := make(map[string][]string)
+		for _, instanceID := range instanceIDs {
+			instance, ok := instances[instanceID]
+			switch {
+			case !ok:
+				issues = append(issues, AffinityIssue{Kind: AffinityIssueMemberNotFound, RuleID: rule.ID, InstanceIDs: []string{instanceID}, Unsatisfiable: true,
+					Detail: fmt.Sprintf("instance [%s] not found", instanceID)})
+				continue
+			case instance.VPCID != rule.VPCID:
+				issues = append(issues, AffinityIssue{Kind: AffinityIssueVPCMismatch, RuleID: rule.ID, InstanceIDs: []string{instanceID}, Unsatisfiable: true,
+					Detail: fmt.Sprintf("instance [%s] is in vpc [%s], rule is in vpc [%s]", instanceID, instance.VPCID, rule.VPCID)})
+			case instance.AvailabilityZoneID != rule.AvailabilityZoneID:
+				issues = append(issues, AffinityIssue{Kind: AffinityIssueAvailabilityZoneMismatch, RuleID: rule.ID, InstanceIDs: []string{instanceID}, Unsatisfiable: true,
+					Detail: fmt.Sprintf("instance [%s] is in availability zone [%s], rule is in availability zone [%s]", instanceID, instance.AvailabilityZoneID, rule.AvailabilityZoneID)})
+			}
+			byHostGroup[instance.HostGroupID] = append(byHostGroup[instance.HostGroupID], instanceID)
+		}
+
+		hostGroupIDs := make([]string, 0, len(byHostGroup))
+		for hostGroupID := range byHostGroup {
+			hostGroupIDs = append(hostGroupIDs, hostGroupID)
+		}
+		sort.Strings(hostGroupIDs)
+
+		switch rule.Type {
+		case AntiAffinity:
+			for _, hostGroupID := range hostGroupIDs {
+				groupMembers := byHostGroup[hostGroupID]
+				// Members on shared capacity aren't constrained by a host count
+				if hostGroupID == "" || len(groupMembers) <= hosts[hostGroupID] {
+					continue
+				}
+				issues = append(issues, AffinityIssue{Kind: AffinityIssueInsufficientHosts, RuleID: rule.ID, InstanceIDs: groupMembers, Unsatisfiable: true,
+					Detail: fmt.Sprintf("%d members on host group [%s] with %d hosts", len(groupMembers), hostGroupID, hosts[hostGroupID])})
+			}
+		case Affinity:
+			if len(hostGroupIDs) > 1 {
+				labels := make([]string, len(hostGroupIDs))
+				for i, hostGroupID := range hostGroupIDs {
+					labels[i] = hostGroupID
+					if hostGroupID == "" {
+						labels[i] = "shared"
+					}
+				}
+				issues = append(issues, AffinityIssue{Kind: AffinityIssueSplitHostGroups, RuleID: rule.ID, InstanceIDs: instanceIDs, Unsatisfiable: true,
+					Detail: fmt.Sprintf("members are placed on %s", strings.Join(labels, ", "))})
+			}
+		}
+	}
+
+	return append(issues, conflictingAffinityRules(rules, members)...)
+}
+
+// conflictingAffinityRules finds pairs of instances which are members of both an affinity and an
+// anti-affinity rule
+func conflictingAffinityRules(rules []AffinityRule, members map[string][]string) []AffinityIssue {
+	together := make(map[[2]string]string)
+	for _, rule := range rules {
+		if rule.Type != Affinity {
+			continue
+		}
+		for _, pair := range instancePairs(members[rule.ID]) {
+			if _, ok := together[pair]; !ok {
+				together[pair] = rule.ID
+			}
+		}
+	}
+
+	var issues []AffinityIssue
+	for _, rule := range rules {
+		if rule.Type != AntiAffinity {
+			continue
+		}
+		for _, pair := range instancePairs(members[rule.ID]) {
+			affinityRuleID, ok := together[pair]
+			if !ok {
+				continue
+			}
+			issues = append(issues, AffinityIssue{Kind: AffinityIssueConflictingRules, RuleID: rule.ID, InstanceIDs: pair[:], Unsatisfiable: true,
+				Detail: fmt.Sprintf("instances [%s] and [%s] are also members of affinity rule [%s]", pair[0], pair[1], affinityRuleID)})
+		}
+	}
+	return issues
+}
+
+func instancePairs(instanceIDs []string) [][2]string {
+	sorted := append([]string{}, instanceIDs...)
+	sort.Strings(sorted)
+
+	var pairs [][2]string
+	for i := range sorted {
+		for j := i + 1; j < len(sorted); j++ {
+			pairs = append(pairs, [2]string{sorted[i], sorted[j]})
+		}
+	}
+	return pairs
+}
+
+// AffinityRuleDocument declares the members of a set of affinity rules. Rules are identified by ID,
+// or by name where the name is unique. Live members of declared rules which aren't listed are
+// removed, and rules which aren't declared are left untouched. For example:
+//
+//	rules:
+//	  - name: web-spread
+//	    instances: [i-abcdef12, i-abcdef34]
+type AffinityRuleDocument struct {
+	Rules []AffinityRuleDocumentRule `yaml:"rules"`
+}
+
+// AffinityRuleDocumentRule declares the member instances of a single rule
+type AffinityRuleDocumentRule struct {
+	ID        string   `yaml:"id,omitempty"`
+	Name      string   `yaml:"name,omitempty"`
+	Instances []string `yaml:"instances"`
+}
+
+func (r AffinityRuleDocumentRule) label() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return r.Name
+}
+
+// LoadAffinityRuleDocument reads and validates an affinity rule document in YAML format
+func LoadAffinityRuleDocument(r io.Reader) (AffinityRuleDocument, error) {
+	var doc AffinityRuleDocument
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	err := dec.Decode(&doc)
+	if err != nil {
+		return doc, fmt.Errorf("failed to decode affinity rule document: %w", err)
+	}
+
+	return doc, doc.Validate()
+}
+
+// Validate checks the document for missing fields and duplicate rules or instances
+func (d AffinityRuleDocument) Validate() error {
+	rules := make(map[string]bool)
+	for _, rule := range d.Rules {
+		if rule.ID == "" && rule.Name == "" {
+			return fmt.Errorf("rule id or name is required")
+		}
+		if rules[rule.label()] {
+			return fmt.Errorf("duplicate rule [%s]", rule.label())
+		}
+		rules[rule.label()] = true
+
+		instances := make(map[string]bool)
+		for _, instanceID := range rule.Instances {
+			if instances[instanceID] {
+				return fmt.Errorf("duplicate instance [%s] for rule [%s]", instanceID, rule.label())
+			}
+			instances[instanceID] = true
+		}
+	}
+	return nil
+}
+
+// AffinityMemberOperation is a single member creation or deletion within an AffinityRulePlan
+type AffinityMemberOperation struct {
+	Action     EnvironmentAction `json:"action"`
+	RuleID     string            `json:"rule_id"`
+	RuleName   string            `json:"rule_name"`
+	InstanceID string            `json:"instance_id"`
+	// MemberID is set for deletions
+	MemberID string `json:"member_id,omitempty"`
+}
+
+func (o AffinityMemberOperation) String() string {
+	return fmt.Sprintf("%s member [%s] of affinity rule [%s]", o.Action, o.InstanceID, o.RuleID)
+}
+
+// AffinityRulePlan is the set of member operations which converge live affinity rules with an
+// AffinityRuleDocument. Issues are those found by ValidateAffinityRules once the plan is applied
+type AffinityRulePlan struct {
+	Operations []AffinityMemberOperation `json:"operations"`
+	Issues     []AffinityIssue           `json:"issues"`
+}
+
+// Empty returns true if the plan contains no operations
+func (p *AffinityRulePlan) Empty() bool {
+	return len(p.Operations) == 0
+}
+
+// Unsatisfiable returns true if applying the plan would leave any rule unsatisfiable
+func (p *AffinityRulePlan) Unsatisfiable() bool {
+	for _, issue := range p.Issues {
+		if issue.Unsatisfiable {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes a human-readable summary of the plan followed by any issues
+func (p *AffinityRulePlan) Write(w io.Writer) error {
+	for _, o := range p.Operations {
+		_, err := fmt.Fprintf(w, "%s affinity_rule/%s: %s\n", o.Action.symbol(), o.RuleName, o.InstanceID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, issue := range p.Issues {
+		_, err := fmt.Fprintf(w, "! %s\n", issue)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PlanAffinityRuleMembers compares the members declared in doc with set, returning the member
+// operations required to converge them. Deletions are ordered before creations, so that rules are
+// relaxed before they're extended
+func PlanAffinityRuleMembers(set AffinityRuleSet, doc AffinityRuleDocument) (*AffinityRulePlan, error) {
+	err := doc.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string][]AffinityRuleMember)
+	for _, member := range set.Members {
+		members[member.AffinityRuleID] = append(members[member.AffinityRuleID], member)
+	}
+
+	plan := &AffinityRulePlan{}
+	var creates []AffinityMemberOperation
+	desired := AffinityRuleSet{Rules: set.Rules, Instances: set.Instances, Hosts: set.Hosts}
+	declared := make(map[string]bool)
+	for _, spec := range doc.Rules {
+		rule, err := resolveAffinityRule(set.Rules, spec)
+		if err != nil {
+			return nil, err
+		}
+		if declared[rule.ID] {
+			return nil, fmt.Errorf("affinity rule [%s] is declared more than once", rule.ID)
+		}
+		declared[rule.ID] = true
+
+		want := make(map[string]bool)
+		for _, instanceID := range spec.Instances {
+			want[instanceID] = true
+			desired.Members = append(desired.Members, AffinityRuleMember{AffinityRuleID: rule.ID, InstanceID: instanceID})
+		}
+
+		have := make(map[string]bool)
+		for _, member := range members[rule.ID] {
+			have[member.InstanceID] = true
+			if !want[member.InstanceID] {
+				plan.Operations = append(plan.Operations, AffinityMemberOperation{
+					Action:     EnvironmentActionDelete,
+					RuleID:     rule.ID,
+					RuleName:   rule.Name,
+					InstanceID: member.InstanceID,
+					MemberID:   member.ID,
+				})
+			}
+		}
+		for _, instanceID := range spec.Instances {
+			if !have[instanceID] {
+				creates = append(creates, AffinityMemberOperation{
+					Action:     EnvironmentActionCreate,
+					RuleID:     rule.ID,
+					RuleName:   rule.Name,
+					InstanceID: instanceID,
+				})
+			}
+		}
+	}
+	plan.Operations = append(plan.Operations, creates...)
+
+	for _, member := range set.Members {
+		if !declared[member.AffinityRuleID] {
+			desired.Members = append(desired.Members, member)
+		}
+	}
+	plan.Issues = ValidateAffinityRules(desired)
+
+	return plan, nil
+}
+
+func resolveAffinityRule(rules []AffinityRule, spec AffinityRuleDocumentRule) (AffinityRule, error) {
+	var matches []AffinityRule
+	for _, rule := range rules {
+		if (spec.ID != "" && rule.ID == spec.ID) || (spec.ID == "" && rule.Name == spec.Name) {
+			matches = append(matches, rule)
+		}
+	}
+
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case spec.ID != "":
+		return AffinityRule{}, &AffinityRuleNotFoundError{ID: spec.ID}
+	case len(matches) == 0:
+		return AffinityRule{}, fmt.Errorf("affinity rule [%s] not found", spec.Name)
+	}
+	return AffinityRule{}, fmt.Errorf("found %d affinity rules named [%s], specify id", len(matches), spec.Name)
+}
+
+// AffinityRulePlanOptions configures ApplyAffinityRulePlan
+type AffinityRulePlanOptions struct {
+	// Force applies plans which would leave rules unsatisfiable
+	Force bool
+	// Wait configures polling for tasks
+	Wait WaitOptions
+	// Progress is invoked after each operation is applied or fails
+	Progress func(op AffinityMemberOperation, err error)
+}
+
+// ApplyAffinityRulePlan applies the operations in plan in order, waiting for the task of each to
+// complete before continuing. Application stops at the first failure. Unsatisfiable plans are
+// rejected unless opts.Force is set
+func ApplyAffinityRulePlan(ctx context.Context, svc ECloudService, plan *AffinityRulePlan, opts AffinityRulePlanOptions) error {
+	if plan.Unsatisfiable() && !opts.Force {
+		return fmt.Errorf("plan would leave affinity rules unsatisfiable")
+	}
+
+	for _, op := range plan.Operations {
+		var taskID string
+		var err error
+		switch op.Action {
+		case EnvironmentActionCreate:
+			var task TaskReference
+			task, err = svc.CreateAffinityRuleMember(CreateAffinityRuleMemberRequest{AffinityRuleID: op.RuleID, InstanceID: op.InstanceID})
+			taskID = task.TaskID
+		case EnvironmentActionDelete:
+			taskID, err = svc.DeleteAffinityRuleMember(op.MemberID)
+		default:
+			err = fmt.Errorf("unsupported action [%s]", op.Action)
+		}
+		if err == nil {
+			_, err = WaitForTask(ctx, svc, taskID, TaskWaitOptions{WaitOptions: opts.Wait})
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(op, err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", op, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package ecloud
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+func testAffinityRuleSet() AffinityRuleSet {
+	return AffinityRuleSet{
+		Rules: []AffinityRule{
+			{ID: "ar-00000001", Name: "web-spread", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000001", Type: AntiAffinity},
+			{ID: "ar-00000002", Name: "db-pair", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000001", Type: Affinity},
+		},
+		Members: []AffinityRuleMember{
+			{ID: "arm-00000001", AffinityRuleID: "ar-00000001", InstanceID: "i-00000001"},
+			{ID: "arm-00000002", AffinityRuleID: "ar-00000001", InstanceID: "i-00000002"},
+			{ID: "arm-00000003", AffinityRuleID: "ar-00000002", InstanceID: "i-00000003"},
+			{ID: "arm-00000004", AffinityRuleID: "ar-00000002", InstanceID: "i-00000004"},
+		},
+		Instances: []Instance{
+			{ID: "i-00000001", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000001", HostGroupID: "hg-00000001"},
+			{ID: "i-00000002", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000001", HostGroupID: "hg-00000001"},
+			{ID: "i-00000003", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000001", HostGroupID: "hg-00000001"},
+			{ID: "i-00000004", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000001", HostGroupID: "hg-00000001"},
+			{ID: "i-00000005", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000001", HostGroupID: "hg-00000001"},
+			{ID: "i-00000006", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000002"},
+			{ID: "i-00000007", VPCID: "vpc-abcdef34", AvailabilityZoneID: "az-00000001", HostGroupID: "hg-00000002"},
+		},
+		Hosts: []Host{
+			{ID: "h-00000001", HostGroupID: "hg-00000001"},
+			{ID: "h-00000002", HostGroupID: "hg-00000001"},
+		},
+	}
+}
+
+func TestValidateAffinityRules(t *testing.T) {
+	t.Run("Valid_NoIssues", func(t *testing.T) {
+		issues := ValidateAffinityRules(testAffinityRuleSet())
+
+		assert.Empty(t, issues)
+	})
+
+	t.Run("Invalid_ReportsIssues", func(t *testing.T) {
+		set := testAffinityRuleSet()
+		set.Members = append(set.Members,
+			AffinityRuleMember{AffinityRuleID: "ar-00000001", InstanceID: "i-00000005"},
+			AffinityRuleMember{AffinityRuleID: "ar-00000001", InstanceID: "i-00000003"},
+			AffinityRuleMember{AffinityRuleID: "ar-00000001", InstanceID: "i-00000004"},
+			AffinityRuleMember{AffinityRuleID: "ar-00000002", InstanceID: "i-00000006"},
+			AffinityRuleMember{AffinityRuleID: "ar-00000002", InstanceID: "i-00000007"},
+			AffinityRuleMember{AffinityRuleID: "ar-00000002", InstanceID: "i-unknown"},
+		)
+		set.Rules = append(set.Rules, AffinityRule{ID: "ar-00000003", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000001", Type: AntiAffinity})
+
+		issues := ValidateAffinityRules(set)
+
+		var got []string
+		for _, issue := range issues {
+			got = append(got, issue.String())
+		}
+		assert.Equal(t, []string{
+			"insufficient_hosts: affinity rule [ar-00000001]: 5 members on host group [hg-00000001] with 2 hosts",
+			"availability_zone_mismatch: affinity rule [ar-00000002]: instance [i-00000006] is in availability zone [az-00000002], rule is in availability zone [az-00000001]",
+			"vpc_mismatch: affinity rule [ar-00000002]: instance [i-00000007] is in vpc [vpc-abcdef34], rule is in vpc [vpc-abcdef12]",
+			"member_not_found: affinity rule [ar-00000002]: instance [i-unknown] not found",
+			"split_host_groups: affinity rule [ar-00000002]: members are placed on shared, hg-00000001, hg-00000002",
+			"too_few_members: affinity rule [ar-00000003]: rule has 0 members",
+		}, got[:6])
+		assert.Equal(t, AffinityIssue{
+			Kind:          AffinityIssueConflictingRules,
+			RuleID:        "ar-00000001",
+			InstanceIDs:   []string{"i-00000003", "i-00000004"},
+			Unsatisfiable: true,
+			Detail:        "instances [i-00000003] and [i-00000004] are also members of affinity rule [ar-00000002]",
+		}, issues[6])
+		assert.False(t, issues[5].Unsatisfiable)
+	})
+}
+
+func TestLoadAffinityRuleDocument(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		doc, err := LoadAffinityRuleDocument(strings.NewReader("rules:\n  - name: web-spread\n    instances: [i-00000001, i-00000005]\n"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, AffinityRuleDocument{Rules: []AffinityRuleDocumentRule{{Name: "web-spread", Instances: []string{"i-00000001", "i-00000005"}}}}, doc)
+	})
+
+	t.Run("DuplicateInstance_ReturnsError", func(t *testing.T) {
+		_, err := LoadAffinityRuleDocument(strings.NewReader("rules:\n  - id: ar-00000001\n    instances: [i-00000001, i-00000001]\n"))
+
+		assert.Equal(t, "duplicate instance [i-00000001] for rule [ar-00000001]", err.Error())
+	})
+
+	t.Run("UnknownField_ReturnsError", func(t *testing.T) {
+		_, err := LoadAffinityRuleDocument(strings.NewReader("rules:\n  - name: web-spread\n    members: []\n"))
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestPlanAffinityRuleMembers(t *testing.T) {
+	t.Run("CreatesAndDeletes", func(t *testing.T) {
+		doc := AffinityRuleDocument{Rules: []AffinityRuleDocumentRule{{Name: "web-spread", Instances: []string{"i-00000001", "i-00000005"}}}}
+
+		plan, err := PlanAffinityRuleMembers(testAffinityRuleSet(), doc)
+
+		assert.Nil(t, err)
+		assert.False(t, plan.Unsatisfiable())
+		buf := new(bytes.Buffer)
+		assert.Nil(t, plan.Write(buf))
+		assert.Equal(t, "- affinity_rule/web-spread: i-00000002\n+ affinity_rule/web-spread: i-00000005\n", buf.String())
+	})
+
+	t.Run("Unsatisfiable_ReportsIssues", func(t *testing.T) {
+		doc := AffinityRuleDocument{Rules: []AffinityRuleDocumentRule{{ID: "ar-00000001", Instances: []string{"i-00000001", "i-00000002", "i-00000005"}}}}
+
+		plan, err := PlanAffinityRuleMembers(testAffinityRuleSet(), doc)
+
+		assert.Nil(t, err)
+		assert.True(t, plan.Unsatisfiable())
+		assert.Len(t, plan.Operations, 1)
+		assert.Equal(t, AffinityIssueInsufficientHosts, plan.Issues[0].Kind)
+	})
+
+	t.Run("InSync_Empty", func(t *testing.T) {
+		doc := AffinityRuleDocument{Rules: []AffinityRuleDocumentRule{{Name: "db-pair", Instances: []string{"i-00000004", "i-00000003"}}}}
+
+		plan, err := PlanAffinityRuleMembers(testAffinityRuleSet(), doc)
+
+		assert.Nil(t, err)
+		assert.True(t, plan.Empty())
+		assert.Empty(t, plan.Issues)
+	})
+
+	t.Run("UnknownRule_ReturnsError", func(t *testing.T) {
+		_, err := PlanAffinityRuleMembers(testAffinityRuleSet(), AffinityRuleDocument{Rules: []AffinityRuleDocumentRule{{Name: "unknown"}}})
+
+		assert.Equal(t, "affinity rule [unknown] not found", err.Error())
+	})
+}
+
+type fakeAffinityService struct {
+	fakeTaskService
+
+	calls []string
+}
+
+func (f *fakeAffinityService) GetAffinityRules(parameters connection.APIRequestParameters) ([]AffinityRule, error) {
+	return testAffinityRuleSet().Rules, nil
+}
+
+func (f *fakeAffinityService) GetAffinityRuleMembers(ruleID string, parameters connection.APIRequestParameters) ([]AffinityRuleMember, error) {
+	var members []AffinityRuleMember
+	for _, member := range testAffinityRuleSet().Members {
+		if member.AffinityRuleID == ruleID {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (f *fakeAffinityService) GetInstances(parameters connection.APIRequestParameters) ([]Instance, error) {
+	return testAffinityRuleSet().Instances, nil
+}
+
+func (f *fakeAffinityService) GetHosts(parameters connection.APIRequestParameters) ([]Host, error) {
+	return testAffinityRuleSet().Hosts, nil
+}
+
+func (f *fakeAffinityService) CreateAffinityRuleMember(req CreateAffinityRuleMemberRequest) (TaskReference, error) {
+	f.calls = append(f.calls, "create "+req.AffinityRuleID+" "+req.InstanceID)
+	return TaskReference{TaskID: "task-create"}, nil
+}
+
+func (f *fakeAffinityService) DeleteAffinityRuleMember(memberID string) (string, error) {
+	f.calls = append(f.calls, "delete "+memberID)
+	return "task-delete", nil
+}
+
+func TestApplyAffinityRulePlan(t *testing.T) {
+	opts := AffinityRulePlanOptions{Wait: WaitOptions{Interval: time.Second, Clock: newFakeClock()}}
+
+	t.Run("Valid", func(t *testing.T) {
+		svc := &fakeAffinityService{}
+		set, err := GetAffinityRuleSet(svc, "vpc-abcdef12")
+		assert.Nil(t, err)
+		assert.Equal(t, testAffinityRuleSet(), set)
+		plan, err := PlanAffinityRuleMembers(set, AffinityRuleDocument{Rules: []AffinityRuleDocumentRule{{Name: "web-spread", Instances: []string{"i-00000001", "i-00000005"}}}})
+		assert.Nil(t, err)
+
+		err = ApplyAffinityRulePlan(context.Background(), svc, plan, opts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"delete arm-00000002", "create ar-00000001 i-00000005"}, svc.calls)
+	})
+
+	t.Run("Unsatisfiable_ReturnsError", func(t *testing.T) {
+		svc := &fakeAffinityService{}
+		plan := &AffinityRulePlan{
+			Operations: []AffinityMemberOperation{{Action: EnvironmentActionCreate, RuleID: "ar-00000001", InstanceID: "i-00000005"}},
+			Issues:     []AffinityIssue{{Kind: AffinityIssueInsufficientHosts, Unsatisfiable: true}},
+		}
+
+		err := ApplyAffinityRulePlan(context.Background(), svc, plan, opts)
+
+		assert.NotNil(t, err)
+		assert.Empty(t, svc.calls)
+
+		opts.Force = true
+		err = ApplyAffinityRulePlan(context.Background(), svc, plan, opts)
+
+		assert.Nil(t, err)
+		assert.Len(t, svc.calls, 1)
+	})
+}
@@ -0,0 +1,262 @@
+package ecloud
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"gopkg.in/yaml.v3"
+)
+
+type BillingPeriod string
+
+func (s BillingPeriod) String() string {
+	return string(s)
+}
+
+const (
+	BillingPeriodDay   BillingPeriod = "day"
+	BillingPeriodMonth BillingPeriod = "month"
+)
+
+var BillingPeriodEnum connection.Enum[BillingPeriod] = []BillingPeriod{
+	BillingPeriodDay,
+	BillingPeriodMonth,
+}
+
+// bucket returns the label of the period containing t
+func (s BillingPeriod) bucket(t time.Time) string {
+	if s == BillingPeriodDay {
+		return t.UTC().Format("2006-01-02")
+	}
+	return t.UTC().Format("2006-01")
+}
+
+// BillingRateCard maps billing metric keys to the cost of a single unit of the metric's value
+type BillingRateCard map[string]float64
+
+// LoadBillingRateCard reads a rate card from r, encoded as a YAML (or JSON) map of metric key to unit cost
+func LoadBillingRateCard(r io.Reader) (BillingRateCard, error) {
+	var card BillingRateCard
+	err := yaml.NewDecoder(r).Decode(&card)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode rate card: %w", err)
+	}
+	return card, nil
+}
+
+// BillingReportOptions configures GetBillingReport and AggregateBillingMetrics
+type BillingReportOptions struct {
+	// From and To bound the report. Metrics are included when their start falls within [From, To).
+	// A zero From or To leaves that end of the range open
+	From time.Time
+	To   time.Time
+	// VPCID restricts the report to a single VPC
+	VPCID string
+	// Period is the time bucket metrics are aggregated into, defaulting to BillingPeriodMonth
+	Period BillingPeriod
+	// ByResource aggregates per resource within each VPC, rather than per VPC
+	ByResource bool
+	// RateCard, when set, is used to estimate the cost of each row
+	RateCard BillingRateCard
+}
+
+// BillingReportRow is the aggregated value of a billing metric key for a VPC (or resource) and period
+type BillingReportRow struct {
+	Period       string   `json:"period"`
+	VPCID        string   `json:"vpc_id"`
+	ResourceID   string   `json:"resource_id,omitempty"`
+	ResourceName string   `json:"resource_name,omitempty"`
+	Key          string   `json:"key"`
+	Value        int      `json:"value"`
+	Cost         *float64 `json:"cost,omitempty"`
+}
+
+// BillingReport is a summary of billing metrics. Rows are sorted by period, VPC, resource and key
+type BillingReport struct {
+	Period BillingPeriod      `json:"period"`
+	Rows   []BillingReportRow `json:"rows"`
+}
+
+// TotalCost returns the sum of the estimated cost of each row. Rows without a rate are excluded
+func (r *BillingReport) TotalCost() float64 {
+	var total float64
+	for _, row := range r.Rows {
+		if row.Cost != nil {
+			total += *row.Cost
+		}
+	}
+	return total
+}
+
+// WriteJSON writes the report to w as indented JSON
+func (r *BillingReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes the report rows to w as CSV with a header row. The cost column is empty for rows
+// without a rate
+func (r *BillingReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"period", "vpc_id", "resource_id", "resource_name", "key", "value", "cost"})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range r.Rows {
+		cost := ""
+		if row.Cost != nil {
+			cost = strconv.FormatFloat(*row.Cost, 'f', 2, 64)
+		}
+		err := cw.Write([]string{row.Period, row.VPCID, row.ResourceID, row.ResourceName, row.Key, strconv.Itoa(row.Value), cost})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// GetBillingReport retrieves billing metrics within the range and VPC given by opts and aggregates
+// them with AggregateBillingMetrics. When opts.ByResource is set, resource names are joined from
+// instances and volumes
+func GetBillingReport(svc ECloudService, opts BillingReportOptions) (*BillingReport, error) {
+	params := connection.NewAPIRequestParameters()
+	if opts.VPCID != "" {
+		params.WithFilter(connection.APIRequestFiltering{Property: "vpc_id", Operator: connection.EQOperator, Value: []string{opts.VPCID}})
+	}
+	if !opts.From.IsZero() {
+		params.WithFilter(connection.APIRequestFiltering{Property: "start", Operator: connection.GTOperator, Value: []string{opts.From.Add(-time.Second).UTC().Format(time.RFC3339)}})
+	}
+	if !opts.To.IsZero() {
+		params.WithFilter(connection.APIRequestFiltering{Property: "start", Operator: connection.LTOperator, Value: []string{opts.To.UTC().Format(time.RFC3339)}})
+	}
+
+	metrics, err := svc.GetBillingMetrics(*params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve billing metrics: %w", err)
+	}
+
+	var names map[string]string
+	if opts.ByResource {
+		names, err = billingResourceNames(svc, opts.VPCID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return AggregateBillingMetrics(metrics, names, opts)
+}
+
+func billingResourceNames(svc ECloudService, vpcID string) (map[string]string, error) {
+	params := connection.APIRequestParameters{}
+	if vpcID != "" {
+		params = eqFilter("vpc_id", vpcID)
+	}
+
+	names := make(map[string]string)
+	instances, err := svc.GetInstances(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve instances: %w", err)
+	}
+	for _, instance := range instances {
+		names[instance.ID] = instance.Name
+	}
+
+	volumes, err := svc.GetVolumes(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve volumes: %w", err)
+	}
+	for _, volume := range volumes {
+		names[volume.ID] = volume.Name
+	}
+
+	return names, nil
+}
+
+// AggregateBillingMetrics sums metrics by VPC (or resource, when opts.ByResource is set), key and
+// period. Metrics are assigned to the period containing their start, and aren't apportioned across
+// periods. names optionally maps resource IDs to names
+func AggregateBillingMetrics(metrics []BillingMetric, names map[string]string, opts BillingReportOptions) (*BillingReport, error) {
+	period := BillingPeriodMonth
+	if opts.Period != "" {
+		var err error
+		period, err = BillingPeriodEnum.Parse(opts.Period.String())
+		if err != nil {
+			return nil, fmt.Errorf("invalid period [%s]", opts.Period)
+		}
+	}
+
+	type rowKey struct {
+		period     string
+		vpcID      string
+		resourceID string
+		key        string
+	}
+	rows := make(map[rowKey]*BillingReportRow)
+
+	for _, metric := range metrics {
+		start, err := parseBillingTime(metric.Start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start for billing metric [%s]: %w", metric.ID, err)
+		}
+		if (!opts.From.IsZero() && start.Before(opts.From)) || (!opts.To.IsZero() && !start.Before(opts.To)) {
+			continue
+		}
+		if opts.VPCID != "" && metric.VPCID != opts.VPCID {
+			continue
+		}
+
+		k := rowKey{period: period.bucket(start), vpcID: metric.VPCID, key: metric.Key}
+		if opts.ByResource {
+			k.resourceID = metric.ResourceID
+		}
+		row, ok := rows[k]
+		if !ok {
+			row = &BillingReportRow{Period: k.period, VPCID: k.vpcID, ResourceID: k.resourceID, ResourceName: names[k.resourceID], Key: k.key}
+			rows[k] = row
+		}
+		row.Value += metric.Value
+	}
+
+	report := &BillingReport{Period: period}
+	for _, row := range rows {
+		if rate, ok := opts.RateCard[row.Key]; ok {
+			cost := rate * float64(row.Value)
+			row.Cost = &cost
+		}
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		if a.VPCID != b.VPCID {
+			return a.VPCID < b.VPCID
+		}
+		if a.ResourceID != b.ResourceID {
+			return a.ResourceID < b.ResourceID
+		}
+		return a.Key < b.Key
+	})
+
+	return report, nil
+}
+
+// parseBillingTime parses d, accepting both numeric zone offsets with and without a colon
+func parseBillingTime(d connection.DateTime) (time.Time, error) {
+	t := d.Time()
+	if !t.IsZero() {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, d.String())
+}
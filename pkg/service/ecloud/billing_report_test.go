@@ -0,0 +1,151 @@
+package ecloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeBillingService struct {
+	ECloudService
+
+	metrics    []BillingMetric
+	parameters connection.APIRequestParameters
+}
+
+func (f *fakeBillingService) GetBillingMetrics(parameters connection.APIRequestParameters) ([]BillingMetric, error) {
+	f.parameters = parameters
+	return f.metrics, nil
+}
+
+func (f *fakeBillingService) GetInstances(parameters connection.APIRequestParameters) ([]Instance, error) {
+	return []Instance{{ID: "i-abcdef12", Name: "web"}}, nil
+}
+
+func (f *fakeBillingService) GetVolumes(parameters connection.APIRequestParameters) ([]Volume, error) {
+	return []Volume{{ID: "vol-abcdef12", Name: "web-data"}}, nil
+}
+
+func testBillingMetrics() []BillingMetric {
+	return []BillingMetric{
+		{ID: "1", VPCID: "vpc-abcdef12", ResourceID: "i-abcdef12", Key: "ram.capacity", Value: 2, Start: "2024-01-01T00:00:00+0000"},
+		{ID: "2", VPCID: "vpc-abcdef12", ResourceID: "i-abcdef12", Key: "ram.capacity", Value: 4, Start: "2024-01-15T00:00:00+00:00"},
+		{ID: "3", VPCID: "vpc-abcdef12", ResourceID: "vol-abcdef12", Key: "disk.capacity", Value: 40, Start: "2024-01-20T00:00:00+0000"},
+		{ID: "4", VPCID: "vpc-abcdef12", ResourceID: "i-abcdef12", Key: "ram.capacity", Value: 8, Start: "2024-02-01T00:00:00+0000"},
+		{ID: "5", VPCID: "vpc-abcdef34", ResourceID: "i-abcdef34", Key: "ram.capacity", Value: 1, Start: "2024-01-03T00:00:00+0000"},
+		{ID: "6", VPCID: "vpc-abcdef12", ResourceID: "i-abcdef12", Key: "ram.capacity", Value: 100, Start: "2023-12-31T23:00:00+0000"},
+	}
+}
+
+func TestAggregateBillingMetrics(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("MonthlyPerVPC", func(t *testing.T) {
+		report, err := AggregateBillingMetrics(testBillingMetrics(), nil, BillingReportOptions{From: from, To: to})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []BillingReportRow{
+			{Period: "2024-01", VPCID: "vpc-abcdef12", Key: "disk.capacity", Value: 40},
+			{Period: "2024-01", VPCID: "vpc-abcdef12", Key: "ram.capacity", Value: 6},
+			{Period: "2024-01", VPCID: "vpc-abcdef34", Key: "ram.capacity", Value: 1},
+			{Period: "2024-02", VPCID: "vpc-abcdef12", Key: "ram.capacity", Value: 8},
+		}, report.Rows)
+	})
+
+	t.Run("DailyPerResource", func(t *testing.T) {
+		report, err := AggregateBillingMetrics(testBillingMetrics(), map[string]string{"i-abcdef12": "web"}, BillingReportOptions{
+			From:       from,
+			To:         time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
+			VPCID:      "vpc-abcdef12",
+			Period:     BillingPeriodDay,
+			ByResource: true,
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []BillingReportRow{
+			{Period: "2024-01-01", VPCID: "vpc-abcdef12", ResourceID: "i-abcdef12", ResourceName: "web", Key: "ram.capacity", Value: 2},
+			{Period: "2024-01-15", VPCID: "vpc-abcdef12", ResourceID: "i-abcdef12", ResourceName: "web", Key: "ram.capacity", Value: 4},
+		}, report.Rows)
+	})
+
+	t.Run("RateCard_EstimatesCost", func(t *testing.T) {
+		card, err := LoadBillingRateCard(strings.NewReader("ram.capacity: 1.5\n"))
+		assert.Nil(t, err)
+
+		report, err := AggregateBillingMetrics(testBillingMetrics(), nil, BillingReportOptions{From: from, To: to, RateCard: card})
+
+		assert.Nil(t, err)
+		assert.Nil(t, report.Rows[0].Cost)
+		assert.Equal(t, 9.0, *report.Rows[1].Cost)
+		assert.Equal(t, 22.5, report.TotalCost())
+	})
+
+	t.Run("InvalidPeriod_ReturnsError", func(t *testing.T) {
+		_, err := AggregateBillingMetrics(testBillingMetrics(), nil, BillingReportOptions{Period: "week"})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid period [week]", err.Error())
+	})
+
+	t.Run("InvalidStart_ReturnsError", func(t *testing.T) {
+		_, err := AggregateBillingMetrics([]BillingMetric{{ID: "1", Start: "yesterday"}}, nil, BillingReportOptions{})
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestGetBillingReport(t *testing.T) {
+	svc := &fakeBillingService{metrics: testBillingMetrics()}
+
+	report, err := GetBillingReport(svc, BillingReportOptions{
+		From:       time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		VPCID:      "vpc-abcdef12",
+		ByResource: true,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []BillingReportRow{
+		{Period: "2024-02", VPCID: "vpc-abcdef12", ResourceID: "i-abcdef12", ResourceName: "web", Key: "ram.capacity", Value: 8},
+	}, report.Rows)
+	assert.Equal(t, "vpc-abcdef12", filterValue(svc.parameters, "vpc_id"))
+}
+
+func TestBillingReport_Write(t *testing.T) {
+	cost := 12.5
+	report := &BillingReport{
+		Period: BillingPeriodMonth,
+		Rows: []BillingReportRow{
+			{Period: "2024-01", VPCID: "vpc-abcdef12", Key: "ram.capacity", Value: 6, Cost: &cost},
+			{Period: "2024-01", VPCID: "vpc-abcdef12", ResourceID: "vol-abcdef12", ResourceName: "data, logs", Key: "disk.capacity", Value: 40},
+		},
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+
+		err := report.WriteCSV(buf)
+
+		assert.Nil(t, err)
+		assert.Equal(t, `period,vpc_id,resource_id,resource_name,key,value,cost
+2024-01,vpc-abcdef12,,,ram.capacity,6,12.50
+2024-01,vpc-abcdef12,vol-abcdef12,"data, logs",disk.capacity,40,
+`, buf.String())
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+
+		err := report.WriteJSON(buf)
+
+		assert.Nil(t, err)
+		var decoded BillingReport
+		assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, *report, decoded)
+	})
+}
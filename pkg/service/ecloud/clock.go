@@ -0,0 +1,29 @@
+package ecloud
+
+import "time"
+
+// Clock provides the current time and timers to helpers which poll or schedule, allowing a fake
+// clock to be substituted in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock implements Clock using the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// clockOrDefault returns c, or the real clock where c is nil
+func clockOrDefault(c Clock) Clock {
+	if c == nil {
+		return realClock{}
+	}
+	return c
+}
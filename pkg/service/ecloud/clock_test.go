@@ -0,0 +1,33 @@
+package ecloud
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock is a Clock which advances immediately when After is called, recording each delay
+type fakeClock struct {
+	now    time.Time
+	delays []time.Duration
+	mutex  sync.Mutex
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	c.delays = append(c.delays, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
@@ -0,0 +1,283 @@
+package ecloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Resource kinds addressed by environment plans and reported by TeardownVPC
+const (
+	ResourceKindVPNSession     = "vpn_session"
+	ResourceKindVPNEndpoint    = "vpn_endpoint"
+	ResourceKindVPNService     = "vpn_service"
+	ResourceKindVIP            = "vip"
+	ResourceKindLoadBalancer   = "load_balancer"
+	ResourceKindFloatingIP     = "floating_ip"
+	ResourceKindInstance       = "instance"
+	ResourceKindNIC            = "nic"
+	ResourceKindVolume         = "volume"
+	ResourceKindFirewallPolicy = "firewall_policy"
+	ResourceKindFirewallRule   = "firewall_rule"
+	ResourceKindNetworkPolicy  = "network_policy"
+	ResourceKindNetwork        = "network"
+	ResourceKindRouter         = "router"
+	ResourceKindVPC            = "vpc"
+)
+
+// EnvironmentSpec declares the desired state of a VPC and the resources within it. Resources are
+// matched against live state by name, and additionally by tag for instances. For example:
+//
+//	name: prod
+//	region_id: reg-abcdef12
+//	availability_zone_id: az-abcdef12
+//	deploy_defaults: true
+//	routers:
+//	  - name: main
+//	    networks:
+//	      - name: web
+//	        subnet: 10.0.0.0/24
+//	    firewall_policies:
+//	      - name: web
+//	        sequence: 10
+//	        rules:
+//	          - name: https
+//	            sequence: 1
+//	            source: ANY
+//	            destination: 10.0.0.0/24
+//	            action: ALLOW
+//	            direction: IN
+//	            ports:
+//	              - protocol: TCP
+//	                destination: "443"
+//	instances:
+//	  - name: web-01
+//	    network: web
+//	    image_id: img-abcdef12
+//	    vcpu_cores: 2
+//	    ram_capacity: 2048
+//	    volume_capacity: 40
+//	    floating_ip: true
+type EnvironmentSpec struct {
+	// Name is the name of the VPC
+	Name               string `yaml:"name"`
+	RegionID           string `yaml:"region_id"`
+	AvailabilityZoneID string `yaml:"availability_zone_id"`
+	ClientID           int    `yaml:"client_id,omitempty"`
+	AdvancedNetworking *bool  `yaml:"advanced_networking,omitempty"`
+	// DeployDefaults deploys the default router, network and firewall policies when the VPC is created
+	DeployDefaults bool                  `yaml:"deploy_defaults,omitempty"`
+	Routers        []RouterSpec          `yaml:"routers,omitempty"`
+	Instances      []EnvironmentInstance `yaml:"instances,omitempty"`
+}
+
+// RouterSpec declares a router along with its networks and firewall policies
+type RouterSpec struct {
+	Name               string               `yaml:"name"`
+	RouterThroughputID string               `yaml:"router_throughput_id,omitempty"`
+	Networks           []NetworkSpec        `yaml:"networks,omitempty"`
+	FirewallPolicies   []FirewallPolicySpec `yaml:"firewall_policies,omitempty"`
+}
+
+// NetworkSpec declares a network
+type NetworkSpec struct {
+	Name   string `yaml:"name"`
+	Subnet string `yaml:"subnet"`
+}
+
+// FirewallPolicySpec declares a firewall policy and its rules
+type FirewallPolicySpec struct {
+	Name     string             `yaml:"name"`
+	Sequence int                `yaml:"sequence"`
+	Rules    []FirewallRuleSpec `yaml:"rules,omitempty"`
+}
+
+// FirewallRuleSpec declares a firewall rule. Ports are applied when the rule is created only
+type FirewallRuleSpec struct {
+	Name        string                 `yaml:"name"`
+	Sequence    int                    `yaml:"sequence"`
+	Source      string                 `yaml:"source"`
+	Destination string                 `yaml:"destination"`
+	Action      FirewallRuleAction     `yaml:"action"`
+	Direction   FirewallRuleDirection  `yaml:"direction"`
+	Enabled     *bool                  `yaml:"enabled,omitempty"`
+	Ports       []FirewallRulePortSpec `yaml:"ports,omitempty"`
+}
+
+// FirewallRulePortSpec declares a firewall rule port
+type FirewallRulePortSpec struct {
+	Protocol    FirewallRulePortProtocol `yaml:"protocol"`
+	Source      string                   `yaml:"source,omitempty"`
+	Destination string                   `yaml:"destination,omitempty"`
+}
+
+// EnvironmentInstance declares an instance
+type EnvironmentInstance struct {
+	Name string `yaml:"name"`
+	// Network is the name of a network declared within Routers
+	Network        string   `yaml:"network"`
+	ImageID        string   `yaml:"image_id"`
+	VCPUCores      int      `yaml:"vcpu_cores"`
+	RAMCapacity    int      `yaml:"ram_capacity"`
+	VolumeCapacity int      `yaml:"volume_capacity"`
+	VolumeIOPS     int      `yaml:"volume_iops,omitempty"`
+	Locked         bool     `yaml:"locked,omitempty"`
+	SSHKeyPairIDs  []string `yaml:"ssh_key_pair_ids,omitempty"`
+	// TagIDs are applied to the instance on creation, and must all be present on an existing
+	// instance for it to match
+	TagIDs []string `yaml:"tag_ids,omitempty"`
+	// FloatingIP creates and assigns a floating IP to the instance
+	FloatingIP bool `yaml:"floating_ip,omitempty"`
+}
+
+// LoadEnvironmentSpec reads and validates an environment spec in YAML format
+func LoadEnvironmentSpec(r io.Reader) (EnvironmentSpec, error) {
+	var spec EnvironmentSpec
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	err := dec.Decode(&spec)
+	if err != nil {
+		return spec, fmt.Errorf("failed to decode environment spec: %w", err)
+	}
+
+	return spec, spec.Validate()
+}
+
+// LoadEnvironmentSpecFile reads and validates an environment spec from the YAML file at path
+func LoadEnvironmentSpecFile(path string) (EnvironmentSpec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return EnvironmentSpec{}, err
+	}
+	defer f.Close()
+
+	return LoadEnvironmentSpec(f)
+}
+
+// Validate checks the spec for missing fields, duplicate names and unknown network references
+func (s EnvironmentSpec) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if s.RegionID == "" {
+		return fmt.Errorf("region_id is required")
+	}
+	if s.AvailabilityZoneID == "" && (len(s.Routers) > 0 || len(s.Instances) > 0) {
+		return fmt.Errorf("availability_zone_id is required when declaring routers or instances")
+	}
+
+	routers := make(map[string]bool)
+	networks := make(map[string]bool)
+	for _, router := range s.Routers {
+		if router.Name == "" {
+			return fmt.Errorf("router name is required")
+		}
+		if routers[router.Name] {
+			return fmt.Errorf("duplicate router [%s]", router.Name)
+		}
+		routers[router.Name] = true
+
+		for _, network := range router.Networks {
+			if network.Name == "" || network.Subnet == "" {
+				return fmt.Errorf("network name and subnet are required for router [%s]", router.Name)
+			}
+			if networks[network.Name] {
+				return fmt.Errorf("duplicate network [%s]", network.Name)
+			}
+			networks[network.Name] = true
+		}
+
+		policies := make(map[string]bool)
+		for _, policy := range router.FirewallPolicies {
+			if policy.Name == "" {
+				return fmt.Errorf("firewall policy name is required for router [%s]", router.Name)
+			}
+			if policies[policy.Name] {
+				return fmt.Errorf("duplicate firewall policy [%s] for router [%s]", policy.Name, router.Name)
+			}
+			policies[policy.Name] = true
+
+			rules := make(map[string]bool)
+			for _, rule := range policy.Rules {
+				if rule.Name == "" {
+					return fmt.Errorf("firewall rule name is required for firewall policy [%s]", policy.Name)
+				}
+				if rules[rule.Name] {
+					return fmt.Errorf("duplicate firewall rule [%s] for firewall policy [%s]", rule.Name, policy.Name)
+				}
+				rules[rule.Name] = true
+			}
+		}
+	}
+
+	instances := make(map[string]bool)
+	for _, instance := range s.Instances {
+		if instance.Name == "" {
+			return fmt.Errorf("instance name is required")
+		}
+		if instances[instance.Name] {
+			return fmt.Errorf("duplicate instance [%s]", instance.Name)
+		}
+		instances[instance.Name] = true
+
+		if !networks[instance.Network] {
+			return fmt.Errorf("instance [%s] references unknown network [%s]", instance.Name, instance.Network)
+		}
+		if instance.ImageID == "" {
+			return fmt.Errorf("image_id is required for instance [%s]", instance.Name)
+		}
+	}
+
+	return nil
+}
+
+// EnvironmentState records the IDs of resources managed by an environment spec, keyed by
+// address. It is updated as changes are applied, allowing a partially applied plan to be
+// resumed by planning again with the same state
+type EnvironmentState struct {
+	Resources map[string]string `json:"resources"`
+}
+
+// NewEnvironmentState returns an empty EnvironmentState
+func NewEnvironmentState() *EnvironmentState {
+	return &EnvironmentState{Resources: make(map[string]string)}
+}
+
+// ReadEnvironmentState reads state in JSON format
+func ReadEnvironmentState(r io.Reader) (*EnvironmentState, error) {
+	state := NewEnvironmentState()
+	err := json.NewDecoder(r).Decode(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode environment state: %w", err)
+	}
+	if state.Resources == nil {
+		state.Resources = make(map[string]string)
+	}
+	return state, nil
+}
+
+// Write writes state in JSON format
+func (s *EnvironmentState) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// ID returns the recorded ID for address, or an empty string
+func (s *EnvironmentState) ID(address string) string {
+	if s == nil {
+		return ""
+	}
+	return s.Resources[address]
+}
+
+func (s *EnvironmentState) set(address, id string) {
+	if id == "" {
+		delete(s.Resources, address)
+		return
+	}
+	s.Resources[address] = id
+}
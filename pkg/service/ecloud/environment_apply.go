@@ -0,0 +1,353 @@
+package ecloud
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+)
+
+// EnvironmentApplyOptions configures ApplyEnvironment
+type EnvironmentApplyOptions struct {
+	// Wait configures polling for tasks and resource syncs
+	Wait WaitOptions
+	// SaveState is invoked whenever state is updated, allowing progress to be persisted so that
+	// a failed apply can be resumed
+	SaveState func(state *EnvironmentState) error
+	// Progress is invoked after each change is applied or fails
+	Progress func(change EnvironmentChange, err error)
+}
+
+// EnvironmentApplyError indicates a change in an EnvironmentPlan failed to apply. Changes prior
+// to Change were applied and recorded in state
+type EnvironmentApplyError struct {
+	Change EnvironmentChange
+	Err    error
+}
+
+func (e *EnvironmentApplyError) Error() string {
+	return fmt.Sprintf("failed to %s: %s", e.Change, e.Err)
+}
+
+func (e *EnvironmentApplyError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyEnvironment applies the changes in plan in order, waiting for each to complete and recording
+// the IDs of resources in state. On failure an *EnvironmentApplyError is returned; the apply can be
+// resumed by planning again with the same state
+func ApplyEnvironment(ctx context.Context, svc ECloudService, plan *EnvironmentPlan, state *EnvironmentState, opts EnvironmentApplyOptions) error {
+	if state == nil {
+		return fmt.Errorf("state is required")
+	}
+	for _, change := range plan.Changes {
+		if change.apply == nil {
+			return fmt.Errorf("change [%s] has no action, plans must be produced by PlanEnvironment", change.Address)
+		}
+	}
+	if state.Resources == nil {
+		state.Resources = make(map[string]string)
+	}
+
+	a := &environmentApplier{
+		svc:   svc,
+		state: state,
+		opts:  opts,
+	}
+
+	for address, id := range plan.unchanged {
+		state.set(address, id)
+	}
+	err := a.save()
+	if err != nil {
+		return err
+	}
+
+	for _, change := range plan.Changes {
+		id, err := change.apply(ctx, a)
+		if err == nil {
+			state.set(change.Address, id)
+			err = a.save()
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(change, err)
+		}
+		if err != nil {
+			return &EnvironmentApplyError{Change: change, Err: err}
+		}
+	}
+
+	return nil
+}
+
+type environmentApplier struct {
+	svc   ECloudService
+	state *EnvironmentState
+	opts  EnvironmentApplyOptions
+}
+
+func (a *environmentApplier) save() error {
+	if a.opts.SaveState == nil {
+		return nil
+	}
+	err := a.opts.SaveState(a.state)
+	if err != nil {
+		return fmt.Errorf("failed to save environment state: %w", err)
+	}
+	return nil
+}
+
+// record stores the ID for address in state prior to a change completing
+func (a *environmentApplier) record(address, id string) error {
+	a.state.set(address, id)
+	return a.save()
+}
+
+func (a *environmentApplier) waitTask(ctx context.Context, taskID string) error {
+	_, err := WaitForTask(ctx, a.svc, taskID, TaskWaitOptions{WaitOptions: a.opts.Wait})
+	return err
+}
+
+func waitForSync[T any](ctx context.Context, a *environmentApplier, get func(id string) (T, error), id string, sync func(T) ResourceSync) error {
+	_, err := WaitForSync(ctx, get, id, sync, SyncWaitOptions[T]{WaitOptions: a.opts.Wait})
+	return err
+}
+
+func (a *environmentApplier) createVPC(ctx context.Context, address string, spec EnvironmentSpec) (string, error) {
+	id, err := a.svc.CreateVPC(CreateVPCRequest{
+		Name:               spec.Name,
+		RegionID:           spec.RegionID,
+		ClientID:           spec.ClientID,
+		AdvancedNetworking: spec.AdvancedNetworking,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Record the VPC before waiting so a failure doesn't result in a second VPC
+	err = a.record(address, id)
+	if err != nil {
+		return id, err
+	}
+	vpcSync := func(v VPC) ResourceSync { return v.Sync }
+	err = waitForSync(ctx, a, a.svc.GetVPC, id, vpcSync)
+	if err != nil || !spec.DeployDefaults {
+		return id, err
+	}
+
+	err = a.svc.DeployVPCDefaults(id)
+	if err != nil {
+		return id, fmt.Errorf("failed to deploy vpc defaults: %w", err)
+	}
+	return id, waitForSync(ctx, a, a.svc.GetVPC, id, vpcSync)
+}
+
+func (a *environmentApplier) createRouter(ctx context.Context, address string, spec RouterSpec, vpcID string, availabilityZoneID string) (string, error) {
+	id, err := a.svc.CreateRouter(CreateRouterRequest{
+		Name:               spec.Name,
+		VPCID:              vpcID,
+		AvailabilityZoneID: availabilityZoneID,
+		RouterThroughputID: spec.RouterThroughputID,
+	})
+	if err != nil {
+		return "", err
+	}
+	err = a.record(address, id)
+	if err != nil {
+		return id, err
+	}
+	return id, waitForSync(ctx, a, a.svc.GetRouter, id, func(r Router) ResourceSync { return r.Sync })
+}
+
+func (a *environmentApplier) updateRouter(ctx context.Context, id string, spec RouterSpec) (string, error) {
+	err := a.svc.PatchRouter(id, PatchRouterRequest{RouterThroughputID: spec.RouterThroughputID})
+	if err != nil {
+		return id, err
+	}
+	return id, waitForSync(ctx, a, a.svc.GetRouter, id, func(r Router) ResourceSync { return r.Sync })
+}
+
+func (a *environmentApplier) createNetwork(ctx context.Context, address string, routerID string, spec NetworkSpec) (string, error) {
+	id, err := a.svc.CreateNetwork(CreateNetworkRequest{
+		Name:     spec.Name,
+		RouterID: routerID,
+		Subnet:   spec.Subnet,
+	})
+	if err != nil {
+		return "", err
+	}
+	err = a.record(address, id)
+	if err != nil {
+		return id, err
+	}
+	return id, waitForSync(ctx, a, a.svc.GetNetwork, id, func(n Network) ResourceSync { return n.Sync })
+}
+
+func (a *environmentApplier) createFirewallPolicy(ctx context.Context, address string, routerID string, spec FirewallPolicySpec) (string, error) {
+	ref, err := a.svc.CreateFirewallPolicy(CreateFirewallPolicyRequest{
+		Name:     spec.Name,
+		RouterID: routerID,
+		Sequence: spec.Sequence,
+	})
+	if err != nil {
+		return "", err
+	}
+	err = a.record(address, ref.ResourceID)
+	if err != nil {
+		return ref.ResourceID, err
+	}
+	return ref.ResourceID, a.waitTask(ctx, ref.TaskID)
+}
+
+func (a *environmentApplier) updateFirewallPolicy(ctx context.Context, id string, spec FirewallPolicySpec) (string, error) {
+	ref, err := a.svc.PatchFirewallPolicy(id, PatchFirewallPolicyRequest{Sequence: &spec.Sequence})
+	if err != nil {
+		return id, err
+	}
+	return id, a.waitTask(ctx, ref.TaskID)
+}
+
+func (a *environmentApplier) createFirewallRule(ctx context.Context, address string, policyID string, spec FirewallRuleSpec) (string, error) {
+	req := CreateFirewallRuleRequest{
+		Name:             spec.Name,
+		FirewallPolicyID: policyID,
+		Sequence:         spec.Sequence,
+		Source:           spec.Source,
+		Destination:      spec.Destination,
+		Action:           spec.Action,
+		Direction:        spec.Direction,
+		Enabled:          firewallRuleEnabled(spec),
+	}
+	for _, port := range spec.Ports {
+		req.Ports = append(req.Ports, CreateFirewallRulePortRequest{
+			Protocol:    port.Protocol,
+			Source:      port.Source,
+			Destination: port.Destination,
+		})
+	}
+
+	ref, err := a.svc.CreateFirewallRule(req)
+	if err != nil {
+		return "", err
+	}
+	err = a.record(address, ref.ResourceID)
+	if err != nil {
+		return ref.ResourceID, err
+	}
+	return ref.ResourceID, a.waitTask(ctx, ref.TaskID)
+}
+
+func (a *environmentApplier) updateFirewallRule(ctx context.Context, id string, spec FirewallRuleSpec) (string, error) {
+	enabled := firewallRuleEnabled(spec)
+	ref, err := a.svc.PatchFirewallRule(id, PatchFirewallRuleRequest{
+		Sequence:    &spec.Sequence,
+		Source:      spec.Source,
+		Destination: spec.Destination,
+		Action:      spec.Action,
+		Direction:   spec.Direction,
+		Enabled:     &enabled,
+	})
+	if err != nil {
+		return id, err
+	}
+	return id, a.waitTask(ctx, ref.TaskID)
+}
+
+func (a *environmentApplier) createInstance(ctx context.Context, address string, vpcID string, networkID string, spec EnvironmentInstance) (string, error) {
+	id, err := a.svc.CreateInstance(CreateInstanceRequest{
+		Name:           spec.Name,
+		VPCID:          vpcID,
+		ImageID:        spec.ImageID,
+		VCPUCores:      spec.VCPUCores,
+		RAMCapacity:    spec.RAMCapacity,
+		VolumeCapacity: spec.VolumeCapacity,
+		VolumeIOPS:     spec.VolumeIOPS,
+		Locked:         spec.Locked,
+		NetworkID:      networkID,
+		SSHKeyPairIDs:  spec.SSHKeyPairIDs,
+		TagIDs:         spec.TagIDs,
+	})
+	if err != nil {
+		return "", err
+	}
+	err = a.record(address, id)
+	if err != nil {
+		return id, err
+	}
+	return id, waitForSync(ctx, a, a.svc.GetInstance, id, func(i Instance) ResourceSync { return i.Sync })
+}
+
+func (a *environmentApplier) updateInstance(ctx context.Context, id string, spec EnvironmentInstance) (string, error) {
+	err := a.svc.PatchInstance(id, PatchInstanceRequest{
+		VCPUCores:   spec.VCPUCores,
+		RAMCapacity: spec.RAMCapacity,
+	})
+	if err != nil {
+		return id, err
+	}
+	return id, waitForSync(ctx, a, a.svc.GetInstance, id, func(i Instance) ResourceSync { return i.Sync })
+}
+
+func (a *environmentApplier) createFloatingIP(ctx context.Context, address string, vpcID string, availabilityZoneID string, instanceID string, spec EnvironmentInstance) (string, error) {
+	ref, err := a.svc.CreateFloatingIP(CreateFloatingIPRequest{
+		Name:               spec.Name,
+		VPCID:              vpcID,
+		AvailabilityZoneID: availabilityZoneID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Record the floating IP prior to assignment so a failed assignment is retried rather than
+	// creating another floating IP
+	err = a.record(address, ref.ResourceID)
+	if err != nil {
+		return ref.ResourceID, err
+	}
+	err = a.waitTask(ctx, ref.TaskID)
+	if err != nil {
+		return ref.ResourceID, err
+	}
+
+	return ref.ResourceID, a.assignFloatingIP(ctx, ref.ResourceID, instanceID)
+}
+
+// assignFloatingIP assigns the floating IP to the first NIC of the instance
+func (a *environmentApplier) assignFloatingIP(ctx context.Context, fipID string, instanceID string) error {
+	nics, err := a.svc.GetInstanceNICs(instanceID, connection.APIRequestParameters{})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve NICs for instance [%s]: %w", instanceID, err)
+	}
+	if len(nics) == 0 {
+		return fmt.Errorf("instance [%s] has no NICs", instanceID)
+	}
+
+	taskID, err := a.svc.AssignFloatingIP(fipID, AssignFloatingIPRequest{ResourceID: nics[0].ID})
+	if err != nil {
+		return err
+	}
+	return a.waitTask(ctx, taskID)
+}
+
+func (a *environmentApplier) deleteTask(ctx context.Context, id string, del func(id string) (string, error), notFound func(error) bool) error {
+	taskID, err := del(id)
+	if err != nil {
+		if notFound(err) {
+			return nil
+		}
+		return err
+	}
+	return a.waitTask(ctx, taskID)
+}
+
+func (a *environmentApplier) deleteAndWait(ctx context.Context, id string, del func(id string) error, get func(id string) error, notFound func(error) bool) error {
+	err := del(id)
+	if err != nil {
+		if notFound(err) {
+			return nil
+		}
+		return err
+	}
+	return waitForRemoval(ctx, id, get, notFound, a.opts.Wait)
+}
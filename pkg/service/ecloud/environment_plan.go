@@ -0,0 +1,749 @@
+package ecloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+)
+
+// EnvironmentAction is the action taken by an EnvironmentChange
+type EnvironmentAction string
+
+func (a EnvironmentAction) String() string {
+	return string(a)
+}
+
+// symbol returns the diff symbol for the action
+func (a EnvironmentAction) symbol() string {
+	switch a {
+	case EnvironmentActionCreate:
+		return "+"
+	case EnvironmentActionUpdate:
+		return "~"
+	case EnvironmentActionDelete:
+		return "-"
+	}
+	return " "
+}
+
+const (
+	EnvironmentActionCreate EnvironmentAction = "create"
+	EnvironmentActionUpdate EnvironmentAction = "update"
+	EnvironmentActionDelete EnvironmentAction = "delete"
+)
+
+// EnvironmentChange is a single step of an EnvironmentPlan
+type EnvironmentChange struct {
+	Action EnvironmentAction `json:"action"`
+	Kind   string            `json:"kind"`
+	// Address identifies the resource within the spec, e.g. router.main/network.web
+	Address string `json:"address"`
+	// ID is the ID of the existing resource for updates and deletes
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+	// Diff describes the fields changed by an update
+	Diff []string `json:"diff,omitempty"`
+
+	apply func(ctx context.Context, a *environmentApplier) (string, error)
+}
+
+// EnvironmentPlan is an ordered set of changes which converge live state with an EnvironmentSpec.
+// Deletions are ordered first, followed by creations and updates in dependency order.
+//
+// The JSON encoding of a plan is output only, e.g. for review before apply. The requests behind
+// each change are held in memory by the plan, so ApplyEnvironment accepts only a plan returned by
+// PlanEnvironment in the same process, and rejects one decoded from JSON
+type EnvironmentPlan struct {
+	Changes []EnvironmentChange `json:"changes"`
+
+	// unchanged maps the addresses of matched resources which require no changes to their IDs,
+	// recorded in state on apply
+	unchanged map[string]string
+}
+
+// Empty returns true if the plan contains no changes
+func (p *EnvironmentPlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Write writes a human-readable summary of the plan
+func (p *EnvironmentPlan) Write(w io.Writer) error {
+	for _, c := range p.Changes {
+		_, err := fmt.Fprintf(w, "%s %s\n", c.Action.symbol(), c.Address)
+		if err != nil {
+			return err
+		}
+		for _, d := range c.Diff {
+			_, err := fmt.Fprintf(w, "    %s\n", d)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// EnvironmentPlanOptions configures PlanEnvironment
+type EnvironmentPlanOptions struct {
+	// Prune plans the deletion of routers, networks, firewall policies, firewall rules and instances
+	// within the VPC which aren't declared in the spec. Locked instances are never deleted
+	Prune bool
+}
+
+// PlanEnvironment computes the changes required to converge the live state of the VPC declared by
+// spec. Resources are matched by the IDs recorded in state where present, falling back to matching
+// by name (and tags for instances). state may be nil
+func PlanEnvironment(svc ECloudService, spec EnvironmentSpec, state *EnvironmentState, opts EnvironmentPlanOptions) (*EnvironmentPlan, error) {
+	err := spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &environmentPlanner{
+		svc:      svc,
+		spec:     spec,
+		state:    state,
+		opts:     opts,
+		networks: make(map[string]string),
+	}
+	err = p.plan()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &EnvironmentPlan{unchanged: make(map[string]string)}
+	plan.Changes = append(plan.Changes, p.deletes...)
+	for _, c := range p.changes {
+		if c.Action == "" {
+			plan.unchanged[c.Address] = c.ID
+			continue
+		}
+		plan.Changes = append(plan.Changes, c)
+	}
+	return plan, nil
+}
+
+type environmentPlanner struct {
+	svc   ECloudService
+	spec  EnvironmentSpec
+	state *EnvironmentState
+	opts  EnvironmentPlanOptions
+
+	// networks maps network names to their addresses
+	networks map[string]string
+	// changes holds creations, updates and unchanged resources (with an empty Action) in
+	// dependency order
+	changes []EnvironmentChange
+	deletes []EnvironmentChange
+}
+
+func routerAddress(name string) string {
+	return "router." + name
+}
+
+func networkAddress(router, name string) string {
+	return routerAddress(router) + "/network." + name
+}
+
+func firewallPolicyAddress(router, name string) string {
+	return routerAddress(router) + "/firewall_policy." + name
+}
+
+func firewallRuleAddress(router, policy, name string) string {
+	return firewallPolicyAddress(router, policy) + "/firewall_rule." + name
+}
+
+func instanceAddress(name string) string {
+	return "instance." + name
+}
+
+func floatingIPAddress(instance string) string {
+	return instanceAddress(instance) + "/floating_ip"
+}
+
+// matchResource returns the item with the ID recorded in state for address if present, otherwise
+// the single item satisfying match
+func matchResource[T any](items []T, stateID string, id func(T) string, match func(T) bool, name string) (T, bool, error) {
+	var zero T
+	if stateID != "" {
+		for _, item := range items {
+			if id(item) == stateID {
+				return item, true, nil
+			}
+		}
+	}
+
+	var matches []T
+	for _, item := range items {
+		if match(item) {
+			matches = append(matches, item)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return zero, false, nil
+	case 1:
+		return matches[0], true, nil
+	}
+	return zero, false, fmt.Errorf("found %d resources matching %s", len(matches), name)
+}
+
+func diffValue[T comparable](field string, current, desired T) []string {
+	if current == desired {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: %v -> %v", field, current, desired)}
+}
+
+func (p *environmentPlanner) plan() error {
+	vpcID := ""
+	if id := p.state.ID(ResourceKindVPC); id != "" {
+		vpc, err := p.svc.GetVPC(id)
+		if err == nil {
+			vpcID = vpc.ID
+		} else if !isNotFound[*VPCNotFoundError](err) {
+			return fmt.Errorf("failed to retrieve vpc [%s]: %w", id, err)
+		}
+	}
+	if vpcID == "" {
+		vpcs, err := p.svc.GetVPCs(eqFilter("name", p.spec.Name))
+		if err != nil {
+			return fmt.Errorf("failed to retrieve vpcs: %w", err)
+		}
+		vpc, found, err := matchResource(vpcs, "", func(v VPC) string { return v.ID }, func(v VPC) bool {
+			return v.Name == p.spec.Name && v.RegionID == p.spec.RegionID
+		}, fmt.Sprintf("vpc [%s]", p.spec.Name))
+		if err != nil {
+			return err
+		}
+		if found {
+			vpcID = vpc.ID
+		}
+	}
+
+	if vpcID == "" {
+		p.changes = append(p.changes, EnvironmentChange{
+			Action:  EnvironmentActionCreate,
+			Kind:    ResourceKindVPC,
+			Address: ResourceKindVPC,
+			Name:    p.spec.Name,
+			apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+				return a.createVPC(ctx, ResourceKindVPC, p.spec)
+			},
+		})
+	} else {
+		p.changes = append(p.changes, EnvironmentChange{
+			Kind:    ResourceKindVPC,
+			Address: ResourceKindVPC,
+			ID:      vpcID,
+		})
+	}
+
+	err := p.planRouters(vpcID)
+	if err != nil {
+		return err
+	}
+
+	err = p.planInstances(vpcID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *environmentPlanner) planRouters(vpcID string) error {
+	var routers []Router
+	if vpcID != "" {
+		var err error
+		routers, err = p.svc.GetRouters(eqFilter("vpc_id", vpcID))
+		if err != nil {
+			return fmt.Errorf("failed to retrieve routers: %w", err)
+		}
+	}
+
+	matched := make(map[string]bool)
+	for _, spec := range p.spec.Routers {
+		spec := spec
+		address := routerAddress(spec.Name)
+		router, found, err := matchResource(routers, p.state.ID(address), func(r Router) string { return r.ID }, func(r Router) bool {
+			return r.Name == spec.Name
+		}, address)
+		if err != nil {
+			return err
+		}
+
+		routerID := ""
+		if !found {
+			p.changes = append(p.changes, EnvironmentChange{
+				Action:  EnvironmentActionCreate,
+				Kind:    ResourceKindRouter,
+				Address: address,
+				Name:    spec.Name,
+				apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+					return a.createRouter(ctx, address, spec, a.state.ID(ResourceKindVPC), p.spec.AvailabilityZoneID)
+				},
+			})
+		} else {
+			routerID = router.ID
+			matched[router.ID] = true
+			c := EnvironmentChange{Kind: ResourceKindRouter, Address: address, ID: router.ID, Name: spec.Name}
+			if spec.RouterThroughputID != "" {
+				c.Diff = diffValue("router_throughput_id", router.RouterThroughputID, spec.RouterThroughputID)
+			}
+			if len(c.Diff) > 0 {
+				c.Action = EnvironmentActionUpdate
+				c.apply = func(ctx context.Context, a *environmentApplier) (string, error) {
+					return a.updateRouter(ctx, router.ID, spec)
+				}
+			}
+			p.changes = append(p.changes, c)
+		}
+
+		err = p.planNetworks(spec, routerID)
+		if err != nil {
+			return err
+		}
+
+		err = p.planFirewallPolicies(spec, routerID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+
+	var deletes []EnvironmentChange
+	for _, router := range routers {
+		if matched[router.ID] {
+			continue
+		}
+
+		// Networks and firewall policies of undeclared routers are removed with the router
+		err := p.planNetworks(RouterSpec{Name: router.Name}, router.ID)
+		if err != nil {
+			return err
+		}
+		err = p.planFirewallPolicies(RouterSpec{Name: router.Name}, router.ID)
+		if err != nil {
+			return err
+		}
+
+		id := router.ID
+		deletes = append(deletes, EnvironmentChange{
+			Action:  EnvironmentActionDelete,
+			Kind:    ResourceKindRouter,
+			Address: routerAddress(router.Name),
+			ID:      id,
+			Name:    router.Name,
+			apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+				return "", a.deleteAndWait(ctx, id, a.svc.DeleteRouter, func(id string) error {
+					_, err := a.svc.GetRouter(id)
+					return err
+				}, isNotFound[*RouterNotFoundError])
+			},
+		})
+	}
+	p.deletes = append(p.deletes, deletes...)
+	return nil
+}
+
+func (p *environmentPlanner) planNetworks(router RouterSpec, routerID string) error {
+	var networks []Network
+	if routerID != "" {
+		var err error
+		networks, err = p.svc.GetRouterNetworks(routerID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve networks for router [%s]: %w", routerID, err)
+		}
+	}
+
+	matched := make(map[string]bool)
+	for _, spec := range router.Networks {
+		spec := spec
+		address := networkAddress(router.Name, spec.Name)
+		p.networks[spec.Name] = address
+
+		network, found, err := matchResource(networks, p.state.ID(address), func(n Network) string { return n.ID }, func(n Network) bool {
+			return n.Name == spec.Name
+		}, address)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			routerAddr := routerAddress(router.Name)
+			p.changes = append(p.changes, EnvironmentChange{
+				Action:  EnvironmentActionCreate,
+				Kind:    ResourceKindNetwork,
+				Address: address,
+				Name:    spec.Name,
+				apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+					return a.createNetwork(ctx, address, a.state.ID(routerAddr), spec)
+				},
+			})
+			continue
+		}
+
+		matched[network.ID] = true
+		if network.Subnet != spec.Subnet {
+			return fmt.Errorf("subnet of network [%s] cannot be changed from %s to %s", address, network.Subnet, spec.Subnet)
+		}
+		p.changes = append(p.changes, EnvironmentChange{Kind: ResourceKindNetwork, Address: address, ID: network.ID, Name: spec.Name})
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+
+	for _, network := range networks {
+		if matched[network.ID] {
+			continue
+		}
+
+		id := network.ID
+		p.deletes = append(p.deletes, EnvironmentChange{
+			Action:  EnvironmentActionDelete,
+			Kind:    ResourceKindNetwork,
+			Address: networkAddress(router.Name, network.Name),
+			ID:      id,
+			Name:    network.Name,
+			apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+				return "", a.deleteAndWait(ctx, id, a.svc.DeleteNetwork, func(id string) error {
+					_, err := a.svc.GetNetwork(id)
+					return err
+				}, isNotFound[*NetworkNotFoundError])
+			},
+		})
+	}
+	return nil
+}
+
+func (p *environmentPlanner) planFirewallPolicies(router RouterSpec, routerID string) error {
+	var policies []FirewallPolicy
+	if routerID != "" {
+		var err error
+		policies, err = p.svc.GetRouterFirewallPolicies(routerID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve firewall policies for router [%s]: %w", routerID, err)
+		}
+	}
+
+	matched := make(map[string]bool)
+	for _, spec := range router.FirewallPolicies {
+		spec := spec
+		address := firewallPolicyAddress(router.Name, spec.Name)
+		policy, found, err := matchResource(policies, p.state.ID(address), func(f FirewallPolicy) string { return f.ID }, func(f FirewallPolicy) bool {
+			return f.Name == spec.Name
+		}, address)
+		if err != nil {
+			return err
+		}
+
+		policyID := ""
+		if !found {
+			routerAddr := routerAddress(router.Name)
+			p.changes = append(p.changes, EnvironmentChange{
+				Action:  EnvironmentActionCreate,
+				Kind:    ResourceKindFirewallPolicy,
+				Address: address,
+				Name:    spec.Name,
+				apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+					return a.createFirewallPolicy(ctx, address, a.state.ID(routerAddr), spec)
+				},
+			})
+		} else {
+			policyID = policy.ID
+			matched[policy.ID] = true
+			c := EnvironmentChange{Kind: ResourceKindFirewallPolicy, Address: address, ID: policy.ID, Name: spec.Name}
+			c.Diff = diffValue("sequence", policy.Sequence, spec.Sequence)
+			if len(c.Diff) > 0 {
+				c.Action = EnvironmentActionUpdate
+				c.apply = func(ctx context.Context, a *environmentApplier) (string, error) {
+					return a.updateFirewallPolicy(ctx, policy.ID, spec)
+				}
+			}
+			p.changes = append(p.changes, c)
+		}
+
+		err = p.planFirewallRules(router.Name, spec, policyID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+
+	for _, policy := range policies {
+		if matched[policy.ID] {
+			continue
+		}
+
+		id := policy.ID
+		p.deletes = append(p.deletes, EnvironmentChange{
+			Action:  EnvironmentActionDelete,
+			Kind:    ResourceKindFirewallPolicy,
+			Address: firewallPolicyAddress(router.Name, policy.Name),
+			ID:      id,
+			Name:    policy.Name,
+			apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+				return "", a.deleteTask(ctx, id, a.svc.DeleteFirewallPolicy, isNotFound[*FirewallPolicyNotFoundError])
+			},
+		})
+	}
+	return nil
+}
+
+func firewallRuleEnabled(spec FirewallRuleSpec) bool {
+	return spec.Enabled == nil || *spec.Enabled
+}
+
+func (p *environmentPlanner) planFirewallRules(router string, policy FirewallPolicySpec, policyID string) error {
+	var rules []FirewallRule
+	if policyID != "" {
+		var err error
+		rules, err = p.svc.GetFirewallPolicyFirewallRules(policyID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve firewall rules for firewall policy [%s]: %w", policyID, err)
+		}
+	}
+
+	matched := make(map[string]bool)
+	for _, spec := range policy.Rules {
+		spec := spec
+		address := firewallRuleAddress(router, policy.Name, spec.Name)
+		rule, found, err := matchResource(rules, p.state.ID(address), func(f FirewallRule) string { return f.ID }, func(f FirewallRule) bool {
+			return f.Name == spec.Name
+		}, address)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			policyAddr := firewallPolicyAddress(router, policy.Name)
+			p.changes = append(p.changes, EnvironmentChange{
+				Action:  EnvironmentActionCreate,
+				Kind:    ResourceKindFirewallRule,
+				Address: address,
+				Name:    spec.Name,
+				apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+					return a.createFirewallRule(ctx, address, a.state.ID(policyAddr), spec)
+				},
+			})
+			continue
+		}
+
+		matched[rule.ID] = true
+		c := EnvironmentChange{Kind: ResourceKindFirewallRule, Address: address, ID: rule.ID, Name: spec.Name}
+		c.Diff = append(c.Diff, diffValue("sequence", rule.Sequence, spec.Sequence)...)
+		c.Diff = append(c.Diff, diffValue("source", rule.Source, spec.Source)...)
+		c.Diff = append(c.Diff, diffValue("destination", rule.Destination, spec.Destination)...)
+		c.Diff = append(c.Diff, diffValue("action", rule.Action, spec.Action)...)
+		c.Diff = append(c.Diff, diffValue("direction", rule.Direction, spec.Direction)...)
+		c.Diff = append(c.Diff, diffValue("enabled", rule.Enabled, firewallRuleEnabled(spec))...)
+		if len(c.Diff) > 0 {
+			c.Action = EnvironmentActionUpdate
+			c.apply = func(ctx context.Context, a *environmentApplier) (string, error) {
+				return a.updateFirewallRule(ctx, rule.ID, spec)
+			}
+		}
+		p.changes = append(p.changes, c)
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+
+	for _, rule := range rules {
+		if matched[rule.ID] {
+			continue
+		}
+
+		id := rule.ID
+		p.deletes = append(p.deletes, EnvironmentChange{
+			Action:  EnvironmentActionDelete,
+			Kind:    ResourceKindFirewallRule,
+			Address: firewallRuleAddress(router, policy.Name, rule.Name),
+			ID:      id,
+			Name:    rule.Name,
+			apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+				return "", a.deleteTask(ctx, id, a.svc.DeleteFirewallRule, isNotFound[*FirewallRuleNotFoundError])
+			},
+		})
+	}
+	return nil
+}
+
+func instanceHasTags(instance Instance, tagIDs []string) bool {
+	for _, tagID := range tagIDs {
+		found := false
+		for _, tag := range instance.Tags {
+			if tag.ID == tagID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *environmentPlanner) planInstances(vpcID string) error {
+	var instances []Instance
+	if vpcID != "" {
+		var err error
+		instances, err = p.svc.GetVPCInstances(vpcID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve instances: %w", err)
+		}
+	}
+
+	matched := make(map[string]bool)
+	for _, spec := range p.spec.Instances {
+		spec := spec
+		address := instanceAddress(spec.Name)
+		instance, found, err := matchResource(instances, p.state.ID(address), func(i Instance) string { return i.ID }, func(i Instance) bool {
+			return i.Name == spec.Name && instanceHasTags(i, spec.TagIDs)
+		}, address)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			networkAddr := p.networks[spec.Network]
+			p.changes = append(p.changes, EnvironmentChange{
+				Action:  EnvironmentActionCreate,
+				Kind:    ResourceKindInstance,
+				Address: address,
+				Name:    spec.Name,
+				apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+					return a.createInstance(ctx, address, a.state.ID(ResourceKindVPC), a.state.ID(networkAddr), spec)
+				},
+			})
+			if spec.FloatingIP {
+				err := p.planFloatingIP(spec, "")
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		matched[instance.ID] = true
+		c := EnvironmentChange{Kind: ResourceKindInstance, Address: address, ID: instance.ID, Name: spec.Name}
+		if spec.VCPUCores > 0 {
+			c.Diff = append(c.Diff, diffValue("vcpu_cores", instance.VCPUCores, spec.VCPUCores)...)
+		}
+		if spec.RAMCapacity > 0 {
+			c.Diff = append(c.Diff, diffValue("ram_capacity", instance.RAMCapacity, spec.RAMCapacity)...)
+		}
+		if len(c.Diff) > 0 {
+			c.Action = EnvironmentActionUpdate
+			c.apply = func(ctx context.Context, a *environmentApplier) (string, error) {
+				return a.updateInstance(ctx, instance.ID, spec)
+			}
+		}
+		p.changes = append(p.changes, c)
+
+		if spec.FloatingIP {
+			err := p.planFloatingIP(spec, instance.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+
+	for _, instance := range instances {
+		if matched[instance.ID] || instance.Locked {
+			continue
+		}
+
+		id := instance.ID
+		p.deletes = append([]EnvironmentChange{{
+			Action:  EnvironmentActionDelete,
+			Kind:    ResourceKindInstance,
+			Address: instanceAddress(instance.Name),
+			ID:      id,
+			Name:    instance.Name,
+			apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+				return "", a.deleteAndWait(ctx, id, a.svc.DeleteInstance, func(id string) error {
+					_, err := a.svc.GetInstance(id)
+					return err
+				}, isNotFound[*InstanceNotFoundError])
+			},
+		}}, p.deletes...)
+	}
+	return nil
+}
+
+// planFloatingIP plans the floating IP for an instance. A floating IP recorded in state which
+// exists but isn't assigned (e.g. following a failed apply) is assigned rather than recreated
+func (p *environmentPlanner) planFloatingIP(spec EnvironmentInstance, instanceID string) error {
+	address := floatingIPAddress(spec.Name)
+	instanceAddr := instanceAddress(spec.Name)
+
+	if instanceID != "" {
+		fips, err := p.svc.GetInstanceFloatingIPs(instanceID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve floating IPs for instance [%s]: %w", instanceID, err)
+		}
+		if len(fips) > 0 {
+			p.changes = append(p.changes, EnvironmentChange{Kind: ResourceKindFloatingIP, Address: address, ID: fips[0].ID, Name: spec.Name})
+			return nil
+		}
+	}
+
+	if id := p.state.ID(address); id != "" {
+		fip, err := p.svc.GetFloatingIP(id)
+		if err == nil && fip.ResourceID == "" {
+			p.changes = append(p.changes, EnvironmentChange{
+				Action:  EnvironmentActionUpdate,
+				Kind:    ResourceKindFloatingIP,
+				Address: address,
+				ID:      fip.ID,
+				Name:    spec.Name,
+				Diff:    []string{"assign to " + instanceAddr},
+				apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+					return fip.ID, a.assignFloatingIP(ctx, fip.ID, a.state.ID(instanceAddr))
+				},
+			})
+			return nil
+		}
+		if err != nil && !isNotFound[*FloatingIPNotFoundError](err) {
+			return fmt.Errorf("failed to retrieve floating IP [%s]: %w", id, err)
+		}
+	}
+
+	p.changes = append(p.changes, EnvironmentChange{
+		Action:  EnvironmentActionCreate,
+		Kind:    ResourceKindFloatingIP,
+		Address: address,
+		Name:    spec.Name,
+		apply: func(ctx context.Context, a *environmentApplier) (string, error) {
+			return a.createFloatingIP(ctx, address, a.state.ID(ResourceKindVPC), p.spec.AvailabilityZoneID, a.state.ID(instanceAddr), spec)
+		},
+	})
+	return nil
+}
+
+// String returns a short description of the change, e.g. create router.main
+func (c EnvironmentChange) String() string {
+	s := fmt.Sprintf("%s %s", c.Action, c.Address)
+	if len(c.Diff) > 0 {
+		s += " (" + strings.Join(c.Diff, ", ") + ")"
+	}
+	return s
+}
@@ -0,0 +1,543 @@
+package ecloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEnvService is an in-memory ECloudService covering the methods used by PlanEnvironment and
+// ApplyEnvironment
+type fakeEnvService struct {
+	fakeTaskService
+
+	vpcs      []VPC
+	routers   []Router
+	networks  []Network
+	policies  []FirewallPolicy
+	rules     []FirewallRule
+	instances []Instance
+	nics      []NIC
+	fips      []FloatingIP
+
+	// failAssign causes AssignFloatingIP to fail
+	failAssign error
+	// failNetworkSync causes created networks to report a failed sync
+	failNetworkSync bool
+
+	calls []string
+	seq   int
+}
+
+func (f *fakeEnvService) nextID(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("%s-%08d", prefix, f.seq)
+}
+
+func (f *fakeEnvService) GetVPC(vpcID string) (VPC, error) {
+	for _, v := range f.vpcs {
+		if v.ID == vpcID {
+			return v, nil
+		}
+	}
+	return VPC{}, &VPCNotFoundError{ID: vpcID}
+}
+
+func (f *fakeEnvService) GetVPCs(parameters connection.APIRequestParameters) ([]VPC, error) {
+	return f.vpcs, nil
+}
+
+func (f *fakeEnvService) CreateVPC(req CreateVPCRequest) (string, error) {
+	id := f.nextID("vpc")
+	f.vpcs = append(f.vpcs, VPC{ID: id, Name: req.Name, RegionID: req.RegionID, Sync: ResourceSync{Status: SyncStatusComplete}})
+	f.calls = append(f.calls, "CreateVPC "+req.Name)
+	return id, nil
+}
+
+func (f *fakeEnvService) DeployVPCDefaults(vpcID string) error {
+	f.calls = append(f.calls, "DeployVPCDefaults "+vpcID)
+	return nil
+}
+
+func (f *fakeEnvService) GetRouters(parameters connection.APIRequestParameters) ([]Router, error) {
+	return f.routers, nil
+}
+
+func (f *fakeEnvService) GetRouter(routerID string) (Router, error) {
+	for _, r := range f.routers {
+		if r.ID == routerID {
+			return r, nil
+		}
+	}
+	return Router{}, &RouterNotFoundError{ID: routerID}
+}
+
+func (f *fakeEnvService) CreateRouter(req CreateRouterRequest) (string, error) {
+	id := f.nextID("rtr")
+	f.routers = append(f.routers, Router{ID: id, Name: req.Name, VPCID: req.VPCID, Sync: ResourceSync{Status: SyncStatusComplete}})
+	f.calls = append(f.calls, fmt.Sprintf("CreateRouter %s vpc=%s", req.Name, req.VPCID))
+	return id, nil
+}
+
+func (f *fakeEnvService) DeleteRouter(routerID string) error {
+	f.calls = append(f.calls, "DeleteRouter "+routerID)
+	for i, r := range f.routers {
+		if r.ID == routerID {
+			f.routers = append(f.routers[:i], f.routers[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeEnvService) GetRouterNetworks(routerID string, parameters connection.APIRequestParameters) ([]Network, error) {
+	var networks []Network
+	for _, n := range f.networks {
+		if n.RouterID == routerID {
+			networks = append(networks, n)
+		}
+	}
+	return networks, nil
+}
+
+func (f *fakeEnvService) GetNetwork(networkID string) (Network, error) {
+	for _, n := range f.networks {
+		if n.ID == networkID {
+			return n, nil
+		}
+	}
+	return Network{}, &NetworkNotFoundError{ID: networkID}
+}
+
+func (f *fakeEnvService) CreateNetwork(req CreateNetworkRequest) (string, error) {
+	id := f.nextID("net")
+	sync := ResourceSync{Status: SyncStatusComplete}
+	if f.failNetworkSync {
+		sync.Status = SyncStatusFailed
+	}
+	f.networks = append(f.networks, Network{ID: id, Name: req.Name, RouterID: req.RouterID, Subnet: req.Subnet, Sync: sync})
+	f.calls = append(f.calls, fmt.Sprintf("CreateNetwork %s router=%s", req.Name, req.RouterID))
+	return id, nil
+}
+
+func (f *fakeEnvService) GetRouterFirewallPolicies(routerID string, parameters connection.APIRequestParameters) ([]FirewallPolicy, error) {
+	var policies []FirewallPolicy
+	for _, p := range f.policies {
+		if p.RouterID == routerID {
+			policies = append(policies, p)
+		}
+	}
+	return policies, nil
+}
+
+func (f *fakeEnvService) CreateFirewallPolicy(req CreateFirewallPolicyRequest) (TaskReference, error) {
+	id := f.nextID("fwp")
+	f.policies = append(f.policies, FirewallPolicy{ID: id, Name: req.Name, RouterID: req.RouterID, Sequence: req.Sequence})
+	f.calls = append(f.calls, fmt.Sprintf("CreateFirewallPolicy %s router=%s", req.Name, req.RouterID))
+	return TaskReference{TaskID: "task-" + id, ResourceID: id}, nil
+}
+
+func (f *fakeEnvService) PatchFirewallPolicy(policyID string, req PatchFirewallPolicyRequest) (TaskReference, error) {
+	f.calls = append(f.calls, fmt.Sprintf("PatchFirewallPolicy %s sequence=%d", policyID, *req.Sequence))
+	return TaskReference{TaskID: "task-" + policyID, ResourceID: policyID}, nil
+}
+
+func (f *fakeEnvService) GetFirewallPolicyFirewallRules(policyID string, parameters connection.APIRequestParameters) ([]FirewallRule, error) {
+	var rules []FirewallRule
+	for _, r := range f.rules {
+		if r.FirewallPolicyID == policyID {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+func (f *fakeEnvService) CreateFirewallRule(req CreateFirewallRuleRequest) (TaskReference, error) {
+	id := f.nextID("fwr")
+	f.rules = append(f.rules, FirewallRule{
+		ID:               id,
+		Name:             req.Name,
+		FirewallPolicyID: req.FirewallPolicyID,
+		Sequence:         req.Sequence,
+		Source:           req.Source,
+		Destination:      req.Destination,
+		Action:           req.Action,
+		Direction:        req.Direction,
+		Enabled:          req.Enabled,
+	})
+	f.calls = append(f.calls, fmt.Sprintf("CreateFirewallRule %s policy=%s ports=%d", req.Name, req.FirewallPolicyID, len(req.Ports)))
+	return TaskReference{TaskID: "task-" + id, ResourceID: id}, nil
+}
+
+func (f *fakeEnvService) DeleteFirewallRule(ruleID string) (string, error) {
+	f.calls = append(f.calls, "DeleteFirewallRule "+ruleID)
+	return "task-" + ruleID, nil
+}
+
+func (f *fakeEnvService) GetVPCInstances(vpcID string, parameters connection.APIRequestParameters) ([]Instance, error) {
+	return f.instances, nil
+}
+
+func (f *fakeEnvService) GetInstance(instanceID string) (Instance, error) {
+	for _, i := range f.instances {
+		if i.ID == instanceID {
+			return i, nil
+		}
+	}
+	return Instance{}, &InstanceNotFoundError{ID: instanceID}
+}
+
+func (f *fakeEnvService) CreateInstance(req CreateInstanceRequest) (string, error) {
+	id := f.nextID("i")
+	f.instances = append(f.instances, Instance{ID: id, Name: req.Name, VPCID: req.VPCID, VCPUCores: req.VCPUCores, RAMCapacity: req.RAMCapacity, Sync: ResourceSync{Status: SyncStatusComplete}})
+	f.nics = append(f.nics, NIC{ID: f.nextID("nic"), InstanceID: id, NetworkID: req.NetworkID})
+	f.calls = append(f.calls, fmt.Sprintf("CreateInstance %s network=%s", req.Name, req.NetworkID))
+	return id, nil
+}
+
+func (f *fakeEnvService) PatchInstance(instanceID string, req PatchInstanceRequest) error {
+	f.calls = append(f.calls, fmt.Sprintf("PatchInstance %s ram=%d", instanceID, req.RAMCapacity))
+	return nil
+}
+
+func (f *fakeEnvService) GetInstanceNICs(instanceID string, parameters connection.APIRequestParameters) ([]NIC, error) {
+	var nics []NIC
+	for _, n := range f.nics {
+		if n.InstanceID == instanceID {
+			nics = append(nics, n)
+		}
+	}
+	return nics, nil
+}
+
+func (f *fakeEnvService) GetInstanceFloatingIPs(instanceID string, parameters connection.APIRequestParameters) ([]FloatingIP, error) {
+	var fips []FloatingIP
+	for _, fip := range f.fips {
+		if fip.ResourceID != "" {
+			for _, n := range f.nics {
+				if n.ID == fip.ResourceID && n.InstanceID == instanceID {
+					fips = append(fips, fip)
+				}
+			}
+		}
+	}
+	return fips, nil
+}
+
+func (f *fakeEnvService) GetFloatingIP(fipID string) (FloatingIP, error) {
+	for _, fip := range f.fips {
+		if fip.ID == fipID {
+			return fip, nil
+		}
+	}
+	return FloatingIP{}, &FloatingIPNotFoundError{ID: fipID}
+}
+
+func (f *fakeEnvService) CreateFloatingIP(req CreateFloatingIPRequest) (TaskReference, error) {
+	id := f.nextID("fip")
+	f.fips = append(f.fips, FloatingIP{ID: id, Name: req.Name, VPCID: req.VPCID})
+	f.calls = append(f.calls, "CreateFloatingIP "+req.Name)
+	return TaskReference{TaskID: "task-" + id, ResourceID: id}, nil
+}
+
+func (f *fakeEnvService) AssignFloatingIP(fipID string, req AssignFloatingIPRequest) (string, error) {
+	if f.failAssign != nil {
+		return "", f.failAssign
+	}
+	for i := range f.fips {
+		if f.fips[i].ID == fipID {
+			f.fips[i].ResourceID = req.ResourceID
+		}
+	}
+	f.calls = append(f.calls, fmt.Sprintf("AssignFloatingIP %s resource=%s", fipID, req.ResourceID))
+	return "task-assign-" + fipID, nil
+}
+
+const testEnvironmentSpec = `
+name: prod
+region_id: reg-abcdef12
+availability_zone_id: az-abcdef12
+deploy_defaults: true
+routers:
+  - name: main
+    networks:
+      - name: web
+        subnet: 10.0.0.0/24
+    firewall_policies:
+      - name: web
+        sequence: 10
+        rules:
+          - name: https
+            sequence: 1
+            source: ANY
+            destination: 10.0.0.0/24
+            action: ALLOW
+            direction: IN
+            ports:
+              - protocol: TCP
+                destination: "443"
+instances:
+  - name: web-01
+    network: web
+    image_id: img-abcdef12
+    vcpu_cores: 2
+    ram_capacity: 2048
+    volume_capacity: 40
+    floating_ip: true
+`
+
+func testEnvironmentApplyOptions() EnvironmentApplyOptions {
+	return EnvironmentApplyOptions{Wait: WaitOptions{Interval: time.Second, Clock: newFakeClock()}}
+}
+
+func TestLoadEnvironmentSpec(t *testing.T) {
+	t.Run("Valid_ReturnsSpec", func(t *testing.T) {
+		spec, err := LoadEnvironmentSpec(strings.NewReader(testEnvironmentSpec))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "prod", spec.Name)
+		assert.Equal(t, "10.0.0.0/24", spec.Routers[0].Networks[0].Subnet)
+		assert.Equal(t, FirewallRuleActionAllow, spec.Routers[0].FirewallPolicies[0].Rules[0].Action)
+		assert.True(t, spec.Instances[0].FloatingIP)
+	})
+
+	t.Run("UnknownField_ReturnsError", func(t *testing.T) {
+		_, err := LoadEnvironmentSpec(strings.NewReader("name: prod\nunknown: true\n"))
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("UnknownNetwork_ReturnsError", func(t *testing.T) {
+		spec := strings.Replace(testEnvironmentSpec, "network: web", "network: db", 1)
+
+		_, err := LoadEnvironmentSpec(strings.NewReader(spec))
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "instance [web-01] references unknown network [db]", err.Error())
+	})
+}
+
+func TestPlanEnvironment(t *testing.T) {
+	spec, err := LoadEnvironmentSpec(strings.NewReader(testEnvironmentSpec))
+	assert.Nil(t, err)
+
+	t.Run("NoLiveState_PlansCreates", func(t *testing.T) {
+		plan, err := PlanEnvironment(&fakeEnvService{}, spec, nil, EnvironmentPlanOptions{})
+
+		assert.Nil(t, err)
+		buf := new(bytes.Buffer)
+		assert.Nil(t, plan.Write(buf))
+		assert.Equal(t, `+ vpc
++ router.main
++ router.main/network.web
++ router.main/firewall_policy.web
++ router.main/firewall_policy.web/firewall_rule.https
++ instance.web-01
++ instance.web-01/floating_ip
+`, buf.String())
+	})
+
+	t.Run("ExistingResources_PlansUpdatesAndDeletes", func(t *testing.T) {
+		svc := &fakeEnvService{
+			vpcs:     []VPC{{ID: "vpc-abcdef12", Name: "prod", RegionID: "reg-abcdef12"}},
+			routers:  []Router{{ID: "rtr-abcdef12", Name: "main"}},
+			networks: []Network{{ID: "net-abcdef12", Name: "web", RouterID: "rtr-abcdef12", Subnet: "10.0.0.0/24"}},
+			policies: []FirewallPolicy{{ID: "fwp-abcdef12", Name: "web", RouterID: "rtr-abcdef12", Sequence: 20}},
+			rules: []FirewallRule{
+				{ID: "fwr-abcdef12", Name: "https", FirewallPolicyID: "fwp-abcdef12", Sequence: 1, Source: "ANY", Destination: "10.0.0.0/24", Action: FirewallRuleActionAllow, Direction: FirewallRuleDirectionIn, Enabled: true},
+				{ID: "fwr-abcdef34", Name: "ssh", FirewallPolicyID: "fwp-abcdef12"},
+			},
+			instances: []Instance{{ID: "i-abcdef12", Name: "web-01", VCPUCores: 2, RAMCapacity: 1024}},
+			nics:      []NIC{{ID: "nic-abcdef12", InstanceID: "i-abcdef12"}},
+			fips:      []FloatingIP{{ID: "fip-abcdef12", ResourceID: "nic-abcdef12"}},
+		}
+
+		plan, err := PlanEnvironment(svc, spec, nil, EnvironmentPlanOptions{Prune: true})
+
+		assert.Nil(t, err)
+		buf := new(bytes.Buffer)
+		assert.Nil(t, plan.Write(buf))
+		assert.Equal(t, `- router.main/firewall_policy.web/firewall_rule.ssh
+~ router.main/firewall_policy.web
+    sequence: 20 -> 10
+~ instance.web-01
+    ram_capacity: 1024 -> 2048
+`, buf.String())
+	})
+
+	t.Run("WithoutPrune_OmitsDeletes", func(t *testing.T) {
+		svc := &fakeEnvService{
+			vpcs:    []VPC{{ID: "vpc-abcdef12", Name: "prod", RegionID: "reg-abcdef12"}},
+			routers: []Router{{ID: "rtr-abcdef12", Name: "main"}, {ID: "rtr-abcdef34", Name: "legacy"}},
+		}
+
+		plan, err := PlanEnvironment(svc, spec, nil, EnvironmentPlanOptions{})
+
+		assert.Nil(t, err)
+		for _, c := range plan.Changes {
+			assert.NotEqual(t, EnvironmentActionDelete, c.Action)
+		}
+	})
+
+	t.Run("SubnetChange_ReturnsError", func(t *testing.T) {
+		svc := &fakeEnvService{
+			vpcs:     []VPC{{ID: "vpc-abcdef12", Name: "prod", RegionID: "reg-abcdef12"}},
+			routers:  []Router{{ID: "rtr-abcdef12", Name: "main"}},
+			networks: []Network{{ID: "net-abcdef12", Name: "web", RouterID: "rtr-abcdef12", Subnet: "10.1.0.0/24"}},
+		}
+
+		_, err := PlanEnvironment(svc, spec, nil, EnvironmentPlanOptions{})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "subnet of network [router.main/network.web] cannot be changed from 10.1.0.0/24 to 10.0.0.0/24", err.Error())
+	})
+
+	t.Run("AmbiguousMatch_ReturnsError", func(t *testing.T) {
+		svc := &fakeEnvService{
+			vpcs: []VPC{{ID: "vpc-abcdef12", Name: "prod", RegionID: "reg-abcdef12"}, {ID: "vpc-abcdef34", Name: "prod", RegionID: "reg-abcdef12"}},
+		}
+
+		_, err := PlanEnvironment(svc, spec, nil, EnvironmentPlanOptions{})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "found 2 resources matching vpc [prod]", err.Error())
+	})
+}
+
+func TestApplyEnvironment(t *testing.T) {
+	spec, err := LoadEnvironmentSpec(strings.NewReader(testEnvironmentSpec))
+	assert.Nil(t, err)
+
+	t.Run("CreatesInDependencyOrder", func(t *testing.T) {
+		svc := &fakeEnvService{}
+		state := NewEnvironmentState()
+		plan, err := PlanEnvironment(svc, spec, state, EnvironmentPlanOptions{})
+		assert.Nil(t, err)
+
+		err = ApplyEnvironment(context.Background(), svc, plan, state, testEnvironmentApplyOptions())
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{
+			"CreateVPC prod",
+			"DeployVPCDefaults vpc-00000001",
+			"CreateRouter main vpc=vpc-00000001",
+			"CreateNetwork web router=rtr-00000002",
+			"CreateFirewallPolicy web router=rtr-00000002",
+			"CreateFirewallRule https policy=fwp-00000004 ports=1",
+			"CreateInstance web-01 network=net-00000003",
+			"CreateFloatingIP web-01",
+			"AssignFloatingIP fip-00000008 resource=nic-00000007",
+		}, svc.calls)
+		assert.Equal(t, "i-00000006", state.ID("instance.web-01"))
+		assert.Equal(t, "fip-00000008", state.ID("instance.web-01/floating_ip"))
+
+		plan, err = PlanEnvironment(svc, spec, state, EnvironmentPlanOptions{})
+
+		assert.Nil(t, err)
+		assert.True(t, plan.Empty())
+	})
+
+	t.Run("PartialFailure_ResumesFromState", func(t *testing.T) {
+		svc := &fakeEnvService{failAssign: errors.New("test error 1")}
+		state := NewEnvironmentState()
+		var saved int
+		opts := testEnvironmentApplyOptions()
+		opts.SaveState = func(*EnvironmentState) error {
+			saved++
+			return nil
+		}
+		plan, err := PlanEnvironment(svc, spec, state, EnvironmentPlanOptions{})
+		assert.Nil(t, err)
+
+		err = ApplyEnvironment(context.Background(), svc, plan, state, opts)
+
+		assert.NotNil(t, err)
+		var applyErr *EnvironmentApplyError
+		assert.True(t, errors.As(err, &applyErr))
+		assert.Equal(t, "instance.web-01/floating_ip", applyErr.Change.Address)
+		assert.Equal(t, "fip-00000008", state.ID("instance.web-01/floating_ip"))
+		assert.Greater(t, saved, 0)
+
+		svc.failAssign = nil
+		svc.calls = nil
+		plan, err = PlanEnvironment(svc, spec, state, EnvironmentPlanOptions{})
+		assert.Nil(t, err)
+		assert.Len(t, plan.Changes, 1)
+		assert.Equal(t, EnvironmentActionUpdate, plan.Changes[0].Action)
+
+		err = ApplyEnvironment(context.Background(), svc, plan, state, opts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"AssignFloatingIP fip-00000008 resource=nic-00000007"}, svc.calls)
+	})
+
+	t.Run("FailedWait_RecordsCreatedID", func(t *testing.T) {
+		svc := &fakeEnvService{failNetworkSync: true}
+		state := NewEnvironmentState()
+		plan, err := PlanEnvironment(svc, spec, state, EnvironmentPlanOptions{})
+		assert.Nil(t, err)
+
+		err = ApplyEnvironment(context.Background(), svc, plan, state, testEnvironmentApplyOptions())
+
+		assert.NotNil(t, err)
+		var applyErr *EnvironmentApplyError
+		assert.True(t, errors.As(err, &applyErr))
+		assert.Equal(t, "router.main/network.web", applyErr.Change.Address)
+		assert.Equal(t, "net-00000003", state.ID("router.main/network.web"))
+
+		svc.failNetworkSync = false
+		svc.networks[0].Sync.Status = SyncStatusComplete
+		svc.calls = nil
+		plan, err = PlanEnvironment(svc, spec, state, EnvironmentPlanOptions{})
+		assert.Nil(t, err)
+
+		err = ApplyEnvironment(context.Background(), svc, plan, state, testEnvironmentApplyOptions())
+
+		assert.Nil(t, err)
+		assert.NotContains(t, svc.calls, "CreateNetwork web router=rtr-00000002")
+	})
+
+	t.Run("DecodedPlan_ReturnsError", func(t *testing.T) {
+		svc := &fakeEnvService{}
+		state := NewEnvironmentState()
+		plan, err := PlanEnvironment(svc, spec, state, EnvironmentPlanOptions{})
+		assert.Nil(t, err)
+		data, err := json.Marshal(plan)
+		assert.Nil(t, err)
+		var decoded EnvironmentPlan
+		err = json.Unmarshal(data, &decoded)
+		assert.Nil(t, err)
+
+		err = ApplyEnvironment(context.Background(), svc, &decoded, state, testEnvironmentApplyOptions())
+
+		assert.NotNil(t, err)
+		assert.Empty(t, svc.calls)
+	})
+
+	t.Run("NilState_ReturnsError", func(t *testing.T) {
+		err := ApplyEnvironment(context.Background(), &fakeEnvService{}, &EnvironmentPlan{}, nil, testEnvironmentApplyOptions())
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestEnvironmentState_ReadWrite(t *testing.T) {
+	state := NewEnvironmentState()
+	state.set("vpc", "vpc-abcdef12")
+	buf := new(bytes.Buffer)
+
+	err := state.Write(buf)
+	assert.Nil(t, err)
+	read, err := ReadEnvironmentState(buf)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "vpc-abcdef12", read.ID("vpc"))
+}
@@ -200,15 +200,6 @@ func (e *RouterNotFoundError) Error() string {
 	return fmt.Sprintf("Router not found with ID [%s]", e.ID)
 }
 
-// RegionNotFoundError indicates a region was not found
-type RegionNotFoundError struct {
-	ID string
-}
-
-func (e *RegionNotFoundError) Error() string {
-	return fmt.Sprintf("Router not found with ID [%s]", e.ID)
-}
-
 // LoadBalancerClusterNotFoundError indicates a load balancer cluster was not found
 type LoadBalancerClusterNotFoundError struct {
 	ID string
@@ -425,15 +416,6 @@ func (e *IPAddressNotFoundError) Error() string {
 	return fmt.Sprintf("IP Address not found with ID [%s]", e.ID)
 }
 
-// AffinityRuleNotFoundError indicates an affinity rule was not found
-type AffinityRuleNotFoundError struct {
-	ID string
-}
-
-func (e *AffinityRuleNotFoundError) Error() string {
-	return fmt.Sprintf("Affinity Rule not found with ID [%s]", e.ID)
-}
-
 // AffinityRuleMemberNotFoundError indicates an affinity rule member was not found
 type AffinityRuleMemberNotFoundError struct {
 	ID string
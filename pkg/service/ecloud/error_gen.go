@@ -0,0 +1,23 @@
+// Code generated by resourcegen. DO NOT EDIT.
+
+package ecloud
+
+import "fmt"
+
+// RegionNotFoundError indicates a region was not found
+type RegionNotFoundError struct {
+	ID string
+}
+
+func (e *RegionNotFoundError) Error() string {
+	return fmt.Sprintf("Router not found with ID [%s]", e.ID)
+}
+
+// AffinityRuleNotFoundError indicates an affinity rule was not found
+type AffinityRuleNotFoundError struct {
+	ID string
+}
+
+func (e *AffinityRuleNotFoundError) Error() string {
+	return fmt.Sprintf("Affinity Rule not found with ID [%s]", e.ID)
+}
@@ -0,0 +1,12 @@
+package ecloud
+
+// fakeTaskService is the base for in-memory ECloudService fakes, whose tasks complete
+// immediately. Fakes embed it and add the methods they use; calls to other methods panic via
+// the nil embedded interface
+type fakeTaskService struct {
+	ECloudService
+}
+
+func (f *fakeTaskService) GetTask(taskID string) (Task, error) {
+	return Task{ID: taskID, Status: TaskStatusComplete}, nil
+}
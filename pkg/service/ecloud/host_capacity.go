@@ -0,0 +1,364 @@
+package ecloud
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+)
+
+const (
+	defaultMaxVCPURatio = 4.0
+	defaultMaxRAMRatio  = 1.0
+
+	// hostSpecRAMCapacityMB converts host spec RAM capacity, given in GB, to MB as used by instances
+	hostSpecRAMCapacityMB = 1024
+)
+
+// CapacityReportOptions configures GetCapacityReport and BuildCapacityReport
+type CapacityReportOptions struct {
+	// VPCID restricts the report to host groups and instances within a VPC
+	VPCID string
+	// MaxVCPURatio is the highest acceptable ratio of allocated vCPUs to physical cores, defaulting
+	// to 4
+	MaxVCPURatio float64
+	// MaxRAMRatio is the highest acceptable ratio of allocated to physical RAM, defaulting to 1
+	MaxRAMRatio float64
+}
+
+func (o CapacityReportOptions) maxVCPURatio() float64 {
+	if o.MaxVCPURatio <= 0 {
+		return defaultMaxVCPURatio
+	}
+	return o.MaxVCPURatio
+}
+
+func (o CapacityReportOptions) maxRAMRatio() float64 {
+	if o.MaxRAMRatio <= 0 {
+		return defaultMaxRAMRatio
+	}
+	return o.MaxRAMRatio
+}
+
+// CapacityUsage is the physical capacity and instance allocation of a host group or availability
+// zone. RAM is given in MB
+type CapacityUsage struct {
+	Hosts         int `json:"hosts"`
+	Instances     int `json:"instances"`
+	PhysicalCores int `json:"physical_cores"`
+	PhysicalRAM   int `json:"physical_ram"`
+	AllocatedVCPU int `json:"allocated_vcpu"`
+	AllocatedRAM  int `json:"allocated_ram"`
+	// VCPURatio and RAMRatio are allocation relative to physical capacity, and are zero where there
+	// is no physical capacity
+	VCPURatio float64 `json:"vcpu_ratio"`
+	RAMRatio  float64 `json:"ram_ratio"`
+	// VCPUHeadroom and RAMHeadroom are the capacity remaining before the maximum ratios are
+	// reached, and are negative when over-committed
+	VCPUHeadroom  int  `json:"vcpu_headroom"`
+	RAMHeadroom   int  `json:"ram_headroom"`
+	OverCommitted bool `json:"over_committed"`
+}
+
+func (u *CapacityUsage) add(o CapacityUsage) {
+	u.Hosts += o.Hosts
+	u.Instances += o.Instances
+	u.PhysicalCores += o.PhysicalCores
+	u.PhysicalRAM += o.PhysicalRAM
+	u.AllocatedVCPU += o.AllocatedVCPU
+	u.AllocatedRAM += o.AllocatedRAM
+}
+
+func (u *CapacityUsage) compute(opts CapacityReportOptions) {
+	u.VCPURatio, u.RAMRatio = 0, 0
+	if u.PhysicalCores > 0 {
+		u.VCPURatio = float64(u.AllocatedVCPU) / float64(u.PhysicalCores)
+	}
+	if u.PhysicalRAM > 0 {
+		u.RAMRatio = float64(u.AllocatedRAM) / float64(u.PhysicalRAM)
+	}
+	u.VCPUHeadroom = int(float64(u.PhysicalCores)*opts.maxVCPURatio()) - u.AllocatedVCPU
+	u.RAMHeadroom = int(float64(u.PhysicalRAM)*opts.maxRAMRatio()) - u.AllocatedRAM
+	u.OverCommitted = u.VCPUHeadroom < 0 || u.RAMHeadroom < 0
+}
+
+// HostGroupCapacity is the capacity usage of a single host group
+type HostGroupCapacity struct {
+	HostGroupID        string `json:"host_group_id"`
+	Name               string `json:"name"`
+	VPCID              string `json:"vpc_id"`
+	AvailabilityZoneID string `json:"availability_zone_id"`
+	HostSpecID         string `json:"host_spec_id"`
+	CapacityUsage
+}
+
+// AvailabilityZoneCapacity is the combined capacity usage of the host groups in an availability zone
+type AvailabilityZoneCapacity struct {
+	AvailabilityZoneID string `json:"availability_zone_id"`
+	CapacityUsage
+}
+
+// MigrationSuggestion proposes moving an instance off an over-committed host group. Request is
+// suitable for passing to MigrateInstance
+type MigrationSuggestion struct {
+	InstanceID      string                 `json:"instance_id"`
+	InstanceName    string                 `json:"instance_name"`
+	FromHostGroupID string                 `json:"from_host_group_id"`
+	Request         MigrateInstanceRequest `json:"request"`
+}
+
+// CapacityReport describes host group capacity usage, ordered by availability zone then host group
+// ID, along with migrations which would relieve over-committed host groups
+type CapacityReport struct {
+	HostGroups        []HostGroupCapacity        `json:"host_groups"`
+	AvailabilityZones []AvailabilityZoneCapacity `json:"availability_zones"`
+	Suggestions       []MigrationSuggestion      `json:"suggestions"`
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *CapacityReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes a row per host group followed by a row per availability zone, distinguished by
+// the scope column
+func (r *CapacityReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"scope", "id", "name", "availability_zone_id", "hosts", "instances", "physical_cores", "physical_ram",
+		"allocated_vcpu", "allocated_ram", "vcpu_ratio", "ram_ratio", "vcpu_headroom", "ram_headroom", "over_committed"})
+	if err != nil {
+		return err
+	}
+
+	row := func(scope, id, name, zoneID string, u CapacityUsage) []string {
+		return []string{scope, id, name, zoneID, strconv.Itoa(u.Hosts), strconv.Itoa(u.Instances),
+			strconv.Itoa(u.PhysicalCores), strconv.Itoa(u.PhysicalRAM), strconv.Itoa(u.AllocatedVCPU), strconv.Itoa(u.AllocatedRAM),
+			strconv.FormatFloat(u.VCPURatio, 'f', 2, 64), strconv.FormatFloat(u.RAMRatio, 'f', 2, 64),
+			strconv.Itoa(u.VCPUHeadroom), strconv.Itoa(u.RAMHeadroom), strconv.FormatBool(u.OverCommitted)}
+	}
+	for _, g := range r.HostGroups {
+		err := cw.Write(row("host_group", g.HostGroupID, g.Name, g.AvailabilityZoneID, g.CapacityUsage))
+		if err != nil {
+			return err
+		}
+	}
+	for _, z := range r.AvailabilityZones {
+		err := cw.Write(row("availability_zone", z.AvailabilityZoneID, "", z.AvailabilityZoneID, z.CapacityUsage))
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteSuggestionsCSV writes a row per migration suggestion
+func (r *CapacityReport) WriteSuggestionsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"instance_id", "instance_name", "from_host_group_id", "to_host_group_id", "to_resource_tier_id"})
+	if err != nil {
+		return err
+	}
+
+	for _, s := range r.Suggestions {
+		err := cw.Write([]string{s.InstanceID, s.InstanceName, s.FromHostGroupID, s.Request.HostGroupID, s.Request.ResourceTierID})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// GetCapacityReport retrieves host groups, hosts, host specs, resource tiers and instances, and
+// builds a capacity report with BuildCapacityReport
+func GetCapacityReport(svc ECloudService, opts CapacityReportOptions) (*CapacityReport, error) {
+	params := connection.APIRequestParameters{}
+	if opts.VPCID != "" {
+		params = eqFilter("vpc_id", opts.VPCID)
+	}
+
+	groups, err := svc.GetHostGroups(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve host groups: %w", err)
+	}
+	hosts, err := svc.GetHosts(connection.APIRequestParameters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve hosts: %w", err)
+	}
+	specs, err := svc.GetHostSpecs(connection.APIRequestParameters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve host specs: %w", err)
+	}
+	tiers, err := svc.GetResourceTiers(connection.APIRequestParameters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve resource tiers: %w", err)
+	}
+	instances, err := svc.GetInstances(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve instances: %w", err)
+	}
+
+	return BuildCapacityReport(groups, hosts, specs, tiers, instances, opts)
+}
+
+// BuildCapacityReport computes capacity usage for each host group from the hosts in the group and
+// the host group's spec, and the instances placed on it. Physical cores are CPU sockets multiplied
+// by cores per socket. Instances not on a host group are ignored.
+//
+// For each over-committed host group, instances are suggested for migration largest first until
+// the group is within the maximum ratios. Each is moved to the host group in the same VPC and
+// availability zone with the most vCPU headroom able to accommodate it, or failing that to the
+// first resource tier in the availability zone
+func BuildCapacityReport(groups []HostGroup, hosts []Host, specs []HostSpec, tiers []ResourceTier, instances []Instance, opts CapacityReportOptions) (*CapacityReport, error) {
+	specsByID := make(map[string]HostSpec)
+	for _, spec := range specs {
+		specsByID[spec.ID] = spec
+	}
+
+	report := &CapacityReport{}
+	groupIndex := make(map[string]int)
+	sortedGroups := append([]HostGroup{}, groups...)
+	sort.Slice(sortedGroups, func(i, j int) bool {
+		if sortedGroups[i].AvailabilityZoneID != sortedGroups[j].AvailabilityZoneID {
+			return sortedGroups[i].AvailabilityZoneID < sortedGroups[j].AvailabilityZoneID
+		}
+		return sortedGroups[i].ID < sortedGroups[j].ID
+	})
+	for _, group := range sortedGroups {
+		spec, ok := specsByID[group.HostSpecID]
+		if !ok {
+			return nil, fmt.Errorf("host spec [%s] for host group [%s] not found", group.HostSpecID, group.ID)
+		}
+
+		c := HostGroupCapacity{
+			HostGroupID:        group.ID,
+			Name:               group.Name,
+			VPCID:              group.VPCID,
+			AvailabilityZoneID: group.AvailabilityZoneID,
+			HostSpecID:         group.HostSpecID,
+		}
+		for _, host := range hosts {
+			if host.HostGroupID == group.ID {
+				c.Hosts++
+			}
+		}
+		c.PhysicalCores = c.Hosts * spec.CPUSockets * spec.CPUCores
+		c.PhysicalRAM = c.Hosts * spec.RAMCapacity * hostSpecRAMCapacityMB
+
+		groupIndex[group.ID] = len(report.HostGroups)
+		report.HostGroups = append(report.HostGroups, c)
+	}
+
+	placed := make(map[string][]Instance)
+	for _, instance := range instances {
+		i, ok := groupIndex[instance.HostGroupID]
+		if !ok {
+			continue
+		}
+		c := &report.HostGroups[i]
+		c.Instances++
+		c.AllocatedVCPU += instance.VCPUCores
+		c.AllocatedRAM += instance.RAMCapacity
+		placed[instance.HostGroupID] = append(placed[instance.HostGroupID], instance)
+	}
+
+	for i := range report.HostGroups {
+		report.HostGroups[i].compute(opts)
+	}
+
+	report.Suggestions = suggestMigrations(report.HostGroups, placed, tiers, opts)
+
+	zoneIndex := make(map[string]int)
+	for _, g := range report.HostGroups {
+		i, ok := zoneIndex[g.AvailabilityZoneID]
+		if !ok {
+			i = len(report.AvailabilityZones)
+			zoneIndex[g.AvailabilityZoneID] = i
+			report.AvailabilityZones = append(report.AvailabilityZones, AvailabilityZoneCapacity{AvailabilityZoneID: g.AvailabilityZoneID})
+		}
+		report.AvailabilityZones[i].add(g.CapacityUsage)
+	}
+	for i := range report.AvailabilityZones {
+		report.AvailabilityZones[i].compute(opts)
+	}
+
+	return report, nil
+}
+
+// suggestMigrations simulates moving instances off over-committed host groups, leaving the
+// reported usage of groups untouched
+func suggestMigrations(groups []HostGroupCapacity, placed map[string][]Instance, tiers []ResourceTier, opts CapacityReportOptions) []MigrationSuggestion {
+	usage := make([]CapacityUsage, len(groups))
+	for i, g := range groups {
+		usage[i] = g.CapacityUsage
+	}
+
+	sortedTiers := append([]ResourceTier{}, tiers...)
+	sort.Slice(sortedTiers, func(i, j int) bool { return sortedTiers[i].ID < sortedTiers[j].ID })
+
+	var suggestions []MigrationSuggestion
+	for i, g := range groups {
+		if !usage[i].OverCommitted {
+			continue
+		}
+
+		candidates := append([]Instance{}, placed[g.HostGroupID]...)
+		sort.Slice(candidates, func(a, b int) bool {
+			if candidates[a].VCPUCores != candidates[b].VCPUCores {
+				return candidates[a].VCPUCores > candidates[b].VCPUCores
+			}
+			if candidates[a].RAMCapacity != candidates[b].RAMCapacity {
+				return candidates[a].RAMCapacity > candidates[b].RAMCapacity
+			}
+			return candidates[a].ID < candidates[b].ID
+		})
+
+		for _, instance := range candidates {
+			if !usage[i].OverCommitted {
+				break
+			}
+
+			suggestion := MigrationSuggestion{InstanceID: instance.ID, InstanceName: instance.Name, FromHostGroupID: g.HostGroupID}
+			target := -1
+			for j, other := range groups {
+				fits := j != i && other.VPCID == g.VPCID && other.AvailabilityZoneID == g.AvailabilityZoneID &&
+					usage[j].VCPUHeadroom >= instance.VCPUCores && usage[j].RAMHeadroom >= instance.RAMCapacity
+				if fits && (target == -1 || usage[j].VCPUHeadroom > usage[target].VCPUHeadroom) {
+					target = j
+				}
+			}
+
+			if target >= 0 {
+				suggestion.Request.HostGroupID = groups[target].HostGroupID
+				usage[target].AllocatedVCPU += instance.VCPUCores
+				usage[target].AllocatedRAM += instance.RAMCapacity
+				usage[target].compute(opts)
+			} else {
+				for _, tier := range sortedTiers {
+					if tier.AvailabilityZoneID == g.AvailabilityZoneID {
+						suggestion.Request.ResourceTierID = tier.ID
+						break
+					}
+				}
+				if suggestion.Request.ResourceTierID == "" {
+					continue
+				}
+			}
+
+			usage[i].AllocatedVCPU -= instance.VCPUCores
+			usage[i].AllocatedRAM -= instance.RAMCapacity
+			usage[i].compute(opts)
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions
+}
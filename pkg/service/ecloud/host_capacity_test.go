@@ -0,0 +1,137 @@
+package ecloud
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCapacityService struct {
+	ECloudService
+
+	hostGroupParameters connection.APIRequestParameters
+}
+
+func (f *fakeCapacityService) GetHostGroups(parameters connection.APIRequestParameters) ([]HostGroup, error) {
+	f.hostGroupParameters = parameters
+	return []HostGroup{
+		{ID: "hg-00000002", Name: "spare", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000001", HostSpecID: "hs-00000001"},
+		{ID: "hg-00000001", Name: "busy", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000001", HostSpecID: "hs-00000001"},
+		{ID: "hg-00000003", Name: "memory", VPCID: "vpc-abcdef12", AvailabilityZoneID: "az-00000002", HostSpecID: "hs-00000001"},
+	}, nil
+}
+
+func (f *fakeCapacityService) GetHosts(parameters connection.APIRequestParameters) ([]Host, error) {
+	return []Host{
+		{ID: "h-00000001", HostGroupID: "hg-00000001"},
+		{ID: "h-00000002", HostGroupID: "hg-00000002"},
+		{ID: "h-00000003", HostGroupID: "hg-00000003"},
+	}, nil
+}
+
+func (f *fakeCapacityService) GetHostSpecs(parameters connection.APIRequestParameters) ([]HostSpec, error) {
+	return []HostSpec{{ID: "hs-00000001", CPUSockets: 2, CPUCores: 8, RAMCapacity: 64}}, nil
+}
+
+func (f *fakeCapacityService) GetResourceTiers(parameters connection.APIRequestParameters) ([]ResourceTier, error) {
+	return []ResourceTier{
+		{ID: "rt-00000002", AvailabilityZoneID: "az-00000002"},
+		{ID: "rt-00000001", AvailabilityZoneID: "az-00000001"},
+	}, nil
+}
+
+func (f *fakeCapacityService) GetInstances(parameters connection.APIRequestParameters) ([]Instance, error) {
+	return []Instance{
+		{ID: "i-00000001", Name: "db", HostGroupID: "hg-00000001", VCPUCores: 32, RAMCapacity: 16384},
+		{ID: "i-00000002", Name: "app", HostGroupID: "hg-00000001", VCPUCores: 24, RAMCapacity: 8192},
+		{ID: "i-00000003", Name: "web", HostGroupID: "hg-00000001", VCPUCores: 16, RAMCapacity: 8192},
+		{ID: "i-00000004", Name: "cache", HostGroupID: "hg-00000002", VCPUCores: 8, RAMCapacity: 4096},
+		{ID: "i-00000005", Name: "analytics", HostGroupID: "hg-00000003", VCPUCores: 4, RAMCapacity: 70000},
+		{ID: "i-00000006", Name: "shared", VCPUCores: 2, RAMCapacity: 2048},
+	}, nil
+}
+
+func TestGetCapacityReport(t *testing.T) {
+	svc := &fakeCapacityService{}
+
+	report, err := GetCapacityReport(svc, CapacityReportOptions{VPCID: "vpc-abcdef12"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "vpc-abcdef12", filterValue(svc.hostGroupParameters, "vpc_id"))
+	assert.Equal(t, HostGroupCapacity{
+		HostGroupID:        "hg-00000001",
+		Name:               "busy",
+		VPCID:              "vpc-abcdef12",
+		AvailabilityZoneID: "az-00000001",
+		HostSpecID:         "hs-00000001",
+		CapacityUsage: CapacityUsage{
+			Hosts:         1,
+			Instances:     3,
+			PhysicalCores: 16,
+			PhysicalRAM:   65536,
+			AllocatedVCPU: 72,
+			AllocatedRAM:  32768,
+			VCPURatio:     4.5,
+			RAMRatio:      0.5,
+			VCPUHeadroom:  -8,
+			RAMHeadroom:   32768,
+			OverCommitted: true,
+		},
+	}, report.HostGroups[0])
+	assert.Equal(t, []MigrationSuggestion{
+		{InstanceID: "i-00000001", InstanceName: "db", FromHostGroupID: "hg-00000001", Request: MigrateInstanceRequest{HostGroupID: "hg-00000002"}},
+		{InstanceID: "i-00000005", InstanceName: "analytics", FromHostGroupID: "hg-00000003", Request: MigrateInstanceRequest{ResourceTierID: "rt-00000002"}},
+	}, report.Suggestions)
+
+	t.Run("CSV", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+
+		err := report.WriteCSV(buf)
+
+		assert.Nil(t, err)
+		assert.Equal(t, `scope,id,name,availability_zone_id,hosts,instances,physical_cores,physical_ram,allocated_vcpu,allocated_ram,vcpu_ratio,ram_ratio,vcpu_headroom,ram_headroom,over_committed
+host_group,hg-00000001,busy,az-00000001,1,3,16,65536,72,32768,4.50,0.50,-8,32768,true
+host_group,hg-00000002,spare,az-00000001,1,1,16,65536,8,4096,0.50,0.06,56,61440,false
+host_group,hg-00000003,memory,az-00000002,1,1,16,65536,4,70000,0.25,1.07,60,-4464,true
+availability_zone,az-00000001,,az-00000001,2,4,32,131072,80,36864,2.50,0.28,48,94208,false
+availability_zone,az-00000002,,az-00000002,1,1,16,65536,4,70000,0.25,1.07,60,-4464,true
+`, buf.String())
+	})
+
+	t.Run("SuggestionsCSV", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+
+		err := report.WriteSuggestionsCSV(buf)
+
+		assert.Nil(t, err)
+		assert.Equal(t, `instance_id,instance_name,from_host_group_id,to_host_group_id,to_resource_tier_id
+i-00000001,db,hg-00000001,hg-00000002,
+i-00000005,analytics,hg-00000003,,rt-00000002
+`, buf.String())
+	})
+}
+
+func TestBuildCapacityReport(t *testing.T) {
+	t.Run("CustomRatios", func(t *testing.T) {
+		groups := []HostGroup{{ID: "hg-00000001", HostSpecID: "hs-00000001"}}
+		hosts := []Host{{HostGroupID: "hg-00000001"}, {HostGroupID: "hg-00000001"}}
+		specs := []HostSpec{{ID: "hs-00000001", CPUSockets: 1, CPUCores: 4, RAMCapacity: 8}}
+		instances := []Instance{{ID: "i-00000001", HostGroupID: "hg-00000001", VCPUCores: 12, RAMCapacity: 16384}}
+
+		report, err := BuildCapacityReport(groups, hosts, specs, nil, instances, CapacityReportOptions{MaxVCPURatio: 1, MaxRAMRatio: 1.5})
+
+		assert.Nil(t, err)
+		assert.Equal(t, -4, report.HostGroups[0].VCPUHeadroom)
+		assert.Equal(t, 8192, report.HostGroups[0].RAMHeadroom)
+		// No other host group or resource tier to migrate to
+		assert.Empty(t, report.Suggestions)
+	})
+
+	t.Run("UnknownHostSpec_ReturnsError", func(t *testing.T) {
+		_, err := BuildCapacityReport([]HostGroup{{ID: "hg-00000001", HostSpecID: "hs-unknown"}}, nil, nil, nil, nil, CapacityReportOptions{})
+
+		assert.Equal(t, "host spec [hs-unknown] for host group [hg-00000001] not found", err.Error())
+	})
+}
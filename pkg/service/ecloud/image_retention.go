@@ -0,0 +1,268 @@
+package ecloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const instanceImageTimeFormat = "20060102T150405Z"
+
+// ImageRetentionPolicy determines which images of an instance are kept. An image is kept if any
+// rule selects it
+type ImageRetentionPolicy struct {
+	// KeepLast keeps the newest N images
+	KeepLast int `json:"keep_last"`
+	// KeepDaily keeps the newest image of each of the last D calendar days (UTC), including today
+	KeepDaily int `json:"keep_daily"`
+	// KeepWeekly keeps the newest image of each of the last W ISO weeks (UTC), including this week
+	KeepWeekly int `json:"keep_weekly"`
+}
+
+// Validate checks the policy keeps at least one image
+func (p ImageRetentionPolicy) Validate() error {
+	if p.KeepLast < 0 || p.KeepDaily < 0 || p.KeepWeekly < 0 {
+		return fmt.Errorf("retention counts must not be negative")
+	}
+	if p.KeepLast == 0 && p.KeepDaily == 0 && p.KeepWeekly == 0 {
+		return fmt.Errorf("retention policy must keep at least one image")
+	}
+	return nil
+}
+
+// ManagedImage is an image of an instance subject to a retention policy
+type ManagedImage struct {
+	// ID is empty for an image which would be created by a dry run
+	ID        string    `json:"id,omitempty"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	// Reasons lists the retention rules which keep the image, e.g. last, daily or weekly
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// ApplyImageRetention partitions images into those kept and pruned by policy as of now. Both are
+// returned newest first. Images with identical timestamps are ordered by ID for determinism
+func ApplyImageRetention(images []ManagedImage, policy ImageRetentionPolicy, now time.Time) (keep []ManagedImage, prune []ManagedImage) {
+	sorted := make([]ManagedImage, len(images))
+	copy(sorted, images)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	dailyFrom := today.AddDate(0, 0, -(policy.KeepDaily - 1))
+	// ISO weeks start on Monday
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	weeklyFrom := weekStart.AddDate(0, 0, -7*(policy.KeepWeekly-1))
+
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for i, image := range sorted {
+		image.Reasons = nil
+		created := image.CreatedAt.UTC()
+
+		if i < policy.KeepLast {
+			image.Reasons = append(image.Reasons, "last")
+		}
+		day := created.Format("2006-01-02")
+		if policy.KeepDaily > 0 && !created.Before(dailyFrom) && !days[day] {
+			days[day] = true
+			image.Reasons = append(image.Reasons, "daily")
+		}
+		year, week := created.ISOWeek()
+		weekKey := fmt.Sprintf("%d-W%02d", year, week)
+		if policy.KeepWeekly > 0 && !created.Before(weeklyFrom) && !weeks[weekKey] {
+			weeks[weekKey] = true
+			image.Reasons = append(image.Reasons, "weekly")
+		}
+
+		if len(image.Reasons) > 0 {
+			keep = append(keep, image)
+		} else {
+			prune = append(prune, image)
+		}
+	}
+	return keep, prune
+}
+
+// InstanceImageOptions configures CreateInstanceImageWithRetention
+type InstanceImageOptions struct {
+	// Prefix is prepended to image names, which take the form <prefix>-<instance id>-<timestamp>.
+	// Defaults to backup
+	Prefix string
+	// MetadataKey, where set, identifies images of the instance by image metadata with this key and
+	// the instance ID as value, instead of by name. The image creation time is then used in place
+	// of the timestamp within the name
+	MetadataKey string
+	Retention   ImageRetentionPolicy
+	// DryRun reports the image which would be created and the images which would be deleted,
+	// without making any changes
+	DryRun bool
+	// Wait configures polling for tasks. Wait.Clock is also used to timestamp and age images
+	Wait WaitOptions
+}
+
+func (o InstanceImageOptions) prefix() string {
+	if o.Prefix == "" {
+		return "backup"
+	}
+	return o.Prefix
+}
+
+// InstanceImageName returns the conventional name of an image of instance instanceID
+// created at t
+func InstanceImageName(prefix string, instanceID string, t time.Time) string {
+	return fmt.Sprintf("%s-%s-%s", prefix, instanceID, t.UTC().Format(instanceImageTimeFormat))
+}
+
+// InstanceImageReport details the image created and the images kept and deleted by
+// CreateInstanceImageWithRetention
+type InstanceImageReport struct {
+	InstanceID string         `json:"instance_id"`
+	DryRun     bool           `json:"dry_run"`
+	Created    ManagedImage   `json:"created"`
+	Kept       []ManagedImage `json:"kept"`
+	Deleted    []ManagedImage `json:"deleted"`
+}
+
+// Write writes a human-readable summary of the report, marking the created image with + and
+// deleted images with -
+func (r *InstanceImageReport) Write(w io.Writer) error {
+	if r.DryRun {
+		_, err := fmt.Fprintln(w, "dry run, no changes made")
+		if err != nil {
+			return err
+		}
+	}
+	for _, image := range r.Kept {
+		symbol := " "
+		if image.Name == r.Created.Name {
+			symbol = "+"
+		}
+		_, err := fmt.Fprintf(w, "%s %s (%s)\n", symbol, image.Name, strings.Join(image.Reasons, ", "))
+		if err != nil {
+			return err
+		}
+	}
+	for _, image := range r.Deleted {
+		_, err := fmt.Fprintf(w, "- %s [%s]\n", image.Name, image.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateInstanceImageWithRetention creates a timestamp-named image of instance instanceID, waits for
+// it to complete, then deletes older images of the instance not kept by opts.Retention. Pruning only
+// considers images matching the naming convention or metadata key, and only occurs once the new
+// image has been created successfully
+func CreateInstanceImageWithRetention(ctx context.Context, svc ECloudService, instanceID string, opts InstanceImageOptions) (*InstanceImageReport, error) {
+	if instanceID == "" {
+		return nil, fmt.Errorf("invalid instance id")
+	}
+	err := opts.Retention.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := svc.GetInstance(instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve instance [%s]: %w", instanceID, err)
+	}
+
+	now := clockOrDefault(opts.Wait.Clock).Now().UTC().Truncate(time.Second)
+	report := &InstanceImageReport{
+		InstanceID: instanceID,
+		DryRun:     opts.DryRun,
+		Created:    ManagedImage{Name: InstanceImageName(opts.prefix(), instanceID, now), CreatedAt: now},
+	}
+
+	if !opts.DryRun {
+		task, err := svc.CreateInstanceImage(instanceID, CreateInstanceImageRequest{Name: report.Created.Name})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create image of instance [%s]: %w", instanceID, err)
+		}
+		report.Created.ID = task.ResourceID
+
+		_, err = WaitForTask(ctx, svc, task.TaskID, TaskWaitOptions{WaitOptions: opts.Wait})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	images, err := getManagedInstanceImages(svc, instance, opts)
+	if err != nil {
+		return report, err
+	}
+	// The new image isn't listed on a dry run, and may lack the metadata used for matching
+	found := false
+	for _, image := range images {
+		found = found || image.Name == report.Created.Name || (image.ID != "" && image.ID == report.Created.ID)
+	}
+	if !found {
+		images = append(images, report.Created)
+	}
+
+	report.Kept, report.Deleted = ApplyImageRetention(images, opts.Retention, now)
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for _, image := range report.Deleted {
+		taskID, err := svc.DeleteImage(image.ID)
+		if err != nil {
+			return report, fmt.Errorf("failed to delete image [%s]: %w", image.ID, err)
+		}
+		_, err = WaitForTask(ctx, svc, taskID, TaskWaitOptions{WaitOptions: opts.Wait})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// getManagedInstanceImages returns the existing images of instance identified by opts
+func getManagedInstanceImages(svc ECloudService, instance Instance, opts InstanceImageOptions) ([]ManagedImage, error) {
+	images, err := svc.GetImages(eqFilter("vpc_id", instance.VPCID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve images for vpc [%s]: %w", instance.VPCID, err)
+	}
+
+	namePrefix := opts.prefix() + "-" + instance.ID + "-"
+	var managed []ManagedImage
+	for _, image := range images {
+		if opts.MetadataKey != "" {
+			metadata, err := svc.GetImageMetadata(image.ID, eqFilter("key", opts.MetadataKey))
+			if err != nil {
+				return nil, fmt.Errorf("failed to retrieve metadata for image [%s]: %w", image.ID, err)
+			}
+			for _, m := range metadata {
+				if m.Key == opts.MetadataKey && m.Value == instance.ID {
+					managed = append(managed, ManagedImage{ID: image.ID, Name: image.Name, CreatedAt: image.CreatedAt.Time().UTC()})
+					break
+				}
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(image.Name, namePrefix) {
+			continue
+		}
+		created, err := time.Parse(instanceImageTimeFormat, strings.TrimPrefix(image.Name, namePrefix))
+		if err != nil {
+			// Images which merely share the prefix aren't managed
+			continue
+		}
+		managed = append(managed, ManagedImage{ID: image.ID, Name: image.Name, CreatedAt: created})
+	}
+	return managed, nil
+}
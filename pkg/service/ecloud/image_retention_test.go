@@ -0,0 +1,184 @@
+package ecloud
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyImageRetention(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)
+	image := func(id string, t time.Time) ManagedImage {
+		return ManagedImage{ID: id, Name: id, CreatedAt: t}
+	}
+	images := []ManagedImage{
+		image("mar13", now.Add(-time.Hour)),
+		image("mar13-early", now.Add(-10*time.Hour)),
+		image("mar12", now.AddDate(0, 0, -1)),
+		image("mar11", now.AddDate(0, 0, -2)),
+		image("mar10", now.AddDate(0, 0, -3)),
+		image("mar04", now.AddDate(0, 0, -9)),
+		image("feb20", now.AddDate(0, 0, -22)),
+	}
+	names := func(images []ManagedImage) []string {
+		var names []string
+		for _, image := range images {
+			names = append(names, image.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name   string
+		policy ImageRetentionPolicy
+		keep   []string
+	}{
+		{"KeepLast", ImageRetentionPolicy{KeepLast: 3}, []string{"mar13", "mar13-early", "mar12"}},
+		{"KeepDaily", ImageRetentionPolicy{KeepDaily: 3}, []string{"mar13", "mar12", "mar11"}},
+		// Weeks start on Monday 11th and 4th, so mar10 is the newest image of the week before
+		{"KeepWeekly", ImageRetentionPolicy{KeepWeekly: 2}, []string{"mar13", "mar10"}},
+		{"Combined", ImageRetentionPolicy{KeepLast: 1, KeepDaily: 2, KeepWeekly: 4}, []string{"mar13", "mar12", "mar10", "feb20"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep, prune := ApplyImageRetention(images, tt.policy, now)
+
+			assert.Equal(t, tt.keep, names(keep))
+			assert.Len(t, prune, len(images)-len(tt.keep))
+		})
+	}
+
+	t.Run("Reasons", func(t *testing.T) {
+		keep, _ := ApplyImageRetention(images, ImageRetentionPolicy{KeepLast: 1, KeepDaily: 1, KeepWeekly: 1}, now)
+
+		assert.Equal(t, []string{"last", "daily", "weekly"}, keep[0].Reasons)
+	})
+}
+
+type fakeImageService struct {
+	fakeTaskService
+
+	images   []Image
+	metadata map[string][]ImageMetadata
+	deleted  []string
+}
+
+func (f *fakeImageService) GetInstance(instanceID string) (Instance, error) {
+	return Instance{ID: instanceID, VPCID: "vpc-abcdef12"}, nil
+}
+
+func (f *fakeImageService) CreateInstanceImage(instanceID string, req CreateInstanceImageRequest) (TaskReference, error) {
+	f.images = append(f.images, Image{ID: "img-new", Name: req.Name, CreatedAt: "2024-03-13T12:00:00+0000"})
+	return TaskReference{TaskID: "task-create", ResourceID: "img-new"}, nil
+}
+
+func (f *fakeImageService) GetImages(parameters connection.APIRequestParameters) ([]Image, error) {
+	return f.images, nil
+}
+
+func (f *fakeImageService) GetImageMetadata(imageID string, parameters connection.APIRequestParameters) ([]ImageMetadata, error) {
+	return f.metadata[imageID], nil
+}
+
+func (f *fakeImageService) DeleteImage(imageID string) (string, error) {
+	f.deleted = append(f.deleted, imageID)
+	return "task-delete-" + imageID, nil
+}
+
+func newFakeImageService() *fakeImageService {
+	return &fakeImageService{
+		images: []Image{
+			{ID: "img-00000001", Name: "backup-i-abcdef12-20240312T020000Z", CreatedAt: "2024-03-12T02:00:05+0000"},
+			{ID: "img-00000002", Name: "backup-i-abcdef12-20240311T020000Z", CreatedAt: "2024-03-11T02:00:05+0000"},
+			{ID: "img-00000003", Name: "backup-i-abcdef12-20240310T020000Z", CreatedAt: "2024-03-10T02:00:05+0000"},
+			// Not managed: another instance, and a name which merely shares the prefix
+			{ID: "img-00000004", Name: "backup-i-abcdef34-20240301T020000Z", CreatedAt: "2024-03-01T02:00:05+0000"},
+			{ID: "img-00000005", Name: "backup-i-abcdef12-golden", CreatedAt: "2024-01-01T00:00:00+0000"},
+		},
+	}
+}
+
+func TestCreateInstanceImageWithRetention(t *testing.T) {
+	clock := newFakeClock()
+	clock.now = time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)
+	opts := InstanceImageOptions{
+		Retention: ImageRetentionPolicy{KeepLast: 2},
+		Wait:      WaitOptions{Interval: time.Second, Clock: clock},
+	}
+
+	t.Run("CreatesAndPrunes", func(t *testing.T) {
+		svc := newFakeImageService()
+
+		report, err := CreateInstanceImageWithRetention(context.Background(), svc, "i-abcdef12", opts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, ManagedImage{ID: "img-new", Name: "backup-i-abcdef12-20240313T120000Z", CreatedAt: clock.now}, report.Created)
+		assert.Equal(t, []string{"img-00000002", "img-00000003"}, svc.deleted)
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		svc := newFakeImageService()
+		opts := opts
+		opts.DryRun = true
+
+		report, err := CreateInstanceImageWithRetention(context.Background(), svc, "i-abcdef12", opts)
+
+		assert.Nil(t, err)
+		assert.Empty(t, svc.deleted)
+		assert.Len(t, svc.images, 5)
+		buf := new(bytes.Buffer)
+		assert.Nil(t, report.Write(buf))
+		assert.Equal(t, `dry run, no changes made
++ backup-i-abcdef12-20240313T120000Z (last)
+  backup-i-abcdef12-20240312T020000Z (last)
+- backup-i-abcdef12-20240311T020000Z [img-00000002]
+- backup-i-abcdef12-20240310T020000Z [img-00000003]
+`, buf.String())
+	})
+
+	t.Run("MetadataKey", func(t *testing.T) {
+		svc := newFakeImageService()
+		svc.metadata = map[string][]ImageMetadata{
+			"img-00000004": {{Key: "source_instance", Value: "i-abcdef12"}},
+			"img-00000005": {{Key: "source_instance", Value: "i-abcdef12"}},
+		}
+		opts := opts
+		opts.MetadataKey = "source_instance"
+		opts.Retention = ImageRetentionPolicy{KeepLast: 1, KeepWeekly: 2}
+
+		report, err := CreateInstanceImageWithRetention(context.Background(), svc, "i-abcdef12", opts)
+
+		assert.Nil(t, err)
+		// The new image lacks metadata but is always considered
+		assert.Equal(t, "img-new", report.Kept[0].ID)
+		assert.Equal(t, []string{"img-00000004", "img-00000005"}, svc.deleted)
+	})
+
+	t.Run("InvalidPolicy_ReturnsError", func(t *testing.T) {
+		_, err := CreateInstanceImageWithRetention(context.Background(), newFakeImageService(), "i-abcdef12", InstanceImageOptions{})
+
+		assert.Equal(t, "retention policy must keep at least one image", err.Error())
+	})
+
+	t.Run("TaskFailed_DoesNotPrune", func(t *testing.T) {
+		svc := &failingImageTaskService{fakeImageService: newFakeImageService()}
+
+		_, err := CreateInstanceImageWithRetention(context.Background(), svc, "i-abcdef12", opts)
+
+		assert.IsType(t, &TaskFailedError{}, err)
+		assert.Empty(t, svc.deleted)
+	})
+}
+
+type failingImageTaskService struct {
+	*fakeImageService
+}
+
+func (f *failingImageTaskService) GetTask(taskID string) (Task, error) {
+	return Task{ID: taskID, Status: TaskStatusFailed}, nil
+}
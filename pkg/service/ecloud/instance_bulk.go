@@ -0,0 +1,292 @@
+package ecloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+)
+
+const defaultInstanceBulkConcurrency = 4
+
+// ErrInstanceBulkDeclined is returned by RunInstanceBulkAction when the confirmation hook declines
+var ErrInstanceBulkDeclined = errors.New("bulk instance action declined")
+
+// InstanceSelector selects instances for a bulk action. Parameters and IDs are applied by the API,
+// with Tags and NamePattern then applied to the returned instances
+type InstanceSelector struct {
+	Parameters connection.APIRequestParameters
+	// IDs restricts selection to the given instances, each of which must exist
+	IDs []string
+	// Tags lists tags which an instance must all carry. Each is matched against the tag ID, name,
+	// or scope and name in the form "scope:name"
+	Tags []string
+	// NamePattern is a glob (see path.Match) which instance names must match, e.g. "web-*"
+	NamePattern string
+}
+
+// Matches returns true if instance matches the selector's IDs, Tags and NamePattern
+func (s InstanceSelector) Matches(instance Instance) bool {
+	if len(s.IDs) > 0 && !slices.Contains(s.IDs, instance.ID) {
+		return false
+	}
+
+	if s.NamePattern != "" {
+		matched, _ := path.Match(s.NamePattern, instance.Name)
+		if !matched {
+			return false
+		}
+	}
+
+	for _, tag := range s.Tags {
+		if !instanceHasTag(instance, tag) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func instanceHasTag(instance Instance, tag string) bool {
+	for _, t := range instance.Tags {
+		if tag == t.ID || tag == t.Name || tag == t.Scope+":"+t.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectInstances retrieves instances matching selector
+func SelectInstances(svc ECloudService, selector InstanceSelector) ([]Instance, error) {
+	_, err := path.Match(selector.NamePattern, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid name pattern [%s]: %w", selector.NamePattern, err)
+	}
+
+	parameters := selector.Parameters
+	if len(selector.IDs) > 0 {
+		parameters = selector.Parameters.Copy()
+		parameters.WithFilter(connection.APIRequestFiltering{
+			Property: "id",
+			Operator: connection.INOperator,
+			Value:    selector.IDs,
+		})
+	}
+
+	instances, err := svc.GetInstances(parameters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve instances: %w", err)
+	}
+
+	for _, id := range selector.IDs {
+		if !slices.ContainsFunc(instances, func(i Instance) bool { return i.ID == id }) {
+			return nil, &InstanceNotFoundError{ID: id}
+		}
+	}
+
+	var selected []Instance
+	for _, instance := range instances {
+		if selector.Matches(instance) {
+			selected = append(selected, instance)
+		}
+	}
+	return selected, nil
+}
+
+// InstanceAction is an operation run against each instance selected by RunInstanceBulkAction
+type InstanceAction struct {
+	Name string
+	// Run performs the action, returning the ID of the task started, if any
+	Run func(svc ECloudService, instance Instance) (string, error)
+	// Sync indicates the action triggers an instance sync rather than a task
+	Sync bool
+}
+
+var (
+	InstanceActionPowerOn = InstanceAction{Name: "power_on", Run: func(svc ECloudService, instance Instance) (string, error) {
+		return svc.PowerOnInstance(instance.ID)
+	}}
+	InstanceActionPowerOff = InstanceAction{Name: "power_off", Run: func(svc ECloudService, instance Instance) (string, error) {
+		return svc.PowerOffInstance(instance.ID)
+	}}
+	InstanceActionPowerShutdown = InstanceAction{Name: "power_shutdown", Run: func(svc ECloudService, instance Instance) (string, error) {
+		return svc.PowerShutdownInstance(instance.ID)
+	}}
+	InstanceActionPowerRestart = InstanceAction{Name: "power_restart", Run: func(svc ECloudService, instance Instance) (string, error) {
+		return svc.PowerRestartInstance(instance.ID)
+	}}
+	InstanceActionPowerReset = InstanceAction{Name: "power_reset", Run: func(svc ECloudService, instance Instance) (string, error) {
+		return svc.PowerResetInstance(instance.ID)
+	}}
+	InstanceActionLock = InstanceAction{Name: "lock", Run: func(svc ECloudService, instance Instance) (string, error) {
+		return "", svc.LockInstance(instance.ID)
+	}}
+	InstanceActionUnlock = InstanceAction{Name: "unlock", Run: func(svc ECloudService, instance Instance) (string, error) {
+		return "", svc.UnlockInstance(instance.ID)
+	}}
+)
+
+// InstancePatchAction returns an action which patches each instance with req
+func InstancePatchAction(req PatchInstanceRequest) InstanceAction {
+	return InstanceAction{
+		Name: "patch",
+		Run: func(svc ECloudService, instance Instance) (string, error) {
+			return "", svc.PatchInstance(instance.ID, req)
+		},
+		Sync: true,
+	}
+}
+
+// InstanceScriptAction returns an action which executes the script in req on each instance
+func InstanceScriptAction(req ExecuteInstanceScriptRequest) InstanceAction {
+	return InstanceAction{
+		Name: "execute_script",
+		Run: func(svc ECloudService, instance Instance) (string, error) {
+			return svc.ExecuteInstanceScript(instance.ID, req)
+		},
+	}
+}
+
+// InstanceBulkResult is the outcome of a bulk action for a single instance
+type InstanceBulkResult struct {
+	Instance Instance
+	TaskID   string
+	// Task is the final state of the task started by the action, where WaitForCompletion is set
+	Task Task
+	Err  error
+}
+
+// InstanceBulkReport describes the outcome of RunInstanceBulkAction. Results are listed in
+// selection order
+type InstanceBulkReport struct {
+	Action  string
+	DryRun  bool
+	Results []InstanceBulkResult
+}
+
+// Failed returns the results for instances where the action failed
+func (r *InstanceBulkReport) Failed() []InstanceBulkResult {
+	var failed []InstanceBulkResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns the errors for all failed instances joined together, or nil if none failed
+func (r *InstanceBulkReport) Err() error {
+	var errs []error
+	for _, result := range r.Failed() {
+		errs = append(errs, fmt.Errorf("failed to %s instance [%s]: %w", r.Action, result.Instance.ID, result.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// InstanceBulkOptions configures RunInstanceBulkAction
+type InstanceBulkOptions struct {
+	// Concurrency limits the number of instances acted on concurrently, defaulting to 4
+	Concurrency int
+	// DryRun selects instances and reports them without running the action or confirming
+	DryRun bool
+	// Confirm is invoked with the selected instances before the action is run. Returning false
+	// aborts with ErrInstanceBulkDeclined
+	Confirm func(action string, instances []Instance) (bool, error)
+	// WaitForCompletion waits for the task started (or sync triggered) by the action for each
+	// instance before reporting it complete
+	WaitForCompletion bool
+	// Wait configures polling when WaitForCompletion is set
+	Wait WaitOptions
+	// Progress is invoked as each instance completes. Progress may be invoked concurrently
+	Progress func(result InstanceBulkResult)
+}
+
+// RunInstanceBulkAction runs action concurrently against the instances matching selector. Failures
+// for individual instances are recorded in the returned report rather than returned, see
+// InstanceBulkReport.Err. For example:
+//
+//	report, err := ecloud.RunInstanceBulkAction(ctx, svc, ecloud.InstanceSelector{Tags: []string{"env=dev"}},
+//		ecloud.InstanceActionPowerOff, ecloud.InstanceBulkOptions{WaitForCompletion: true})
+func RunInstanceBulkAction(ctx context.Context, svc ECloudService, selector InstanceSelector, action InstanceAction, opts InstanceBulkOptions) (*InstanceBulkReport, error) {
+	if action.Run == nil {
+		return nil, fmt.Errorf("invalid action")
+	}
+
+	instances, err := SelectInstances(svc, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &InstanceBulkReport{Action: action.Name, DryRun: opts.DryRun, Results: make([]InstanceBulkResult, len(instances))}
+	for i, instance := range instances {
+		report.Results[i].Instance = instance
+	}
+	if opts.DryRun || len(instances) == 0 {
+		return report, nil
+	}
+
+	if opts.Confirm != nil {
+		confirmed, err := opts.Confirm(action.Name, instances)
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			return nil, ErrInstanceBulkDeclined
+		}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultInstanceBulkConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+
+	for i := range report.Results {
+		result := &report.Results[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				result.TaskID, result.Task, result.Err = runInstanceAction(ctx, svc, action, result.Instance, opts)
+			case <-ctx.Done():
+				result.Err = ctx.Err()
+			}
+
+			if opts.Progress != nil {
+				opts.Progress(*result)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report, ctx.Err()
+}
+
+func runInstanceAction(ctx context.Context, svc ECloudService, action InstanceAction, instance Instance, opts InstanceBulkOptions) (string, Task, error) {
+	if ctx.Err() != nil {
+		return "", Task{}, ctx.Err()
+	}
+
+	taskID, err := action.Run(svc, instance)
+	if err != nil || !opts.WaitForCompletion {
+		return taskID, Task{}, err
+	}
+
+	var task Task
+	switch {
+	case taskID != "":
+		task, err = WaitForTask(ctx, svc, taskID, TaskWaitOptions{WaitOptions: opts.Wait})
+	case action.Sync:
+		_, err = WaitForSync(ctx, svc.GetInstance, instance.ID, func(i Instance) ResourceSync { return i.Sync }, SyncWaitOptions[Instance]{WaitOptions: opts.Wait})
+	}
+	return taskID, task, err
+}
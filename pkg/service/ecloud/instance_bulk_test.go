@@ -0,0 +1,226 @@
+package ecloud
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeBulkInstanceService struct {
+	ECloudService
+
+	instances   []Instance
+	failTasks   map[string]bool
+	failActions map[string]bool
+
+	mutex sync.Mutex
+	calls []string
+}
+
+func (f *fakeBulkInstanceService) record(call string, instanceID string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls = append(f.calls, call+" "+instanceID)
+	if f.failActions[instanceID] {
+		return errors.New("test error")
+	}
+	return nil
+}
+
+func (f *fakeBulkInstanceService) sortedCalls() []string {
+	sort.Strings(f.calls)
+	return f.calls
+}
+
+func (f *fakeBulkInstanceService) GetInstances(parameters connection.APIRequestParameters) ([]Instance, error) {
+	vpcID := filterValue(parameters, "vpc_id")
+	var instances []Instance
+	for _, i := range f.instances {
+		if vpcID == "" || i.VPCID == vpcID {
+			instances = append(instances, i)
+		}
+	}
+	return instances, nil
+}
+
+func (f *fakeBulkInstanceService) GetInstance(instanceID string) (Instance, error) {
+	f.record("GetInstance", instanceID)
+	return Instance{ID: instanceID, Sync: ResourceSync{Status: SyncStatusComplete}}, nil
+}
+
+func (f *fakeBulkInstanceService) GetTask(taskID string) (Task, error) {
+	if f.failTasks[taskID] {
+		return Task{ID: taskID, Name: "instance_power_off", ResourceID: strings.TrimPrefix(taskID, "task-"), Status: TaskStatusFailed}, nil
+	}
+	return Task{ID: taskID, Status: TaskStatusComplete}, nil
+}
+
+func (f *fakeBulkInstanceService) PowerOffInstance(instanceID string) (string, error) {
+	return "task-" + instanceID, f.record("PowerOffInstance", instanceID)
+}
+
+func (f *fakeBulkInstanceService) LockInstance(instanceID string) error {
+	return f.record("LockInstance", instanceID)
+}
+
+func (f *fakeBulkInstanceService) PatchInstance(instanceID string, req PatchInstanceRequest) error {
+	return f.record("PatchInstance", instanceID)
+}
+
+func newFakeBulkInstanceService() *fakeBulkInstanceService {
+	return &fakeBulkInstanceService{
+		instances: []Instance{
+			{ID: "i-00000001", Name: "web-1", VPCID: "vpc-abcdef12", Tags: []ResourceTag{{ID: "tag-dev", Name: "env=dev", Scope: "ecloud"}}},
+			{ID: "i-00000002", Name: "web-2", VPCID: "vpc-abcdef12", Tags: []ResourceTag{{ID: "tag-prod", Name: "env=prod", Scope: "ecloud"}}},
+			{ID: "i-00000003", Name: "db-1", VPCID: "vpc-abcdef12", Tags: []ResourceTag{{ID: "tag-dev", Name: "env=dev", Scope: "ecloud"}}},
+			{ID: "i-00000004", Name: "web-3", VPCID: "vpc-abcdef34"},
+		},
+	}
+}
+
+func TestSelectInstances(t *testing.T) {
+	testCases := []struct {
+		name     string
+		selector InstanceSelector
+		expected []string
+	}{
+		{name: "All", selector: InstanceSelector{}, expected: []string{"i-00000001", "i-00000002", "i-00000003", "i-00000004"}},
+		{name: "Filter", selector: InstanceSelector{Parameters: eqFilter("vpc_id", "vpc-abcdef34")}, expected: []string{"i-00000004"}},
+		{name: "TagName", selector: InstanceSelector{Tags: []string{"env=dev"}}, expected: []string{"i-00000001", "i-00000003"}},
+		{name: "TagScopeAndName", selector: InstanceSelector{Tags: []string{"ecloud:env=prod"}}, expected: []string{"i-00000002"}},
+		{name: "TagAndNamePattern", selector: InstanceSelector{Tags: []string{"tag-dev"}, NamePattern: "web-*"}, expected: []string{"i-00000001"}},
+		{name: "NoMatch", selector: InstanceSelector{NamePattern: "cache-*"}, expected: nil},
+		{name: "IDs", selector: InstanceSelector{IDs: []string{"i-00000002", "i-00000004"}}, expected: []string{"i-00000002", "i-00000004"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			instances, err := SelectInstances(newFakeBulkInstanceService(), tc.selector)
+
+			assert.Nil(t, err)
+			var ids []string
+			for _, i := range instances {
+				ids = append(ids, i.ID)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+
+	t.Run("InvalidNamePattern_ReturnsError", func(t *testing.T) {
+		_, err := SelectInstances(newFakeBulkInstanceService(), InstanceSelector{NamePattern: "web-["})
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("UnknownID_ReturnsNotFoundError", func(t *testing.T) {
+		_, err := SelectInstances(newFakeBulkInstanceService(), InstanceSelector{IDs: []string{"i-00000001", "i-unknown"}})
+
+		assert.IsType(t, &InstanceNotFoundError{}, err)
+	})
+}
+
+func TestRunInstanceBulkAction(t *testing.T) {
+	opts := InstanceBulkOptions{Wait: WaitOptions{Interval: time.Second, Clock: newFakeClock()}}
+	devSelector := InstanceSelector{Tags: []string{"env=dev"}}
+
+	t.Run("RunsActionForSelectedInstances", func(t *testing.T) {
+		svc := newFakeBulkInstanceService()
+
+		report, err := RunInstanceBulkAction(context.Background(), svc, devSelector, InstanceActionPowerOff, opts)
+
+		assert.Nil(t, err)
+		assert.Nil(t, report.Err())
+		assert.Equal(t, "power_off", report.Action)
+		assert.Len(t, report.Results, 2)
+		assert.Equal(t, "task-i-00000001", report.Results[0].TaskID)
+		assert.Equal(t, []string{"PowerOffInstance i-00000001", "PowerOffInstance i-00000003"}, svc.sortedCalls())
+	})
+
+	t.Run("DryRun_DoesNotRunAction", func(t *testing.T) {
+		svc := newFakeBulkInstanceService()
+		opts := opts
+		opts.DryRun = true
+		opts.Confirm = func(action string, instances []Instance) (bool, error) {
+			t.Fatal("confirm should not be invoked for dry run")
+			return false, nil
+		}
+
+		report, err := RunInstanceBulkAction(context.Background(), svc, devSelector, InstanceActionLock, opts)
+
+		assert.Nil(t, err)
+		assert.True(t, report.DryRun)
+		assert.Len(t, report.Results, 2)
+		assert.Empty(t, svc.calls)
+	})
+
+	t.Run("ConfirmDeclined_ReturnsError", func(t *testing.T) {
+		svc := newFakeBulkInstanceService()
+		opts := opts
+		var confirmed []Instance
+		opts.Confirm = func(action string, instances []Instance) (bool, error) {
+			confirmed = instances
+			return false, nil
+		}
+
+		_, err := RunInstanceBulkAction(context.Background(), svc, devSelector, InstanceActionLock, opts)
+
+		assert.Equal(t, ErrInstanceBulkDeclined, err)
+		assert.Len(t, confirmed, 2)
+		assert.Empty(t, svc.calls)
+	})
+
+	t.Run("FailedInstances_ReportedAndAggregated", func(t *testing.T) {
+		svc := newFakeBulkInstanceService()
+		svc.failActions = map[string]bool{"i-00000001": true}
+		svc.failTasks = map[string]bool{"task-i-00000003": true}
+		opts := opts
+		opts.WaitForCompletion = true
+		var progress []string
+		var mutex sync.Mutex
+		opts.Progress = func(result InstanceBulkResult) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			progress = append(progress, result.Instance.ID)
+		}
+
+		report, err := RunInstanceBulkAction(context.Background(), svc, devSelector, InstanceActionPowerOff, opts)
+
+		assert.Nil(t, err)
+		assert.Len(t, report.Failed(), 2)
+		assert.IsType(t, &TaskFailedError{}, report.Results[1].Err)
+		assert.Equal(t, "failed to power_off instance [i-00000001]: test error\nfailed to power_off instance [i-00000003]: task [task-i-00000003] (instance_power_off) for resource [i-00000003] failed", report.Err().Error())
+		assert.ElementsMatch(t, []string{"i-00000001", "i-00000003"}, progress)
+	})
+
+	t.Run("SyncAction_WaitsForSync", func(t *testing.T) {
+		svc := newFakeBulkInstanceService()
+		opts := opts
+		opts.WaitForCompletion = true
+		opts.Concurrency = 1
+
+		report, err := RunInstanceBulkAction(context.Background(), svc, InstanceSelector{NamePattern: "db-*"}, InstancePatchAction(PatchInstanceRequest{RAMCapacity: 4096}), opts)
+
+		assert.Nil(t, err)
+		assert.Nil(t, report.Err())
+		assert.Equal(t, []string{"PatchInstance i-00000003", "GetInstance i-00000003"}, svc.calls)
+	})
+
+	t.Run("CancelledContext_ReturnsError", func(t *testing.T) {
+		svc := newFakeBulkInstanceService()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		report, err := RunInstanceBulkAction(ctx, svc, devSelector, InstanceActionLock, opts)
+
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Len(t, report.Failed(), 2)
+		assert.Empty(t, svc.calls)
+	})
+}
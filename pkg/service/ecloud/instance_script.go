@@ -0,0 +1,166 @@
+package ecloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// InstanceScriptOptions configures RunInstanceScript
+type InstanceScriptOptions struct {
+	// Concurrency limits the number of instances the script runs on concurrently, defaulting to 4
+	Concurrency int
+	// Confirm is invoked with the selected instances before the script is run. Returning false
+	// aborts with ErrInstanceBulkDeclined
+	Confirm func(instances []Instance) (bool, error)
+	// Wait configures polling for script tasks
+	Wait WaitOptions
+	// Progress is invoked as each instance completes. Progress may be invoked concurrently
+	Progress func(result InstanceScriptResult)
+}
+
+// InstanceScriptResult is the outcome of a script for a single instance. The API doesn't return
+// script output, so the outcome is limited to the final state of the script task
+type InstanceScriptResult struct {
+	InstanceID   string     `json:"instance_id"`
+	InstanceName string     `json:"instance_name"`
+	TaskID       string     `json:"task_id,omitempty"`
+	Status       TaskStatus `json:"status,omitempty"`
+	// StartedAt and FinishedAt are the creation and last update times of the task
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Duration returns the time taken by the script task, or zero if unknown
+func (r InstanceScriptResult) Duration() time.Duration {
+	if r.StartedAt.IsZero() || r.FinishedAt.IsZero() {
+		return 0
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// Succeeded returns true if the script task completed
+func (r InstanceScriptResult) Succeeded() bool {
+	return r.Error == "" && r.Status == TaskStatusComplete
+}
+
+func newInstanceScriptResult(result InstanceBulkResult) InstanceScriptResult {
+	r := InstanceScriptResult{
+		InstanceID:   result.Instance.ID,
+		InstanceName: result.Instance.Name,
+		TaskID:       result.TaskID,
+		Status:       result.Task.Status,
+	}
+	if result.Task.CreatedAt != "" {
+		r.StartedAt = result.Task.CreatedAt.Time().UTC()
+	}
+	if result.Task.UpdatedAt != "" {
+		r.FinishedAt = result.Task.UpdatedAt.Time().UTC()
+	}
+	if result.Err != nil {
+		r.Error = result.Err.Error()
+	}
+	return r
+}
+
+// InstanceScriptReport describes the outcome of RunInstanceScript. Results are listed in
+// selection order
+type InstanceScriptReport struct {
+	Results []InstanceScriptResult `json:"results"`
+}
+
+// Failed returns the results for instances where the script failed or couldn't be run
+func (r *InstanceScriptReport) Failed() []InstanceScriptResult {
+	var failed []InstanceScriptResult
+	for _, result := range r.Results {
+		if !result.Succeeded() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns the errors for all failed instances joined together, or nil if none failed
+func (r *InstanceScriptReport) Err() error {
+	var errs []error
+	for _, result := range r.Failed() {
+		errs = append(errs, fmt.Errorf("failed to execute script on instance [%s]: %s", result.InstanceID, result.Error))
+	}
+	return errors.Join(errs...)
+}
+
+// Write writes a human-readable summary of the report, one line per instance
+func (r *InstanceScriptReport) Write(w io.Writer) error {
+	for _, result := range r.Results {
+		status := result.Status.String()
+		if status == "" {
+			status = "not started"
+		}
+		line := fmt.Sprintf("%s (%s): %s", result.InstanceID, result.InstanceName, status)
+		if d := result.Duration(); d > 0 {
+			line += fmt.Sprintf(" in %s", d)
+		}
+		if result.Error != "" {
+			line += ": " + result.Error
+		}
+		_, err := fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d instances, %d failed\n", len(r.Results), len(r.Failed()))
+	return err
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *InstanceScriptReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// RunInstanceScript executes the script in req on the instances matching selector, waiting for the
+// task of each and collecting the outcomes into a report. Failures for individual instances are
+// recorded in the report rather than returned, see InstanceScriptReport.Err. req.Password is only
+// sent to the API, and never included in the report or logged. For example:
+//
+//	report, err := ecloud.RunInstanceScript(ctx, svc, ecloud.InstanceSelector{IDs: []string{"i-abcdef12"}},
+//		ecloud.ExecuteInstanceScriptRequest{Script: "apt-get update", Username: "root", Password: password},
+//		ecloud.InstanceScriptOptions{Concurrency: 8})
+func RunInstanceScript(ctx context.Context, svc ECloudService, selector InstanceSelector, req ExecuteInstanceScriptRequest, opts InstanceScriptOptions) (*InstanceScriptReport, error) {
+	if req.Script == "" {
+		return nil, fmt.Errorf("script is required")
+	}
+
+	bulkOpts := InstanceBulkOptions{
+		Concurrency:       opts.Concurrency,
+		WaitForCompletion: true,
+		Wait:              opts.Wait,
+	}
+	if opts.Confirm != nil {
+		bulkOpts.Confirm = func(action string, instances []Instance) (bool, error) {
+			return opts.Confirm(instances)
+		}
+	}
+	if opts.Progress != nil {
+		bulkOpts.Progress = func(result InstanceBulkResult) {
+			opts.Progress(newInstanceScriptResult(result))
+		}
+	}
+
+	bulkReport, err := RunInstanceBulkAction(ctx, svc, selector, InstanceScriptAction(req), bulkOpts)
+	if bulkReport == nil {
+		return nil, err
+	}
+
+	report := &InstanceScriptReport{Results: make([]InstanceScriptResult, len(bulkReport.Results))}
+	for i, result := range bulkReport.Results {
+		report.Results[i] = newInstanceScriptResult(result)
+	}
+	return report, err
+}
@@ -0,0 +1,105 @@
+package ecloud
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeScriptInstanceService struct {
+	*fakeBulkInstanceService
+
+	requests []ExecuteInstanceScriptRequest
+}
+
+func (f *fakeScriptInstanceService) ExecuteInstanceScript(instanceID string, req ExecuteInstanceScriptRequest) (string, error) {
+	f.mutex.Lock()
+	f.requests = append(f.requests, req)
+	f.mutex.Unlock()
+	return "task-" + instanceID, f.record("ExecuteInstanceScript", instanceID)
+}
+
+func (f *fakeScriptInstanceService) GetTask(taskID string) (Task, error) {
+	task, err := f.fakeBulkInstanceService.GetTask(taskID)
+	task.CreatedAt = "2024-01-01T00:00:00+0000"
+	task.UpdatedAt = "2024-01-01T00:01:30+0000"
+	return task, err
+}
+
+func TestRunInstanceScript(t *testing.T) {
+	req := ExecuteInstanceScriptRequest{Script: "uptime", Username: "root", Password: "s3cr3t"}
+	opts := InstanceScriptOptions{Wait: WaitOptions{Interval: time.Second, Clock: newFakeClock()}}
+
+	t.Run("CollectsResults", func(t *testing.T) {
+		svc := &fakeScriptInstanceService{fakeBulkInstanceService: newFakeBulkInstanceService()}
+		svc.failTasks = map[string]bool{"task-i-00000002": true}
+		svc.failActions = map[string]bool{"i-00000004": true}
+		var progressed []string
+		opts := opts
+		opts.Progress = func(result InstanceScriptResult) {
+			svc.mutex.Lock()
+			defer svc.mutex.Unlock()
+			progressed = append(progressed, result.InstanceID)
+		}
+
+		report, err := RunInstanceScript(context.Background(), svc, InstanceSelector{IDs: []string{"i-00000001", "i-00000002", "i-00000004"}}, req, opts)
+
+		assert.Nil(t, err)
+		assert.Len(t, progressed, 3)
+		assert.Equal(t, []ExecuteInstanceScriptRequest{req, req, req}, svc.requests)
+		assert.Equal(t, InstanceScriptResult{
+			InstanceID:   "i-00000001",
+			InstanceName: "web-1",
+			TaskID:       "task-i-00000001",
+			Status:       TaskStatusComplete,
+			StartedAt:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			FinishedAt:   time.Date(2024, 1, 1, 0, 1, 30, 0, time.UTC),
+		}, report.Results[0])
+		assert.Equal(t, 90*time.Second, report.Results[0].Duration())
+		assert.Len(t, report.Failed(), 2)
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, report.Write(buf))
+		assert.Equal(t, `i-00000001 (web-1): complete in 1m30s
+i-00000002 (web-2): failed in 1m30s: task [task-i-00000002] (instance_power_off) for resource [i-00000002] failed
+i-00000004 (web-3): not started: test error
+3 instances, 2 failed
+`, buf.String())
+	})
+
+	t.Run("PasswordNeverReported", func(t *testing.T) {
+		svc := &fakeScriptInstanceService{fakeBulkInstanceService: newFakeBulkInstanceService()}
+		svc.failActions = map[string]bool{"i-00000001": true}
+
+		report, err := RunInstanceScript(context.Background(), svc, InstanceSelector{}, req, opts)
+
+		assert.Nil(t, err)
+		buf := new(bytes.Buffer)
+		assert.Nil(t, report.Write(buf))
+		assert.Nil(t, report.WriteJSON(buf))
+		assert.NotContains(t, buf.String(), req.Password)
+		assert.NotContains(t, report.Err().Error(), req.Password)
+	})
+
+	t.Run("Declined_ReturnsError", func(t *testing.T) {
+		svc := &fakeScriptInstanceService{fakeBulkInstanceService: newFakeBulkInstanceService()}
+		opts := opts
+		opts.Confirm = func(instances []Instance) (bool, error) { return false, nil }
+
+		_, err := RunInstanceScript(context.Background(), svc, InstanceSelector{}, req, opts)
+
+		assert.True(t, errors.Is(err, ErrInstanceBulkDeclined))
+		assert.Empty(t, svc.requests)
+	})
+
+	t.Run("EmptyScript_ReturnsError", func(t *testing.T) {
+		_, err := RunInstanceScript(context.Background(), newFakeBulkInstanceService(), InstanceSelector{}, ExecuteInstanceScriptRequest{}, opts)
+
+		assert.True(t, strings.Contains(err.Error(), "script is required"))
+	})
+}
@@ -0,0 +1,678 @@
+package ecloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+)
+
+// policyDefaultAction is applied when no rule matches a packet and the policy has no catch-all rule
+const policyDefaultAction = "DROP"
+
+// FirewallPolicySet is a snapshot of a router's firewall policies, rules and rule ports. It can be
+// retrieved with GetFirewallPolicySet, or decoded from JSON with ReadFirewallPolicySet for offline use
+type FirewallPolicySet struct {
+	RouterID string             `json:"router_id"`
+	Policies []FirewallPolicy   `json:"policies"`
+	Rules    []FirewallRule     `json:"rules"`
+	Ports    []FirewallRulePort `json:"ports"`
+}
+
+// NetworkPolicySet is a snapshot of a network policy, its rules (including the catch-all rule) and
+// rule ports. It can be retrieved with GetNetworkPolicySet, or decoded from JSON with ReadNetworkPolicySet
+type NetworkPolicySet struct {
+	Policy NetworkPolicy     `json:"policy"`
+	Rules  []NetworkRule     `json:"rules"`
+	Ports  []NetworkRulePort `json:"ports"`
+}
+
+// GetFirewallPolicySet retrieves the firewall policies, rules and rule ports for router with ID routerID
+func GetFirewallPolicySet(svc ECloudService, routerID string) (FirewallPolicySet, error) {
+	set := FirewallPolicySet{RouterID: routerID}
+	if routerID == "" {
+		return set, fmt.Errorf("invalid router id")
+	}
+
+	policies, err := svc.GetFirewallPolicies(eqFilter("router_id", routerID))
+	if err != nil {
+		return set, fmt.Errorf("failed to retrieve firewall policies for router [%s]: %w", routerID, err)
+	}
+	set.Policies = policies
+
+	for _, policy := range policies {
+		rules, err := svc.GetFirewallPolicyFirewallRules(policy.ID, connection.APIRequestParameters{})
+		if err != nil {
+			return set, fmt.Errorf("failed to retrieve firewall rules for policy [%s]: %w", policy.ID, err)
+		}
+		set.Rules = append(set.Rules, rules...)
+
+		for _, rule := range rules {
+			ports, err := svc.GetFirewallRuleFirewallRulePorts(rule.ID, connection.APIRequestParameters{})
+			if err != nil {
+				return set, fmt.Errorf("failed to retrieve ports for firewall rule [%s]: %w", rule.ID, err)
+			}
+			set.Ports = append(set.Ports, ports...)
+		}
+	}
+
+	return set, nil
+}
+
+// GetNetworkPolicySet retrieves the network policy with ID policyID along with its rules and rule ports
+func GetNetworkPolicySet(svc ECloudService, policyID string) (NetworkPolicySet, error) {
+	var set NetworkPolicySet
+	if policyID == "" {
+		return set, fmt.Errorf("invalid network policy id")
+	}
+
+	policy, err := svc.GetNetworkPolicy(policyID)
+	if err != nil {
+		return set, fmt.Errorf("failed to retrieve network policy [%s]: %w", policyID, err)
+	}
+	set.Policy = policy
+
+	rules, err := svc.GetNetworkPolicyNetworkRules(policyID, connection.APIRequestParameters{})
+	if err != nil {
+		return set, fmt.Errorf("failed to retrieve network rules for policy [%s]: %w", policyID, err)
+	}
+	set.Rules = rules
+
+	for _, rule := range rules {
+		ports, err := svc.GetNetworkRuleNetworkRulePorts(rule.ID, connection.APIRequestParameters{})
+		if err != nil {
+			return set, fmt.Errorf("failed to retrieve ports for network rule [%s]: %w", rule.ID, err)
+		}
+		set.Ports = append(set.Ports, ports...)
+	}
+
+	return set, nil
+}
+
+// ReadFirewallPolicySet decodes a JSON encoded FirewallPolicySet from r
+func ReadFirewallPolicySet(r io.Reader) (FirewallPolicySet, error) {
+	var set FirewallPolicySet
+	err := json.NewDecoder(r).Decode(&set)
+	return set, err
+}
+
+// ReadNetworkPolicySet decodes a JSON encoded NetworkPolicySet from r
+func ReadNetworkPolicySet(r io.Reader) (NetworkPolicySet, error) {
+	var set NetworkPolicySet
+	err := json.NewDecoder(r).Decode(&set)
+	return set, err
+}
+
+// PolicyPacket describes a packet to evaluate against a policy. A zero SourcePort or DestinationPort
+// only matches rules which don't restrict that port, and an empty Direction matches rules of any direction
+type PolicyPacket struct {
+	Source          netip.Addr
+	Destination     netip.Addr
+	Protocol        string
+	SourcePort      int
+	DestinationPort int
+	Direction       string
+}
+
+// PolicyVerdict is the result of evaluating a packet. Default is set when no rule matched, in which
+// case RuleID is the catch-all rule of a network policy, or empty when the built-in default applied
+type PolicyVerdict struct {
+	Action   string
+	PolicyID string
+	RuleID   string
+	RuleName string
+	Sequence int
+	Default  bool
+}
+
+// Allowed returns true if the verdict permits the packet
+func (v PolicyVerdict) Allowed() bool {
+	return strings.EqualFold(v.Action, "ALLOW")
+}
+
+func (v PolicyVerdict) String() string {
+	if v.RuleID == "" {
+		return fmt.Sprintf("%s (default)", v.Action)
+	}
+	return fmt.Sprintf("%s by rule %s (%s) in policy %s", v.Action, v.RuleID, v.RuleName, v.PolicyID)
+}
+
+type PolicyFindingKind string
+
+func (s PolicyFindingKind) String() string {
+	return string(s)
+}
+
+const (
+	// PolicyFindingShadowed indicates a rule can never match, as an earlier rule with a different
+	// action matches every packet it would
+	PolicyFindingShadowed PolicyFindingKind = "shadowed"
+	// PolicyFindingRedundant indicates a rule can never match, as an earlier rule with the same
+	// action matches every packet it would
+	PolicyFindingRedundant PolicyFindingKind = "redundant"
+)
+
+// PolicyFinding describes a rule which is fully covered by an earlier rule
+type PolicyFinding struct {
+	Kind          PolicyFindingKind
+	PolicyID      string
+	RuleID        string
+	RuleName      string
+	CoveredByID   string
+	CoveredByName string
+}
+
+func (f PolicyFinding) String() string {
+	return fmt.Sprintf("rule %s (%s) is %s by rule %s (%s)", f.RuleID, f.RuleName, f.Kind, f.CoveredByID, f.CoveredByName)
+}
+
+// PolicyEvaluator evaluates packets against an in-memory copy of firewall or network policy rules,
+// returning the first matching rule in sequence order
+type PolicyEvaluator struct {
+	rules    []evaluatedRule
+	catchall *evaluatedRule
+}
+
+type evaluatedRule struct {
+	policyID    string
+	rule        policyRule
+	source      addressSet
+	destination addressSet
+	// services is empty when the rule has no ports, matching all protocols
+	services []ruleService
+}
+
+type ruleService struct {
+	protocol    string
+	source      portSet
+	destination portSet
+}
+
+// NewFirewallPolicyEvaluator returns an evaluator for set. Policies are evaluated in policy sequence
+// order, with rules in rule sequence order within each policy. Disabled rules are ignored
+func NewFirewallPolicyEvaluator(set FirewallPolicySet) (*PolicyEvaluator, error) {
+	policies := append([]FirewallPolicy(nil), set.Policies...)
+	sort.SliceStable(policies, func(i, j int) bool {
+		return policies[i].Sequence < policies[j].Sequence
+	})
+
+	ports := make(map[string][]policyRulePort)
+	for _, p := range set.Ports {
+		ports[p.FirewallRuleID] = append(ports[p.FirewallRuleID], policyRulePort{ID: p.ID, Protocol: p.Protocol.String(), Source: p.Source, Destination: p.Destination})
+	}
+
+	rules := make(map[string][]FirewallRule)
+	for _, r := range set.Rules {
+		rules[r.FirewallPolicyID] = append(rules[r.FirewallPolicyID], r)
+	}
+
+	e := &PolicyEvaluator{}
+	for _, policy := range policies {
+		policyRules := rules[policy.ID]
+		delete(rules, policy.ID)
+		sort.SliceStable(policyRules, func(i, j int) bool {
+			return policyRules[i].Sequence < policyRules[j].Sequence
+		})
+
+		for _, r := range policyRules {
+			if !r.Enabled {
+				continue
+			}
+			rule, err := newEvaluatedRule(policy.ID, policyRule{
+				ID:          r.ID,
+				Name:        r.Name,
+				Sequence:    r.Sequence,
+				Source:      r.Source,
+				Destination: r.Destination,
+				Action:      r.Action.String(),
+				Direction:   r.Direction.String(),
+				Enabled:     r.Enabled,
+			}, ports[r.ID])
+			if err != nil {
+				return nil, err
+			}
+			e.rules = append(e.rules, rule)
+		}
+	}
+
+	for policyID := range rules {
+		return nil, fmt.Errorf("firewall rules reference unknown firewall policy [%s]", policyID)
+	}
+
+	return e, nil
+}
+
+// NewNetworkPolicyEvaluator returns an evaluator for set. Rules are evaluated in sequence order, with
+// the catch-all rule applied to packets which match no other rule. Disabled rules are ignored
+func NewNetworkPolicyEvaluator(set NetworkPolicySet) (*PolicyEvaluator, error) {
+	ports := make(map[string][]policyRulePort)
+	for _, p := range set.Ports {
+		ports[p.NetworkRuleID] = append(ports[p.NetworkRuleID], policyRulePort{ID: p.ID, Protocol: p.Protocol.String(), Source: p.Source, Destination: p.Destination})
+	}
+
+	rules := append([]NetworkRule(nil), set.Rules...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Sequence < rules[j].Sequence
+	})
+
+	e := &PolicyEvaluator{}
+	for _, r := range rules {
+		if r.NetworkPolicyID != set.Policy.ID {
+			return nil, fmt.Errorf("network rule [%s] references unknown network policy [%s]", r.ID, r.NetworkPolicyID)
+		}
+		if !r.Enabled {
+			continue
+		}
+		rule, err := newEvaluatedRule(set.Policy.ID, policyRule{
+			ID:          r.ID,
+			Name:        r.Name,
+			Sequence:    r.Sequence,
+			Source:      r.Source,
+			Destination: r.Destination,
+			Action:      r.Action.String(),
+			Direction:   r.Direction.String(),
+			Enabled:     r.Enabled,
+		}, ports[r.ID])
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Type == "catchall" {
+			if e.catchall != nil {
+				return nil, fmt.Errorf("network policy [%s] has multiple catch-all rules", set.Policy.ID)
+			}
+			e.catchall = &rule
+			continue
+		}
+		e.rules = append(e.rules, rule)
+	}
+
+	return e, nil
+}
+
+func newEvaluatedRule(policyID string, rule policyRule, ports []policyRulePort) (evaluatedRule, error) {
+	e := evaluatedRule{policyID: policyID, rule: rule}
+
+	var err error
+	e.source, err = parseAddressSet(rule.Source)
+	if err != nil {
+		return e, fmt.Errorf("invalid source for rule [%s]: %w", rule.ID, err)
+	}
+	e.destination, err = parseAddressSet(rule.Destination)
+	if err != nil {
+		return e, fmt.Errorf("invalid destination for rule [%s]: %w", rule.ID, err)
+	}
+
+	for _, p := range ports {
+		service := ruleService{protocol: strings.ToUpper(p.Protocol)}
+		service.source, err = parsePortSet(p.Source)
+		if err != nil {
+			return e, fmt.Errorf("invalid source port for rule port [%s]: %w", p.ID, err)
+		}
+		service.destination, err = parsePortSet(p.Destination)
+		if err != nil {
+			return e, fmt.Errorf("invalid destination port for rule port [%s]: %w", p.ID, err)
+		}
+		e.services = append(e.services, service)
+	}
+
+	return e, nil
+}
+
+// Evaluate returns the verdict for packet
+func (e *PolicyEvaluator) Evaluate(packet PolicyPacket) (PolicyVerdict, error) {
+	if !packet.Source.IsValid() {
+		return PolicyVerdict{}, fmt.Errorf("invalid source address")
+	}
+	if !packet.Destination.IsValid() {
+		return PolicyVerdict{}, fmt.Errorf("invalid destination address")
+	}
+	packet.Source = packet.Source.Unmap()
+	packet.Destination = packet.Destination.Unmap()
+	packet.Protocol = strings.ToUpper(packet.Protocol)
+
+	for _, rule := range e.rules {
+		if rule.matches(packet) {
+			return rule.verdict(false), nil
+		}
+	}
+
+	if e.catchall != nil {
+		return e.catchall.verdict(true), nil
+	}
+
+	return PolicyVerdict{Action: policyDefaultAction, Default: true}, nil
+}
+
+// Findings returns rules which can never match a packet, as an earlier rule matches every packet
+// they would. Only coverage by a single earlier rule is detected
+func (e *PolicyEvaluator) Findings() []PolicyFinding {
+	var findings []PolicyFinding
+	for i, rule := range e.rules {
+		for _, earlier := range e.rules[:i] {
+			if !earlier.covers(rule) {
+				continue
+			}
+
+			kind := PolicyFindingShadowed
+			if strings.EqualFold(earlier.rule.Action, rule.rule.Action) {
+				kind = PolicyFindingRedundant
+			}
+			findings = append(findings, PolicyFinding{
+				Kind:          kind,
+				PolicyID:      rule.policyID,
+				RuleID:        rule.rule.ID,
+				RuleName:      rule.rule.Name,
+				CoveredByID:   earlier.rule.ID,
+				CoveredByName: earlier.rule.Name,
+			})
+			break
+		}
+	}
+	return findings
+}
+
+func (r evaluatedRule) verdict(def bool) PolicyVerdict {
+	return PolicyVerdict{
+		Action:   strings.ToUpper(r.rule.Action),
+		PolicyID: r.policyID,
+		RuleID:   r.rule.ID,
+		RuleName: r.rule.Name,
+		Sequence: r.rule.Sequence,
+		Default:  def,
+	}
+}
+
+func (r evaluatedRule) matches(packet PolicyPacket) bool {
+	if packet.Direction != "" && !directionCovers(r.rule.Direction, strings.ToUpper(packet.Direction)) {
+		return false
+	}
+	if !r.source.contains(packet.Source) || !r.destination.contains(packet.Destination) {
+		return false
+	}
+	if len(r.services) == 0 {
+		return true
+	}
+	for _, s := range r.services {
+		if s.protocol != packet.Protocol {
+			continue
+		}
+		if strings.EqualFold(s.protocol, FirewallRulePortProtocolICMPv4.String()) {
+			return true
+		}
+		if s.source.contains(packet.SourcePort) && s.destination.contains(packet.DestinationPort) {
+			return true
+		}
+	}
+	return false
+}
+
+// covers returns true if r matches every packet other would
+func (r evaluatedRule) covers(other evaluatedRule) bool {
+	if !directionCovers(r.rule.Direction, other.rule.Direction) {
+		return false
+	}
+	if !r.source.covers(other.source) || !r.destination.covers(other.destination) {
+		return false
+	}
+	if len(r.services) == 0 {
+		return true
+	}
+	if len(other.services) == 0 {
+		return false
+	}
+	for _, s := range other.services {
+		covered := false
+		for _, rs := range r.services {
+			if rs.protocol != s.protocol {
+				continue
+			}
+			if strings.EqualFold(rs.protocol, FirewallRulePortProtocolICMPv4.String()) || (rs.source.covers(s.source) && rs.destination.covers(s.destination)) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+func directionCovers(direction string, other string) bool {
+	return direction == FirewallRuleDirectionInOut.String() || direction == other
+}
+
+type addressRange struct {
+	from netip.Addr
+	to   netip.Addr
+}
+
+// addressSet is a parsed rule source or destination: "ANY", or a comma separated list of addresses,
+// CIDRs and address ranges (e.g. 10.0.0.1-10.0.0.10)
+type addressSet struct {
+	any    bool
+	ranges []addressRange
+}
+
+func parseAddressSet(s string) (addressSet, error) {
+	var set addressSet
+	if strings.TrimSpace(s) == "" {
+		return addressSet{any: true}, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+			// An empty item mustn't widen the set to any address
+			return set, fmt.Errorf("empty address in %q", s)
+		case strings.EqualFold(item, "ANY"):
+			return addressSet{any: true}, nil
+		case strings.Contains(item, "/"):
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return set, err
+			}
+			prefix = prefix.Masked()
+			set.ranges = append(set.ranges, addressRange{from: prefix.Addr(), to: lastAddr(prefix)})
+		case strings.Contains(item, "-"):
+			from, to, _ := strings.Cut(item, "-")
+			fromAddr, err := netip.ParseAddr(strings.TrimSpace(from))
+			if err != nil {
+				return set, err
+			}
+			toAddr, err := netip.ParseAddr(strings.TrimSpace(to))
+			if err != nil {
+				return set, err
+			}
+			if fromAddr.BitLen() != toAddr.BitLen() || toAddr.Less(fromAddr) {
+				return set, fmt.Errorf("invalid address range %q", item)
+			}
+			set.ranges = append(set.ranges, addressRange{from: fromAddr, to: toAddr})
+		default:
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return set, err
+			}
+			set.ranges = append(set.ranges, addressRange{from: addr, to: addr})
+		}
+	}
+	set.ranges = mergeAddressRanges(set.ranges)
+	return set, nil
+}
+
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// mergeAddressRanges sorts ranges and combines those which overlap or are adjacent
+func mergeAddressRanges(ranges []addressRange) []addressRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].from.Less(ranges[j].from)
+	})
+
+	var merged []addressRange
+	for _, r := range ranges {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			next := last.to.Next()
+			if last.to.BitLen() == r.from.BitLen() && (!next.IsValid() || !next.Less(r.from)) {
+				if last.to.Less(r.to) {
+					last.to = r.to
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+func (s addressSet) contains(addr netip.Addr) bool {
+	if s.any {
+		return true
+	}
+	for _, r := range s.ranges {
+		if !addr.Less(r.from) && !r.to.Less(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// covers returns true if every address in other is in s. Ranges are merged on parse, so each range of
+// other must fall within a single range of s
+func (s addressSet) covers(other addressSet) bool {
+	if s.any {
+		return true
+	}
+	if other.any {
+		return false
+	}
+	for _, o := range other.ranges {
+		covered := false
+		for _, r := range s.ranges {
+			if !o.from.Less(r.from) && !r.to.Less(o.to) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+type portRange struct {
+	from int
+	to   int
+}
+
+// portSet is a parsed rule port: "ANY", or a comma separated list of ports and port ranges (e.g. 8000-8080)
+type portSet struct {
+	any    bool
+	ranges []portRange
+}
+
+func parsePortSet(s string) (portSet, error) {
+	var set portSet
+	if strings.TrimSpace(s) == "" {
+		return portSet{any: true}, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			// An empty item mustn't widen the set to any port
+			return set, fmt.Errorf("empty port in %q", s)
+		}
+		if strings.EqualFold(item, "ANY") {
+			return portSet{any: true}, nil
+		}
+
+		from, to, isRange := strings.Cut(item, "-")
+		fromPort, err := parsePort(from)
+		if err != nil {
+			return set, err
+		}
+		toPort := fromPort
+		if isRange {
+			toPort, err = parsePort(to)
+			if err != nil {
+				return set, err
+			}
+			if toPort < fromPort {
+				return set, fmt.Errorf("invalid port range %q", item)
+			}
+		}
+		set.ranges = append(set.ranges, portRange{from: fromPort, to: toPort})
+	}
+
+	sort.Slice(set.ranges, func(i, j int) bool {
+		return set.ranges[i].from < set.ranges[j].from
+	})
+	var merged []portRange
+	for _, r := range set.ranges {
+		if len(merged) > 0 && r.from <= merged[len(merged)-1].to+1 {
+			if r.to > merged[len(merged)-1].to {
+				merged[len(merged)-1].to = r.to
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	set.ranges = merged
+	return set, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+func (s portSet) contains(port int) bool {
+	if s.any {
+		return true
+	}
+	for _, r := range s.ranges {
+		if port >= r.from && port <= r.to {
+			return true
+		}
+	}
+	return false
+}
+
+func (s portSet) covers(other portSet) bool {
+	if s.any {
+		return true
+	}
+	if other.any {
+		return false
+	}
+	for _, o := range other.ranges {
+		covered := false
+		for _, r := range s.ranges {
+			if o.from >= r.from && o.to <= r.to {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+package ecloud
+
+import (
+	"net/netip"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadFirewallPolicyEvaluator(t *testing.T) *PolicyEvaluator {
+	f, err := os.Open("testdata/policy_eval/firewall.json")
+	assert.Nil(t, err)
+	defer f.Close()
+
+	set, err := ReadFirewallPolicySet(f)
+	assert.Nil(t, err)
+
+	e, err := NewFirewallPolicyEvaluator(set)
+	assert.Nil(t, err)
+	return e
+}
+
+func loadNetworkPolicyEvaluator(t *testing.T) *PolicyEvaluator {
+	f, err := os.Open("testdata/policy_eval/network.json")
+	assert.Nil(t, err)
+	defer f.Close()
+
+	set, err := ReadNetworkPolicySet(f)
+	assert.Nil(t, err)
+
+	e, err := NewNetworkPolicyEvaluator(set)
+	assert.Nil(t, err)
+	return e
+}
+
+func TestPolicyEvaluator_Evaluate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		evaluator func(t *testing.T) *PolicyEvaluator
+		packet    PolicyPacket
+		expected  PolicyVerdict
+	}{
+		{
+			name:      "Firewall_MatchesCIDR",
+			evaluator: loadFirewallPolicyEvaluator,
+			packet:    PolicyPacket{Source: netip.MustParseAddr("1.2.3.4"), Destination: netip.MustParseAddr("10.0.0.5"), Protocol: "tcp", DestinationPort: 443, Direction: "IN"},
+			expected:  PolicyVerdict{Action: "ALLOW", PolicyID: "fwp-web", RuleID: "fwr-https", RuleName: "https", Sequence: 10},
+		},
+		{
+			name:      "Firewall_MatchesAddressAndPortRange",
+			evaluator: loadFirewallPolicyEvaluator,
+			packet:    PolicyPacket{Source: netip.MustParseAddr("192.168.1.15"), Destination: netip.MustParseAddr("10.0.0.5"), Protocol: "TCP", SourcePort: 50000, DestinationPort: 2250},
+			expected:  PolicyVerdict{Action: "ALLOW", PolicyID: "fwp-web", RuleID: "fwr-ssh", RuleName: "ssh", Sequence: 20},
+		},
+		{
+			name:      "Firewall_OutsideRange_ReturnsDefault",
+			evaluator: loadFirewallPolicyEvaluator,
+			packet:    PolicyPacket{Source: netip.MustParseAddr("192.168.1.21"), Destination: netip.MustParseAddr("10.0.0.5"), Protocol: "TCP", DestinationPort: 22},
+			expected:  PolicyVerdict{Action: "DROP", Default: true},
+		},
+		{
+			name:      "Firewall_ShadowedRuleNeverMatches",
+			evaluator: loadFirewallPolicyEvaluator,
+			packet:    PolicyPacket{Source: netip.MustParseAddr("203.0.113.5"), Destination: netip.MustParseAddr("10.0.0.10"), Protocol: "TCP", DestinationPort: 443},
+			expected:  PolicyVerdict{Action: "ALLOW", PolicyID: "fwp-web", RuleID: "fwr-https", RuleName: "https", Sequence: 10},
+		},
+		{
+			name:      "Firewall_ICMPIgnoresPorts",
+			evaluator: loadFirewallPolicyEvaluator,
+			packet:    PolicyPacket{Source: netip.MustParseAddr("8.8.8.8"), Destination: netip.MustParseAddr("10.0.0.5"), Protocol: "ICMPv4"},
+			expected:  PolicyVerdict{Action: "ALLOW", PolicyID: "fwp-default", RuleID: "fwr-ping", RuleName: "ping", Sequence: 10},
+		},
+		{
+			name:      "Firewall_MatchesDirection",
+			evaluator: loadFirewallPolicyEvaluator,
+			packet:    PolicyPacket{Source: netip.MustParseAddr("10.0.0.5"), Destination: netip.MustParseAddr("8.8.8.8"), Protocol: "UDP", DestinationPort: 53, Direction: "OUT"},
+			expected:  PolicyVerdict{Action: "ALLOW", PolicyID: "fwp-default", RuleID: "fwr-egress", RuleName: "egress", Sequence: 20},
+		},
+		{
+			name:      "Firewall_WrongDirection_ReturnsDefault",
+			evaluator: loadFirewallPolicyEvaluator,
+			packet:    PolicyPacket{Source: netip.MustParseAddr("10.0.0.5"), Destination: netip.MustParseAddr("8.8.8.8"), Protocol: "UDP", DestinationPort: 53, Direction: "IN"},
+			expected:  PolicyVerdict{Action: "DROP", Default: true},
+		},
+		{
+			name:      "Network_MatchesRule",
+			evaluator: loadNetworkPolicyEvaluator,
+			packet:    PolicyPacket{Source: netip.MustParseAddr("10.0.0.200"), Destination: netip.MustParseAddr("10.0.1.53"), Protocol: "UDP", DestinationPort: 53},
+			expected:  PolicyVerdict{Action: "ALLOW", PolicyID: "np-abcdef12", RuleID: "nr-dns", RuleName: "dns", Sequence: 10},
+		},
+		{
+			name:      "Network_RuleWithoutPortsMatchesAnyProtocol",
+			evaluator: loadNetworkPolicyEvaluator,
+			packet:    PolicyPacket{Source: netip.MustParseAddr("10.0.0.9"), Destination: netip.MustParseAddr("10.0.2.7"), Protocol: "TCP", DestinationPort: 8080},
+			expected:  PolicyVerdict{Action: "ALLOW", PolicyID: "np-abcdef12", RuleID: "nr-all", RuleName: "all", Sequence: 30},
+		},
+		{
+			name:      "Network_NoMatch_ReturnsCatchall",
+			evaluator: loadNetworkPolicyEvaluator,
+			packet:    PolicyPacket{Source: netip.MustParseAddr("10.0.0.5"), Destination: netip.MustParseAddr("10.0.1.53"), Protocol: "TCP", DestinationPort: 80},
+			expected:  PolicyVerdict{Action: "REJECT", PolicyID: "np-abcdef12", RuleID: "nr-catchall", RuleName: "catchall", Default: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			verdict, err := tc.evaluator(t).Evaluate(tc.packet)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, verdict)
+		})
+	}
+
+	t.Run("InvalidSource_ReturnsError", func(t *testing.T) {
+		_, err := loadFirewallPolicyEvaluator(t).Evaluate(PolicyPacket{Destination: netip.MustParseAddr("10.0.0.5")})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid source address", err.Error())
+	})
+}
+
+func TestPolicyEvaluator_ICMPWithPortFields(t *testing.T) {
+	e, err := NewFirewallPolicyEvaluator(FirewallPolicySet{
+		Policies: []FirewallPolicy{{ID: "fwp-abcdef12", Sequence: 10}},
+		Rules: []FirewallRule{
+			{ID: "fwr-ping", Name: "ping", FirewallPolicyID: "fwp-abcdef12", Sequence: 10, Source: "ANY", Destination: "ANY", Action: "ALLOW", Direction: "IN", Enabled: true},
+		},
+		Ports: []FirewallRulePort{{ID: "fwrp-ping", FirewallRuleID: "fwr-ping", Protocol: FirewallRulePortProtocolICMPv4, Source: "1", Destination: "2"}},
+	})
+	assert.Nil(t, err)
+
+	verdict, err := e.Evaluate(PolicyPacket{Source: netip.MustParseAddr("8.8.8.8"), Destination: netip.MustParseAddr("10.0.0.5"), Protocol: "ICMPv4", Direction: "IN"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, PolicyVerdict{Action: "ALLOW", PolicyID: "fwp-abcdef12", RuleID: "fwr-ping", RuleName: "ping", Sequence: 10}, verdict)
+}
+
+func TestPolicyEvaluator_Findings(t *testing.T) {
+	t.Run("Firewall", func(t *testing.T) {
+		findings := loadFirewallPolicyEvaluator(t).Findings()
+
+		assert.Equal(t, []PolicyFinding{
+			{Kind: PolicyFindingRedundant, PolicyID: "fwp-web", RuleID: "fwr-ssh-admin", RuleName: "ssh-admin", CoveredByID: "fwr-ssh", CoveredByName: "ssh"},
+			{Kind: PolicyFindingShadowed, PolicyID: "fwp-web", RuleID: "fwr-block-https", RuleName: "block-https", CoveredByID: "fwr-https", CoveredByName: "https"},
+		}, findings)
+		assert.Equal(t, "rule fwr-block-https (block-https) is shadowed by rule fwr-https (https)", findings[1].String())
+	})
+
+	t.Run("Network", func(t *testing.T) {
+		findings := loadNetworkPolicyEvaluator(t).Findings()
+
+		assert.Equal(t, []PolicyFinding{
+			{Kind: PolicyFindingRedundant, PolicyID: "np-abcdef12", RuleID: "nr-dns-again", RuleName: "dns-again", CoveredByID: "nr-dns", CoveredByName: "dns"},
+		}, findings)
+	})
+}
+
+func TestNewFirewallPolicyEvaluator(t *testing.T) {
+	t.Run("InvalidSource_ReturnsError", func(t *testing.T) {
+		_, err := NewFirewallPolicyEvaluator(FirewallPolicySet{
+			Policies: []FirewallPolicy{{ID: "fwp-abcdef12"}},
+			Rules:    []FirewallRule{{ID: "fwr-abcdef12", FirewallPolicyID: "fwp-abcdef12", Source: "10.0.0.10-10.0.0.1", Destination: "ANY", Enabled: true}},
+		})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, `invalid source for rule [fwr-abcdef12]: invalid address range "10.0.0.10-10.0.0.1"`, err.Error())
+	})
+
+	t.Run("InvalidPort_ReturnsError", func(t *testing.T) {
+		_, err := NewFirewallPolicyEvaluator(FirewallPolicySet{
+			Policies: []FirewallPolicy{{ID: "fwp-abcdef12"}},
+			Rules:    []FirewallRule{{ID: "fwr-abcdef12", FirewallPolicyID: "fwp-abcdef12", Source: "ANY", Destination: "ANY", Enabled: true}},
+			Ports:    []FirewallRulePort{{ID: "fwrp-abcdef12", FirewallRuleID: "fwr-abcdef12", Protocol: "TCP", Destination: "70000"}},
+		})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, `invalid destination port for rule port [fwrp-abcdef12]: invalid port "70000"`, err.Error())
+	})
+
+	t.Run("EmptyAddressItem_ReturnsError", func(t *testing.T) {
+		_, err := NewFirewallPolicyEvaluator(FirewallPolicySet{
+			Policies: []FirewallPolicy{{ID: "fwp-abcdef12"}},
+			Rules:    []FirewallRule{{ID: "fwr-abcdef12", FirewallPolicyID: "fwp-abcdef12", Source: "10.0.0.1,", Destination: "ANY", Enabled: true}},
+		})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, `invalid source for rule [fwr-abcdef12]: empty address in "10.0.0.1,"`, err.Error())
+	})
+
+	t.Run("EmptyPortItem_ReturnsError", func(t *testing.T) {
+		_, err := NewFirewallPolicyEvaluator(FirewallPolicySet{
+			Policies: []FirewallPolicy{{ID: "fwp-abcdef12"}},
+			Rules:    []FirewallRule{{ID: "fwr-abcdef12", FirewallPolicyID: "fwp-abcdef12", Source: "ANY", Destination: "ANY", Enabled: true}},
+			Ports:    []FirewallRulePort{{ID: "fwrp-abcdef12", FirewallRuleID: "fwr-abcdef12", Protocol: "TCP", Destination: "80,,443"}},
+		})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, `invalid destination port for rule port [fwrp-abcdef12]: empty port in "80,,443"`, err.Error())
+	})
+
+	t.Run("UnknownPolicy_ReturnsError", func(t *testing.T) {
+		_, err := NewFirewallPolicyEvaluator(FirewallPolicySet{
+			Rules: []FirewallRule{{ID: "fwr-abcdef12", FirewallPolicyID: "fwp-unknown"}},
+		})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "firewall rules reference unknown firewall policy [fwp-unknown]", err.Error())
+	})
+}
+
+func TestGetFirewallPolicySet(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		svc := newFirewallPolicyService()
+
+		set, err := GetFirewallPolicySet(svc, "rtr-abcdef12")
+
+		assert.Nil(t, err)
+		assert.Len(t, set.Policies, 1)
+		assert.Len(t, set.Rules, 3)
+		assert.Len(t, set.Ports, 1)
+	})
+
+	t.Run("InvalidRouterID_ReturnsError", func(t *testing.T) {
+		_, err := GetFirewallPolicySet(newFirewallPolicyService(), "")
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid router id", err.Error())
+	})
+}
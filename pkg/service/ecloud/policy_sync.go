@@ -0,0 +1,819 @@
+package ecloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyDocument declares the rules and ports of a set of firewall or network policies. Policies
+// are identified by ID, or by name where the name is unique. Rules are matched by name, and ports
+// by protocol, source and destination. Live rules and ports which aren't declared are deleted.
+// For example:
+//
+//	policies:
+//	  - name: web
+//	    rules:
+//	      - name: https
+//	        sequence: 10
+//	        source: ANY
+//	        destination: 10.0.0.0/24
+//	        action: ALLOW
+//	        direction: IN
+//	        ports:
+//	          - protocol: TCP
+//	            destination: "443"
+type PolicyDocument struct {
+	Policies []PolicyDocumentPolicy `yaml:"policies"`
+}
+
+// PolicyDocumentPolicy declares the rules of a single policy
+type PolicyDocumentPolicy struct {
+	ID    string           `yaml:"id,omitempty"`
+	Name  string           `yaml:"name,omitempty"`
+	Rules []PolicyRuleSpec `yaml:"rules"`
+}
+
+// PolicyRuleSpec declares a firewall or network rule. Enabled defaults to true
+type PolicyRuleSpec struct {
+	Name        string               `yaml:"name"`
+	Sequence    int                  `yaml:"sequence"`
+	Source      string               `yaml:"source"`
+	Destination string               `yaml:"destination"`
+	Action      string               `yaml:"action"`
+	Direction   string               `yaml:"direction"`
+	Enabled     *bool                `yaml:"enabled,omitempty"`
+	Ports       []PolicyRulePortSpec `yaml:"ports,omitempty"`
+}
+
+// PolicyRulePortSpec declares a firewall or network rule port
+type PolicyRulePortSpec struct {
+	Protocol    string `yaml:"protocol"`
+	Source      string `yaml:"source,omitempty"`
+	Destination string `yaml:"destination,omitempty"`
+}
+
+// LoadPolicyDocument reads and validates a policy document in YAML format
+func LoadPolicyDocument(r io.Reader) (PolicyDocument, error) {
+	var doc PolicyDocument
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	err := dec.Decode(&doc)
+	if err != nil {
+		return doc, fmt.Errorf("failed to decode policy document: %w", err)
+	}
+
+	return doc, doc.Validate()
+}
+
+// Validate checks the document for missing fields and duplicate rule names
+func (d PolicyDocument) Validate() error {
+	for _, policy := range d.Policies {
+		if policy.ID == "" && policy.Name == "" {
+			return fmt.Errorf("policy id or name is required")
+		}
+
+		names := make(map[string]bool)
+		for _, rule := range policy.Rules {
+			if rule.Name == "" {
+				return fmt.Errorf("rule name is required for policy [%s]", policy.label())
+			}
+			if names[rule.Name] {
+				return fmt.Errorf("duplicate rule [%s] for policy [%s]", rule.Name, policy.label())
+			}
+			names[rule.Name] = true
+
+			if rule.Action == "" || rule.Direction == "" {
+				return fmt.Errorf("action and direction are required for rule [%s]", rule.Name)
+			}
+			for _, port := range rule.Ports {
+				if port.Protocol == "" {
+					return fmt.Errorf("protocol is required for ports of rule [%s]", rule.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p PolicyDocumentPolicy) label() string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return p.Name
+}
+
+func (r PolicyRuleSpec) enabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+// policyRule and policyRulePort are the normalised forms of firewall and network rules/ports
+type policyRule struct {
+	ID          string
+	Name        string
+	Sequence    int
+	Source      string
+	Destination string
+	Action      string
+	Direction   string
+	Enabled     bool
+}
+
+type policyRulePort struct {
+	ID          string
+	Protocol    string
+	Source      string
+	Destination string
+}
+
+func (p policyRulePort) key() string {
+	return strings.Join([]string{strings.ToUpper(p.Protocol), p.Source, p.Destination}, "|")
+}
+
+func (p policyRulePort) String() string {
+	return fmt.Sprintf("%s %s -> %s", p.Protocol, portOrAny(p.Source), portOrAny(p.Destination))
+}
+
+func portOrAny(port string) string {
+	if port == "" {
+		return "ANY"
+	}
+	return port
+}
+
+// policyBackend abstracts the firewall and network policy APIs
+type policyBackend interface {
+	kind() string
+	findPolicies(name string) ([]string, error)
+	getPolicy(id string) error
+	listRules(policyID string) ([]policyRule, error)
+	listPorts(ruleID string) ([]policyRulePort, error)
+	createRule(policyID string, rule policyRule, ports []policyRulePort) (TaskReference, error)
+	patchRule(ruleID string, rule policyRule) (TaskReference, error)
+	deleteRule(ruleID string) (string, error)
+	createPort(ruleID string, port policyRulePort) (TaskReference, error)
+	deletePort(portID string) (string, error)
+}
+
+// PolicySyncOperation is a single API call within a PolicySyncPlan
+type PolicySyncOperation struct {
+	// Action is one of create, update or delete
+	Action EnvironmentAction `json:"action"`
+	// Kind is one of rule or port
+	Kind     string   `json:"kind"`
+	PolicyID string   `json:"policy_id"`
+	Rule     string   `json:"rule"`
+	ID       string   `json:"id,omitempty"`
+	Detail   string   `json:"detail"`
+	Diff     []string `json:"diff,omitempty"`
+	// Widening is true for operations which can only permit additional traffic. These are applied
+	// before narrowing operations to avoid transient lockouts
+	Widening bool `json:"widening"`
+
+	// stage orders the operations on a single rule, which are applied in ascending stage
+	// regardless of whether they widen access
+	stage int
+	apply func(b policyBackend) (string, error)
+}
+
+func (o PolicySyncOperation) String() string {
+	s := fmt.Sprintf("%s %s %s/%s", o.Action, o.Kind, o.PolicyID, o.Rule)
+	if o.Kind == "port" {
+		s += " " + o.Detail
+	}
+	return s
+}
+
+// PolicySyncPlan is an ordered set of operations which converge live firewall or network
+// policies with a PolicyDocument.
+//
+// A plan marshals to JSON for reporting, but can't be restored from it: each operation is bound
+// to the rules and ports read while planning, which the encoding doesn't include. To apply a
+// reviewed plan, plan again and compare
+type PolicySyncPlan struct {
+	// Kind is firewall_policy or network_policy
+	Kind       string                `json:"kind"`
+	Operations []PolicySyncOperation `json:"operations"`
+}
+
+// Empty returns true if the plan contains no operations
+func (p *PolicySyncPlan) Empty() bool {
+	return len(p.Operations) == 0
+}
+
+// Write writes a human-readable diff of the plan
+func (p *PolicySyncPlan) Write(w io.Writer) error {
+	for _, o := range p.Operations {
+		_, err := fmt.Fprintf(w, "%s %s %s/%s: %s\n", o.Action.symbol(), o.Kind, o.PolicyID, o.Rule, o.Detail)
+		if err != nil {
+			return err
+		}
+		for _, d := range o.Diff {
+			_, err := fmt.Fprintf(w, "    %s\n", d)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PlanFirewallPolicySync compares the firewall rules and ports of the policies declared in doc with
+// live state, returning the operations required to converge them
+func PlanFirewallPolicySync(svc ECloudService, doc PolicyDocument) (*PolicySyncPlan, error) {
+	return planPolicySync(&firewallPolicyBackend{svc: svc}, doc)
+}
+
+// PlanNetworkPolicySync compares the network rules and ports of the policies declared in doc with
+// live state, returning the operations required to converge them
+func PlanNetworkPolicySync(svc ECloudService, doc PolicyDocument) (*PolicySyncPlan, error) {
+	return planPolicySync(&networkPolicyBackend{svc: svc}, doc)
+}
+
+func describeRule(r policyRule) string {
+	return fmt.Sprintf("sequence=%d %s %s -> %s %s enabled=%t", r.Sequence, r.Direction, r.Source, r.Destination, r.Action, r.Enabled)
+}
+
+func isAllow(action string) bool {
+	return strings.EqualFold(action, "ALLOW")
+}
+
+func planPolicySync(b policyBackend, doc PolicyDocument) (*PolicySyncPlan, error) {
+	err := doc.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &PolicySyncPlan{Kind: b.kind()}
+	for _, policy := range doc.Policies {
+		policyID, err := resolvePolicy(b, policy)
+		if err != nil {
+			return nil, err
+		}
+
+		ops, err := planPolicyRules(b, policyID, policy.Rules)
+		if err != nil {
+			return nil, err
+		}
+		plan.Operations = append(plan.Operations, ops...)
+	}
+
+	sortPolicySyncOperations(plan.Operations)
+	return plan, nil
+}
+
+func resolvePolicy(b policyBackend, policy PolicyDocumentPolicy) (string, error) {
+	if policy.ID != "" {
+		err := b.getPolicy(policy.ID)
+		if err != nil {
+			return "", fmt.Errorf("failed to retrieve %s [%s]: %w", b.kind(), policy.ID, err)
+		}
+		return policy.ID, nil
+	}
+
+	ids, err := b.findPolicies(policy.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve %s [%s]: %w", b.kind(), policy.Name, err)
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("%s [%s] not found", b.kind(), policy.Name)
+	case 1:
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("found %d %s resources named [%s], specify id", len(ids), b.kind(), policy.Name)
+}
+
+func planPolicyRules(b policyBackend, policyID string, specs []PolicyRuleSpec) ([]PolicySyncOperation, error) {
+	live, err := b.listRules(policyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve rules for %s [%s]: %w", b.kind(), policyID, err)
+	}
+	// Where live rules share a name the first is reconciled and the remainder deleted
+	liveByName := make(map[string]policyRule)
+	for _, rule := range live {
+		if _, exists := liveByName[rule.Name]; !exists {
+			liveByName[rule.Name] = rule
+		}
+	}
+
+	var ops []PolicySyncOperation
+	declared := make(map[string]bool)
+	for _, spec := range specs {
+		declared[spec.Name] = true
+		desired := policyRule{
+			Name:        spec.Name,
+			Sequence:    spec.Sequence,
+			Source:      spec.Source,
+			Destination: spec.Destination,
+			Action:      strings.ToUpper(spec.Action),
+			Direction:   strings.ToUpper(spec.Direction),
+			Enabled:     spec.enabled(),
+		}
+		var desiredPorts []policyRulePort
+		for _, port := range spec.Ports {
+			desiredPorts = append(desiredPorts, policyRulePort{
+				Protocol:    strings.ToUpper(port.Protocol),
+				Source:      port.Source,
+				Destination: port.Destination,
+			})
+		}
+
+		current, exists := liveByName[spec.Name]
+		if !exists {
+			ops = append(ops, PolicySyncOperation{
+				Action:   EnvironmentActionCreate,
+				Kind:     "rule",
+				PolicyID: policyID,
+				Rule:     spec.Name,
+				Detail:   describeRule(desired),
+				Widening: isAllow(desired.Action),
+				apply: func(b policyBackend) (string, error) {
+					ref, err := b.createRule(policyID, desired, desiredPorts)
+					return ref.TaskID, err
+				},
+			})
+			continue
+		}
+
+		// A widening patch is applied once the rule's ports are in their final state, and a
+		// narrowing patch before they change, so that the ports of one state are never combined
+		// with the action of the other
+		patchWidens := ruleUpdateWidens(current, desired)
+		patchStage := 0
+		var diff []string
+		diff = append(diff, diffValue("sequence", current.Sequence, desired.Sequence)...)
+		diff = append(diff, diffValue("source", current.Source, desired.Source)...)
+		diff = append(diff, diffValue("destination", current.Destination, desired.Destination)...)
+		diff = append(diff, diffValue("action", current.Action, desired.Action)...)
+		diff = append(diff, diffValue("direction", current.Direction, desired.Direction)...)
+		diff = append(diff, diffValue("enabled", current.Enabled, desired.Enabled)...)
+		if len(diff) > 0 {
+			ruleID := current.ID
+			if patchWidens {
+				patchStage = 2
+			}
+			ops = append(ops, PolicySyncOperation{
+				Action:   EnvironmentActionUpdate,
+				Kind:     "rule",
+				PolicyID: policyID,
+				Rule:     spec.Name,
+				ID:       ruleID,
+				Detail:   describeRule(desired),
+				Diff:     diff,
+				Widening: patchWidens,
+				stage:    patchStage,
+				apply: func(b policyBackend) (string, error) {
+					ref, err := b.patchRule(ruleID, desired)
+					return ref.TaskID, err
+				},
+			})
+		}
+
+		portOps, err := planPolicyRulePorts(b, policyID, current, desired, desiredPorts)
+		if err != nil {
+			return nil, err
+		}
+		if patchStage == 0 {
+			for i := range portOps {
+				portOps[i].stage++
+			}
+		}
+		ops = append(ops, portOps...)
+	}
+
+	for _, rule := range live {
+		if declared[rule.Name] && liveByName[rule.Name].ID == rule.ID {
+			continue
+		}
+
+		ruleID := rule.ID
+		ops = append(ops, PolicySyncOperation{
+			Action:   EnvironmentActionDelete,
+			Kind:     "rule",
+			PolicyID: policyID,
+			Rule:     rule.Name,
+			ID:       ruleID,
+			Detail:   describeRule(rule),
+			Widening: !isAllow(rule.Action),
+			apply: func(b policyBackend) (string, error) {
+				return b.deleteRule(ruleID)
+			},
+		})
+	}
+
+	return ops, nil
+}
+
+// ruleEffect ranks a rule by the traffic it permits: a disabled rule has no effect, ranking
+// between an enabled drop rule and an enabled allow rule
+func ruleEffect(r policyRule) int {
+	switch {
+	case !r.Enabled:
+		return 0
+	case isAllow(r.Action):
+		return 1
+	}
+	return -1
+}
+
+// ruleUpdateWidens returns true if patching current to desired can only permit additional traffic.
+// Rules are evaluated in ascending sequence, so an allow rule widens access by moving earlier and
+// a drop rule by moving later, ahead of which other rules may then allow the traffic it matched
+func ruleUpdateWidens(current policyRule, desired policyRule) bool {
+	if desired.Source != current.Source || desired.Destination != current.Destination || desired.Direction != current.Direction {
+		return false
+	}
+
+	before, after := ruleEffect(current), ruleEffect(desired)
+	switch {
+	case before != after:
+		return after > before
+	case after > 0:
+		return desired.Sequence <= current.Sequence
+	case after < 0:
+		return desired.Sequence >= current.Sequence
+	}
+	return true
+}
+
+// planPolicyRulePorts returns the operations converging the ports of the live rule with desired.
+// Operations are classified by the desired state of the rule, as any patch to the rule is ordered
+// around them. Creations are staged before deletions, so the rule's ports never pass through an
+// empty set, which would match all ports
+func planPolicyRulePorts(b policyBackend, policyID string, rule policyRule, desiredRule policyRule, desired []policyRulePort) ([]PolicySyncOperation, error) {
+	live, err := b.listPorts(rule.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve ports for rule [%s]: %w", rule.ID, err)
+	}
+
+	liveKeys := make(map[string]bool)
+	for _, port := range live {
+		liveKeys[port.key()] = true
+	}
+	desiredKeys := make(map[string]bool)
+	for _, port := range desired {
+		desiredKeys[port.key()] = true
+	}
+
+	// A rule without ports matches all ports, so adding the first port to a rule or removing the
+	// last inverts the effect of the change. Port changes to a disabled rule have no effect
+	effect := ruleEffect(desiredRule)
+	createWidens, deleteWidens := effect >= 0, effect <= 0
+	if len(live) == 0 {
+		createWidens = effect <= 0
+	}
+	if len(desired) == 0 {
+		deleteWidens = effect >= 0
+	}
+
+	var ops []PolicySyncOperation
+	for _, port := range desired {
+		if liveKeys[port.key()] {
+			continue
+		}
+
+		port := port
+		ruleID := rule.ID
+		ops = append(ops, PolicySyncOperation{
+			Action:   EnvironmentActionCreate,
+			Kind:     "port",
+			PolicyID: policyID,
+			Rule:     rule.Name,
+			Detail:   port.String(),
+			Widening: createWidens,
+			apply: func(b policyBackend) (string, error) {
+				ref, err := b.createPort(ruleID, port)
+				return ref.TaskID, err
+			},
+		})
+	}
+	for _, port := range live {
+		if desiredKeys[port.key()] {
+			continue
+		}
+
+		portID := port.ID
+		ops = append(ops, PolicySyncOperation{
+			Action:   EnvironmentActionDelete,
+			Kind:     "port",
+			PolicyID: policyID,
+			Rule:     rule.Name,
+			ID:       portID,
+			Detail:   port.String(),
+			Widening: deleteWidens,
+			stage:    1,
+			apply: func(b policyBackend) (string, error) {
+				return b.deletePort(portID)
+			},
+		})
+	}
+	return ops, nil
+}
+
+// sortPolicySyncOperations orders widening operations before narrowing operations. Within each
+// group creations precede updates and deletions, so that e.g. a replacement allow rule exists
+// before the rule it replaces is removed. An operation is then delayed until the operations on
+// the same rule in earlier stages have been applied
+func sortPolicySyncOperations(ops []PolicySyncOperation) {
+	rank := map[EnvironmentAction]int{
+		EnvironmentActionCreate: 0,
+		EnvironmentActionUpdate: 1,
+		EnvironmentActionDelete: 2,
+	}
+	sort.SliceStable(ops, func(i, j int) bool {
+		if ops[i].Widening != ops[j].Widening {
+			return ops[i].Widening
+		}
+		return rank[ops[i].Action] < rank[ops[j].Action]
+	})
+
+	blocked := func(op PolicySyncOperation, pending []PolicySyncOperation) bool {
+		for _, other := range pending {
+			if other.PolicyID == op.PolicyID && other.Rule == op.Rule && other.stage < op.stage {
+				return true
+			}
+		}
+		return false
+	}
+
+	pending := append([]PolicySyncOperation(nil), ops...)
+	for n := range ops {
+		for i, op := range pending {
+			if !blocked(op, pending) {
+				ops[n] = op
+				pending = append(pending[:i:i], pending[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// PolicySyncOptions configures ApplyPolicySync
+type PolicySyncOptions struct {
+	// Wait configures polling for tasks
+	Wait WaitOptions
+	// Progress is invoked after each operation is applied or fails
+	Progress func(op PolicySyncOperation, err error)
+}
+
+// ApplyPolicySync applies the operations in plan in order, waiting for the task of each to
+// complete before continuing. Application stops at the first failure
+func ApplyPolicySync(ctx context.Context, svc ECloudService, plan *PolicySyncPlan, opts PolicySyncOptions) error {
+	var b policyBackend
+	switch plan.Kind {
+	case ResourceKindFirewallPolicy:
+		b = &firewallPolicyBackend{svc: svc}
+	case ResourceKindNetworkPolicy:
+		b = &networkPolicyBackend{svc: svc}
+	default:
+		return fmt.Errorf("unsupported policy kind [%s]", plan.Kind)
+	}
+	for _, op := range plan.Operations {
+		if op.apply == nil {
+			return fmt.Errorf("operation [%s] has no action, plans must be produced by PlanFirewallPolicySync or PlanNetworkPolicySync", op)
+		}
+	}
+
+	for _, op := range plan.Operations {
+		taskID, err := op.apply(b)
+		if err == nil {
+			_, err = WaitForTask(ctx, svc, taskID, TaskWaitOptions{WaitOptions: opts.Wait})
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(op, err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", op, err)
+		}
+	}
+	return nil
+}
+
+type firewallPolicyBackend struct {
+	svc ECloudService
+}
+
+func (b *firewallPolicyBackend) kind() string {
+	return ResourceKindFirewallPolicy
+}
+
+func (b *firewallPolicyBackend) findPolicies(name string) ([]string, error) {
+	policies, err := b.svc.GetFirewallPolicies(eqFilter("name", name))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, policy := range policies {
+		if policy.Name == name {
+			ids = append(ids, policy.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (b *firewallPolicyBackend) getPolicy(id string) error {
+	_, err := b.svc.GetFirewallPolicy(id)
+	return err
+}
+
+func (b *firewallPolicyBackend) listRules(policyID string) ([]policyRule, error) {
+	rules, err := b.svc.GetFirewallPolicyFirewallRules(policyID, connection.APIRequestParameters{})
+	if err != nil {
+		return nil, err
+	}
+	var normalised []policyRule
+	for _, r := range rules {
+		normalised = append(normalised, policyRule{
+			ID:          r.ID,
+			Name:        r.Name,
+			Sequence:    r.Sequence,
+			Source:      r.Source,
+			Destination: r.Destination,
+			Action:      r.Action.String(),
+			Direction:   r.Direction.String(),
+			Enabled:     r.Enabled,
+		})
+	}
+	return normalised, nil
+}
+
+func (b *firewallPolicyBackend) listPorts(ruleID string) ([]policyRulePort, error) {
+	ports, err := b.svc.GetFirewallRuleFirewallRulePorts(ruleID, connection.APIRequestParameters{})
+	if err != nil {
+		return nil, err
+	}
+	var normalised []policyRulePort
+	for _, p := range ports {
+		normalised = append(normalised, policyRulePort{ID: p.ID, Protocol: p.Protocol.String(), Source: p.Source, Destination: p.Destination})
+	}
+	return normalised, nil
+}
+
+func (b *firewallPolicyBackend) createRule(policyID string, rule policyRule, ports []policyRulePort) (TaskReference, error) {
+	req := CreateFirewallRuleRequest{
+		Name:             rule.Name,
+		FirewallPolicyID: policyID,
+		Sequence:         rule.Sequence,
+		Source:           rule.Source,
+		Destination:      rule.Destination,
+		Action:           FirewallRuleAction(rule.Action),
+		Direction:        FirewallRuleDirection(rule.Direction),
+		Enabled:          rule.Enabled,
+	}
+	for _, p := range ports {
+		req.Ports = append(req.Ports, CreateFirewallRulePortRequest{
+			Protocol:    FirewallRulePortProtocol(p.Protocol),
+			Source:      p.Source,
+			Destination: p.Destination,
+		})
+	}
+	return b.svc.CreateFirewallRule(req)
+}
+
+func (b *firewallPolicyBackend) patchRule(ruleID string, rule policyRule) (TaskReference, error) {
+	return b.svc.PatchFirewallRule(ruleID, PatchFirewallRuleRequest{
+		Sequence:    &rule.Sequence,
+		Source:      rule.Source,
+		Destination: rule.Destination,
+		Action:      FirewallRuleAction(rule.Action),
+		Direction:   FirewallRuleDirection(rule.Direction),
+		Enabled:     &rule.Enabled,
+	})
+}
+
+func (b *firewallPolicyBackend) deleteRule(ruleID string) (string, error) {
+	return b.svc.DeleteFirewallRule(ruleID)
+}
+
+func (b *firewallPolicyBackend) createPort(ruleID string, port policyRulePort) (TaskReference, error) {
+	return b.svc.CreateFirewallRulePort(CreateFirewallRulePortRequest{
+		FirewallRuleID: ruleID,
+		Protocol:       FirewallRulePortProtocol(port.Protocol),
+		Source:         port.Source,
+		Destination:    port.Destination,
+	})
+}
+
+func (b *firewallPolicyBackend) deletePort(portID string) (string, error) {
+	return b.svc.DeleteFirewallRulePort(portID)
+}
+
+type networkPolicyBackend struct {
+	svc ECloudService
+}
+
+func (b *networkPolicyBackend) kind() string {
+	return ResourceKindNetworkPolicy
+}
+
+func (b *networkPolicyBackend) findPolicies(name string) ([]string, error) {
+	policies, err := b.svc.GetNetworkPolicies(eqFilter("name", name))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, policy := range policies {
+		if policy.Name == name {
+			ids = append(ids, policy.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (b *networkPolicyBackend) getPolicy(id string) error {
+	_, err := b.svc.GetNetworkPolicy(id)
+	return err
+}
+
+func (b *networkPolicyBackend) listRules(policyID string) ([]policyRule, error) {
+	rules, err := b.svc.GetNetworkPolicyNetworkRules(policyID, connection.APIRequestParameters{})
+	if err != nil {
+		return nil, err
+	}
+	var normalised []policyRule
+	for _, r := range rules {
+		// Catch-all rules are managed via the policy's catch-all action
+		if r.Type == "catchall" {
+			continue
+		}
+		normalised = append(normalised, policyRule{
+			ID:          r.ID,
+			Name:        r.Name,
+			Sequence:    r.Sequence,
+			Source:      r.Source,
+			Destination: r.Destination,
+			Action:      r.Action.String(),
+			Direction:   r.Direction.String(),
+			Enabled:     r.Enabled,
+		})
+	}
+	return normalised, nil
+}
+
+func (b *networkPolicyBackend) listPorts(ruleID string) ([]policyRulePort, error) {
+	ports, err := b.svc.GetNetworkRuleNetworkRulePorts(ruleID, connection.APIRequestParameters{})
+	if err != nil {
+		return nil, err
+	}
+	var normalised []policyRulePort
+	for _, p := range ports {
+		normalised = append(normalised, policyRulePort{ID: p.ID, Protocol: p.Protocol.String(), Source: p.Source, Destination: p.Destination})
+	}
+	return normalised, nil
+}
+
+func (b *networkPolicyBackend) createRule(policyID string, rule policyRule, ports []policyRulePort) (TaskReference, error) {
+	req := CreateNetworkRuleRequest{
+		Name:            rule.Name,
+		NetworkPolicyID: policyID,
+		Sequence:        rule.Sequence,
+		Source:          rule.Source,
+		Destination:     rule.Destination,
+		Action:          NetworkRuleAction(rule.Action),
+		Direction:       NetworkRuleDirection(rule.Direction),
+		Enabled:         rule.Enabled,
+	}
+	for _, p := range ports {
+		req.Ports = append(req.Ports, CreateNetworkRulePortRequest{
+			Protocol:    NetworkRulePortProtocol(p.Protocol),
+			Source:      p.Source,
+			Destination: p.Destination,
+		})
+	}
+	return b.svc.CreateNetworkRule(req)
+}
+
+func (b *networkPolicyBackend) patchRule(ruleID string, rule policyRule) (TaskReference, error) {
+	return b.svc.PatchNetworkRule(ruleID, PatchNetworkRuleRequest{
+		Sequence:    &rule.Sequence,
+		Source:      rule.Source,
+		Destination: rule.Destination,
+		Action:      NetworkRuleAction(rule.Action),
+		Direction:   NetworkRuleDirection(rule.Direction),
+		Enabled:     &rule.Enabled,
+	})
+}
+
+func (b *networkPolicyBackend) deleteRule(ruleID string) (string, error) {
+	return b.svc.DeleteNetworkRule(ruleID)
+}
+
+func (b *networkPolicyBackend) createPort(ruleID string, port policyRulePort) (TaskReference, error) {
+	return b.svc.CreateNetworkRulePort(CreateNetworkRulePortRequest{
+		NetworkRuleID: ruleID,
+		Protocol:      NetworkRulePortProtocol(port.Protocol),
+		Source:        port.Source,
+		Destination:   port.Destination,
+	})
+}
+
+func (b *networkPolicyBackend) deletePort(portID string) (string, error) {
+	return b.svc.DeleteNetworkRulePort(portID)
+}
@@ -0,0 +1,425 @@
+package ecloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePolicyService is an in-memory ECloudService covering the firewall and network policy methods
+// used by policy sync
+type fakePolicyService struct {
+	fakeTaskService
+
+	firewallPolicies []FirewallPolicy
+	firewallRules    []FirewallRule
+	firewallPorts    []FirewallRulePort
+	networkPolicies  []NetworkPolicy
+	networkRules     []NetworkRule
+	networkPorts     []NetworkRulePort
+
+	calls []string
+}
+
+func (f *fakePolicyService) GetFirewallPolicies(parameters connection.APIRequestParameters) ([]FirewallPolicy, error) {
+	return f.firewallPolicies, nil
+}
+
+func (f *fakePolicyService) GetFirewallPolicy(policyID string) (FirewallPolicy, error) {
+	for _, p := range f.firewallPolicies {
+		if p.ID == policyID {
+			return p, nil
+		}
+	}
+	return FirewallPolicy{}, &FirewallPolicyNotFoundError{ID: policyID}
+}
+
+func (f *fakePolicyService) GetFirewallPolicyFirewallRules(policyID string, parameters connection.APIRequestParameters) ([]FirewallRule, error) {
+	var rules []FirewallRule
+	for _, r := range f.firewallRules {
+		if r.FirewallPolicyID == policyID {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+func (f *fakePolicyService) GetFirewallRuleFirewallRulePorts(ruleID string, parameters connection.APIRequestParameters) ([]FirewallRulePort, error) {
+	var ports []FirewallRulePort
+	for _, p := range f.firewallPorts {
+		if p.FirewallRuleID == ruleID {
+			ports = append(ports, p)
+		}
+	}
+	return ports, nil
+}
+
+func (f *fakePolicyService) CreateFirewallRule(req CreateFirewallRuleRequest) (TaskReference, error) {
+	f.calls = append(f.calls, fmt.Sprintf("CreateFirewallRule %s %s ports=%d", req.Name, req.Action, len(req.Ports)))
+	return TaskReference{TaskID: "task-abcdef12"}, nil
+}
+
+func (f *fakePolicyService) PatchFirewallRule(ruleID string, req PatchFirewallRuleRequest) (TaskReference, error) {
+	f.calls = append(f.calls, fmt.Sprintf("PatchFirewallRule %s %s", ruleID, req.Action))
+	return TaskReference{TaskID: "task-abcdef12"}, nil
+}
+
+func (f *fakePolicyService) DeleteFirewallRule(ruleID string) (string, error) {
+	f.calls = append(f.calls, "DeleteFirewallRule "+ruleID)
+	return "task-abcdef12", nil
+}
+
+func (f *fakePolicyService) CreateFirewallRulePort(req CreateFirewallRulePortRequest) (TaskReference, error) {
+	f.calls = append(f.calls, fmt.Sprintf("CreateFirewallRulePort %s %s %s", req.FirewallRuleID, req.Protocol, req.Destination))
+	return TaskReference{TaskID: "task-abcdef12"}, nil
+}
+
+func (f *fakePolicyService) DeleteFirewallRulePort(portID string) (string, error) {
+	f.calls = append(f.calls, "DeleteFirewallRulePort "+portID)
+	return "task-abcdef12", nil
+}
+
+func (f *fakePolicyService) GetNetworkPolicies(parameters connection.APIRequestParameters) ([]NetworkPolicy, error) {
+	return f.networkPolicies, nil
+}
+
+func (f *fakePolicyService) GetNetworkPolicyNetworkRules(policyID string, parameters connection.APIRequestParameters) ([]NetworkRule, error) {
+	var rules []NetworkRule
+	for _, r := range f.networkRules {
+		if r.NetworkPolicyID == policyID {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+func (f *fakePolicyService) GetNetworkRuleNetworkRulePorts(ruleID string, parameters connection.APIRequestParameters) ([]NetworkRulePort, error) {
+	var ports []NetworkRulePort
+	for _, p := range f.networkPorts {
+		if p.NetworkRuleID == ruleID {
+			ports = append(ports, p)
+		}
+	}
+	return ports, nil
+}
+
+func (f *fakePolicyService) CreateNetworkRule(req CreateNetworkRuleRequest) (TaskReference, error) {
+	f.calls = append(f.calls, fmt.Sprintf("CreateNetworkRule %s %s ports=%d", req.Name, req.Action, len(req.Ports)))
+	return TaskReference{TaskID: "task-abcdef12"}, nil
+}
+
+func (f *fakePolicyService) DeleteNetworkRule(ruleID string) (string, error) {
+	f.calls = append(f.calls, "DeleteNetworkRule "+ruleID)
+	return "task-abcdef12", nil
+}
+
+const testPolicyDocument = `
+policies:
+  - name: web
+    rules:
+      - name: https
+        sequence: 10
+        source: ANY
+        destination: 10.0.0.0/24
+        action: ALLOW
+        direction: IN
+        ports:
+          - protocol: TCP
+            destination: "443"
+      - name: ssh
+        sequence: 20
+        source: 192.168.0.0/24
+        destination: 10.0.0.0/24
+        action: allow
+        direction: IN
+        ports:
+          - protocol: TCP
+            destination: "22"
+      - name: block-smtp
+        sequence: 30
+        source: ANY
+        destination: ANY
+        action: DROP
+        direction: OUT
+        ports:
+          - protocol: TCP
+            destination: "25"
+`
+
+func newFirewallPolicyService() *fakePolicyService {
+	return &fakePolicyService{
+		firewallPolicies: []FirewallPolicy{{ID: "fwp-abcdef12", Name: "web"}},
+		firewallRules: []FirewallRule{
+			{ID: "fwr-ssh", Name: "ssh", FirewallPolicyID: "fwp-abcdef12", Sequence: 20, Source: "ANY", Destination: "10.0.0.0/24", Action: FirewallRuleActionAllow, Direction: FirewallRuleDirectionIn, Enabled: true},
+			{ID: "fwr-old", Name: "old", FirewallPolicyID: "fwp-abcdef12", Sequence: 40, Source: "ANY", Destination: "ANY", Action: FirewallRuleActionAllow, Direction: FirewallRuleDirectionIn, Enabled: true},
+			{ID: "fwr-deny", Name: "deny-web", FirewallPolicyID: "fwp-abcdef12", Sequence: 50, Source: "ANY", Destination: "ANY", Action: FirewallRuleActionDrop, Direction: FirewallRuleDirectionIn, Enabled: true},
+		},
+		firewallPorts: []FirewallRulePort{
+			{ID: "fwrp-2222", FirewallRuleID: "fwr-ssh", Protocol: FirewallRulePortProtocolTCP, Destination: "2222"},
+		},
+	}
+}
+
+func TestLoadPolicyDocument(t *testing.T) {
+	t.Run("Valid_ReturnsDocument", func(t *testing.T) {
+		doc, err := LoadPolicyDocument(strings.NewReader(testPolicyDocument))
+
+		assert.Nil(t, err)
+		assert.Len(t, doc.Policies[0].Rules, 3)
+		assert.Equal(t, "443", doc.Policies[0].Rules[0].Ports[0].Destination)
+	})
+
+	t.Run("DuplicateRule_ReturnsError", func(t *testing.T) {
+		_, err := LoadPolicyDocument(strings.NewReader("policies:\n  - id: fwp-abcdef12\n    rules:\n      - {name: a, action: ALLOW, direction: IN}\n      - {name: a, action: ALLOW, direction: IN}\n"))
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "duplicate rule [a] for policy [fwp-abcdef12]", err.Error())
+	})
+}
+
+func TestPlanFirewallPolicySync(t *testing.T) {
+	doc, err := LoadPolicyDocument(strings.NewReader(testPolicyDocument))
+	assert.Nil(t, err)
+
+	t.Run("OrdersWideningBeforeNarrowing", func(t *testing.T) {
+		svc := newFirewallPolicyService()
+
+		plan, err := PlanFirewallPolicySync(svc, doc)
+
+		assert.Nil(t, err)
+		buf := new(bytes.Buffer)
+		assert.Nil(t, plan.Write(buf))
+		assert.Equal(t, `+ rule fwp-abcdef12/https: sequence=10 IN ANY -> 10.0.0.0/24 ALLOW enabled=true
+- rule fwp-abcdef12/deny-web: sequence=50 IN ANY -> ANY DROP enabled=true
++ rule fwp-abcdef12/block-smtp: sequence=30 OUT ANY -> ANY DROP enabled=true
+~ rule fwp-abcdef12/ssh: sequence=20 IN 192.168.0.0/24 -> 10.0.0.0/24 ALLOW enabled=true
+    source: ANY -> 192.168.0.0/24
++ port fwp-abcdef12/ssh: TCP ANY -> 22
+- port fwp-abcdef12/ssh: TCP ANY -> 2222
+- rule fwp-abcdef12/old: sequence=40 IN ANY -> ANY ALLOW enabled=true
+`, buf.String())
+	})
+
+	t.Run("InSync_ReturnsEmptyPlan", func(t *testing.T) {
+		svc := &fakePolicyService{
+			firewallPolicies: []FirewallPolicy{{ID: "fwp-abcdef12", Name: "web"}},
+			firewallRules: []FirewallRule{
+				{ID: "fwr-https", Name: "https", FirewallPolicyID: "fwp-abcdef12", Sequence: 10, Source: "ANY", Destination: "10.0.0.0/24", Action: FirewallRuleActionAllow, Direction: FirewallRuleDirectionIn, Enabled: true},
+			},
+			firewallPorts: []FirewallRulePort{{ID: "fwrp-443", FirewallRuleID: "fwr-https", Protocol: FirewallRulePortProtocolTCP, Destination: "443"}},
+		}
+		doc := PolicyDocument{Policies: []PolicyDocumentPolicy{{ID: "fwp-abcdef12", Rules: doc.Policies[0].Rules[:1]}}}
+
+		plan, err := PlanFirewallPolicySync(svc, doc)
+
+		assert.Nil(t, err)
+		assert.True(t, plan.Empty())
+	})
+
+	t.Run("RemovingAllPortsFromAllowRule_IsWidening", func(t *testing.T) {
+		svc := &fakePolicyService{
+			firewallPolicies: []FirewallPolicy{{ID: "fwp-abcdef12", Name: "web"}},
+			firewallRules: []FirewallRule{
+				{ID: "fwr-https", Name: "https", FirewallPolicyID: "fwp-abcdef12", Sequence: 10, Source: "ANY", Destination: "10.0.0.0/24", Action: FirewallRuleActionAllow, Direction: FirewallRuleDirectionIn, Enabled: true},
+			},
+			firewallPorts: []FirewallRulePort{{ID: "fwrp-443", FirewallRuleID: "fwr-https", Protocol: FirewallRulePortProtocolTCP, Destination: "443"}},
+		}
+		rule := doc.Policies[0].Rules[0]
+		rule.Ports = nil
+		doc := PolicyDocument{Policies: []PolicyDocumentPolicy{{ID: "fwp-abcdef12", Rules: []PolicyRuleSpec{rule}}}}
+
+		plan, err := PlanFirewallPolicySync(svc, doc)
+
+		assert.Nil(t, err)
+		assert.Len(t, plan.Operations, 1)
+		assert.Equal(t, EnvironmentActionDelete, plan.Operations[0].Action)
+		assert.True(t, plan.Operations[0].Widening)
+	})
+
+	t.Run("SequenceChange_ClassifiedByDirection", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			action   FirewallRuleAction
+			sequence int
+			widening bool
+		}{
+			{name: "AllowEarlier", action: FirewallRuleActionAllow, sequence: 5, widening: true},
+			{name: "AllowLater", action: FirewallRuleActionAllow, sequence: 60, widening: false},
+			{name: "DropEarlier", action: FirewallRuleActionDrop, sequence: 5, widening: false},
+			{name: "DropLater", action: FirewallRuleActionDrop, sequence: 60, widening: true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				svc := &fakePolicyService{
+					firewallPolicies: []FirewallPolicy{{ID: "fwp-abcdef12", Name: "web"}},
+					firewallRules: []FirewallRule{
+						{ID: "fwr-web", Name: "web", FirewallPolicyID: "fwp-abcdef12", Sequence: 10, Source: "ANY", Destination: "ANY", Action: tt.action, Direction: FirewallRuleDirectionIn, Enabled: true},
+					},
+				}
+				doc := PolicyDocument{Policies: []PolicyDocumentPolicy{{ID: "fwp-abcdef12", Rules: []PolicyRuleSpec{
+					{Name: "web", Sequence: tt.sequence, Source: "ANY", Destination: "ANY", Action: tt.action.String(), Direction: "IN"},
+				}}}}
+
+				plan, err := PlanFirewallPolicySync(svc, doc)
+
+				assert.Nil(t, err)
+				assert.Len(t, plan.Operations, 1)
+				assert.Equal(t, tt.widening, plan.Operations[0].Widening)
+			})
+		}
+	})
+
+	t.Run("ActionChangeWithPortSwap_PortsFollowRuleState", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			current  FirewallRuleAction
+			desired  FirewallRuleAction
+			expected []string
+		}{
+			{
+				name:     "DropToAllow",
+				current:  FirewallRuleActionDrop,
+				desired:  FirewallRuleActionAllow,
+				expected: []string{"create port fwp-abcdef12/web TCP ANY -> 443", "delete port fwp-abcdef12/web TCP ANY -> 22", "update rule fwp-abcdef12/web"},
+			},
+			{
+				name:     "AllowToDrop",
+				current:  FirewallRuleActionAllow,
+				desired:  FirewallRuleActionDrop,
+				expected: []string{"update rule fwp-abcdef12/web", "create port fwp-abcdef12/web TCP ANY -> 443", "delete port fwp-abcdef12/web TCP ANY -> 22"},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				svc := &fakePolicyService{
+					firewallPolicies: []FirewallPolicy{{ID: "fwp-abcdef12", Name: "web"}},
+					firewallRules: []FirewallRule{
+						{ID: "fwr-web", Name: "web", FirewallPolicyID: "fwp-abcdef12", Sequence: 10, Source: "ANY", Destination: "ANY", Action: tt.current, Direction: FirewallRuleDirectionIn, Enabled: true},
+					},
+					firewallPorts: []FirewallRulePort{{ID: "fwrp-22", FirewallRuleID: "fwr-web", Protocol: FirewallRulePortProtocolTCP, Destination: "22"}},
+				}
+				doc := PolicyDocument{Policies: []PolicyDocumentPolicy{{ID: "fwp-abcdef12", Rules: []PolicyRuleSpec{
+					{Name: "web", Sequence: 10, Source: "ANY", Destination: "ANY", Action: tt.desired.String(), Direction: "IN", Ports: []PolicyRulePortSpec{{Protocol: "TCP", Destination: "443"}}},
+				}}}}
+
+				plan, err := PlanFirewallPolicySync(svc, doc)
+
+				assert.Nil(t, err)
+				var ops []string
+				for _, op := range plan.Operations {
+					ops = append(ops, op.String())
+				}
+				assert.Equal(t, tt.expected, ops)
+			})
+		}
+	})
+
+	t.Run("DuplicateLiveRules_DeletesDuplicates", func(t *testing.T) {
+		svc := &fakePolicyService{
+			firewallPolicies: []FirewallPolicy{{ID: "fwp-abcdef12", Name: "web"}},
+			firewallRules: []FirewallRule{
+				{ID: "fwr-web1", Name: "web", FirewallPolicyID: "fwp-abcdef12", Sequence: 10, Source: "ANY", Destination: "ANY", Action: FirewallRuleActionAllow, Direction: FirewallRuleDirectionIn, Enabled: true},
+				{ID: "fwr-web2", Name: "web", FirewallPolicyID: "fwp-abcdef12", Sequence: 20, Source: "ANY", Destination: "ANY", Action: FirewallRuleActionAllow, Direction: FirewallRuleDirectionIn, Enabled: true},
+			},
+		}
+		doc := PolicyDocument{Policies: []PolicyDocumentPolicy{{ID: "fwp-abcdef12", Rules: []PolicyRuleSpec{
+			{Name: "web", Sequence: 10, Source: "ANY", Destination: "ANY", Action: "ALLOW", Direction: "IN"},
+		}}}}
+
+		plan, err := PlanFirewallPolicySync(svc, doc)
+
+		assert.Nil(t, err)
+		assert.Len(t, plan.Operations, 1)
+		assert.Equal(t, EnvironmentActionDelete, plan.Operations[0].Action)
+		assert.Equal(t, "fwr-web2", plan.Operations[0].ID)
+	})
+
+	t.Run("UnknownPolicy_ReturnsError", func(t *testing.T) {
+		_, err := PlanFirewallPolicySync(&fakePolicyService{}, doc)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "firewall_policy [web] not found", err.Error())
+	})
+
+	t.Run("UnknownPolicyID_ReturnsError", func(t *testing.T) {
+		doc := PolicyDocument{Policies: []PolicyDocumentPolicy{{ID: "fwp-unknown"}}}
+
+		_, err := PlanFirewallPolicySync(&fakePolicyService{}, doc)
+
+		assert.NotNil(t, err)
+		assert.IsType(t, &FirewallPolicyNotFoundError{}, errors.Unwrap(err))
+	})
+}
+
+func TestApplyPolicySync(t *testing.T) {
+	doc, err := LoadPolicyDocument(strings.NewReader(testPolicyDocument))
+	assert.Nil(t, err)
+	opts := PolicySyncOptions{Wait: WaitOptions{Interval: time.Second, Clock: newFakeClock()}}
+
+	t.Run("Firewall_AppliesInOrder", func(t *testing.T) {
+		svc := newFirewallPolicyService()
+		plan, err := PlanFirewallPolicySync(svc, doc)
+		assert.Nil(t, err)
+
+		err = ApplyPolicySync(context.Background(), svc, plan, opts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{
+			"CreateFirewallRule https ALLOW ports=1",
+			"DeleteFirewallRule fwr-deny",
+			"CreateFirewallRule block-smtp DROP ports=1",
+			"PatchFirewallRule fwr-ssh ALLOW",
+			"CreateFirewallRulePort fwr-ssh TCP 22",
+			"DeleteFirewallRulePort fwrp-2222",
+			"DeleteFirewallRule fwr-old",
+		}, svc.calls)
+	})
+
+	t.Run("Network_SkipsCatchallRule", func(t *testing.T) {
+		svc := &fakePolicyService{
+			networkPolicies: []NetworkPolicy{{ID: "np-abcdef12", Name: "web"}},
+			networkRules: []NetworkRule{
+				{ID: "nr-catchall", Name: "catchall", NetworkPolicyID: "np-abcdef12", Type: "catchall", Action: NetworkRuleActionDrop},
+				{ID: "nr-old", Name: "old", NetworkPolicyID: "np-abcdef12", Action: NetworkRuleActionAllow},
+			},
+		}
+		doc := PolicyDocument{Policies: []PolicyDocumentPolicy{{Name: "web", Rules: doc.Policies[0].Rules[:1]}}}
+		plan, err := PlanNetworkPolicySync(svc, doc)
+		assert.Nil(t, err)
+
+		err = ApplyPolicySync(context.Background(), svc, plan, opts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"CreateNetworkRule https ALLOW ports=1", "DeleteNetworkRule nr-old"}, svc.calls)
+	})
+
+	t.Run("DecodedPlan_ReturnsError", func(t *testing.T) {
+		svc := newFirewallPolicyService()
+		plan, err := PlanFirewallPolicySync(svc, doc)
+		assert.Nil(t, err)
+		data, err := json.Marshal(plan)
+		assert.Nil(t, err)
+		var decoded PolicySyncPlan
+		err = json.Unmarshal(data, &decoded)
+		assert.Nil(t, err)
+
+		err = ApplyPolicySync(context.Background(), svc, &decoded, opts)
+
+		assert.NotNil(t, err)
+		assert.Empty(t, svc.calls)
+	})
+
+	t.Run("UnsupportedKind_ReturnsError", func(t *testing.T) {
+		err := ApplyPolicySync(context.Background(), &fakePolicyService{}, &PolicySyncPlan{Kind: "vpc"}, opts)
+
+		assert.NotNil(t, err)
+	})
+}
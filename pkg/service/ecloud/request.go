@@ -639,12 +639,21 @@ type PatchNATOverloadRuleRequest struct {
 	Action NATOverloadRuleAction `json:"action,omitempty"`
 }
 
+// ExecuteInstanceScriptRequest represents a request to execute a script on an instance
 type ExecuteInstanceScriptRequest struct {
 	Script   string `json:"script"`
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
+// Redacted returns a copy of the request with the password masked, for logging
+func (r ExecuteInstanceScriptRequest) Redacted() interface{} {
+	if r.Password != "" {
+		r.Password = "REDACTED"
+	}
+	return r
+}
+
 // CreateVPNGatewayRequest represents a request to create a VPN gateway
 type CreateVPNGatewayRequest struct {
 	Name            string `json:"name,omitempty"`
@@ -156,3 +156,12 @@ func TestCreateVirtualMachineTemplateRequest_Validate(t *testing.T) {
 		assert.NotNil(t, err)
 	})
 }
+
+func TestExecuteInstanceScriptRequest_Redacted(t *testing.T) {
+	r := ExecuteInstanceScriptRequest{Script: "uptime", Username: "root", Password: "s3cr3t"}
+
+	redacted := r.Redacted()
+
+	assert.Equal(t, ExecuteInstanceScriptRequest{Script: "uptime", Username: "root", Password: "REDACTED"}, redacted)
+	assert.Equal(t, "s3cr3t", r.Password)
+}
@@ -0,0 +1,70 @@
+// Code generated by resourcegen. DO NOT EDIT.
+
+package ecloud
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/ans-group/sdk-go/test/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// genTestCase represents a single generated test case
+type genTestCase struct {
+	name        string
+	invalidID   bool
+	call        bool
+	statusCode  int
+	body        string
+	connErr     error
+	wantErr     string
+	wantErrType error
+}
+
+// genExpect registers the expected call for tc against c
+func genExpect(c *mocks.MockConnection, method string, path string, tc genTestCase) {
+	if !tc.call {
+		return
+	}
+
+	resp := &connection.APIResponse{}
+	if tc.connErr == nil {
+		resp.Response = &http.Response{
+			Body:       io.NopCloser(bytes.NewReader([]byte(tc.body))),
+			StatusCode: tc.statusCode,
+		}
+	}
+
+	switch method {
+	case "GET":
+		c.EXPECT().Get(path, gomock.Any()).Return(resp, tc.connErr).Times(1)
+	case "POST":
+		c.EXPECT().Post(path, gomock.Any()).Return(resp, tc.connErr).Times(1)
+	case "PATCH":
+		c.EXPECT().Patch(path, gomock.Any()).Return(resp, tc.connErr).Times(1)
+	case "DELETE":
+		c.EXPECT().Delete(path, gomock.Any()).Return(resp, tc.connErr).Times(1)
+	}
+}
+
+// genAssertErr asserts err against the expectations of tc, returning true if no error was expected
+func genAssertErr(t *testing.T, tc genTestCase, err error) bool {
+	if tc.wantErr == "" && tc.wantErrType == nil {
+		assert.Nil(t, err)
+		return true
+	}
+
+	assert.NotNil(t, err)
+	if tc.wantErr != "" {
+		assert.Equal(t, tc.wantErr, err.Error())
+	}
+	if tc.wantErrType != nil {
+		assert.IsType(t, tc.wantErrType, err)
+	}
+	return false
+}
@@ -1,5 +1,7 @@
 package ecloud
 
+//go:generate go run ../internal/resourcegen -spec resources.yaml
+
 import (
 	"github.com/ans-group/sdk-go/pkg/connection"
 	"github.com/ans-group/sdk-go/pkg/service/account"
@@ -241,10 +243,12 @@ type ECloudService interface {
 	GetRouterTasks(routerID string, parameters connection.APIRequestParameters) ([]Task, error)
 	GetRouterTasksPaginated(routerID string, parameters connection.APIRequestParameters) (*connection.Paginated[Task], error)
 
+	// resourcegen:begin Region
 	// Region
 	GetRegions(parameters connection.APIRequestParameters) ([]Region, error)
 	GetRegionsPaginated(parameters connection.APIRequestParameters) (*connection.Paginated[Region], error)
 	GetRegion(regionID string) (Region, error)
+	// resourcegen:end
 
 	// Volumes
 	GetVolumes(parameters connection.APIRequestParameters) ([]Volume, error)
@@ -456,6 +460,7 @@ type ECloudService interface {
 	PatchIPAddress(ipID string, patch PatchIPAddressRequest) (TaskReference, error)
 	DeleteIPAddress(ipID string) (string, error)
 
+	// resourcegen:begin AffinityRule
 	// Affinity Rules
 	GetAffinityRules(parameters connection.APIRequestParameters) ([]AffinityRule, error)
 	GetAffinityRulesPaginated(parameters connection.APIRequestParameters) (*connection.Paginated[AffinityRule], error)
@@ -463,6 +468,7 @@ type ECloudService interface {
 	CreateAffinityRule(req CreateAffinityRuleRequest) (TaskReference, error)
 	PatchAffinityRule(ruleID string, patch PatchAffinityRuleRequest) (TaskReference, error)
 	DeleteAffinityRule(ruleID string) (string, error)
+	// resourcegen:end
 
 	// Affinity Rule Members
 	GetAffinityRuleMembers(ruleID string, parameters connection.APIRequestParameters) ([]AffinityRuleMember, error)
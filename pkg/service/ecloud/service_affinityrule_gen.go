@@ -0,0 +1,45 @@
+// Code generated by resourcegen. DO NOT EDIT.
+
+package ecloud
+
+import (
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/ans-group/sdk-go/pkg/service/internal/resource"
+)
+
+func (s *Service) affinityRuleRes() *resource.Resource[AffinityRule, string] {
+	return resource.NewStringResource[AffinityRule](s.connection, "/ecloud/v2/affinity-rules", "affinity rule", func(id string) error {
+		return &AffinityRuleNotFoundError{ID: id}
+	})
+}
+
+// GetAffinityRules retrieves a list of affinity rules
+func (s *Service) GetAffinityRules(parameters connection.APIRequestParameters) ([]AffinityRule, error) {
+	return s.affinityRuleRes().List(parameters)
+}
+
+// GetAffinityRulesPaginated retrieves a paginated list of affinity rules
+func (s *Service) GetAffinityRulesPaginated(parameters connection.APIRequestParameters) (*connection.Paginated[AffinityRule], error) {
+	return s.affinityRuleRes().ListPaginated(parameters)
+}
+
+// GetAffinityRule retrieves a single affinity rule by id
+func (s *Service) GetAffinityRule(ruleID string) (AffinityRule, error) {
+	return s.affinityRuleRes().Get(ruleID)
+}
+
+// CreateAffinityRule creates a new affinity rule
+func (s *Service) CreateAffinityRule(req CreateAffinityRuleRequest) (TaskReference, error) {
+	return resource.CreateAs[TaskReference](s.affinityRuleRes(), &req)
+}
+
+// PatchAffinityRule patches an affinity rule
+func (s *Service) PatchAffinityRule(ruleID string, patch PatchAffinityRuleRequest) (TaskReference, error) {
+	return resource.PatchAs[TaskReference](s.affinityRuleRes(), ruleID, &patch)
+}
+
+// DeleteAffinityRule deletes an affinity rule
+func (s *Service) DeleteAffinityRule(ruleID string) (string, error) {
+	task, err := resource.DeleteAs[TaskReference](s.affinityRuleRes(), ruleID)
+	return task.TaskID, err
+}
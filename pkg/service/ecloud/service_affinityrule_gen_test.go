@@ -0,0 +1,143 @@
+// Code generated by resourcegen. DO NOT EDIT.
+
+package ecloud
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/ans-group/sdk-go/test/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAffinityRules_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+		{name: "Single", call: true, statusCode: 200, body: `{"data":[{"id":"ar-abcdef12"}],"meta":{"pagination":{"total_pages":1}}}`},
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "GET", "/ecloud/v2/affinity-rules", tc)
+
+			items, err := s.GetAffinityRules(connection.APIRequestParameters{})
+
+			if genAssertErr(t, tc, err) {
+				assert.Len(t, items, 1)
+				assert.Equal(t, "ar-abcdef12", items[0].ID)
+			}
+		})
+	}
+}
+
+func TestGetAffinityRule_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+		{name: "Valid", call: true, statusCode: 200, body: `{"data":{"id":"ar-abcdef12"}}`},
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+		{name: "InvalidAffinityRuleID_ReturnsError", invalidID: true, wantErr: "invalid affinity rule id"},
+		{name: "404_ReturnsAffinityRuleNotFoundError", call: true, statusCode: 404, wantErrType: &AffinityRuleNotFoundError{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "GET", "/ecloud/v2/affinity-rules/ar-abcdef12", tc)
+
+			ruleID := "ar-abcdef12"
+			if tc.invalidID {
+				ruleID = ""
+			}
+			item, err := s.GetAffinityRule(ruleID)
+
+			if genAssertErr(t, tc, err) {
+				assert.Equal(t, "ar-abcdef12", item.ID)
+			}
+		})
+	}
+}
+
+func TestCreateAffinityRule_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+		{name: "Valid", call: true, statusCode: 202, body: `{"data":{"id":"ar-abcdef12","task_id":"task-abcdef12"}}`},
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "POST", "/ecloud/v2/affinity-rules", tc)
+
+			result, err := s.CreateAffinityRule(CreateAffinityRuleRequest{})
+
+			if genAssertErr(t, tc, err) {
+				assert.Equal(t, "ar-abcdef12", result.ResourceID)
+				assert.Equal(t, "task-abcdef12", result.TaskID)
+			}
+		})
+	}
+}
+
+func TestPatchAffinityRule_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+		{name: "Valid", call: true, statusCode: 202, body: `{"data":{"id":"ar-abcdef12","task_id":"task-abcdef12"}}`},
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+		{name: "InvalidAffinityRuleID_ReturnsError", invalidID: true, wantErr: "invalid affinity rule id"},
+		{name: "404_ReturnsAffinityRuleNotFoundError", call: true, statusCode: 404, wantErrType: &AffinityRuleNotFoundError{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "PATCH", "/ecloud/v2/affinity-rules/ar-abcdef12", tc)
+
+			ruleID := "ar-abcdef12"
+			if tc.invalidID {
+				ruleID = ""
+			}
+			task, err := s.PatchAffinityRule(ruleID, PatchAffinityRuleRequest{})
+
+			if genAssertErr(t, tc, err) {
+				assert.Equal(t, "task-abcdef12", task.TaskID)
+			}
+		})
+	}
+}
+
+func TestDeleteAffinityRule_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+		{name: "Valid", call: true, statusCode: 202, body: `{"data":{"task_id":"task-abcdef12"}}`},
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+		{name: "InvalidAffinityRuleID_ReturnsError", invalidID: true, wantErr: "invalid affinity rule id"},
+		{name: "404_ReturnsAffinityRuleNotFoundError", call: true, statusCode: 404, wantErrType: &AffinityRuleNotFoundError{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "DELETE", "/ecloud/v2/affinity-rules/ar-abcdef12", tc)
+
+			ruleID := "ar-abcdef12"
+			if tc.invalidID {
+				ruleID = ""
+			}
+			taskID, err := s.DeleteAffinityRule(ruleID)
+
+			if genAssertErr(t, tc, err) {
+				assert.Equal(t, "task-abcdef12", taskID)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Code generated by resourcegen. DO NOT EDIT.
+
+package ecloud
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/ans-group/sdk-go/test/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRegions_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+		{name: "Single", call: true, statusCode: 200, body: `{"data":[{"id":"reg-abcdef12"}],"meta":{"pagination":{"total_pages":1}}}`},
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "GET", "/ecloud/v2/regions", tc)
+
+			items, err := s.GetRegions(connection.APIRequestParameters{})
+
+			if genAssertErr(t, tc, err) {
+				assert.Len(t, items, 1)
+				assert.Equal(t, "reg-abcdef12", items[0].ID)
+			}
+		})
+	}
+}
+
+func TestGetRegion_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+		{name: "Valid", call: true, statusCode: 200, body: `{"data":{"id":"reg-abcdef12"}}`},
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+		{name: "InvalidRegionID_ReturnsError", invalidID: true, wantErr: "invalid region id"},
+		{name: "404_ReturnsRegionNotFoundError", call: true, statusCode: 404, wantErrType: &RegionNotFoundError{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "GET", "/ecloud/v2/regions/reg-abcdef12", tc)
+
+			regionID := "reg-abcdef12"
+			if tc.invalidID {
+				regionID = ""
+			}
+			item, err := s.GetRegion(regionID)
+
+			if genAssertErr(t, tc, err) {
+				assert.Equal(t, "reg-abcdef12", item.ID)
+			}
+		})
+	}
+}
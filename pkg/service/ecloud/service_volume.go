@@ -30,26 +30,18 @@ func (s *Service) GetVolume(volumeID string) (Volume, error) {
 
 // CreateVolume creates a volume
 func (s *Service) CreateVolume(req CreateVolumeRequest) (TaskReference, error) {
-	body, err := connection.Post[TaskReference](s.connection, "/ecloud/v2/volumes", &req)
-	return body.Data, err
+	return resource.CreateAs[TaskReference](s.volumeRes(), &req)
 }
 
 // PatchVolume patches a Volume
 func (s *Service) PatchVolume(volumeID string, req PatchVolumeRequest) (TaskReference, error) {
-	if volumeID == "" {
-		return TaskReference{}, fmt.Errorf("invalid volume id")
-	}
-	body, err := connection.Patch[TaskReference](s.connection, fmt.Sprintf("/ecloud/v2/volumes/%s", volumeID), &req, connection.NotFoundResponseHandler(&VolumeNotFoundError{ID: volumeID}))
-	return body.Data, err
+	return resource.PatchAs[TaskReference](s.volumeRes(), volumeID, &req)
 }
 
 // DeleteVolume deletes a Volume
 func (s *Service) DeleteVolume(volumeID string) (string, error) {
-	if volumeID == "" {
-		return "", fmt.Errorf("invalid volume id")
-	}
-	body, err := connection.Delete[TaskReference](s.connection, fmt.Sprintf("/ecloud/v2/volumes/%s", volumeID), nil, connection.NotFoundResponseHandler(&VolumeNotFoundError{ID: volumeID}))
-	return body.Data.TaskID, err
+	task, err := resource.DeleteAs[TaskReference](s.volumeRes(), volumeID)
+	return task.TaskID, err
 }
 
 // GetVolumeInstances retrieves a list of volume instances
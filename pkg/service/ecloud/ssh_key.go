@@ -0,0 +1,526 @@
+package ecloud
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+)
+
+const (
+	sshKeyTypeRSA       = "ssh-rsa"
+	sshKeyTypeED25519   = "ssh-ed25519"
+	sshKeyTypeECDSAP256 = "ecdsa-sha2-nistp256"
+	sshKeyTypeECDSAP384 = "ecdsa-sha2-nistp384"
+	sshKeyTypeECDSAP521 = "ecdsa-sha2-nistp521"
+
+	// SSHKeyMinRSABits is the minimum accepted RSA modulus size
+	SSHKeyMinRSABits = 2048
+
+	rfc4716Begin = "---- BEGIN SSH2 PUBLIC KEY ----"
+	rfc4716End   = "---- END SSH2 PUBLIC KEY ----"
+)
+
+var sshECDSACurves = map[string]struct {
+	name  string
+	bits  int
+	curve ecdh.Curve
+}{
+	sshKeyTypeECDSAP256: {name: "nistp256", bits: 256, curve: ecdh.P256()},
+	sshKeyTypeECDSAP384: {name: "nistp384", bits: 384, curve: ecdh.P384()},
+	sshKeyTypeECDSAP521: {name: "nistp521", bits: 521, curve: ecdh.P521()},
+}
+
+func isSSHKeyType(s string) bool {
+	_, ecdsa := sshECDSACurves[s]
+	return s == sshKeyTypeRSA || s == sshKeyTypeED25519 || ecdsa
+}
+
+// SSHPublicKey is a parsed and validated SSH public key. Supported key types are RSA of at least
+// 2048 bits, ed25519, and ECDSA on the NIST P-256, P-384 and P-521 curves
+type SSHPublicKey struct {
+	// Type is the key algorithm, e.g. ssh-ed25519
+	Type string
+	Bits int
+	// Comment is the trailing comment of an OpenSSH key, or the Comment header of an RFC4716 key
+	Comment string
+	// Blob is the key in SSH wire format
+	Blob []byte
+}
+
+// String returns the key in OpenSSH authorized_keys format, as accepted by CreateSSHKeyPairRequest
+func (k *SSHPublicKey) String() string {
+	s := k.Type + " " + base64.StdEncoding.EncodeToString(k.Blob)
+	if k.Comment != "" {
+		s += " " + k.Comment
+	}
+	return s
+}
+
+// FingerprintSHA256 returns the SHA256 fingerprint of the key in the format used by ssh-keygen,
+// e.g. SHA256:mqz7NigAcGlscblX0/UbjO2mMgEAuZybhQqW9GJAtT4
+func (k *SSHPublicKey) FingerprintSHA256() string {
+	sum := sha256.Sum256(k.Blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// FingerprintMD5 returns the legacy MD5 fingerprint of the key in the format used by ssh-keygen,
+// e.g. MD5:09:da:41:78:a3:07:20:50:07:9a:c3:32:f0:22:5a:16
+func (k *SSHPublicKey) FingerprintMD5() string {
+	sum := md5.Sum(k.Blob)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+	return "MD5:" + strings.Join(hex, ":")
+}
+
+// ParseSSHPublicKey parses and validates a single public key in OpenSSH authorized_keys format
+// (with or without options) or RFC4716 format
+func ParseSSHPublicKey(s string) (*SSHPublicKey, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, rfc4716Begin) {
+		return parseRFC4716PublicKey(strings.Split(s, "\n"))
+	}
+	if strings.ContainsAny(s, "\r\n") {
+		return nil, fmt.Errorf("invalid ssh public key: expected a single line")
+	}
+	return parseAuthorizedKey(s)
+}
+
+func parseAuthorizedKey(line string) (*SSHPublicKey, error) {
+	fields := strings.Fields(line)
+	if len(fields) > 0 && !isSSHKeyType(fields[0]) {
+		// The first field is either an unsupported key type or authorized_keys options, which may
+		// contain quoted whitespace
+		rest, ok := skipAuthorizedKeyOptions(line)
+		if !ok {
+			return nil, fmt.Errorf("invalid ssh public key: unterminated quote in options")
+		}
+		fields = strings.Fields(rest)
+		if len(fields) == 0 || !isSSHKeyType(fields[0]) {
+			return nil, fmt.Errorf("invalid ssh public key: unsupported key type [%s]", strings.Fields(line)[0])
+		}
+	}
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid ssh public key: expected key type and base64 encoded key")
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh public key: %w", err)
+	}
+
+	key, err := parseSSHPublicKeyBlob(blob)
+	if err != nil {
+		return nil, err
+	}
+	if key.Type != fields[0] {
+		return nil, fmt.Errorf("invalid ssh public key: key type [%s] doesn't match encoded key type [%s]", fields[0], key.Type)
+	}
+	key.Comment = strings.Join(fields[2:], " ")
+	return key, nil
+}
+
+// skipAuthorizedKeyOptions returns line with the leading options field removed
+func skipAuthorizedKeyOptions(line string) (string, bool) {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ' ', '\t':
+			if !quoted {
+				return line[i:], true
+			}
+		}
+	}
+	return "", !quoted
+}
+
+func parseRFC4716PublicKey(lines []string) (*SSHPublicKey, error) {
+	var comment string
+	var body strings.Builder
+	header := ""
+	inBody := false
+	ended := false
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == rfc4716End {
+			ended = true
+			break
+		}
+		if header != "" || (!inBody && strings.Contains(line, ":")) {
+			header += line
+			if strings.HasSuffix(header, "\\") {
+				header = strings.TrimSuffix(header, "\\")
+				continue
+			}
+			tag, value, _ := strings.Cut(header, ":")
+			if strings.EqualFold(strings.TrimSpace(tag), "Comment") {
+				comment = strings.Trim(strings.TrimSpace(value), `"`)
+			}
+			header = ""
+			continue
+		}
+		inBody = true
+		body.WriteString(line)
+	}
+	if !ended {
+		return nil, fmt.Errorf("invalid ssh public key: missing %q", rfc4716End)
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(body.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh public key: %w", err)
+	}
+
+	key, err := parseSSHPublicKeyBlob(blob)
+	if err != nil {
+		return nil, err
+	}
+	key.Comment = comment
+	return key, nil
+}
+
+// sshWireReader reads length-prefixed fields from an SSH wire format key
+type sshWireReader struct {
+	b   []byte
+	err error
+}
+
+func (r *sshWireReader) next() []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.b) < 4 {
+		r.err = fmt.Errorf("invalid ssh public key: truncated key data")
+		return nil
+	}
+	n := binary.BigEndian.Uint32(r.b)
+	if uint64(len(r.b)-4) < uint64(n) {
+		r.err = fmt.Errorf("invalid ssh public key: truncated key data")
+		return nil
+	}
+	v := r.b[4 : 4+n]
+	r.b = r.b[4+n:]
+	return v
+}
+
+func parseSSHPublicKeyBlob(blob []byte) (*SSHPublicKey, error) {
+	r := &sshWireReader{b: blob}
+	key := &SSHPublicKey{Type: string(r.next()), Blob: blob}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	switch key.Type {
+	case sshKeyTypeRSA:
+		e := new(big.Int).SetBytes(r.next())
+		n := new(big.Int).SetBytes(r.next())
+		if r.err != nil {
+			return nil, r.err
+		}
+		if e.Cmp(big.NewInt(3)) < 0 || e.Bit(0) == 0 {
+			return nil, fmt.Errorf("invalid ssh public key: invalid RSA exponent")
+		}
+		key.Bits = n.BitLen()
+		if key.Bits < SSHKeyMinRSABits {
+			return nil, fmt.Errorf("invalid ssh public key: RSA key is %d bits, minimum is %d", key.Bits, SSHKeyMinRSABits)
+		}
+	case sshKeyTypeED25519:
+		point := r.next()
+		if r.err != nil {
+			return nil, r.err
+		}
+		if len(point) != 32 {
+			return nil, fmt.Errorf("invalid ssh public key: invalid ed25519 key length %d", len(point))
+		}
+		key.Bits = 256
+	case sshKeyTypeECDSAP256, sshKeyTypeECDSAP384, sshKeyTypeECDSAP521:
+		curve := sshECDSACurves[key.Type]
+		name := string(r.next())
+		point := r.next()
+		if r.err != nil {
+			return nil, r.err
+		}
+		if name != curve.name {
+			return nil, fmt.Errorf("invalid ssh public key: curve [%s] doesn't match key type [%s]", name, key.Type)
+		}
+		_, err := curve.curve.NewPublicKey(point)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ssh public key: invalid ECDSA point: %w", err)
+		}
+		key.Bits = curve.bits
+	default:
+		return nil, fmt.Errorf("invalid ssh public key: unsupported key type [%s]", key.Type)
+	}
+
+	if len(r.b) != 0 {
+		return nil, fmt.Errorf("invalid ssh public key: unexpected trailing key data")
+	}
+	return key, nil
+}
+
+// ParseAuthorizedKeys parses each key in an authorized_keys file, skipping blank lines and comments.
+// RFC4716 key blocks are also accepted. Errors identify the offending line
+func ParseAuthorizedKeys(r io.Reader) ([]*SSHPublicKey, error) {
+	var keys []*SSHPublicKey
+	var block []string
+	blockStart := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		if block != nil {
+			block = append(block, line)
+			if line == rfc4716End {
+				key, err := parseRFC4716PublicKey(block)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", blockStart, err)
+				}
+				keys = append(keys, key)
+				block = nil
+			}
+			continue
+		}
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == rfc4716Begin {
+			block = []string{line}
+			blockStart = lineNum
+			continue
+		}
+
+		key, err := parseAuthorizedKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if block != nil {
+		return nil, fmt.Errorf("line %d: invalid ssh public key: missing %q", blockStart, rfc4716End)
+	}
+
+	return keys, nil
+}
+
+// ReadSSHPublicKeyFiles reads the public keys at path, which is either an authorized_keys or
+// public key file, or a directory. For a directory, files named *.pub or authorized_keys* are
+// read in name order; other files, including private keys, are ignored
+func ReadSSHPublicKeyFiles(path string) ([]*SSHPublicKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return readSSHPublicKeyFile(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var keys []*SSHPublicKey
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !(strings.HasSuffix(name, ".pub") || strings.HasPrefix(name, "authorized_keys")) {
+			continue
+		}
+		fileKeys, err := readSSHPublicKeyFile(filepath.Join(path, name))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+	return keys, nil
+}
+
+func readSSHPublicKeyFile(path string) ([]*SSHPublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := ParseAuthorizedKeys(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// ValidateCreateSSHKeyPairRequest checks the public key of req before it's sent to the API
+func ValidateCreateSSHKeyPairRequest(req CreateSSHKeyPairRequest) error {
+	_, err := ParseSSHPublicKey(req.PublicKey)
+	return err
+}
+
+// SSHKeyPairSyncOptions configures PlanSSHKeyPairSync
+type SSHKeyPairSyncOptions struct {
+	// Prune deletes live key pairs whose keys aren't in the desired set, along with duplicates of
+	// desired keys. Live key pairs with unparseable public keys are never deleted
+	Prune bool
+	// Name returns the name of a key pair to be created, defaulting to the key comment, or the
+	// SHA256 fingerprint where the key has no comment
+	Name func(key *SSHPublicKey) string
+}
+
+// SSHKeyPairSyncOperation is a single create or delete within an SSHKeyPairSyncPlan
+type SSHKeyPairSyncOperation struct {
+	Action      EnvironmentAction `json:"action"`
+	ID          string            `json:"id,omitempty"`
+	Name        string            `json:"name"`
+	Fingerprint string            `json:"fingerprint"`
+	// PublicKey is the OpenSSH encoded key to be created
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+func (o SSHKeyPairSyncOperation) String() string {
+	s := fmt.Sprintf("%s ssh key pair %s", o.Action, o.Name)
+	if o.ID != "" {
+		s += fmt.Sprintf(" [%s]", o.ID)
+	}
+	return s
+}
+
+// SSHKeyPairSyncPlan is the set of operations which converge live SSH key pairs with a desired
+// set of public keys, matched by SHA256 fingerprint
+type SSHKeyPairSyncPlan struct {
+	Operations []SSHKeyPairSyncOperation `json:"operations"`
+}
+
+// Empty returns true if the plan contains no operations
+func (p *SSHKeyPairSyncPlan) Empty() bool {
+	return len(p.Operations) == 0
+}
+
+// Write writes a human-readable summary of the plan
+func (p *SSHKeyPairSyncPlan) Write(w io.Writer) error {
+	for _, o := range p.Operations {
+		_, err := fmt.Fprintf(w, "%s ssh_key_pair/%s: %s\n", o.Action.symbol(), o.Name, o.Fingerprint)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PlanSSHKeyPairSync compares keys with the key pairs returned by GetSSHKeyPairs, returning the
+// operations required to create missing keys and, with opts.Prune, delete extraneous key pairs.
+// Keys are matched by fingerprint, so a key is never created twice regardless of its name
+func PlanSSHKeyPairSync(svc ECloudService, keys []*SSHPublicKey, opts SSHKeyPairSyncOptions) (*SSHKeyPairSyncPlan, error) {
+	live, err := svc.GetSSHKeyPairs(connection.APIRequestParameters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve ssh key pairs: %w", err)
+	}
+
+	name := opts.Name
+	if name == nil {
+		name = func(key *SSHPublicKey) string {
+			if key.Comment != "" {
+				return key.Comment
+			}
+			return key.FingerprintSHA256()
+		}
+	}
+
+	type livePair struct {
+		pair        SSHKeyPair
+		fingerprint string
+	}
+	var pairs []livePair
+	existing := make(map[string]bool)
+	for _, pair := range live {
+		key, err := ParseSSHPublicKey(pair.PublicKey)
+		if err != nil {
+			continue
+		}
+		pairs = append(pairs, livePair{pair: pair, fingerprint: key.FingerprintSHA256()})
+		existing[key.FingerprintSHA256()] = true
+	}
+
+	plan := &SSHKeyPairSyncPlan{}
+	desired := make(map[string]bool)
+	for _, key := range keys {
+		fingerprint := key.FingerprintSHA256()
+		if desired[fingerprint] {
+			continue
+		}
+		desired[fingerprint] = true
+		if existing[fingerprint] {
+			continue
+		}
+		plan.Operations = append(plan.Operations, SSHKeyPairSyncOperation{
+			Action:      EnvironmentActionCreate,
+			Name:        name(key),
+			Fingerprint: fingerprint,
+			PublicKey:   key.String(),
+		})
+	}
+
+	if opts.Prune {
+		kept := make(map[string]bool)
+		for _, p := range pairs {
+			if desired[p.fingerprint] && !kept[p.fingerprint] {
+				kept[p.fingerprint] = true
+				continue
+			}
+			plan.Operations = append(plan.Operations, SSHKeyPairSyncOperation{
+				Action:      EnvironmentActionDelete,
+				ID:          p.pair.ID,
+				Name:        p.pair.Name,
+				Fingerprint: p.fingerprint,
+			})
+		}
+	}
+
+	return plan, nil
+}
+
+// ApplySSHKeyPairSync applies the operations in plan in order, stopping at the first failure
+func ApplySSHKeyPairSync(svc ECloudService, plan *SSHKeyPairSyncPlan) error {
+	for _, op := range plan.Operations {
+		var err error
+		switch op.Action {
+		case EnvironmentActionCreate:
+			if op.PublicKey == "" {
+				err = fmt.Errorf("public key is required")
+				break
+			}
+			_, err = svc.CreateSSHKeyPair(CreateSSHKeyPairRequest{Name: op.Name, PublicKey: op.PublicKey})
+		case EnvironmentActionDelete:
+			err = svc.DeleteSSHKeyPair(op.ID)
+		default:
+			err = fmt.Errorf("unsupported action [%s]", op.Action)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", op, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package ecloud
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+func readTestSSHKey(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "ssh_keys", name))
+	assert.Nil(t, err)
+	return string(data)
+}
+
+func TestParseSSHPublicKey(t *testing.T) {
+	tests := []struct {
+		file    string
+		keyType string
+		bits    int
+		sha256  string
+		md5     string
+		comment string
+	}{
+		{"rsa.pub", "ssh-rsa", 2048, "SHA256:mqz7NigAcGlscblX0/UbjO2mMgEAuZybhQqW9GJAtT4", "MD5:09:da:41:78:a3:07:20:50:07:9a:c3:32:f0:22:5a:16", "rsa@example"},
+		{"ed25519.pub", "ssh-ed25519", 256, "SHA256:0gN8/WnYtlLJ0PSLYNTm4JbzL6CPkqEQSc7KeHPZZe0", "MD5:37:c4:f2:38:44:39:aa:f7:cc:c4:d6:24:77:3e:07:e7", "ed25519@example"},
+		{"ed25519.rfc4716", "ssh-ed25519", 256, "SHA256:0gN8/WnYtlLJ0PSLYNTm4JbzL6CPkqEQSc7KeHPZZe0", "MD5:37:c4:f2:38:44:39:aa:f7:cc:c4:d6:24:77:3e:07:e7", "ed25519@example"},
+		{"ecdsa.pub", "ecdsa-sha2-nistp384", 384, "SHA256:tRo4+OGOa5yLokvc+ACzdFmyX2O8n5LaJUDMgkssGr4", "MD5:fe:b0:03:a6:88:91:79:23:fb:b6:ca:af:8d:5e:49:66", "ecdsa@example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			key, err := ParseSSHPublicKey(readTestSSHKey(t, tt.file))
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.keyType, key.Type)
+			assert.Equal(t, tt.bits, key.Bits)
+			assert.Equal(t, tt.sha256, key.FingerprintSHA256())
+			assert.Equal(t, tt.md5, key.FingerprintMD5())
+			assert.Equal(t, tt.comment, key.Comment)
+		})
+	}
+
+	t.Run("RFC4716_StringIsOpenSSH", func(t *testing.T) {
+		key, err := ParseSSHPublicKey(readTestSSHKey(t, "ed25519.rfc4716"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, strings.TrimSpace(readTestSSHKey(t, "ed25519.pub")), key.String())
+	})
+
+	t.Run("Options_Skipped", func(t *testing.T) {
+		key, err := ParseSSHPublicKey(`command="echo hello world",no-pty ` + readTestSSHKey(t, "ed25519.pub"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "ed25519@example", key.Comment)
+	})
+
+	ed25519 := strings.Fields(readTestSSHKey(t, "ed25519.pub"))
+	blob, _ := base64.StdEncoding.DecodeString(ed25519[1])
+	ecdsa := strings.Fields(readTestSSHKey(t, "ecdsa.pub"))
+	ecdsaBlob, _ := base64.StdEncoding.DecodeString(ecdsa[1])
+	ecdsaBlob[len(ecdsaBlob)-1] ^= 1
+	invalid := map[string]string{
+		"ShortRSA":          readTestSSHKey(t, "invalid/rsa1024.pub"),
+		"UnsupportedType":   "ssh-dss AAAAB3NzaC1kc3MAAACBAP",
+		"TypeMismatch":      "ssh-rsa " + ed25519[1],
+		"InvalidBase64":     "ssh-ed25519 not*base64",
+		"MissingKey":        "ssh-ed25519",
+		"TruncatedKey":      "ssh-ed25519 " + base64.StdEncoding.EncodeToString(blob[:len(blob)-1]),
+		"TrailingData":      "ssh-ed25519 " + base64.StdEncoding.EncodeToString(append(blob, 0)),
+		"UnterminatedQuote": `command="echo ` + strings.Join(ed25519, " "),
+		"MultipleLines":     strings.Join(ed25519, " ") + "\n" + strings.Join(ed25519, " "),
+		"RFC4716MissingEnd": "---- BEGIN SSH2 PUBLIC KEY ----\n" + ed25519[1],
+		"InvalidECDSAPoint": ecdsa[0] + " " + base64.StdEncoding.EncodeToString(ecdsaBlob),
+	}
+	for name, s := range invalid {
+		t.Run(name+"_ReturnsError", func(t *testing.T) {
+			_, err := ParseSSHPublicKey(s)
+
+			assert.NotNil(t, err)
+		})
+	}
+
+	t.Run("ShortRSA_ErrorMessage", func(t *testing.T) {
+		err := ValidateCreateSSHKeyPairRequest(CreateSSHKeyPairRequest{PublicKey: readTestSSHKey(t, "invalid/rsa1024.pub")})
+
+		assert.Equal(t, "invalid ssh public key: RSA key is 1024 bits, minimum is 2048", err.Error())
+	})
+}
+
+func TestParseAuthorizedKeys(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		input := readTestSSHKey(t, "authorized_keys") + readTestSSHKey(t, "ed25519.rfc4716")
+
+		keys, err := ParseAuthorizedKeys(strings.NewReader(input))
+
+		assert.Nil(t, err)
+		assert.Len(t, keys, 3)
+		assert.Equal(t, "ssh-rsa", keys[0].Type)
+		assert.Equal(t, "ecdsa-sha2-nistp384", keys[1].Type)
+		assert.Equal(t, "ssh-ed25519", keys[2].Type)
+	})
+
+	t.Run("InvalidLine_ReturnsErrorWithLine", func(t *testing.T) {
+		_, err := ParseAuthorizedKeys(strings.NewReader("# comment\n\nssh-ed25519 invalid\n"))
+
+		assert.NotNil(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), "line 3: "))
+	})
+}
+
+func TestReadSSHPublicKeyFiles(t *testing.T) {
+	t.Run("Directory", func(t *testing.T) {
+		keys, err := ReadSSHPublicKeyFiles(filepath.Join("testdata", "ssh_keys"))
+
+		assert.Nil(t, err)
+		var comments []string
+		for _, key := range keys {
+			comments = append(comments, key.Comment)
+		}
+		// authorized_keys, ecdsa.pub, ed25519.pub, rsa.pub; ed25519.rfc4716 and invalid/ are ignored
+		assert.Equal(t, []string{"rsa@example", "ecdsa@example", "ecdsa@example", "ed25519@example", "rsa@example"}, comments)
+	})
+
+	t.Run("InvalidFile_ReturnsErrorWithPath", func(t *testing.T) {
+		path := filepath.Join("testdata", "ssh_keys", "invalid", "rsa1024.pub")
+
+		_, err := ReadSSHPublicKeyFiles(path)
+
+		assert.NotNil(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), path+": line 1: "))
+	})
+}
+
+type fakeSSHKeyPairService struct {
+	ECloudService
+
+	pairs   []SSHKeyPair
+	created []CreateSSHKeyPairRequest
+	deleted []string
+}
+
+func (f *fakeSSHKeyPairService) GetSSHKeyPairs(parameters connection.APIRequestParameters) ([]SSHKeyPair, error) {
+	return f.pairs, nil
+}
+
+func (f *fakeSSHKeyPairService) CreateSSHKeyPair(req CreateSSHKeyPairRequest) (string, error) {
+	f.created = append(f.created, req)
+	return "ssh-new", nil
+}
+
+func (f *fakeSSHKeyPairService) DeleteSSHKeyPair(keypairID string) error {
+	f.deleted = append(f.deleted, keypairID)
+	return nil
+}
+
+func TestPlanSSHKeyPairSync(t *testing.T) {
+	keys, err := ReadSSHPublicKeyFiles(filepath.Join("testdata", "ssh_keys"))
+	assert.Nil(t, err)
+	newSvc := func() *fakeSSHKeyPairService {
+		return &fakeSSHKeyPairService{
+			pairs: []SSHKeyPair{
+				// Matched by fingerprint despite a different name and comment
+				{ID: "ssh-00000001", Name: "laptop", PublicKey: strings.Fields(readTestSSHKey(t, "rsa.pub"))[0] + " " + strings.Fields(readTestSSHKey(t, "rsa.pub"))[1]},
+				{ID: "ssh-00000002", Name: "laptop-copy", PublicKey: readTestSSHKey(t, "rsa.pub")},
+				{ID: "ssh-00000003", Name: "old", PublicKey: readTestSSHKey(t, "invalid/rsa1024.pub")},
+			},
+		}
+	}
+
+	t.Run("CreatesMissingKeysOnce", func(t *testing.T) {
+		svc := newSvc()
+
+		plan, err := PlanSSHKeyPairSync(svc, keys, SSHKeyPairSyncOptions{})
+
+		assert.Nil(t, err)
+		buf := new(bytes.Buffer)
+		assert.Nil(t, plan.Write(buf))
+		assert.Equal(t, `+ ssh_key_pair/ecdsa@example: SHA256:tRo4+OGOa5yLokvc+ACzdFmyX2O8n5LaJUDMgkssGr4
++ ssh_key_pair/ed25519@example: SHA256:0gN8/WnYtlLJ0PSLYNTm4JbzL6CPkqEQSc7KeHPZZe0
+`, buf.String())
+
+		err = ApplySSHKeyPairSync(svc, plan)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []CreateSSHKeyPairRequest{
+			{Name: "ecdsa@example", PublicKey: strings.TrimSpace(readTestSSHKey(t, "ecdsa.pub"))},
+			{Name: "ed25519@example", PublicKey: strings.TrimSpace(readTestSSHKey(t, "ed25519.pub"))},
+		}, svc.created)
+		assert.Empty(t, svc.deleted)
+	})
+
+	t.Run("DecodedPlan_CreatesKeys", func(t *testing.T) {
+		svc := newSvc()
+		plan, err := PlanSSHKeyPairSync(svc, keys, SSHKeyPairSyncOptions{})
+		assert.Nil(t, err)
+		data, err := json.Marshal(plan)
+		assert.Nil(t, err)
+		var decoded SSHKeyPairSyncPlan
+		err = json.Unmarshal(data, &decoded)
+		assert.Nil(t, err)
+
+		err = ApplySSHKeyPairSync(svc, &decoded)
+
+		assert.Nil(t, err)
+		assert.Len(t, svc.created, 2)
+		assert.Equal(t, strings.TrimSpace(readTestSSHKey(t, "ed25519.pub")), svc.created[1].PublicKey)
+	})
+
+	t.Run("CreateWithoutPublicKey_ReturnsError", func(t *testing.T) {
+		svc := newSvc()
+		plan := &SSHKeyPairSyncPlan{Operations: []SSHKeyPairSyncOperation{{Action: EnvironmentActionCreate, Name: "deploy"}}}
+
+		err := ApplySSHKeyPairSync(svc, plan)
+
+		assert.NotNil(t, err)
+		assert.Empty(t, svc.created)
+	})
+
+	t.Run("Prune_DeletesDuplicatesAndExtraneous", func(t *testing.T) {
+		svc := newSvc()
+
+		plan, err := PlanSSHKeyPairSync(svc, keys[3:4], SSHKeyPairSyncOptions{Prune: true, Name: func(key *SSHPublicKey) string { return "deploy" }})
+		assert.Nil(t, err)
+		err = ApplySSHKeyPairSync(svc, plan)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []CreateSSHKeyPairRequest{{Name: "deploy", PublicKey: strings.TrimSpace(readTestSSHKey(t, "ed25519.pub"))}}, svc.created)
+		// ssh-00000003 has an unparseable key, so is left alone
+		assert.Equal(t, []string{"ssh-00000001", "ssh-00000002"}, svc.deleted)
+	})
+
+	t.Run("DesiredKeyPresent_DeletesDuplicateOnly", func(t *testing.T) {
+		plan, err := PlanSSHKeyPairSync(newSvc(), keys[4:], SSHKeyPairSyncOptions{Prune: true})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []SSHKeyPairSyncOperation{{Action: EnvironmentActionDelete, ID: "ssh-00000002", Name: "laptop-copy", Fingerprint: "SHA256:mqz7NigAcGlscblX0/UbjO2mMgEAuZybhQqW9GJAtT4"}}, plan.Operations)
+	})
+}
@@ -0,0 +1,286 @@
+package ecloud
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+)
+
+type SubnetRole string
+
+func (s SubnetRole) String() string {
+	return string(s)
+}
+
+const (
+	SubnetRoleNetwork   SubnetRole = "network"
+	SubnetRoleVPNLocal  SubnetRole = "vpn_local"
+	SubnetRoleVPNRemote SubnetRole = "vpn_remote"
+)
+
+// AllocatedSubnet is a subnet in use by a network, or advertised as a local or remote network by a
+// VPN session
+type AllocatedSubnet struct {
+	Prefix     netip.Prefix
+	Role       SubnetRole
+	ResourceID string
+	Name       string
+}
+
+func (a AllocatedSubnet) String() string {
+	return fmt.Sprintf("%s (%s %s)", a.Prefix, a.Role, a.ResourceID)
+}
+
+// reserves returns true if new networks must not overlap the subnet. VPN local networks describe
+// networks within the VPC, so are expected to contain them
+func (a AllocatedSubnet) reserves() bool {
+	return a.Role != SubnetRoleVPNLocal
+}
+
+// SubnetOverlap describes two allocated subnets which overlap
+type SubnetOverlap struct {
+	A AllocatedSubnet
+	B AllocatedSubnet
+}
+
+func (o SubnetOverlap) String() string {
+	return fmt.Sprintf("%s overlaps %s", o.A, o.B)
+}
+
+// SubnetConflictError indicates a proposed subnet overlaps subnets already in use
+type SubnetConflictError struct {
+	Subnet    netip.Prefix
+	Conflicts []AllocatedSubnet
+}
+
+func (e *SubnetConflictError) Error() string {
+	conflicts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		conflicts[i] = c.String()
+	}
+	return fmt.Sprintf("subnet [%s] overlaps %s", e.Subnet, strings.Join(conflicts, ", "))
+}
+
+// SubnetPlanner holds the subnets allocated within a VPC or router, and validates and suggests
+// subnets for new networks against them. A SubnetPlanner can be built from subnets gathered
+// offline, or retrieved with GetVPCSubnetPlanner or GetRouterSubnetPlanner
+type SubnetPlanner struct {
+	Subnets []AllocatedSubnet
+}
+
+// GetVPCSubnetPlanner returns a SubnetPlanner for the networks and VPN sessions within VPC with ID vpcID
+func GetVPCSubnetPlanner(svc ECloudService, vpcID string) (*SubnetPlanner, error) {
+	if vpcID == "" {
+		return nil, fmt.Errorf("invalid vpc id")
+	}
+
+	filter := eqFilter("vpc_id", vpcID)
+	routers, err := svc.GetRouters(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve routers: %w", err)
+	}
+
+	p := &SubnetPlanner{}
+	for _, router := range routers {
+		err := p.addRouterNetworks(svc, router.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = p.addVPNSessions(svc, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GetRouterSubnetPlanner returns a SubnetPlanner for the networks and VPN sessions attached to router
+// with ID routerID
+func GetRouterSubnetPlanner(svc ECloudService, routerID string) (*SubnetPlanner, error) {
+	if routerID == "" {
+		return nil, fmt.Errorf("invalid router id")
+	}
+
+	p := &SubnetPlanner{}
+	err := p.addRouterNetworks(svc, routerID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.addVPNSessions(svc, eqFilter("router_id", routerID))
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *SubnetPlanner) addRouterNetworks(svc ECloudService, routerID string) error {
+	networks, err := svc.GetRouterNetworks(routerID, connection.APIRequestParameters{})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve networks for router [%s]: %w", routerID, err)
+	}
+	for _, network := range networks {
+		err := p.add(SubnetRoleNetwork, network.ID, network.Name, network.Subnet)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *SubnetPlanner) addVPNSessions(svc ECloudService, serviceFilter connection.APIRequestParameters) error {
+	services, err := svc.GetVPNServices(serviceFilter)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve vpn services: %w", err)
+	}
+	for _, service := range services {
+		sessions, err := svc.GetVPNSessions(eqFilter("vpn_service_id", service.ID))
+		if err != nil {
+			return fmt.Errorf("failed to retrieve vpn sessions for vpn service [%s]: %w", service.ID, err)
+		}
+		for _, session := range sessions {
+			err := p.add(SubnetRoleVPNLocal, session.ID, session.Name, session.LocalNetworks)
+			if err != nil {
+				return err
+			}
+			err = p.add(SubnetRoleVPNRemote, session.ID, session.Name, session.RemoteNetworks)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// add records each subnet in the comma separated list subnets
+func (p *SubnetPlanner) add(role SubnetRole, resourceID string, name string, subnets string) error {
+	for _, subnet := range strings.Split(subnets, ",") {
+		subnet = strings.TrimSpace(subnet)
+		if subnet == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(subnet)
+		if err != nil {
+			return fmt.Errorf("invalid subnet [%s] for %s [%s]: %w", subnet, role, resourceID, err)
+		}
+		p.Subnets = append(p.Subnets, AllocatedSubnet{Prefix: prefix.Masked(), Role: role, ResourceID: resourceID, Name: name})
+	}
+	return nil
+}
+
+// Overlaps returns pairs of overlapping subnets. VPN local networks are only compared against the
+// remote networks of the same session, as they're expected to contain the VPC's networks
+func (p *SubnetPlanner) Overlaps() []SubnetOverlap {
+	var overlaps []SubnetOverlap
+	for i, a := range p.Subnets {
+		for _, b := range p.Subnets[i+1:] {
+			if !a.Prefix.Overlaps(b.Prefix) {
+				continue
+			}
+			if (!a.reserves() || !b.reserves()) && !(a.ResourceID == b.ResourceID && a.Role != b.Role) {
+				continue
+			}
+			overlaps = append(overlaps, SubnetOverlap{A: a, B: b})
+		}
+	}
+	return overlaps
+}
+
+// ValidateSubnet checks subnet is a valid IPv4 network address which doesn't overlap an existing
+// network or VPN remote network. A *SubnetConflictError is returned for overlaps
+func (p *SubnetPlanner) ValidateSubnet(subnet string) error {
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return fmt.Errorf("invalid subnet [%s]: %w", subnet, err)
+	}
+	if !prefix.Addr().Is4() {
+		return fmt.Errorf("invalid subnet [%s]: must be an IPv4 subnet", subnet)
+	}
+	if prefix.Masked() != prefix {
+		return fmt.Errorf("invalid subnet [%s]: host bits set, did you mean [%s]?", subnet, prefix.Masked())
+	}
+
+	var conflicts []AllocatedSubnet
+	for _, allocated := range p.Subnets {
+		if allocated.reserves() && allocated.Prefix.Overlaps(prefix) {
+			conflicts = append(conflicts, allocated)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &SubnetConflictError{Subnet: prefix, Conflicts: conflicts}
+	}
+
+	return nil
+}
+
+// ValidateCreateNetworkRequest validates the subnet of req with ValidateSubnet
+func (p *SubnetPlanner) ValidateCreateNetworkRequest(req CreateNetworkRequest) error {
+	return p.ValidateSubnet(req.Subnet)
+}
+
+// NextFreeSubnet returns the lowest subnet with prefix length bits within supernet which doesn't
+// overlap an existing network or VPN remote network
+func (p *SubnetPlanner) NextFreeSubnet(supernet string, bits int) (netip.Prefix, error) {
+	parent, err := netip.ParsePrefix(supernet)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid supernet [%s]: %w", supernet, err)
+	}
+	parent = parent.Masked()
+	if bits < parent.Bits() || bits > parent.Addr().BitLen() {
+		return netip.Prefix{}, fmt.Errorf("invalid prefix length [%d] for supernet [%s]", bits, parent)
+	}
+
+	var used []netip.Prefix
+	for _, allocated := range p.Subnets {
+		if allocated.reserves() {
+			used = append(used, allocated.Prefix)
+		}
+	}
+
+	next, ok := nextFreePrefix(parent, bits, used)
+	if !ok {
+		return netip.Prefix{}, fmt.Errorf("no free /%d subnet within [%s]", bits, parent)
+	}
+	return next, nil
+}
+
+// nextFreePrefix returns the lowest prefix of length bits within parent which overlaps none of used.
+// Candidates overlapping a used prefix skip straight past the end of it
+func nextFreePrefix(parent netip.Prefix, bits int, used []netip.Prefix) (netip.Prefix, bool) {
+	sort.Slice(used, func(i, j int) bool {
+		return used[i].Addr().Less(used[j].Addr())
+	})
+
+	candidate := netip.PrefixFrom(parent.Addr(), bits)
+	for parent.Contains(candidate.Addr()) {
+		var blocking *netip.Prefix
+		for i := range used {
+			if used[i].Overlaps(candidate) {
+				blocking = &used[i]
+				break
+			}
+		}
+		if blocking == nil {
+			return candidate, true
+		}
+
+		// Prefixes are aligned to their size, so the address after whichever of the candidate and the
+		// blocking prefix ends last is aligned for the next candidate
+		end := lastAddr(candidate)
+		if blockingEnd := lastAddr(*blocking); end.Less(blockingEnd) {
+			end = blockingEnd
+		}
+		next := end.Next()
+		if !next.IsValid() {
+			break
+		}
+		candidate = netip.PrefixFrom(next, bits)
+	}
+	return netip.Prefix{}, false
+}
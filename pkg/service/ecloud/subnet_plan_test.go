@@ -0,0 +1,160 @@
+package ecloud
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSubnetPlanner() *SubnetPlanner {
+	return &SubnetPlanner{
+		Subnets: []AllocatedSubnet{
+			{Prefix: netip.MustParsePrefix("10.0.0.0/24"), Role: SubnetRoleNetwork, ResourceID: "net-abcdef12"},
+			{Prefix: netip.MustParsePrefix("10.0.1.0/24"), Role: SubnetRoleNetwork, ResourceID: "net-abcdef34"},
+			{Prefix: netip.MustParsePrefix("10.0.0.0/22"), Role: SubnetRoleVPNLocal, ResourceID: "vpns-abcdef12"},
+			{Prefix: netip.MustParsePrefix("10.0.4.0/22"), Role: SubnetRoleVPNRemote, ResourceID: "vpns-abcdef12"},
+		},
+	}
+}
+
+func TestSubnetPlanner_Overlaps(t *testing.T) {
+	t.Run("NoOverlaps_ReturnsEmpty", func(t *testing.T) {
+		assert.Empty(t, newTestSubnetPlanner().Overlaps())
+	})
+
+	t.Run("ReturnsOverlaps", func(t *testing.T) {
+		p := newTestSubnetPlanner()
+		p.Subnets = append(p.Subnets,
+			AllocatedSubnet{Prefix: netip.MustParsePrefix("10.0.1.128/25"), Role: SubnetRoleNetwork, ResourceID: "net-abcdef56"},
+			AllocatedSubnet{Prefix: netip.MustParsePrefix("172.16.0.0/24"), Role: SubnetRoleVPNLocal, ResourceID: "vpns-abcdef34"},
+			AllocatedSubnet{Prefix: netip.MustParsePrefix("172.16.0.0/16"), Role: SubnetRoleVPNRemote, ResourceID: "vpns-abcdef34"},
+		)
+
+		overlaps := p.Overlaps()
+
+		assert.Len(t, overlaps, 2)
+		assert.Equal(t, "10.0.1.0/24 (network net-abcdef34) overlaps 10.0.1.128/25 (network net-abcdef56)", overlaps[0].String())
+		assert.Equal(t, "172.16.0.0/24 (vpn_local vpns-abcdef34) overlaps 172.16.0.0/16 (vpn_remote vpns-abcdef34)", overlaps[1].String())
+	})
+}
+
+func TestSubnetPlanner_ValidateSubnet(t *testing.T) {
+	t.Run("Free_ReturnsNil", func(t *testing.T) {
+		err := newTestSubnetPlanner().ValidateSubnet("10.0.2.0/24")
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("OverlapsNetwork_ReturnsSubnetConflictError", func(t *testing.T) {
+		err := newTestSubnetPlanner().ValidateCreateNetworkRequest(CreateNetworkRequest{Subnet: "10.0.0.0/23"})
+
+		assert.NotNil(t, err)
+		assert.IsType(t, &SubnetConflictError{}, err)
+		assert.Equal(t, "subnet [10.0.0.0/23] overlaps 10.0.0.0/24 (network net-abcdef12), 10.0.1.0/24 (network net-abcdef34)", err.Error())
+	})
+
+	t.Run("OverlapsVPNRemote_ReturnsSubnetConflictError", func(t *testing.T) {
+		err := newTestSubnetPlanner().ValidateSubnet("10.0.5.0/24")
+
+		assert.IsType(t, &SubnetConflictError{}, err)
+		assert.Equal(t, "vpns-abcdef12", err.(*SubnetConflictError).Conflicts[0].ResourceID)
+	})
+
+	t.Run("HostBitsSet_ReturnsError", func(t *testing.T) {
+		err := newTestSubnetPlanner().ValidateSubnet("10.0.2.1/24")
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid subnet [10.0.2.1/24]: host bits set, did you mean [10.0.2.0/24]?", err.Error())
+	})
+
+	t.Run("IPv6_ReturnsError", func(t *testing.T) {
+		err := newTestSubnetPlanner().ValidateSubnet("fd00::/64")
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Invalid_ReturnsError", func(t *testing.T) {
+		err := newTestSubnetPlanner().ValidateSubnet("10.0.2.0")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSubnetPlanner_NextFreeSubnet(t *testing.T) {
+	testCases := []struct {
+		name     string
+		supernet string
+		bits     int
+		expected string
+	}{
+		{name: "SkipsUsedNetworks", supernet: "10.0.0.0/16", bits: 24, expected: "10.0.2.0/24"},
+		{name: "SupernetInUse", supernet: "10.0.4.0/22", bits: 22, expected: ""},
+		{name: "FillsGapBeforeRemoteNetwork", supernet: "10.0.0.0/16", bits: 23, expected: "10.0.2.0/23"},
+		{name: "SkipsPastRemoteNetwork", supernet: "10.0.0.0/16", bits: 21, expected: "10.0.8.0/21"},
+		{name: "SmallerPrefix", supernet: "10.0.0.0/16", bits: 28, expected: "10.0.2.0/28"},
+		{name: "UnusedSupernet", supernet: "192.168.0.0/16", bits: 24, expected: "192.168.0.0/24"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next, err := newTestSubnetPlanner().NextFreeSubnet(tc.supernet, tc.bits)
+
+			if tc.expected == "" {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, next.String())
+		})
+	}
+
+	t.Run("Exhausted_ReturnsError", func(t *testing.T) {
+		_, err := newTestSubnetPlanner().NextFreeSubnet("10.0.0.0/23", 24)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "no free /24 subnet within [10.0.0.0/23]", err.Error())
+	})
+
+	t.Run("InvalidPrefixLength_ReturnsError", func(t *testing.T) {
+		_, err := newTestSubnetPlanner().NextFreeSubnet("10.0.0.0/16", 8)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid prefix length [8] for supernet [10.0.0.0/16]", err.Error())
+	})
+}
+
+func TestGetVPCSubnetPlanner(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		svc := newFakeVPCService()
+		svc.networks[0].Subnet = "10.0.0.0/24"
+		svc.vpnSessions[0].LocalNetworks = "10.0.0.0/24"
+		svc.vpnSessions[0].RemoteNetworks = "192.168.0.0/24, 192.168.10.0/24"
+
+		p, err := GetVPCSubnetPlanner(svc, "vpc-abcdef12")
+
+		assert.Nil(t, err)
+		assert.Equal(t, []AllocatedSubnet{
+			{Prefix: netip.MustParsePrefix("10.0.0.0/24"), Role: SubnetRoleNetwork, ResourceID: "net-abcdef12"},
+			{Prefix: netip.MustParsePrefix("10.0.0.0/24"), Role: SubnetRoleVPNLocal, ResourceID: "vpns-abcdef12"},
+			{Prefix: netip.MustParsePrefix("192.168.0.0/24"), Role: SubnetRoleVPNRemote, ResourceID: "vpns-abcdef12"},
+			{Prefix: netip.MustParsePrefix("192.168.10.0/24"), Role: SubnetRoleVPNRemote, ResourceID: "vpns-abcdef12"},
+		}, p.Subnets)
+	})
+
+	t.Run("InvalidSubnet_ReturnsError", func(t *testing.T) {
+		svc := newFakeVPCService()
+		svc.networks[0].Subnet = "10.0.0.0"
+
+		_, err := GetVPCSubnetPlanner(svc, "vpc-abcdef12")
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("InvalidVPCID_ReturnsError", func(t *testing.T) {
+		_, err := GetVPCSubnetPlanner(newFakeVPCService(), "")
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid vpc id", err.Error())
+	})
+}
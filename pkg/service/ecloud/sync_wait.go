@@ -0,0 +1,84 @@
+package ecloud
+
+import (
+	"context"
+	"fmt"
+)
+
+// SyncFailedError indicates the sync of an eCloud resource failed
+type SyncFailedError struct {
+	ResourceID string
+	Sync       ResourceSync
+}
+
+func (e *SyncFailedError) Error() string {
+	return fmt.Sprintf("%s sync for resource [%s] failed", e.Sync.Type, e.ResourceID)
+}
+
+// SyncWaitOptions configures WaitForSync
+type SyncWaitOptions[T any] struct {
+	WaitOptions
+
+	// Progress is invoked with the resource after each poll
+	Progress func(resource T)
+}
+
+// WaitForSync polls the resource with given ID using get until the sync status returned by
+// sync is complete, returning the final resource. A *SyncFailedError is returned if the sync
+// fails, or a *WaitTimeoutError if opts.Timeout is exceeded. For example:
+//
+//	router, err := ecloud.WaitForSync(ctx, svc.GetRouter, "rtr-abcdef12",
+//		func(r ecloud.Router) ecloud.ResourceSync { return r.Sync }, ecloud.SyncWaitOptions[ecloud.Router]{})
+func WaitForSync[T any](ctx context.Context, get func(id string) (T, error), id string, sync func(T) ResourceSync, opts SyncWaitOptions[T]) (T, error) {
+	if id == "" {
+		var zero T
+		return zero, fmt.Errorf("invalid resource id")
+	}
+
+	p := newPoller(opts.WaitOptions)
+	subject := fmt.Sprintf("sync of resource [%s]", id)
+	for {
+		resource, err := get(id)
+		if err != nil {
+			return resource, fmt.Errorf("failed to retrieve resource [%s]: %w", id, err)
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(resource)
+		}
+
+		s := sync(resource)
+		switch s.Status {
+		case SyncStatusComplete:
+			return resource, nil
+		case SyncStatusFailed:
+			return resource, &SyncFailedError{ResourceID: id, Sync: s}
+		}
+
+		err = p.wait(ctx, subject)
+		if err != nil {
+			return resource, err
+		}
+	}
+}
+
+// waitForRemoval polls the resource with given ID using get until notFound reports the returned
+// error as the resource not being found
+func waitForRemoval(ctx context.Context, id string, get func(id string) error, notFound func(error) bool, opts WaitOptions) error {
+	p := newPoller(opts)
+	subject := fmt.Sprintf("removal of resource [%s]", id)
+	for {
+		err := get(id)
+		if err != nil {
+			if notFound(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to retrieve resource [%s]: %w", id, err)
+		}
+
+		err = p.wait(ctx, subject)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package ecloud
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/ans-group/sdk-go/test/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func routerSync(r Router) ResourceSync {
+	return r.Sync
+}
+
+func TestWaitForSync(t *testing.T) {
+	t.Run("Complete_ReturnsResource", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		c := mocks.NewMockConnection(mockCtrl)
+
+		s := Service{
+			connection: c,
+		}
+
+		gomock.InOrder(
+			c.EXPECT().Get("/ecloud/v2/routers/rtr-abcdef12", gomock.Any()).Return(&connection.APIResponse{
+				Response: &http.Response{
+					Body:       io.NopCloser(bytes.NewReader([]byte("{\"data\":{\"id\":\"rtr-abcdef12\",\"sync\":{\"status\":\"in-progress\",\"type\":\"update\"}}}"))),
+					StatusCode: 200,
+				},
+			}, nil),
+			c.EXPECT().Get("/ecloud/v2/routers/rtr-abcdef12", gomock.Any()).Return(&connection.APIResponse{
+				Response: &http.Response{
+					Body:       io.NopCloser(bytes.NewReader([]byte("{\"data\":{\"id\":\"rtr-abcdef12\",\"name\":\"updated\",\"sync\":{\"status\":\"complete\",\"type\":\"update\"}}}"))),
+					StatusCode: 200,
+				},
+			}, nil),
+		)
+
+		clock := newFakeClock()
+		polls := 0
+		router, err := WaitForSync(context.Background(), s.GetRouter, "rtr-abcdef12", routerSync, SyncWaitOptions[Router]{
+			WaitOptions: WaitOptions{Interval: time.Second, Clock: clock},
+			Progress:    func(r Router) { polls++ },
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "updated", router.Name)
+		assert.Equal(t, 2, polls)
+		assert.Equal(t, []time.Duration{time.Second}, clock.delays)
+	})
+
+	t.Run("Failed_ReturnsSyncFailedError", func(t *testing.T) {
+		get := func(id string) (Volume, error) {
+			return Volume{ID: id, Sync: ResourceSync{Status: SyncStatusFailed, Type: SyncTypeUpdate}}, nil
+		}
+
+		_, err := WaitForSync(context.Background(), get, "vol-abcdef12", func(v Volume) ResourceSync { return v.Sync }, SyncWaitOptions[Volume]{})
+
+		assert.IsType(t, &SyncFailedError{}, err)
+		assert.Equal(t, "update sync for resource [vol-abcdef12] failed", err.Error())
+	})
+
+	t.Run("Timeout_ReturnsWaitTimeoutError", func(t *testing.T) {
+		get := func(id string) (VPC, error) {
+			return VPC{ID: id, Sync: ResourceSync{Status: SyncStatusInProgress}}, nil
+		}
+
+		_, err := WaitForSync(context.Background(), get, "vpc-abcdef12", func(v VPC) ResourceSync { return v.Sync }, SyncWaitOptions[VPC]{
+			WaitOptions: WaitOptions{Interval: time.Second, Timeout: 3 * time.Second, Clock: newFakeClock()},
+		})
+
+		assert.IsType(t, &WaitTimeoutError{}, err)
+		assert.Equal(t, "timed out after 3s waiting for sync of resource [vpc-abcdef12]", err.Error())
+	})
+
+	t.Run("GetError_ReturnsWrappedError", func(t *testing.T) {
+		get := func(id string) (Network, error) {
+			return Network{}, &NetworkNotFoundError{ID: id}
+		}
+
+		_, err := WaitForSync(context.Background(), get, "net-abcdef12", func(n Network) ResourceSync { return n.Sync }, SyncWaitOptions[Network]{})
+
+		var notFoundErr *NetworkNotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+	})
+
+	t.Run("InvalidID_ReturnsError", func(t *testing.T) {
+		_, err := WaitForSync(context.Background(), func(id string) (Instance, error) { return Instance{}, nil }, "",
+			func(i Instance) ResourceSync { return i.Sync }, SyncWaitOptions[Instance]{})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid resource id", err.Error())
+	})
+}
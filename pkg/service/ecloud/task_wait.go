@@ -0,0 +1,173 @@
+package ecloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWaitInterval    = 5 * time.Second
+	defaultWaitMaxInterval = time.Minute
+)
+
+// TaskGetter retrieves a task by ID. It is satisfied by ECloudService
+type TaskGetter interface {
+	GetTask(taskID string) (Task, error)
+}
+
+// WaitOptions configures polling for WaitForTask, WaitForTasks and WaitForSync
+type WaitOptions struct {
+	// Interval is the initial delay between polls, defaulting to 5 seconds
+	Interval time.Duration
+	// MaxInterval caps the delay between polls when Backoff is applied, defaulting to 1 minute
+	MaxInterval time.Duration
+	// Backoff multiplies the delay after each poll. Values less than 1 disable backoff
+	Backoff float64
+	// Timeout is the maximum time to wait. Zero waits until the context is done
+	Timeout time.Duration
+	// Clock overrides the clock used for delays and timeouts, defaulting to the real clock
+	Clock Clock
+}
+
+// TaskWaitOptions configures WaitForTask and WaitForTasks
+type TaskWaitOptions struct {
+	WaitOptions
+
+	// Progress is invoked with the task after each poll. For WaitForTasks, Progress may be
+	// invoked concurrently
+	Progress func(task Task)
+}
+
+// TaskFailedError indicates an eCloud task completed with a failed status
+type TaskFailedError struct {
+	Task Task
+}
+
+func (e *TaskFailedError) Error() string {
+	return fmt.Sprintf("task [%s] (%s) for resource [%s] failed", e.Task.ID, e.Task.Name, e.Task.ResourceID)
+}
+
+// WaitTimeoutError indicates a wait exceeded its timeout
+type WaitTimeoutError struct {
+	// Subject describes what was being waited on, e.g. task [task-abcdef12]
+	Subject string
+	Timeout time.Duration
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for %s", e.Timeout, e.Subject)
+}
+
+// poller implements the shared polling loop for waiters
+type poller struct {
+	opts     WaitOptions
+	clock    Clock
+	interval time.Duration
+	deadline time.Time
+}
+
+func newPoller(opts WaitOptions) *poller {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultWaitInterval
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = defaultWaitMaxInterval
+	}
+	if opts.MaxInterval < opts.Interval {
+		opts.MaxInterval = opts.Interval
+	}
+
+	p := &poller{
+		opts:     opts,
+		clock:    clockOrDefault(opts.Clock),
+		interval: opts.Interval,
+	}
+	if opts.Timeout > 0 {
+		p.deadline = p.clock.Now().Add(opts.Timeout)
+	}
+	return p
+}
+
+// wait blocks until the next poll is due, returning an error if the context is done or the
+// timeout would be exceeded
+func (p *poller) wait(ctx context.Context, subject string) error {
+	delay := p.interval
+	if !p.deadline.IsZero() {
+		remaining := p.deadline.Sub(p.clock.Now())
+		if remaining <= 0 {
+			return &WaitTimeoutError{Subject: subject, Timeout: p.opts.Timeout}
+		}
+		if delay > remaining {
+			delay = remaining
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.clock.After(delay):
+	}
+
+	if p.opts.Backoff > 1 {
+		p.interval = time.Duration(float64(p.interval) * p.opts.Backoff)
+		if p.interval > p.opts.MaxInterval {
+			p.interval = p.opts.MaxInterval
+		}
+	}
+	return nil
+}
+
+// WaitForTask polls the task with given ID until it completes, returning the completed task. A
+// *TaskFailedError is returned if the task fails, or a *WaitTimeoutError if opts.Timeout is exceeded
+func WaitForTask(ctx context.Context, svc TaskGetter, taskID string, opts TaskWaitOptions) (Task, error) {
+	if taskID == "" {
+		return Task{}, fmt.Errorf("invalid task id")
+	}
+
+	p := newPoller(opts.WaitOptions)
+	subject := fmt.Sprintf("task [%s]", taskID)
+	for {
+		task, err := svc.GetTask(taskID)
+		if err != nil {
+			return task, fmt.Errorf("failed to retrieve task [%s]: %w", taskID, err)
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(task)
+		}
+
+		switch task.Status {
+		case TaskStatusComplete:
+			return task, nil
+		case TaskStatusFailed:
+			return task, &TaskFailedError{Task: task}
+		}
+
+		err = p.wait(ctx, subject)
+		if err != nil {
+			return task, err
+		}
+	}
+}
+
+// WaitForTasks concurrently waits for the tasks with given IDs, returning the final state of each
+// task in the order given. All tasks are waited on regardless of failures, with errors joined
+func WaitForTasks(ctx context.Context, svc TaskGetter, taskIDs []string, opts TaskWaitOptions) ([]Task, error) {
+	tasks := make([]Task, len(taskIDs))
+	errs := make([]error, len(taskIDs))
+
+	var wg sync.WaitGroup
+	for i, taskID := range taskIDs {
+		wg.Add(1)
+		go func(i int, taskID string) {
+			defer wg.Done()
+			tasks[i], errs[i] = WaitForTask(ctx, svc, taskID, opts)
+		}(i, taskID)
+	}
+	wg.Wait()
+
+	return tasks, errors.Join(errs...)
+}
@@ -0,0 +1,181 @@
+package ecloud
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTaskGetter returns tasks from a per-ID sequence of statuses, repeating the final status
+type fakeTaskGetter struct {
+	statuses map[string][]TaskStatus
+	err      error
+	calls    map[string]int
+	mutex    sync.Mutex
+}
+
+func (f *fakeTaskGetter) GetTask(taskID string) (Task, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.err != nil {
+		return Task{}, f.err
+	}
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	seq := f.statuses[taskID]
+	i := f.calls[taskID]
+	if i >= len(seq) {
+		i = len(seq) - 1
+	}
+	f.calls[taskID]++
+	return Task{ID: taskID, Name: "instance_power_on", ResourceID: "i-abcdef12", Status: seq[i]}, nil
+}
+
+func TestWaitForTask(t *testing.T) {
+	t.Run("Complete_ReturnsTask", func(t *testing.T) {
+		clock := newFakeClock()
+		svc := &fakeTaskGetter{statuses: map[string][]TaskStatus{
+			"task-abcdef12": {TaskStatusInProgress, TaskStatusInProgress, TaskStatusComplete},
+		}}
+		var progress []TaskStatus
+
+		task, err := WaitForTask(context.Background(), svc, "task-abcdef12", TaskWaitOptions{
+			WaitOptions: WaitOptions{Interval: time.Second, Clock: clock},
+			Progress:    func(task Task) { progress = append(progress, task.Status) },
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, TaskStatusComplete, task.Status)
+		assert.Equal(t, []TaskStatus{TaskStatusInProgress, TaskStatusInProgress, TaskStatusComplete}, progress)
+		assert.Equal(t, []time.Duration{time.Second, time.Second}, clock.delays)
+	})
+
+	t.Run("Failed_ReturnsTaskFailedError", func(t *testing.T) {
+		svc := &fakeTaskGetter{statuses: map[string][]TaskStatus{
+			"task-abcdef12": {TaskStatusInProgress, TaskStatusFailed},
+		}}
+
+		_, err := WaitForTask(context.Background(), svc, "task-abcdef12", TaskWaitOptions{
+			WaitOptions: WaitOptions{Clock: newFakeClock()},
+		})
+
+		var failedErr *TaskFailedError
+		assert.True(t, errors.As(err, &failedErr))
+		assert.Equal(t, "i-abcdef12", failedErr.Task.ResourceID)
+		assert.Equal(t, "task [task-abcdef12] (instance_power_on) for resource [i-abcdef12] failed", err.Error())
+	})
+
+	t.Run("Backoff_IncreasesIntervalUpToMax", func(t *testing.T) {
+		clock := newFakeClock()
+		svc := &fakeTaskGetter{statuses: map[string][]TaskStatus{
+			"task-abcdef12": {TaskStatusInProgress, TaskStatusInProgress, TaskStatusInProgress, TaskStatusInProgress, TaskStatusComplete},
+		}}
+
+		_, err := WaitForTask(context.Background(), svc, "task-abcdef12", TaskWaitOptions{
+			WaitOptions: WaitOptions{Interval: time.Second, Backoff: 2, MaxInterval: 5 * time.Second, Clock: clock},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, clock.delays)
+	})
+
+	t.Run("Timeout_ReturnsWaitTimeoutError", func(t *testing.T) {
+		clock := newFakeClock()
+		svc := &fakeTaskGetter{statuses: map[string][]TaskStatus{
+			"task-abcdef12": {TaskStatusInProgress},
+		}}
+
+		task, err := WaitForTask(context.Background(), svc, "task-abcdef12", TaskWaitOptions{
+			WaitOptions: WaitOptions{Interval: 4 * time.Second, Timeout: 10 * time.Second, Clock: clock},
+		})
+
+		assert.IsType(t, &WaitTimeoutError{}, err)
+		assert.Equal(t, "timed out after 10s waiting for task [task-abcdef12]", err.Error())
+		assert.Equal(t, TaskStatusInProgress, task.Status)
+		assert.Equal(t, []time.Duration{4 * time.Second, 4 * time.Second, 2 * time.Second}, clock.delays)
+	})
+
+	t.Run("ContextCancelled_ReturnsContextError", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		svc := &fakeTaskGetter{statuses: map[string][]TaskStatus{
+			"task-abcdef12": {TaskStatusInProgress},
+		}}
+
+		_, err := WaitForTask(ctx, svc, "task-abcdef12", TaskWaitOptions{
+			WaitOptions: WaitOptions{Clock: &blockingClock{}},
+		})
+
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+
+	t.Run("GetTaskError_ReturnsWrappedError", func(t *testing.T) {
+		svc := &fakeTaskGetter{err: errors.New("test error 1")}
+
+		_, err := WaitForTask(context.Background(), svc, "task-abcdef12", TaskWaitOptions{})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "failed to retrieve task [task-abcdef12]: test error 1", err.Error())
+	})
+
+	t.Run("InvalidTaskID_ReturnsError", func(t *testing.T) {
+		_, err := WaitForTask(context.Background(), &fakeTaskGetter{}, "", TaskWaitOptions{})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid task id", err.Error())
+	})
+}
+
+func TestWaitForTasks(t *testing.T) {
+	t.Run("ReturnsTasksInOrderWithJoinedErrors", func(t *testing.T) {
+		svc := &fakeTaskGetter{statuses: map[string][]TaskStatus{
+			"task-1": {TaskStatusInProgress, TaskStatusComplete},
+			"task-2": {TaskStatusFailed},
+			"task-3": {TaskStatusComplete},
+		}}
+
+		tasks, err := WaitForTasks(context.Background(), svc, []string{"task-1", "task-2", "task-3"}, TaskWaitOptions{
+			WaitOptions: WaitOptions{Clock: newFakeClock()},
+		})
+
+		assert.Len(t, tasks, 3)
+		assert.Equal(t, "task-1", tasks[0].ID)
+		assert.Equal(t, TaskStatusComplete, tasks[0].Status)
+		assert.Equal(t, TaskStatusFailed, tasks[1].Status)
+		assert.Equal(t, TaskStatusComplete, tasks[2].Status)
+
+		var failedErr *TaskFailedError
+		assert.True(t, errors.As(err, &failedErr))
+		assert.Equal(t, "task-2", failedErr.Task.ID)
+	})
+
+	t.Run("AllComplete_ReturnsNilError", func(t *testing.T) {
+		svc := &fakeTaskGetter{statuses: map[string][]TaskStatus{
+			"task-1": {TaskStatusComplete},
+			"task-2": {TaskStatusComplete},
+		}}
+
+		_, err := WaitForTasks(context.Background(), svc, []string{"task-1", "task-2"}, TaskWaitOptions{
+			WaitOptions: WaitOptions{Clock: newFakeClock()},
+		})
+
+		assert.Nil(t, err)
+	})
+}
+
+// blockingClock is a Clock whose timers never fire
+type blockingClock struct{}
+
+func (blockingClock) Now() time.Time {
+	return time.Time{}
+}
+
+func (blockingClock) After(d time.Duration) <-chan time.Time {
+	return make(chan time.Time)
+}
@@ -0,0 +1,490 @@
+package ecloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Terraform resource types of exported resources, keyed by resource kind
+var terraformResourceTypes = map[string]string{
+	ResourceKindVPC:            "ecloud_vpc",
+	ResourceKindRouter:         "ecloud_router",
+	ResourceKindNetwork:        "ecloud_network",
+	ResourceKindFirewallPolicy: "ecloud_firewallpolicy",
+	ResourceKindFirewallRule:   "ecloud_firewallrule",
+	ResourceKindVolume:         "ecloud_volume",
+	ResourceKindInstance:       "ecloud_instance",
+	ResourceKindFloatingIP:     "ecloud_floatingip",
+	ResourceKindLoadBalancer:   "ecloud_loadbalancer",
+	ResourceKindVIP:            "ecloud_loadbalancer_vip",
+}
+
+// terraformExportOrder is the order in which resources are exported, with dependencies first
+var terraformExportOrder = []string{
+	ResourceKindVPC,
+	ResourceKindRouter,
+	ResourceKindNetwork,
+	ResourceKindFirewallPolicy,
+	ResourceKindFirewallRule,
+	ResourceKindVolume,
+	ResourceKindInstance,
+	ResourceKindLoadBalancer,
+	ResourceKindVIP,
+	ResourceKindFloatingIP,
+}
+
+// TerraformReference is an expression referring to an attribute of another resource, e.g.
+// ecloud_vpc.prod.id
+type TerraformReference string
+
+// TerraformAttribute is an argument of a resource or block. Value is a string, int, bool or
+// TerraformReference
+type TerraformAttribute struct {
+	Name  string
+	Value interface{}
+}
+
+// TerraformBlock is a nested block within a resource, e.g. a firewall rule port
+type TerraformBlock struct {
+	Type       string
+	Attributes []TerraformAttribute
+}
+
+// TerraformResource is a resource block for an existing resource, identified for import by ID
+type TerraformResource struct {
+	Kind       string
+	Type       string
+	Name       string
+	ID         string
+	Attributes []TerraformAttribute
+	Blocks     []TerraformBlock
+}
+
+// Address returns the address of the resource, e.g. ecloud_vpc.prod
+func (r TerraformResource) Address() string {
+	return r.Type + "." + r.Name
+}
+
+func (r TerraformResource) reference() TerraformReference {
+	return TerraformReference(r.Address() + ".id")
+}
+
+// TerraformConfig is Terraform configuration for existing resources, along with the import blocks
+// which adopt them into state
+type TerraformConfig struct {
+	Resources []TerraformResource
+}
+
+// Resource returns the resource with given kind and ID
+func (c *TerraformConfig) Resource(kind string, id string) (TerraformResource, bool) {
+	for _, r := range c.Resources {
+		if r.Kind == kind && r.ID == id {
+			return r, true
+		}
+	}
+	return TerraformResource{}, false
+}
+
+// WriteHCL writes a resource block for each resource in HCL native syntax, formatted as terraform
+// fmt would
+func (c *TerraformConfig) WriteHCL(w io.Writer) error {
+	for i, r := range c.Resources {
+		var b strings.Builder
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "resource %s %s {\n", strconv.Quote(r.Type), strconv.Quote(r.Name))
+		writeHCLAttributes(&b, "  ", r.Attributes)
+		for _, block := range r.Blocks {
+			fmt.Fprintf(&b, "\n  %s {\n", block.Type)
+			writeHCLAttributes(&b, "    ", block.Attributes)
+			b.WriteString("  }\n")
+		}
+		b.WriteString("}\n")
+
+		_, err := io.WriteString(w, b.String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteImportsHCL writes an import block for each resource in HCL native syntax
+func (c *TerraformConfig) WriteImportsHCL(w io.Writer) error {
+	for i, r := range c.Resources {
+		separator := ""
+		if i > 0 {
+			separator = "\n"
+		}
+		_, err := fmt.Fprintf(w, "%simport {\n  to = %s\n  id = %s\n}\n", separator, r.Address(), hclString(r.ID))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the resource and import blocks in Terraform JSON syntax, suitable for a
+// .tf.json file
+func (c *TerraformConfig) WriteJSON(w io.Writer) error {
+	resources := make(map[string]map[string]interface{})
+	var imports []map[string]string
+	for _, r := range c.Resources {
+		body := jsonTerraformAttributes(r.Attributes)
+		for _, block := range r.Blocks {
+			blocks, _ := body[block.Type].([]interface{})
+			body[block.Type] = append(blocks, jsonTerraformAttributes(block.Attributes))
+		}
+		if resources[r.Type] == nil {
+			resources[r.Type] = make(map[string]interface{})
+		}
+		resources[r.Type][r.Name] = body
+		imports = append(imports, map[string]string{"to": r.Address(), "id": r.ID})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{"resource": resources, "import": imports})
+}
+
+func writeHCLAttributes(b *strings.Builder, indent string, attributes []TerraformAttribute) {
+	width := 0
+	for _, attribute := range attributes {
+		width = max(width, len(attribute.Name))
+	}
+	for _, attribute := range attributes {
+		fmt.Fprintf(b, "%s%-*s = %s\n", indent, width, attribute.Name, hclValue(attribute.Value))
+	}
+}
+
+func hclValue(v interface{}) string {
+	switch v := v.(type) {
+	case TerraformReference:
+		return string(v)
+	case string:
+		return hclString(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// hclString quotes s as an HCL string literal, escaping template sequences. HCL accepts fewer
+// escapes than Go, so other control characters are written as \uNNNN
+func hclString(s string) string {
+	b := &strings.Builder{}
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if unicode.IsControl(r) {
+				fmt.Fprintf(b, `\u%04x`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+
+	quoted := strings.ReplaceAll(b.String(), "${", "$${")
+	return strings.ReplaceAll(quoted, "%{", "%%{")
+}
+
+func jsonTerraformAttributes(attributes []TerraformAttribute) map[string]interface{} {
+	body := make(map[string]interface{})
+	for _, attribute := range attributes {
+		switch v := attribute.Value.(type) {
+		case TerraformReference:
+			body[attribute.Name] = "${" + string(v) + "}"
+		case string:
+			body[attribute.Name] = strings.ReplaceAll(strings.ReplaceAll(v, "${", "$${"), "%{", "%%{")
+		default:
+			body[attribute.Name] = v
+		}
+	}
+	return body
+}
+
+// ExportTerraform snapshots VPC vpcID and generates Terraform configuration for it with
+// GenerateTerraform
+func ExportTerraform(svc ECloudService, vpcID string) (*TerraformConfig, error) {
+	snapshot, err := GetVPCSnapshot(svc, vpcID, VPCSnapshotOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return GenerateTerraform(snapshot)
+}
+
+// GenerateTerraform generates Terraform configuration for the VPC, routers, networks, firewall
+// policies and rules, data volumes, instances, load balancers, VIPs and floating IPs within
+// snapshot, allowing configuration to be generated offline from a saved snapshot. Resources refer to
+// each other by address where the referenced resource is exported, and by ID otherwise. Firewall rule
+// ports are exported as port blocks within their rule, and each instance is placed on the network
+// of its first NIC. Resource names are derived from resource names, or IDs where unnamed
+func GenerateTerraform(snapshot *VPCSnapshot) (*TerraformConfig, error) {
+	g := &terraformGenerator{
+		config: &TerraformConfig{},
+		names:  make(map[string]bool),
+		byID:   make(map[string]TerraformResource),
+	}
+
+	byKind := make(map[string][]SnapshotResource)
+	for _, resource := range snapshot.Resources {
+		byKind[resource.Kind] = append(byKind[resource.Kind], resource)
+	}
+	for _, resources := range byKind {
+		sort.SliceStable(resources, func(i, j int) bool { return resources[i].ID < resources[j].ID })
+	}
+
+	ports := make(map[string][]FirewallRulePort)
+	for _, resource := range byKind[ResourceKindFirewallRulePort] {
+		port, err := decodeSnapshotResource[FirewallRulePort](resource)
+		if err != nil {
+			return nil, err
+		}
+		ports[port.FirewallRuleID] = append(ports[port.FirewallRuleID], port)
+	}
+	instanceNetworks := make(map[string]string)
+	for _, resource := range byKind[ResourceKindNIC] {
+		nic, err := decodeSnapshotResource[NIC](resource)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := instanceNetworks[nic.InstanceID]; !ok && nic.InstanceID != "" {
+			instanceNetworks[nic.InstanceID] = nic.NetworkID
+		}
+	}
+
+	for _, kind := range terraformExportOrder {
+		for _, resource := range byKind[kind] {
+			err := g.generate(resource, ports, instanceNetworks)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return g.config, nil
+}
+
+type terraformGenerator struct {
+	config *TerraformConfig
+	names  map[string]bool
+	byID   map[string]TerraformResource
+}
+
+// ref returns a reference to the exported resource with ID id, or id itself
+func (g *terraformGenerator) ref(id string) interface{} {
+	if r, ok := g.byID[id]; ok {
+		return r.reference()
+	}
+	return id
+}
+
+// name returns a unique name for a resource of given type
+func (g *terraformGenerator) name(resourceType string, resource SnapshotResource) string {
+	base := terraformName(resource.Name)
+	if base == "" {
+		base = terraformName(resource.ID)
+	}
+
+	name := base
+	for i := 2; g.names[resourceType+"."+name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	g.names[resourceType+"."+name] = true
+	return name
+}
+
+func (g *terraformGenerator) generate(resource SnapshotResource, ports map[string][]FirewallRulePort, instanceNetworks map[string]string) error {
+	r := TerraformResource{Kind: resource.Kind, Type: terraformResourceTypes[resource.Kind], ID: resource.ID}
+	attr := func(name string, value interface{}) {
+		r.Attributes = append(r.Attributes, TerraformAttribute{Name: name, Value: value})
+	}
+
+	switch resource.Kind {
+	case ResourceKindVPC:
+		vpc, err := decodeSnapshotResource[VPC](resource)
+		if err != nil {
+			return err
+		}
+		attr("name", vpc.Name)
+		attr("region_id", vpc.RegionID)
+		attr("advanced_networking", vpc.AdvancedNetworking)
+	case ResourceKindRouter:
+		router, err := decodeSnapshotResource[Router](resource)
+		if err != nil {
+			return err
+		}
+		attr("vpc_id", g.ref(router.VPCID))
+		attr("name", router.Name)
+		attr("availability_zone_id", router.AvailabilityZoneID)
+		attr("router_throughput_id", router.RouterThroughputID)
+	case ResourceKindNetwork:
+		network, err := decodeSnapshotResource[Network](resource)
+		if err != nil {
+			return err
+		}
+		attr("router_id", g.ref(network.RouterID))
+		attr("name", network.Name)
+		attr("subnet", network.Subnet)
+	case ResourceKindFirewallPolicy:
+		policy, err := decodeSnapshotResource[FirewallPolicy](resource)
+		if err != nil {
+			return err
+		}
+		attr("router_id", g.ref(policy.RouterID))
+		attr("name", policy.Name)
+		attr("sequence", policy.Sequence)
+	case ResourceKindFirewallRule:
+		rule, err := decodeSnapshotResource[FirewallRule](resource)
+		if err != nil {
+			return err
+		}
+		attr("firewall_policy_id", g.ref(rule.FirewallPolicyID))
+		attr("name", rule.Name)
+		attr("sequence", rule.Sequence)
+		attr("direction", rule.Direction.String())
+		attr("source", rule.Source)
+		attr("destination", rule.Destination)
+		attr("action", rule.Action.String())
+		attr("enabled", rule.Enabled)
+		for _, port := range ports[rule.ID] {
+			block := TerraformBlock{Type: "port", Attributes: []TerraformAttribute{{Name: "protocol", Value: port.Protocol.String()}}}
+			if port.Source != "" {
+				block.Attributes = append(block.Attributes, TerraformAttribute{Name: "source", Value: port.Source})
+			}
+			if port.Destination != "" {
+				block.Attributes = append(block.Attributes, TerraformAttribute{Name: "destination", Value: port.Destination})
+			}
+			r.Blocks = append(r.Blocks, block)
+		}
+	case ResourceKindVolume:
+		volume, err := decodeSnapshotResource[Volume](resource)
+		if err != nil {
+			return err
+		}
+		// Operating system volumes are managed through their instance
+		if volume.Type == VolumeTypeOS {
+			return nil
+		}
+		attr("vpc_id", g.ref(volume.VPCID))
+		if volume.Name != "" {
+			attr("name", volume.Name)
+		}
+		attr("availability_zone_id", volume.AvailabilityZoneID)
+		attr("capacity", volume.Capacity)
+		if volume.IOPS > 0 {
+			attr("iops", volume.IOPS)
+		}
+	case ResourceKindInstance:
+		instance, err := decodeSnapshotResource[Instance](resource)
+		if err != nil {
+			return err
+		}
+		attr("vpc_id", g.ref(instance.VPCID))
+		if networkID, ok := instanceNetworks[instance.ID]; ok {
+			attr("network_id", g.ref(networkID))
+		}
+		attr("name", instance.Name)
+		attr("image_id", instance.ImageID)
+		attr("vcpu_cores", instance.VCPUCores)
+		attr("ram_capacity", instance.RAMCapacity)
+		attr("volume_capacity", instance.VolumeCapacity)
+		attr("locked", instance.Locked)
+		attr("backup_enabled", instance.BackupEnabled)
+		if instance.HostGroupID != "" {
+			attr("host_group_id", instance.HostGroupID)
+		}
+		if instance.ResourceTierID != "" {
+			attr("resource_tier_id", instance.ResourceTierID)
+		}
+	case ResourceKindLoadBalancer:
+		lb, err := decodeSnapshotResource[LoadBalancer](resource)
+		if err != nil {
+			return err
+		}
+		attr("vpc_id", g.ref(lb.VPCID))
+		attr("network_id", g.ref(lb.NetworkID))
+		attr("name", lb.Name)
+		attr("availability_zone_id", lb.AvailabilityZoneID)
+		attr("load_balancer_spec_id", lb.LoadBalancerSpecID)
+	case ResourceKindVIP:
+		vip, err := decodeSnapshotResource[VIP](resource)
+		if err != nil {
+			return err
+		}
+		attr("load_balancer_id", g.ref(vip.LoadBalancerID))
+		attr("name", vip.Name)
+	case ResourceKindFloatingIP:
+		fip, err := decodeSnapshotResource[FloatingIP](resource)
+		if err != nil {
+			return err
+		}
+		attr("vpc_id", g.ref(fip.VPCID))
+		attr("name", fip.Name)
+		attr("availability_zone_id", fip.AvailabilityZoneID)
+		if fip.ResourceID != "" {
+			attr("resource_id", g.ref(fip.ResourceID))
+		}
+	default:
+		return nil
+	}
+
+	r.Name = g.name(r.Type, resource)
+	g.byID[r.ID] = r
+	g.config.Resources = append(g.config.Resources, r)
+	return nil
+}
+
+// decodeSnapshotResource decodes the attributes of resource into T
+func decodeSnapshotResource[T any](resource SnapshotResource) (T, error) {
+	var v T
+	b, err := json.Marshal(resource.Attributes)
+	if err != nil {
+		return v, fmt.Errorf("failed to encode %s [%s]: %w", resource.Kind, resource.ID, err)
+	}
+	err = json.Unmarshal(b, &v)
+	if err != nil {
+		return v, fmt.Errorf("failed to decode %s [%s]: %w", resource.Kind, resource.ID, err)
+	}
+	return v, nil
+}
+
+// terraformName converts s to a valid Terraform identifier, lowercased with runs of other
+// characters replaced by underscores
+func terraformName(s string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			underscore = false
+			b.WriteRune(r)
+			continue
+		}
+		underscore = true
+	}
+
+	name := b.String()
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "r_" + name
+	}
+	return name
+}
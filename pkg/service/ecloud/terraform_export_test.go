@@ -0,0 +1,111 @@
+package ecloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readTerraformSnapshot(t *testing.T) *VPCSnapshot {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "terraform", "snapshot.json"))
+	assert.Nil(t, err)
+	defer f.Close()
+
+	snapshot, err := ReadVPCSnapshot(f)
+	assert.Nil(t, err)
+	return snapshot
+}
+
+func TestGenerateTerraform(t *testing.T) {
+	t.Run("Addresses", func(t *testing.T) {
+		config, err := GenerateTerraform(readTerraformSnapshot(t))
+
+		assert.Nil(t, err)
+		var addresses []string
+		for _, r := range config.Resources {
+			addresses = append(addresses, r.Address())
+		}
+		assert.Equal(t, []string{
+			"ecloud_vpc.prod_vpc",
+			"ecloud_router.main",
+			"ecloud_network.web",
+			"ecloud_firewallpolicy.web",
+			"ecloud_firewallrule.https",
+			"ecloud_firewallrule.ssh_from_office",
+			"ecloud_volume.vol_abcdef13",
+			"ecloud_instance.web_01",
+			"ecloud_instance.web_01_2",
+			"ecloud_loadbalancer.web",
+			"ecloud_loadbalancer_vip.web",
+			"ecloud_floatingip.web",
+			"ecloud_floatingip.spare",
+		}, addresses)
+
+		fip, ok := config.Resource(ResourceKindFloatingIP, "fip-abcdef12")
+		assert.True(t, ok)
+		assert.Contains(t, fip.Attributes, TerraformAttribute{Name: "resource_id", Value: TerraformReference("ecloud_loadbalancer_vip.web.id")})
+	})
+
+	t.Run("HCL", func(t *testing.T) {
+		config, err := GenerateTerraform(readTerraformSnapshot(t))
+		assert.Nil(t, err)
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, config.WriteHCL(buf))
+		assertGolden(t, "terraform/main.tf", buf.Bytes())
+
+		buf.Reset()
+		assert.Nil(t, config.WriteImportsHCL(buf))
+		assertGolden(t, "terraform/imports.tf", buf.Bytes())
+	})
+
+	t.Run("ControlCharacterInName_EscapedForHCL", func(t *testing.T) {
+		snapshot := readTerraformSnapshot(t)
+		snapshot.Resources[0].Attributes["name"] = "prod\x1b[31m\a\v\"vpc\"\\${x}\n"
+		config, err := GenerateTerraform(snapshot)
+		assert.Nil(t, err)
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, config.WriteHCL(buf))
+
+		assert.Contains(t, buf.String(), `"prod\u001b[31m\u0007\u000b\"vpc\"\\$${x}\n"`)
+		assert.NotContains(t, buf.String(), `\x1b`)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		config, err := GenerateTerraform(readTerraformSnapshot(t))
+		assert.Nil(t, err)
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, config.WriteJSON(buf))
+		assertGolden(t, "terraform/main.tf.json", buf.Bytes())
+
+		var decoded map[string]interface{}
+		assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Len(t, decoded["import"], len(config.Resources))
+	})
+}
+
+func TestExportTerraform(t *testing.T) {
+	config, err := ExportTerraform(newFakeSnapshotService(), "vpc-abcdef12")
+
+	assert.Nil(t, err)
+	instance, ok := config.Resource(ResourceKindInstance, "i-00000001")
+	assert.True(t, ok)
+	assert.Equal(t, "ecloud_instance.web_01", instance.Address())
+	assert.Equal(t, []TerraformAttribute{
+		{Name: "vpc_id", Value: TerraformReference("ecloud_vpc.prod.id")},
+		{Name: "network_id", Value: TerraformReference("ecloud_network.web.id")},
+	}, instance.Attributes[:2])
+}
+
+func TestTerraformName(t *testing.T) {
+	assert.Equal(t, "web_01", terraformName("Web--01 "))
+	assert.Equal(t, "r_443", terraformName("443"))
+	assert.Equal(t, "", terraformName("--"))
+}
@@ -0,0 +1,64 @@
+import {
+  to = ecloud_vpc.prod_vpc
+  id = "vpc-abcdef12"
+}
+
+import {
+  to = ecloud_router.main
+  id = "rtr-abcdef12"
+}
+
+import {
+  to = ecloud_network.web
+  id = "net-abcdef12"
+}
+
+import {
+  to = ecloud_firewallpolicy.web
+  id = "fwp-abcdef12"
+}
+
+import {
+  to = ecloud_firewallrule.https
+  id = "fwr-abcdef12"
+}
+
+import {
+  to = ecloud_firewallrule.ssh_from_office
+  id = "fwr-abcdef13"
+}
+
+import {
+  to = ecloud_volume.vol_abcdef13
+  id = "vol-abcdef13"
+}
+
+import {
+  to = ecloud_instance.web_01
+  id = "i-abcdef12"
+}
+
+import {
+  to = ecloud_instance.web_01_2
+  id = "i-abcdef13"
+}
+
+import {
+  to = ecloud_loadbalancer.web
+  id = "lb-abcdef12"
+}
+
+import {
+  to = ecloud_loadbalancer_vip.web
+  id = "vip-abcdef12"
+}
+
+import {
+  to = ecloud_floatingip.web
+  id = "fip-abcdef12"
+}
+
+import {
+  to = ecloud_floatingip.spare
+  id = "fip-abcdef13"
+}
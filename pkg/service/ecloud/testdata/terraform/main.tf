@@ -0,0 +1,114 @@
+resource "ecloud_vpc" "prod_vpc" {
+  name                = "Prod VPC"
+  region_id           = "reg-abcdef12"
+  advanced_networking = true
+}
+
+resource "ecloud_router" "main" {
+  vpc_id               = ecloud_vpc.prod_vpc.id
+  name                 = "main"
+  availability_zone_id = "az-abcdef12"
+  router_throughput_id = "rtp-abcdef12"
+}
+
+resource "ecloud_network" "web" {
+  router_id = ecloud_router.main.id
+  name      = "web"
+  subnet    = "10.0.0.0/24"
+}
+
+resource "ecloud_firewallpolicy" "web" {
+  router_id = ecloud_router.main.id
+  name      = "web"
+  sequence  = 10
+}
+
+resource "ecloud_firewallrule" "https" {
+  firewall_policy_id = ecloud_firewallpolicy.web.id
+  name               = "https"
+  sequence           = 1
+  direction          = "IN"
+  source             = "ANY"
+  destination        = "10.0.0.0/24"
+  action             = "ALLOW"
+  enabled            = true
+
+  port {
+    protocol    = "TCP"
+    destination = "443"
+  }
+
+  port {
+    protocol    = "UDP"
+    source      = "ANY"
+    destination = "443"
+  }
+}
+
+resource "ecloud_firewallrule" "ssh_from_office" {
+  firewall_policy_id = ecloud_firewallpolicy.web.id
+  name               = "ssh from $${office}"
+  sequence           = 2
+  direction          = "IN"
+  source             = "203.0.113.0/24"
+  destination        = "10.0.0.0/24"
+  action             = "ALLOW"
+  enabled            = false
+}
+
+resource "ecloud_volume" "vol_abcdef13" {
+  vpc_id               = ecloud_vpc.prod_vpc.id
+  availability_zone_id = "az-abcdef12"
+  capacity             = 100
+  iops                 = 600
+}
+
+resource "ecloud_instance" "web_01" {
+  vpc_id           = ecloud_vpc.prod_vpc.id
+  network_id       = ecloud_network.web.id
+  name             = "web-01"
+  image_id         = "img-abcdef12"
+  vcpu_cores       = 2
+  ram_capacity     = 2048
+  volume_capacity  = 40
+  locked           = false
+  backup_enabled   = true
+  resource_tier_id = "rt-abcdef12"
+}
+
+resource "ecloud_instance" "web_01_2" {
+  vpc_id          = ecloud_vpc.prod_vpc.id
+  name            = "web 01"
+  image_id        = "img-abcdef12"
+  vcpu_cores      = 1
+  ram_capacity    = 1024
+  volume_capacity = 20
+  locked          = true
+  backup_enabled  = false
+}
+
+resource "ecloud_loadbalancer" "web" {
+  vpc_id                = ecloud_vpc.prod_vpc.id
+  network_id            = ecloud_network.web.id
+  name                  = "web"
+  availability_zone_id  = "az-abcdef12"
+  load_balancer_spec_id = "lbs-abcdef12"
+}
+
+resource "ecloud_loadbalancer_vip" "web" {
+  load_balancer_id = ecloud_loadbalancer.web.id
+  name             = "web"
+}
+
+resource "ecloud_floatingip" "web" {
+  vpc_id               = ecloud_vpc.prod_vpc.id
+  name                 = "web"
+  availability_zone_id = "az-abcdef12"
+  resource_id          = ecloud_loadbalancer_vip.web.id
+}
+
+resource "ecloud_floatingip" "spare" {
+  vpc_id               = ecloud_vpc.prod_vpc.id
+  name                 = "spare"
+  availability_zone_id = "az-abcdef12"
+}
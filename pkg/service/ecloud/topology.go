@@ -0,0 +1,451 @@
+package ecloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+)
+
+// Additional resource kinds reported in topology graphs
+const (
+	ResourceKindIPAddress  = "ip_address"
+	ResourceKindVPNGateway = "vpn_gateway"
+)
+
+// Relations between nodes in a Topology
+const (
+	// TopologyRelationContains links a parent resource to a child resource
+	TopologyRelationContains = "contains"
+	// TopologyRelationAttached links a resource to the resource it is attached to, e.g. a NIC to
+	// its instance
+	TopologyRelationAttached = "attached"
+	// TopologyRelationAssigned links an address to the resource it is assigned to, e.g. a floating
+	// IP to a NIC
+	TopologyRelationAssigned = "assigned"
+)
+
+const defaultTopologyConcurrency = 4
+
+// TopologyNode is a resource within a Topology
+type TopologyNode struct {
+	ID         string            `json:"id"`
+	Kind       string            `json:"kind"`
+	Name       string            `json:"name"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// TopologyEdge is a directed relation between two nodes in a Topology
+type TopologyEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"`
+}
+
+// Topology is a graph of the resources deployed within a VPC
+type Topology struct {
+	VPCID string         `json:"vpc_id"`
+	Nodes []TopologyNode `json:"nodes"`
+	Edges []TopologyEdge `json:"edges"`
+}
+
+// Node returns the node with given ID
+func (t *Topology) Node(id string) (TopologyNode, bool) {
+	for _, n := range t.Nodes {
+		if n.ID == id {
+			return n, true
+		}
+	}
+	return TopologyNode{}, false
+}
+
+// WriteJSON writes the topology in JSON format
+func (t *Topology) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+var topologyShapes = map[string]string{
+	ResourceKindVPC:          "doubleoctagon",
+	ResourceKindRouter:       "octagon",
+	ResourceKindNetwork:      "box3d",
+	ResourceKindInstance:     "box",
+	ResourceKindNIC:          "ellipse",
+	ResourceKindIPAddress:    "note",
+	ResourceKindFloatingIP:   "note",
+	ResourceKindLoadBalancer: "component",
+	ResourceKindVIP:          "note",
+	ResourceKindVPNService:   "cds",
+	ResourceKindVPNGateway:   "cds",
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// WriteDOT writes the topology in Graphviz DOT format
+func (t *Topology) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(t.VPCID))
+	b.WriteString("  rankdir=LR;\n")
+	for _, n := range t.Nodes {
+		label := n.Kind
+		if n.Name != "" {
+			label += "\n" + n.Name
+		}
+		label += "\n" + n.ID
+		if ip := n.Attributes["ip_address"]; ip != "" {
+			label += "\n" + ip
+		}
+		if subnet := n.Attributes["subnet"]; subnet != "" {
+			label += "\n" + subnet
+		}
+
+		shape := topologyShapes[n.Kind]
+		if shape == "" {
+			shape = "ellipse"
+		}
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s];\n", dotQuote(n.ID), dotQuote(label), shape)
+	}
+	for _, e := range t.Edges {
+		style := ""
+		if e.Relation != TopologyRelationContains {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Relation), style)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// TopologyOptions configures BuildVPCTopology
+type TopologyOptions struct {
+	// Concurrency limits the number of concurrent API requests, defaulting to 4
+	Concurrency int
+}
+
+// BuildVPCTopology walks the VPC with given ID through its routers, networks, NICs, IP addresses,
+// instances, floating IPs, load balancers, VIPs, VPN services and VPN gateways, returning a graph
+// of the resources and their relations. Requests are made concurrently, limited by
+// opts.Concurrency. Nodes and edges are sorted for stable output
+func BuildVPCTopology(ctx context.Context, svc ECloudService, vpcID string, opts TopologyOptions) (*Topology, error) {
+	if vpcID == "" {
+		return nil, fmt.Errorf("invalid vpc id")
+	}
+
+	vpc, err := svc.GetVPC(vpcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve vpc [%s]: %w", vpcID, err)
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultTopologyConcurrency
+	}
+	w := &topologyWalker{
+		ctx:   ctx,
+		svc:   svc,
+		sem:   make(chan struct{}, concurrency),
+		nodes: make(map[string]TopologyNode),
+	}
+
+	w.addNode(TopologyNode{ID: vpc.ID, Kind: ResourceKindVPC, Name: vpc.Name, Attributes: map[string]string{"region_id": vpc.RegionID}})
+	w.walkVPC(vpc.ID)
+	w.wg.Wait()
+
+	if len(w.errs) > 0 {
+		return nil, errors.Join(w.errs...)
+	}
+	return w.topology(vpc.ID), nil
+}
+
+type topologyWalker struct {
+	ctx context.Context
+	svc ECloudService
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mutex sync.Mutex
+	nodes map[string]TopologyNode
+	edges []TopologyEdge
+	errs  []error
+}
+
+// spawn runs fn in a goroutine once a concurrency slot is available
+func (w *topologyWalker) spawn(fn func() error) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		select {
+		case w.sem <- struct{}{}:
+		case <-w.ctx.Done():
+			w.fail(w.ctx.Err())
+			return
+		}
+		if w.ctx.Err() != nil {
+			<-w.sem
+			w.fail(w.ctx.Err())
+			return
+		}
+		err := fn()
+		<-w.sem
+
+		if err != nil {
+			w.fail(err)
+		}
+	}()
+}
+
+func (w *topologyWalker) fail(err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, e := range w.errs {
+		if e == err {
+			return
+		}
+	}
+	w.errs = append(w.errs, err)
+}
+
+func (w *topologyWalker) addNode(n TopologyNode) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.nodes[n.ID] = n
+}
+
+func (w *topologyWalker) addEdge(from, to, relation string) {
+	if from == "" || to == "" {
+		return
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.edges = append(w.edges, TopologyEdge{From: from, To: to, Relation: relation})
+}
+
+func (w *topologyWalker) walkVPC(vpcID string) {
+	vpcFilter := eqFilter("vpc_id", vpcID)
+
+	w.spawn(func() error {
+		routers, err := w.svc.GetRouters(vpcFilter)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve routers: %w", err)
+		}
+		for _, router := range routers {
+			w.addNode(TopologyNode{ID: router.ID, Kind: ResourceKindRouter, Name: router.Name})
+			w.addEdge(vpcID, router.ID, TopologyRelationContains)
+			w.walkRouter(router.ID)
+		}
+		return nil
+	})
+
+	w.spawn(func() error {
+		instances, err := w.svc.GetVPCInstances(vpcID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve instances: %w", err)
+		}
+		for _, instance := range instances {
+			w.addNode(TopologyNode{ID: instance.ID, Kind: ResourceKindInstance, Name: instance.Name, Attributes: map[string]string{
+				"image_id": instance.ImageID,
+				"platform": instance.Platform,
+			}})
+			w.addEdge(vpcID, instance.ID, TopologyRelationContains)
+		}
+		return nil
+	})
+
+	w.spawn(func() error {
+		fips, err := w.svc.GetFloatingIPs(vpcFilter)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve floating IPs: %w", err)
+		}
+		for _, fip := range fips {
+			w.addNode(TopologyNode{ID: fip.ID, Kind: ResourceKindFloatingIP, Name: fip.Name, Attributes: map[string]string{"ip_address": fip.IPAddress}})
+			w.addEdge(vpcID, fip.ID, TopologyRelationContains)
+			w.addEdge(fip.ID, fip.ResourceID, TopologyRelationAssigned)
+		}
+		return nil
+	})
+
+	w.spawn(func() error {
+		lbs, err := w.svc.GetLoadBalancers(vpcFilter)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve load balancers: %w", err)
+		}
+		for _, lb := range lbs {
+			w.addNode(TopologyNode{ID: lb.ID, Kind: ResourceKindLoadBalancer, Name: lb.Name})
+			w.addEdge(vpcID, lb.ID, TopologyRelationContains)
+			w.addEdge(lb.ID, lb.NetworkID, TopologyRelationAttached)
+			w.walkLoadBalancer(lb.ID)
+		}
+		return nil
+	})
+
+	w.spawn(func() error {
+		services, err := w.svc.GetVPNServices(vpcFilter)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve vpn services: %w", err)
+		}
+		for _, service := range services {
+			w.addNode(TopologyNode{ID: service.ID, Kind: ResourceKindVPNService, Name: service.Name})
+			w.addEdge(service.RouterID, service.ID, TopologyRelationContains)
+		}
+		return nil
+	})
+}
+
+func (w *topologyWalker) walkRouter(routerID string) {
+	w.spawn(func() error {
+		networks, err := w.svc.GetRouterNetworks(routerID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve networks for router [%s]: %w", routerID, err)
+		}
+		for _, network := range networks {
+			w.addNode(TopologyNode{ID: network.ID, Kind: ResourceKindNetwork, Name: network.Name, Attributes: map[string]string{"subnet": network.Subnet}})
+			w.addEdge(routerID, network.ID, TopologyRelationContains)
+			w.walkNetwork(network.ID)
+		}
+		return nil
+	})
+
+	w.spawn(func() error {
+		gateways, err := w.svc.GetVPNGateways(eqFilter("router_id", routerID))
+		if err != nil {
+			return fmt.Errorf("failed to retrieve vpn gateways for router [%s]: %w", routerID, err)
+		}
+		for _, gateway := range gateways {
+			w.addNode(TopologyNode{ID: gateway.ID, Kind: ResourceKindVPNGateway, Name: gateway.Name, Attributes: map[string]string{"fqdn": gateway.FQDN}})
+			w.addEdge(routerID, gateway.ID, TopologyRelationContains)
+		}
+		return nil
+	})
+}
+
+func (w *topologyWalker) walkNetwork(networkID string) {
+	w.spawn(func() error {
+		nics, err := w.svc.GetNetworkNICs(networkID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve NICs for network [%s]: %w", networkID, err)
+		}
+		for _, nic := range nics {
+			w.addNode(TopologyNode{ID: nic.ID, Kind: ResourceKindNIC, Name: nic.Name, Attributes: map[string]string{
+				"ip_address":  nic.IPAddress,
+				"mac_address": nic.MACAddress,
+			}})
+			w.addEdge(networkID, nic.ID, TopologyRelationContains)
+			w.addEdge(nic.ID, nic.InstanceID, TopologyRelationAttached)
+			w.walkNIC(nic.ID)
+		}
+		return nil
+	})
+
+	w.spawn(func() error {
+		ips, err := w.svc.GetIPAddresses(eqFilter("network_id", networkID))
+		if err != nil {
+			return fmt.Errorf("failed to retrieve IP addresses for network [%s]: %w", networkID, err)
+		}
+		for _, ip := range ips {
+			w.addNode(TopologyNode{ID: ip.ID, Kind: ResourceKindIPAddress, Name: ip.Name, Attributes: map[string]string{
+				"ip_address": ip.IPAddress.String(),
+				"type":       ip.Type,
+			}})
+			w.addEdge(networkID, ip.ID, TopologyRelationContains)
+		}
+		return nil
+	})
+}
+
+func (w *topologyWalker) walkNIC(nicID string) {
+	w.spawn(func() error {
+		ips, err := w.svc.GetNICIPAddresses(nicID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve IP addresses for NIC [%s]: %w", nicID, err)
+		}
+		for _, ip := range ips {
+			w.addEdge(ip.ID, nicID, TopologyRelationAssigned)
+		}
+		return nil
+	})
+}
+
+func (w *topologyWalker) walkLoadBalancer(lbID string) {
+	w.spawn(func() error {
+		vips, err := w.svc.GetVIPs(eqFilter("load_balancer_id", lbID))
+		if err != nil {
+			return fmt.Errorf("failed to retrieve vips for load balancer [%s]: %w", lbID, err)
+		}
+		for _, vip := range vips {
+			w.addNode(TopologyNode{ID: vip.ID, Kind: ResourceKindVIP, Name: vip.Name})
+			w.addEdge(lbID, vip.ID, TopologyRelationContains)
+			w.addEdge(vip.IPAddressID, vip.ID, TopologyRelationAssigned)
+		}
+		return nil
+	})
+}
+
+var topologyKindOrder = []string{
+	ResourceKindVPC,
+	ResourceKindRouter,
+	ResourceKindVPNGateway,
+	ResourceKindVPNService,
+	ResourceKindNetwork,
+	ResourceKindLoadBalancer,
+	ResourceKindVIP,
+	ResourceKindIPAddress,
+	ResourceKindNIC,
+	ResourceKindInstance,
+	ResourceKindFloatingIP,
+}
+
+// topology builds the sorted topology, dropping edges referencing resources which weren't found
+// (e.g. a floating IP assigned outside of the VPC)
+func (w *topologyWalker) topology(vpcID string) *Topology {
+	rank := make(map[string]int)
+	for i, kind := range topologyKindOrder {
+		rank[kind] = i
+	}
+
+	t := &Topology{VPCID: vpcID}
+	for _, n := range w.nodes {
+		t.Nodes = append(t.Nodes, n)
+	}
+	sort.Slice(t.Nodes, func(i, j int) bool {
+		a, b := t.Nodes[i], t.Nodes[j]
+		if rank[a.Kind] != rank[b.Kind] {
+			return rank[a.Kind] < rank[b.Kind]
+		}
+		return a.ID < b.ID
+	})
+
+	seen := make(map[TopologyEdge]bool)
+	for _, e := range w.edges {
+		_, fromOK := w.nodes[e.From]
+		_, toOK := w.nodes[e.To]
+		if !fromOK || !toOK || seen[e] {
+			continue
+		}
+		seen[e] = true
+		t.Edges = append(t.Edges, e)
+	}
+	sort.Slice(t.Edges, func(i, j int) bool {
+		a, b := t.Edges[i], t.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Relation < b.Relation
+	})
+
+	return t
+}
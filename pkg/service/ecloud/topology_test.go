@@ -0,0 +1,185 @@
+package ecloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+func (f *fakeVPCService) GetVPNGateways(parameters connection.APIRequestParameters) ([]VPNGateway, error) {
+	var gateways []VPNGateway
+	for _, g := range f.vpnGateways {
+		if g.RouterID == filterValue(parameters, "router_id") {
+			gateways = append(gateways, g)
+		}
+	}
+	return gateways, nil
+}
+
+func (f *fakeVPCService) GetIPAddresses(parameters connection.APIRequestParameters) ([]IPAddress, error) {
+	var ips []IPAddress
+	for _, ip := range f.ipAddresses {
+		if ip.NetworkID == filterValue(parameters, "network_id") {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+func (f *fakeVPCService) GetNICIPAddresses(nicID string, parameters connection.APIRequestParameters) ([]IPAddress, error) {
+	return f.nicIPAddresses[nicID], nil
+}
+
+// concurrencyTrackingService records the maximum number of concurrent GetRouterNetworks calls
+type concurrencyTrackingService struct {
+	*fakeVPCService
+	current int32
+	max     int32
+}
+
+func (s *concurrencyTrackingService) GetRouterNetworks(routerID string, parameters connection.APIRequestParameters) ([]Network, error) {
+	n := atomic.AddInt32(&s.current, 1)
+	defer atomic.AddInt32(&s.current, -1)
+	for {
+		m := atomic.LoadInt32(&s.max)
+		if n <= m || atomic.CompareAndSwapInt32(&s.max, m, n) {
+			break
+		}
+	}
+	return s.fakeVPCService.GetRouterNetworks(routerID, parameters)
+}
+
+func newTopologyService() *fakeVPCService {
+	svc := newFakeVPCService()
+	svc.vpc.RegionID = "reg-abcdef12"
+	svc.networks[0].Subnet = "10.0.0.0/24"
+	svc.fips[0].IPAddress = "1.2.3.4"
+	svc.vips[0].IPAddressID = "ip-abcdef12"
+	svc.vpnGateways = []VPNGateway{{ID: "vpng-abcdef12", RouterID: "rtr-abcdef12"}}
+	svc.ipAddresses = []IPAddress{{ID: "ip-abcdef12", NetworkID: "net-abcdef12", IPAddress: "10.0.0.10"}}
+	svc.nicIPAddresses = map[string][]IPAddress{"nic-abcdef12": {{ID: "ip-abcdef12"}}}
+	return svc
+}
+
+func TestBuildVPCTopology(t *testing.T) {
+	t.Run("BuildsGraph", func(t *testing.T) {
+		topology, err := BuildVPCTopology(context.Background(), newTopologyService(), "vpc-abcdef12", TopologyOptions{})
+
+		assert.Nil(t, err)
+
+		var ids []string
+		for _, n := range topology.Nodes {
+			ids = append(ids, n.ID)
+		}
+		assert.Equal(t, []string{
+			"vpc-abcdef12",
+			"rtr-abcdef12",
+			"vpng-abcdef12",
+			"vpn-abcdef12",
+			"net-abcdef12",
+			"lb-abcdef12",
+			"vip-abcdef12",
+			"ip-abcdef12",
+			"nic-abcdef12",
+			"i-abcdef12",
+			"fip-abcdef12",
+			"fip-abcdef34",
+		}, ids)
+
+		assert.Contains(t, topology.Edges, TopologyEdge{From: "nic-abcdef12", To: "i-abcdef12", Relation: TopologyRelationAttached})
+		assert.Contains(t, topology.Edges, TopologyEdge{From: "fip-abcdef12", To: "nic-abcdef12", Relation: TopologyRelationAssigned})
+		assert.Contains(t, topology.Edges, TopologyEdge{From: "ip-abcdef12", To: "vip-abcdef12", Relation: TopologyRelationAssigned})
+		assert.Contains(t, topology.Edges, TopologyEdge{From: "ip-abcdef12", To: "nic-abcdef12", Relation: TopologyRelationAssigned})
+		assert.Contains(t, topology.Edges, TopologyEdge{From: "rtr-abcdef12", To: "vpn-abcdef12", Relation: TopologyRelationContains})
+		assert.Contains(t, topology.Edges, TopologyEdge{From: "lb-abcdef12", To: "net-abcdef12", Relation: TopologyRelationAttached})
+
+		network, ok := topology.Node("net-abcdef12")
+		assert.True(t, ok)
+		assert.Equal(t, "10.0.0.0/24", network.Attributes["subnet"])
+	})
+
+	t.Run("DropsEdgesToUnknownResources", func(t *testing.T) {
+		svc := newTopologyService()
+		svc.fips[1].ResourceID = "nic-outside"
+
+		topology, err := BuildVPCTopology(context.Background(), svc, "vpc-abcdef12", TopologyOptions{})
+
+		assert.Nil(t, err)
+		for _, e := range topology.Edges {
+			assert.NotEqual(t, "nic-outside", e.To)
+		}
+	})
+
+	t.Run("LimitsConcurrency", func(t *testing.T) {
+		svc := newTopologyService()
+		for _, id := range []string{"rtr-abcdef34", "rtr-abcdef56", "rtr-abcdef78"} {
+			svc.routers = append(svc.routers, Router{ID: id})
+		}
+		tracking := &concurrencyTrackingService{fakeVPCService: svc}
+
+		_, err := BuildVPCTopology(context.Background(), tracking, "vpc-abcdef12", TopologyOptions{Concurrency: 1})
+
+		assert.Nil(t, err)
+		assert.Equal(t, int32(1), tracking.max)
+	})
+
+	t.Run("CancelledContext_ReturnsError", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := BuildVPCTopology(ctx, newTopologyService(), "vpc-abcdef12", TopologyOptions{})
+
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+
+	t.Run("GetVPCError_ReturnsError", func(t *testing.T) {
+		_, err := BuildVPCTopology(context.Background(), newTopologyService(), "vpc-unknown", TopologyOptions{})
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestTopology_WriteDOT(t *testing.T) {
+	topology := &Topology{
+		VPCID: "vpc-abcdef12",
+		Nodes: []TopologyNode{
+			{ID: "vpc-abcdef12", Kind: ResourceKindVPC, Name: "prod"},
+			{ID: "net-abcdef12", Kind: ResourceKindNetwork, Name: `web "dmz"`, Attributes: map[string]string{"subnet": "10.0.0.0/24"}},
+		},
+		Edges: []TopologyEdge{{From: "vpc-abcdef12", To: "net-abcdef12", Relation: TopologyRelationContains}},
+	}
+	buf := new(bytes.Buffer)
+
+	err := topology.WriteDOT(buf)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `digraph "vpc-abcdef12" {
+  rankdir=LR;
+  "vpc-abcdef12" [label="vpc\nprod\nvpc-abcdef12", shape=doubleoctagon];
+  "net-abcdef12" [label="network\nweb \"dmz\"\nnet-abcdef12\n10.0.0.0/24", shape=box3d];
+  "vpc-abcdef12" -> "net-abcdef12" [label="contains"];
+}
+`, buf.String())
+}
+
+func TestTopology_WriteJSON(t *testing.T) {
+	topology := &Topology{
+		VPCID: "vpc-abcdef12",
+		Nodes: []TopologyNode{{ID: "vpc-abcdef12", Kind: ResourceKindVPC, Name: "prod"}},
+	}
+	buf := new(bytes.Buffer)
+
+	err := topology.WriteJSON(buf)
+
+	assert.Nil(t, err)
+	var decoded Topology
+	assert.Nil(t, json.NewDecoder(strings.NewReader(buf.String())).Decode(&decoded))
+	assert.Equal(t, *topology, decoded)
+}
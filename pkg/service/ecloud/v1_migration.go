@@ -0,0 +1,489 @@
+package ecloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+)
+
+// V1Inventory is a snapshot of eCloud v1 solutions and the resources within them, as retrieved by
+// GetV1Inventory
+type V1Inventory struct {
+	Solutions []V1SolutionInventory `json:"solutions"`
+}
+
+// V1SolutionInventory is a v1 solution along with its sites, hosts, networks, templates, tags and
+// virtual machines
+type V1SolutionInventory struct {
+	Solution        Solution                    `json:"solution"`
+	Sites           []Site                      `json:"sites"`
+	Hosts           []V1Host                    `json:"hosts"`
+	Networks        []V1Network                 `json:"networks"`
+	Templates       []Template                  `json:"templates"`
+	Tags            []TagV1                     `json:"tags"`
+	VirtualMachines []V1VirtualMachineInventory `json:"virtual_machines"`
+}
+
+// V1VirtualMachineInventory is a v1 virtual machine along with its tags
+type V1VirtualMachineInventory struct {
+	VirtualMachine VirtualMachine `json:"virtual_machine"`
+	Tags           []TagV1        `json:"tags"`
+}
+
+// GetV1Inventory retrieves the solutions with IDs solutionIDs, or all solutions if none are given,
+// along with the resources within each
+func GetV1Inventory(svc ECloudService, solutionIDs []int) (V1Inventory, error) {
+	var inventory V1Inventory
+
+	var solutions []Solution
+	if len(solutionIDs) == 0 {
+		all, err := svc.GetSolutions(connection.APIRequestParameters{})
+		if err != nil {
+			return inventory, fmt.Errorf("failed to retrieve solutions: %w", err)
+		}
+		solutions = all
+	}
+	for _, solutionID := range solutionIDs {
+		solution, err := svc.GetSolution(solutionID)
+		if err != nil {
+			return inventory, fmt.Errorf("failed to retrieve solution [%d]: %w", solutionID, err)
+		}
+		solutions = append(solutions, solution)
+	}
+
+	for _, solution := range solutions {
+		solutionInventory, err := getV1SolutionInventory(svc, solution)
+		if err != nil {
+			return inventory, err
+		}
+		inventory.Solutions = append(inventory.Solutions, solutionInventory)
+	}
+	return inventory, nil
+}
+
+func getV1SolutionInventory(svc ECloudService, solution Solution) (V1SolutionInventory, error) {
+	var err error
+	inventory := V1SolutionInventory{Solution: solution}
+	params := connection.APIRequestParameters{}
+
+	inventory.Sites, err = svc.GetSolutionSites(solution.ID, params)
+	if err != nil {
+		return inventory, fmt.Errorf("failed to retrieve sites for solution [%d]: %w", solution.ID, err)
+	}
+	inventory.Hosts, err = svc.GetSolutionHosts(solution.ID, params)
+	if err != nil {
+		return inventory, fmt.Errorf("failed to retrieve hosts for solution [%d]: %w", solution.ID, err)
+	}
+	inventory.Networks, err = svc.GetSolutionNetworks(solution.ID, params)
+	if err != nil {
+		return inventory, fmt.Errorf("failed to retrieve networks for solution [%d]: %w", solution.ID, err)
+	}
+	inventory.Templates, err = svc.GetSolutionTemplates(solution.ID, params)
+	if err != nil {
+		return inventory, fmt.Errorf("failed to retrieve templates for solution [%d]: %w", solution.ID, err)
+	}
+	inventory.Tags, err = svc.GetSolutionTags(solution.ID, params)
+	if err != nil {
+		return inventory, fmt.Errorf("failed to retrieve tags for solution [%d]: %w", solution.ID, err)
+	}
+
+	vms, err := svc.GetSolutionVirtualMachines(solution.ID, params)
+	if err != nil {
+		return inventory, fmt.Errorf("failed to retrieve virtual machines for solution [%d]: %w", solution.ID, err)
+	}
+	for _, vm := range vms {
+		tags, err := svc.GetVirtualMachineTags(vm.ID, params)
+		if err != nil {
+			return inventory, fmt.Errorf("failed to retrieve tags for virtual machine [%d]: %w", vm.ID, err)
+		}
+		inventory.VirtualMachines = append(inventory.VirtualMachines, V1VirtualMachineInventory{VirtualMachine: vm, Tags: tags})
+	}
+	return inventory, nil
+}
+
+// ReadV1Inventory reads an inventory previously written as JSON, allowing migrations to be planned
+// offline
+func ReadV1Inventory(r io.Reader) (V1Inventory, error) {
+	var inventory V1Inventory
+	err := json.NewDecoder(r).Decode(&inventory)
+	if err != nil {
+		return inventory, fmt.Errorf("failed to decode v1 inventory: %w", err)
+	}
+	return inventory, nil
+}
+
+// V1MigrationOptions configures PlanV1Migration and BuildV1MigrationPlan
+type V1MigrationOptions struct {
+	// SolutionIDs restricts the plan to the given solutions, defaulting to all solutions
+	SolutionIDs []int
+	// RegionID is the region of the planned VPCs. Required
+	RegionID string
+	// AvailabilityZoneID is the availability zone of the planned instances and volumes. Required
+	AvailabilityZoneID string
+	// ResourceTierID is the resource tier of the planned instances, defaulting to the first resource
+	// tier in the availability zone
+	ResourceTierID string
+}
+
+// Validate checks the options required for planning are set
+func (o V1MigrationOptions) Validate() error {
+	if o.RegionID == "" {
+		return fmt.Errorf("region id is required")
+	}
+	if o.AvailabilityZoneID == "" {
+		return fmt.Errorf("availability zone id is required")
+	}
+	return nil
+}
+
+// V1MigrationPlan maps v1 solutions and virtual machines to the v2 resources replacing them. The
+// plan is intended for review rather than direct application: the VPC ID of planned requests is left
+// empty as the VPC is yet to be created, and features without a v2 equivalent are listed as
+// unmappable
+type V1MigrationPlan struct {
+	RegionID           string                `json:"region_id"`
+	AvailabilityZoneID string                `json:"availability_zone_id"`
+	ResourceTierID     string                `json:"resource_tier_id,omitempty"`
+	Solutions          []V1SolutionMigration `json:"solutions"`
+}
+
+// V1SolutionMigration maps a v1 solution to a VPC
+type V1SolutionMigration struct {
+	SolutionID   int              `json:"solution_id"`
+	SolutionName string           `json:"solution_name"`
+	VPC          CreateVPCRequest `json:"vpc"`
+	// Networks are the v1 networks of the solution, which are to be recreated within the VPC
+	Networks        []V1Network                 `json:"networks,omitempty"`
+	VirtualMachines []V1VirtualMachineMigration `json:"virtual_machines"`
+	Unmappable      []string                    `json:"unmappable,omitempty"`
+}
+
+// V1VirtualMachineMigration maps a v1 virtual machine to an instance, with a volume for each disk
+// beyond the first
+type V1VirtualMachineMigration struct {
+	VirtualMachineID int    `json:"virtual_machine_id"`
+	Name             string `json:"name"`
+	Template         string `json:"template"`
+	// ImageName is the name of the closest image to the template, for review
+	ImageName  string                `json:"image_name,omitempty"`
+	IPInternal connection.IPAddress  `json:"ip_internal,omitempty"`
+	Tags       []TagV1               `json:"tags,omitempty"`
+	Instance   CreateInstanceRequest `json:"instance"`
+	Volumes    []CreateVolumeRequest `json:"volumes,omitempty"`
+	Unmappable []string              `json:"unmappable,omitempty"`
+}
+
+// Unmappable returns the unmappable features of every solution and virtual machine, each prefixed
+// with the resource it applies to
+func (p *V1MigrationPlan) Unmappable() []string {
+	var unmappable []string
+	for _, solution := range p.Solutions {
+		for _, detail := range solution.Unmappable {
+			unmappable = append(unmappable, fmt.Sprintf("solution [%d] (%s): %s", solution.SolutionID, solution.SolutionName, detail))
+		}
+		for _, vm := range solution.VirtualMachines {
+			for _, detail := range vm.Unmappable {
+				unmappable = append(unmappable, fmt.Sprintf("virtual machine [%d] (%s): %s", vm.VirtualMachineID, vm.Name, detail))
+			}
+		}
+	}
+	return unmappable
+}
+
+// Write writes a human-readable summary of the plan, marking planned resources with + and
+// unmappable features with !
+func (p *V1MigrationPlan) Write(w io.Writer) error {
+	vms := 0
+	for _, solution := range p.Solutions {
+		var lines []string
+		lines = append(lines, fmt.Sprintf("solution [%d] (%s): vpc [%s] in region [%s]", solution.SolutionID, solution.SolutionName, solution.VPC.Name, solution.VPC.RegionID))
+		for _, vm := range solution.VirtualMachines {
+			vms++
+			image := "no image"
+			if vm.Instance.ImageID != "" {
+				image = fmt.Sprintf("image [%s] (%s)", vm.Instance.ImageID, vm.ImageName)
+			}
+			lines = append(lines, fmt.Sprintf("  + instance [%s] from virtual machine [%d]: %d vcpu, %dMB ram, %dGB volume, %s",
+				vm.Instance.Name, vm.VirtualMachineID, vm.Instance.VCPUCores, vm.Instance.RAMCapacity, vm.Instance.VolumeCapacity, image))
+			for _, volume := range vm.Volumes {
+				lines = append(lines, fmt.Sprintf("  + volume [%s]: %dGB", volume.Name, volume.Capacity))
+			}
+		}
+		for _, detail := range solution.Unmappable {
+			lines = append(lines, "  ! "+detail)
+		}
+		for _, vm := range solution.VirtualMachines {
+			for _, detail := range vm.Unmappable {
+				lines = append(lines, fmt.Sprintf("  ! virtual machine [%d] (%s): %s", vm.VirtualMachineID, vm.Name, detail))
+			}
+		}
+
+		_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d solutions, %d virtual machines, %d unmappable\n", len(p.Solutions), vms, len(p.Unmappable()))
+	return err
+}
+
+// WriteJSON writes the plan as indented JSON
+func (p *V1MigrationPlan) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// PlanV1Migration retrieves the v1 inventory, images and resource tiers, and builds a migration plan
+// with BuildV1MigrationPlan
+func PlanV1Migration(svc ECloudService, opts V1MigrationOptions) (*V1MigrationPlan, error) {
+	err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	inventory, err := GetV1Inventory(svc, opts.SolutionIDs)
+	if err != nil {
+		return nil, err
+	}
+	images, err := svc.GetImages(connection.APIRequestParameters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve images: %w", err)
+	}
+	tiers, err := svc.GetResourceTiers(eqFilter("availability_zone_id", opts.AvailabilityZoneID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve resource tiers for availability zone [%s]: %w", opts.AvailabilityZoneID, err)
+	}
+
+	return BuildV1MigrationPlan(inventory, images, tiers, opts)
+}
+
+// BuildV1MigrationPlan maps each solution in inventory to a VPC, and each virtual machine to an
+// instance with the same vCPU and RAM. The first non-cluster disk of a virtual machine, in key
+// order, becomes the instance volume and other disks become volumes. The image is the one whose name
+// most closely matches the virtual machine template, or the operating system of a solution template,
+// on the same platform
+func BuildV1MigrationPlan(inventory V1Inventory, images []Image, tiers []ResourceTier, opts V1MigrationOptions) (*V1MigrationPlan, error) {
+	err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &V1MigrationPlan{
+		RegionID:           opts.RegionID,
+		AvailabilityZoneID: opts.AvailabilityZoneID,
+		ResourceTierID:     opts.ResourceTierID,
+	}
+	if plan.ResourceTierID == "" {
+		sortedTiers := append([]ResourceTier{}, tiers...)
+		sort.Slice(sortedTiers, func(i, j int) bool { return sortedTiers[i].ID < sortedTiers[j].ID })
+		for _, tier := range sortedTiers {
+			if tier.AvailabilityZoneID == opts.AvailabilityZoneID {
+				plan.ResourceTierID = tier.ID
+				break
+			}
+		}
+	}
+
+	sortedImages := append([]Image{}, images...)
+	sort.Slice(sortedImages, func(i, j int) bool {
+		if sortedImages[i].Name != sortedImages[j].Name {
+			return sortedImages[i].Name < sortedImages[j].Name
+		}
+		return sortedImages[i].ID < sortedImages[j].ID
+	})
+
+	for _, solution := range inventory.Solutions {
+		plan.Solutions = append(plan.Solutions, planV1SolutionMigration(solution, sortedImages, plan))
+	}
+	return plan, nil
+}
+
+func planV1SolutionMigration(inventory V1SolutionInventory, images []Image, plan *V1MigrationPlan) V1SolutionMigration {
+	solution := inventory.Solution
+	name := solution.Name
+	if name == "" {
+		name = fmt.Sprintf("solution-%d", solution.ID)
+	}
+
+	migration := V1SolutionMigration{
+		SolutionID:   solution.ID,
+		SolutionName: solution.Name,
+		VPC:          CreateVPCRequest{Name: name, RegionID: plan.RegionID},
+		Networks:     inventory.Networks,
+	}
+
+	if len(inventory.Networks) > 0 {
+		var names []string
+		for _, network := range inventory.Networks {
+			names = append(names, network.Name)
+		}
+		migration.Unmappable = append(migration.Unmappable,
+			fmt.Sprintf("v1 networks [%s] must be recreated as networks within the vpc, and network_id set on each instance", strings.Join(names, ", ")))
+	}
+	if len(inventory.Sites) > 1 {
+		migration.Unmappable = append(migration.Unmappable,
+			fmt.Sprintf("solution spans %d sites, all instances are planned in availability zone [%s]", len(inventory.Sites), plan.AvailabilityZoneID))
+	}
+	if solution.Environment == SolutionEnvironmentPrivate {
+		migration.Unmappable = append(migration.Unmappable,
+			fmt.Sprintf("private solution runs on %d dedicated hosts, a host group is required for dedicated placement", len(inventory.Hosts)))
+	}
+	if len(inventory.Tags) > 0 {
+		migration.Unmappable = append(migration.Unmappable,
+			fmt.Sprintf("tags [%s] must be recreated as tags", formatV1Tags(inventory.Tags)))
+	}
+
+	templates := make(map[string]Template)
+	for _, template := range inventory.Templates {
+		templates[template.Name] = template
+	}
+	for _, vm := range inventory.VirtualMachines {
+		migration.VirtualMachines = append(migration.VirtualMachines, planV1VirtualMachineMigration(vm, templates, images, plan))
+	}
+	return migration
+}
+
+func planV1VirtualMachineMigration(inventory V1VirtualMachineInventory, templates map[string]Template, images []Image, plan *V1MigrationPlan) V1VirtualMachineMigration {
+	vm := inventory.VirtualMachine
+	name := vm.Name
+	if name == "" {
+		name = vm.Hostname
+	}
+	if name == "" {
+		name = fmt.Sprintf("vm-%d", vm.ID)
+	}
+
+	migration := V1VirtualMachineMigration{
+		VirtualMachineID: vm.ID,
+		Name:             name,
+		Template:         vm.Template,
+		IPInternal:       vm.IPInternal,
+		Tags:             inventory.Tags,
+		Instance: CreateInstanceRequest{
+			Name:               name,
+			VCPUCores:          vm.CPU,
+			RAMCapacity:        vm.RAM * 1024,
+			VolumeCapacity:     vm.HDD,
+			BackupEnabled:      vm.Backup,
+			IsEncrypted:        vm.Encrypted,
+			RequiresFloatingIP: vm.IPExternal != "",
+			ResourceTierID:     plan.ResourceTierID,
+		},
+	}
+
+	if vm.Status != "" && vm.Status != VirtualMachineStatusComplete {
+		migration.Unmappable = append(migration.Unmappable, fmt.Sprintf("virtual machine status is [%s]", vm.Status))
+	}
+
+	description := vm.Template
+	if template, ok := templates[vm.Template]; ok && vm.Template != "" {
+		if template.OperatingSystem != "" {
+			description = template.OperatingSystem
+		}
+		migration.Unmappable = append(migration.Unmappable,
+			fmt.Sprintf("solution template [%s] must be imported as an image, planned with the closest image to [%s]", vm.Template, description))
+	}
+	if description == "" {
+		migration.Unmappable = append(migration.Unmappable, "no template recorded, image must be chosen manually")
+	} else if image, ok := closestImage(images, vm.Platform, description); ok {
+		migration.Instance.ImageID = image.ID
+		migration.ImageName = image.Name
+	} else {
+		migration.Unmappable = append(migration.Unmappable, fmt.Sprintf("no %s image matches template [%s]", vm.Platform, description))
+	}
+
+	disks := append([]VirtualMachineDisk{}, vm.Disks...)
+	sort.SliceStable(disks, func(i, j int) bool { return disks[i].Key < disks[j].Key })
+	// The first disk which isn't shared with other virtual machines becomes the boot volume
+	boot := -1
+	for i, disk := range disks {
+		if disk.Type != VirtualMachineDiskTypeCluster {
+			boot = i
+			break
+		}
+	}
+	if boot == -1 && len(disks) > 0 {
+		migration.Unmappable = append(migration.Unmappable, "all disks are cluster disks, volume capacity must be chosen manually")
+	}
+	for i, disk := range disks {
+		if i == boot {
+			migration.Instance.VolumeCapacity = disk.Capacity
+			continue
+		}
+		if disk.Type == VirtualMachineDiskTypeCluster {
+			migration.Unmappable = append(migration.Unmappable,
+				fmt.Sprintf("cluster disk [%s] is planned as a shared volume, which must be attached to each instance sharing it", disk.Name))
+		}
+		migration.Volumes = append(migration.Volumes, CreateVolumeRequest{
+			Name:               fmt.Sprintf("%s-disk-%d", name, i+1),
+			Capacity:           disk.Capacity,
+			AvailabilityZoneID: plan.AvailabilityZoneID,
+			IsShared:           disk.Type == VirtualMachineDiskTypeCluster,
+		})
+	}
+
+	if vm.GPUProfile != "" {
+		migration.Unmappable = append(migration.Unmappable, fmt.Sprintf("gpu profile [%s] has no equivalent", vm.GPUProfile))
+	}
+	if len(inventory.Tags) > 0 {
+		migration.Unmappable = append(migration.Unmappable,
+			fmt.Sprintf("tags [%s] must be recreated as tags and assigned with tag_ids", formatV1Tags(inventory.Tags)))
+	}
+	return migration
+}
+
+// closestImage returns the image on platform whose name shares the largest proportion of words with
+// description. images are expected to be sorted, with the first of equally close images returned
+func closestImage(images []Image, platform string, description string) (Image, bool) {
+	want := imageNameWords(description)
+
+	var best Image
+	bestScore := 0.0
+	for _, image := range images {
+		if platform != "" && image.Platform != "" && !strings.EqualFold(platform, image.Platform) {
+			continue
+		}
+		words := imageNameWords(image.Name)
+		common := 0
+		for word := range words {
+			if want[word] {
+				common++
+			}
+		}
+		union := len(want) + len(words) - common
+		if union == 0 {
+			continue
+		}
+		score := float64(common) / float64(union)
+		if score > bestScore {
+			best = image
+			bestScore = score
+		}
+	}
+	return best, bestScore > 0
+}
+
+func imageNameWords(name string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[word] = true
+	}
+	return words
+}
+
+func formatV1Tags(tags []TagV1) string {
+	var pairs []string
+	for _, tag := range tags {
+		pairs = append(pairs, tag.Key+"="+tag.Value)
+	}
+	return strings.Join(pairs, ", ")
+}
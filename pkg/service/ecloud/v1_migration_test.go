@@ -0,0 +1,322 @@
+package ecloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+func testV1Inventory() V1Inventory {
+	return V1Inventory{
+		Solutions: []V1SolutionInventory{
+			{
+				Solution:  Solution{ID: 123, Name: "web", Environment: SolutionEnvironmentHybrid},
+				Sites:     []Site{{ID: 1, SolutionID: 123}},
+				Networks:  []V1Network{{ID: 10, Name: "internal"}},
+				Templates: []Template{{Name: "web-golden", OperatingSystem: "Ubuntu 22.04"}},
+				VirtualMachines: []V1VirtualMachineInventory{
+					{
+						VirtualMachine: VirtualMachine{
+							ID: 456, Name: "web-01", CPU: 2, RAM: 4, HDD: 140, Platform: "Linux", Template: "CentOS 7 64-bit",
+							IPInternal: "10.0.0.5", IPExternal: "203.0.113.5", Backup: true, Status: VirtualMachineStatusComplete,
+							Disks: []VirtualMachineDisk{
+								{Name: "Hard disk 2", Key: 2001, Capacity: 100, Type: VirtualMachineDiskTypeStandard},
+								{Name: "Hard disk 1", Key: 2000, Capacity: 40, Type: VirtualMachineDiskTypeStandard},
+							},
+						},
+						Tags: []TagV1{{Key: "role", Value: "web"}},
+					},
+					{
+						VirtualMachine: VirtualMachine{ID: 457, Name: "web-02", CPU: 1, RAM: 2, HDD: 20, Platform: "Linux", Template: "web-golden", Status: VirtualMachineStatusComplete},
+					},
+				},
+			},
+			{
+				Solution: Solution{ID: 124, Name: "db", Environment: SolutionEnvironmentPrivate},
+				Sites:    []Site{{ID: 2, SolutionID: 124}, {ID: 3, SolutionID: 124}},
+				Hosts:    []V1Host{{ID: 20}, {ID: 21}},
+				VirtualMachines: []V1VirtualMachineInventory{
+					{
+						VirtualMachine: VirtualMachine{
+							ID: 458, Hostname: "db-01", CPU: 8, RAM: 32, Platform: "Windows", Template: "Windows Server 2019 Datacenter",
+							Status: VirtualMachineStatusFailed, GPUProfile: "grid_t4-4q",
+							Disks: []VirtualMachineDisk{
+								{Name: "Hard disk 1", Key: 2000, Capacity: 80},
+								{Name: "Hard disk 2", Key: 2001, Capacity: 500, Type: VirtualMachineDiskTypeCluster},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func testV1MigrationImages() []Image {
+	return []Image{
+		{ID: "img-00000001", Name: "CentOS 7", Platform: "Linux"},
+		{ID: "img-00000002", Name: "Ubuntu 22.04 LTS", Platform: "Linux"},
+		{ID: "img-00000003", Name: "Rocky Linux 8", Platform: "Linux"},
+		{ID: "img-00000004", Name: "Ubuntu 22.04", Platform: "Linux"},
+		{ID: "img-00000005", Name: "CentOS 7", Platform: "Windows"},
+	}
+}
+
+func testV1MigrationOptions() V1MigrationOptions {
+	return V1MigrationOptions{RegionID: "reg-00000001", AvailabilityZoneID: "az-00000001"}
+}
+
+func TestBuildV1MigrationPlan(t *testing.T) {
+	tiers := []ResourceTier{
+		{ID: "rt-00000003", AvailabilityZoneID: "az-00000001"},
+		{ID: "rt-00000001", AvailabilityZoneID: "az-00000002"},
+		{ID: "rt-00000002", AvailabilityZoneID: "az-00000001"},
+	}
+
+	t.Run("MapsResources", func(t *testing.T) {
+		plan, err := BuildV1MigrationPlan(testV1Inventory(), testV1MigrationImages(), tiers, testV1MigrationOptions())
+
+		assert.Nil(t, err)
+		assert.Equal(t, "rt-00000002", plan.ResourceTierID)
+		assert.Len(t, plan.Solutions, 2)
+		assert.Equal(t, CreateVPCRequest{Name: "web", RegionID: "reg-00000001"}, plan.Solutions[0].VPC)
+
+		web := plan.Solutions[0].VirtualMachines[0]
+		assert.Equal(t, CreateInstanceRequest{
+			Name:               "web-01",
+			ImageID:            "img-00000001",
+			VCPUCores:          2,
+			RAMCapacity:        4096,
+			VolumeCapacity:     40,
+			BackupEnabled:      true,
+			RequiresFloatingIP: true,
+			ResourceTierID:     "rt-00000002",
+		}, web.Instance)
+		assert.Equal(t, []CreateVolumeRequest{{Name: "web-01-disk-2", Capacity: 100, AvailabilityZoneID: "az-00000001"}}, web.Volumes)
+		assert.Equal(t, "img-00000004", plan.Solutions[0].VirtualMachines[1].Instance.ImageID)
+
+		db := plan.Solutions[1].VirtualMachines[0]
+		assert.Equal(t, "db-01", db.Name)
+		assert.Equal(t, "", db.Instance.ImageID)
+		assert.Equal(t, []CreateVolumeRequest{{Name: "db-01-disk-2", Capacity: 500, AvailabilityZoneID: "az-00000001", IsShared: true}}, db.Volumes)
+	})
+
+	t.Run("ClusterDiskFirst_NotBootVolume", func(t *testing.T) {
+		inventory := testV1Inventory()
+		inventory.Solutions[1].VirtualMachines[0].VirtualMachine.Disks[1].Key = 1999
+
+		plan, err := BuildV1MigrationPlan(inventory, testV1MigrationImages(), tiers, testV1MigrationOptions())
+
+		assert.Nil(t, err)
+		db := plan.Solutions[1].VirtualMachines[0]
+		assert.Equal(t, 80, db.Instance.VolumeCapacity)
+		assert.Equal(t, []CreateVolumeRequest{{Name: "db-01-disk-1", Capacity: 500, AvailabilityZoneID: "az-00000001", IsShared: true}}, db.Volumes)
+	})
+
+	t.Run("OnlyClusterDisks_NoBootVolume", func(t *testing.T) {
+		inventory := testV1Inventory()
+		inventory.Solutions[1].VirtualMachines[0].VirtualMachine.Disks = inventory.Solutions[1].VirtualMachines[0].VirtualMachine.Disks[1:]
+
+		plan, err := BuildV1MigrationPlan(inventory, testV1MigrationImages(), tiers, testV1MigrationOptions())
+
+		assert.Nil(t, err)
+		db := plan.Solutions[1].VirtualMachines[0]
+		assert.Equal(t, 0, db.Instance.VolumeCapacity)
+		assert.Len(t, db.Volumes, 1)
+		assert.Contains(t, db.Unmappable, "all disks are cluster disks, volume capacity must be chosen manually")
+	})
+
+	t.Run("Write", func(t *testing.T) {
+		plan, err := BuildV1MigrationPlan(testV1Inventory(), testV1MigrationImages(), tiers, testV1MigrationOptions())
+		assert.Nil(t, err)
+
+		buf := new(bytes.Buffer)
+		err = plan.Write(buf)
+
+		assert.Nil(t, err)
+		assert.Equal(t, strings.Join([]string{
+			"solution [123] (web): vpc [web] in region [reg-00000001]",
+			"  + instance [web-01] from virtual machine [456]: 2 vcpu, 4096MB ram, 40GB volume, image [img-00000001] (CentOS 7)",
+			"  + volume [web-01-disk-2]: 100GB",
+			"  + instance [web-02] from virtual machine [457]: 1 vcpu, 2048MB ram, 20GB volume, image [img-00000004] (Ubuntu 22.04)",
+			"  ! v1 networks [internal] must be recreated as networks within the vpc, and network_id set on each instance",
+			"  ! virtual machine [456] (web-01): tags [role=web] must be recreated as tags and assigned with tag_ids",
+			"  ! virtual machine [457] (web-02): solution template [web-golden] must be imported as an image, planned with the closest image to [Ubuntu 22.04]",
+			"solution [124] (db): vpc [db] in region [reg-00000001]",
+			"  + instance [db-01] from virtual machine [458]: 8 vcpu, 32768MB ram, 80GB volume, no image",
+			"  + volume [db-01-disk-2]: 500GB",
+			"  ! solution spans 2 sites, all instances are planned in availability zone [az-00000001]",
+			"  ! private solution runs on 2 dedicated hosts, a host group is required for dedicated placement",
+			"  ! virtual machine [458] (db-01): virtual machine status is [Failed]",
+			"  ! virtual machine [458] (db-01): no Windows image matches template [Windows Server 2019 Datacenter]",
+			"  ! virtual machine [458] (db-01): cluster disk [Hard disk 2] is planned as a shared volume, which must be attached to each instance sharing it",
+			"  ! virtual machine [458] (db-01): gpu profile [grid_t4-4q] has no equivalent",
+			"2 solutions, 3 virtual machines, 9 unmappable",
+			"",
+		}, "\n"), buf.String())
+	})
+
+	t.Run("WriteJSON_RoundTrips", func(t *testing.T) {
+		plan, err := BuildV1MigrationPlan(testV1Inventory(), testV1MigrationImages(), tiers, testV1MigrationOptions())
+		assert.Nil(t, err)
+
+		buf := new(bytes.Buffer)
+		err = plan.WriteJSON(buf)
+
+		assert.Nil(t, err)
+		assert.Contains(t, buf.String(), `"unmappable": [`)
+		assert.Contains(t, buf.String(), `"ram_capacity": 4096`)
+	})
+
+	t.Run("MissingRegion_ReturnsError", func(t *testing.T) {
+		_, err := BuildV1MigrationPlan(testV1Inventory(), nil, nil, V1MigrationOptions{AvailabilityZoneID: "az-00000001"})
+
+		assert.Equal(t, "region id is required", err.Error())
+	})
+}
+
+type fakeV1MigrationService struct {
+	ECloudService
+
+	inventory V1Inventory
+	tiersAZ   string
+	tagsErr   error
+}
+
+func (f *fakeV1MigrationService) solution(solutionID int) V1SolutionInventory {
+	for _, solution := range f.inventory.Solutions {
+		if solution.Solution.ID == solutionID {
+			return solution
+		}
+	}
+	return V1SolutionInventory{}
+}
+
+func (f *fakeV1MigrationService) GetSolutions(parameters connection.APIRequestParameters) ([]Solution, error) {
+	var solutions []Solution
+	for _, solution := range f.inventory.Solutions {
+		solutions = append(solutions, solution.Solution)
+	}
+	return solutions, nil
+}
+
+func (f *fakeV1MigrationService) GetSolution(solutionID int) (Solution, error) {
+	solution := f.solution(solutionID)
+	if solution.Solution.ID == 0 {
+		return Solution{}, &SolutionNotFoundError{ID: solutionID}
+	}
+	return solution.Solution, nil
+}
+
+func (f *fakeV1MigrationService) GetSolutionSites(solutionID int, parameters connection.APIRequestParameters) ([]Site, error) {
+	return f.solution(solutionID).Sites, nil
+}
+
+func (f *fakeV1MigrationService) GetSolutionHosts(solutionID int, parameters connection.APIRequestParameters) ([]V1Host, error) {
+	return f.solution(solutionID).Hosts, nil
+}
+
+func (f *fakeV1MigrationService) GetSolutionNetworks(solutionID int, parameters connection.APIRequestParameters) ([]V1Network, error) {
+	return f.solution(solutionID).Networks, nil
+}
+
+func (f *fakeV1MigrationService) GetSolutionTemplates(solutionID int, parameters connection.APIRequestParameters) ([]Template, error) {
+	return f.solution(solutionID).Templates, nil
+}
+
+func (f *fakeV1MigrationService) GetSolutionTags(solutionID int, parameters connection.APIRequestParameters) ([]TagV1, error) {
+	return f.solution(solutionID).Tags, nil
+}
+
+func (f *fakeV1MigrationService) GetSolutionVirtualMachines(solutionID int, parameters connection.APIRequestParameters) ([]VirtualMachine, error) {
+	var vms []VirtualMachine
+	for _, vm := range f.solution(solutionID).VirtualMachines {
+		vms = append(vms, vm.VirtualMachine)
+	}
+	return vms, nil
+}
+
+func (f *fakeV1MigrationService) GetVirtualMachineTags(vmID int, parameters connection.APIRequestParameters) ([]TagV1, error) {
+	if f.tagsErr != nil {
+		return nil, f.tagsErr
+	}
+	for _, solution := range f.inventory.Solutions {
+		for _, vm := range solution.VirtualMachines {
+			if vm.VirtualMachine.ID == vmID {
+				return vm.Tags, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeV1MigrationService) GetImages(parameters connection.APIRequestParameters) ([]Image, error) {
+	return testV1MigrationImages(), nil
+}
+
+func (f *fakeV1MigrationService) GetResourceTiers(parameters connection.APIRequestParameters) ([]ResourceTier, error) {
+	f.tiersAZ = filterValue(parameters, "availability_zone_id")
+	return []ResourceTier{{ID: "rt-00000001", AvailabilityZoneID: "az-00000001"}}, nil
+}
+
+func TestPlanV1Migration(t *testing.T) {
+	t.Run("AllSolutions", func(t *testing.T) {
+		svc := &fakeV1MigrationService{inventory: testV1Inventory()}
+
+		plan, err := PlanV1Migration(svc, testV1MigrationOptions())
+
+		assert.Nil(t, err)
+		assert.Equal(t, "az-00000001", svc.tiersAZ)
+		assert.Equal(t, "rt-00000001", plan.ResourceTierID)
+		assert.Len(t, plan.Solutions, 2)
+		assert.Len(t, plan.Unmappable(), 9)
+	})
+
+	t.Run("SolutionIDs", func(t *testing.T) {
+		svc := &fakeV1MigrationService{inventory: testV1Inventory()}
+		opts := testV1MigrationOptions()
+		opts.SolutionIDs = []int{124}
+
+		plan, err := PlanV1Migration(svc, opts)
+
+		assert.Nil(t, err)
+		assert.Len(t, plan.Solutions, 1)
+		assert.Equal(t, 124, plan.Solutions[0].SolutionID)
+	})
+
+	t.Run("InventoryRoundTrips", func(t *testing.T) {
+		svc := &fakeV1MigrationService{inventory: testV1Inventory()}
+		inventory, err := GetV1Inventory(svc, nil)
+		assert.Nil(t, err)
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, json.NewEncoder(buf).Encode(inventory))
+		read, err := ReadV1Inventory(buf)
+
+		assert.Nil(t, err)
+		assert.Equal(t, inventory, read)
+	})
+
+	t.Run("UnknownSolution_ReturnsError", func(t *testing.T) {
+		svc := &fakeV1MigrationService{inventory: testV1Inventory()}
+		opts := testV1MigrationOptions()
+		opts.SolutionIDs = []int{999}
+
+		_, err := PlanV1Migration(svc, opts)
+
+		assert.IsType(t, &SolutionNotFoundError{}, errors.Unwrap(err))
+	})
+
+	t.Run("GetVirtualMachineTagsError_ReturnsError", func(t *testing.T) {
+		svc := &fakeV1MigrationService{inventory: testV1Inventory(), tagsErr: errors.New("test error")}
+
+		_, err := PlanV1Migration(svc, testV1MigrationOptions())
+
+		assert.Equal(t, "failed to retrieve tags for virtual machine [456]: test error", err.Error())
+	})
+}
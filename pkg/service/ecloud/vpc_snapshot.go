@@ -0,0 +1,550 @@
+package ecloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+)
+
+// Additional resource kinds captured in VPC snapshots
+const (
+	ResourceKindFirewallRulePort = "firewall_rule_port"
+	ResourceKindNetworkRule      = "network_rule"
+	ResourceKindNetworkRulePort  = "network_rule_port"
+	ResourceKindNATOverloadRule  = "nat_overload_rule"
+	ResourceKindTag              = "tag"
+)
+
+// SnapshotResource is a resource captured in a VPCSnapshot. Attributes hold the resource as
+// returned by the API, keyed by JSON field name
+type SnapshotResource struct {
+	Kind       string                 `json:"kind"`
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+func (r SnapshotResource) key() string {
+	return r.Kind + "/" + r.ID
+}
+
+// VPCSnapshot is the state of a VPC and the resources within it at a point in time. It can be
+// retrieved with GetVPCSnapshot, and saved and restored with WriteJSON and ReadVPCSnapshot for later
+// comparison with DiffVPCSnapshots
+type VPCSnapshot struct {
+	VPCID     string             `json:"vpc_id"`
+	TakenAt   time.Time          `json:"taken_at"`
+	Resources []SnapshotResource `json:"resources"`
+}
+
+// WriteJSON writes the snapshot as indented JSON
+func (s *VPCSnapshot) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// ReadVPCSnapshot decodes a JSON encoded VPCSnapshot from r
+func ReadVPCSnapshot(r io.Reader) (*VPCSnapshot, error) {
+	snapshot := &VPCSnapshot{}
+	err := json.NewDecoder(r).Decode(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode vpc snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+func (s *VPCSnapshot) add(kind, id, name string, resource interface{}) error {
+	// Round trip through JSON so live attributes compare equal to those read from a saved snapshot
+	b, err := json.Marshal(resource)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s [%s]: %w", kind, id, err)
+	}
+	var attributes map[string]interface{}
+	err = json.Unmarshal(b, &attributes)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s [%s]: %w", kind, id, err)
+	}
+
+	s.Resources = append(s.Resources, SnapshotResource{Kind: kind, ID: id, Name: name, Attributes: attributes})
+	return nil
+}
+
+// VPCSnapshotOptions configures GetVPCSnapshot
+type VPCSnapshotOptions struct {
+	// Clock is used to timestamp the snapshot
+	Clock Clock
+}
+
+// GetVPCSnapshot retrieves VPC vpcID along with its routers, networks, firewall and network policies
+// with their rules and ports, NAT overload rules, instances, NICs, volumes, floating IPs, load
+// balancers, VIPs and the tags assigned to its instances
+func GetVPCSnapshot(svc ECloudService, vpcID string, opts VPCSnapshotOptions) (*VPCSnapshot, error) {
+	if vpcID == "" {
+		return nil, fmt.Errorf("invalid vpc id")
+	}
+
+	snapshot := &VPCSnapshot{VPCID: vpcID, TakenAt: clockOrDefault(opts.Clock).Now().UTC()}
+
+	vpc, err := svc.GetVPC(vpcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve vpc [%s]: %w", vpcID, err)
+	}
+	err = snapshot.add(ResourceKindVPC, vpc.ID, vpc.Name, vpc)
+	if err != nil {
+		return nil, err
+	}
+
+	vpcFilter := eqFilter("vpc_id", vpcID)
+
+	routers, err := svc.GetRouters(vpcFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve routers: %w", err)
+	}
+	for _, router := range routers {
+		err = snapshotRouter(svc, snapshot, router)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	networkPolicies, err := svc.GetNetworkPolicies(vpcFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve network policies: %w", err)
+	}
+	for _, policy := range networkPolicies {
+		set, err := GetNetworkPolicySet(svc, policy.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = snapshot.add(ResourceKindNetworkPolicy, set.Policy.ID, set.Policy.Name, set.Policy)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range set.Rules {
+			err = snapshot.add(ResourceKindNetworkRule, rule.ID, rule.Name, rule)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, port := range set.Ports {
+			err = snapshot.add(ResourceKindNetworkRulePort, port.ID, port.Name, port)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	instances, err := svc.GetVPCInstances(vpcID, connection.APIRequestParameters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve instances: %w", err)
+	}
+	tagIDs := make(map[string]bool)
+	for _, instance := range instances {
+		err = snapshot.add(ResourceKindInstance, instance.ID, instance.Name, instance)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range instance.Tags {
+			tagIDs[tag.ID] = true
+		}
+	}
+
+	volumes, err := svc.GetVPCVolumes(vpcID, connection.APIRequestParameters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve volumes: %w", err)
+	}
+	for _, volume := range volumes {
+		err = snapshot.add(ResourceKindVolume, volume.ID, volume.Name, volume)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fips, err := svc.GetFloatingIPs(vpcFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve floating IPs: %w", err)
+	}
+	for _, fip := range fips {
+		err = snapshot.add(ResourceKindFloatingIP, fip.ID, fip.Name, fip)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lbs, err := svc.GetLoadBalancers(vpcFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve load balancers: %w", err)
+	}
+	for _, lb := range lbs {
+		err = snapshot.add(ResourceKindLoadBalancer, lb.ID, lb.Name, lb)
+		if err != nil {
+			return nil, err
+		}
+
+		vips, err := svc.GetVIPs(eqFilter("load_balancer_id", lb.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve vips for load balancer [%s]: %w", lb.ID, err)
+		}
+		for _, vip := range vips {
+			err = snapshot.add(ResourceKindVIP, vip.ID, vip.Name, vip)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Tags aren't scoped to a VPC, so only those assigned to instances are captured
+	if len(tagIDs) > 0 {
+		tags, err := svc.GetTags(connection.APIRequestParameters{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve tags: %w", err)
+		}
+		for _, tag := range tags {
+			if !tagIDs[tag.ID] {
+				continue
+			}
+			err = snapshot.add(ResourceKindTag, tag.ID, tag.Name, tag)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return snapshot, nil
+}
+
+func snapshotRouter(svc ECloudService, snapshot *VPCSnapshot, router Router) error {
+	err := snapshot.add(ResourceKindRouter, router.ID, router.Name, router)
+	if err != nil {
+		return err
+	}
+
+	set, err := GetFirewallPolicySet(svc, router.ID)
+	if err != nil {
+		return err
+	}
+	for _, policy := range set.Policies {
+		err = snapshot.add(ResourceKindFirewallPolicy, policy.ID, policy.Name, policy)
+		if err != nil {
+			return err
+		}
+	}
+	for _, rule := range set.Rules {
+		err = snapshot.add(ResourceKindFirewallRule, rule.ID, rule.Name, rule)
+		if err != nil {
+			return err
+		}
+	}
+	for _, port := range set.Ports {
+		err = snapshot.add(ResourceKindFirewallRulePort, port.ID, port.Name, port)
+		if err != nil {
+			return err
+		}
+	}
+
+	networks, err := svc.GetRouterNetworks(router.ID, connection.APIRequestParameters{})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve networks for router [%s]: %w", router.ID, err)
+	}
+	for _, network := range networks {
+		err = snapshot.add(ResourceKindNetwork, network.ID, network.Name, network)
+		if err != nil {
+			return err
+		}
+
+		nics, err := svc.GetNetworkNICs(network.ID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve NICs for network [%s]: %w", network.ID, err)
+		}
+		for _, nic := range nics {
+			err = snapshot.add(ResourceKindNIC, nic.ID, nic.Name, nic)
+			if err != nil {
+				return err
+			}
+		}
+
+		rules, err := svc.GetNATOverloadRules(eqFilter("network_id", network.ID))
+		if err != nil {
+			return fmt.Errorf("failed to retrieve nat overload rules for network [%s]: %w", network.ID, err)
+		}
+		for _, rule := range rules {
+			err = snapshot.add(ResourceKindNATOverloadRule, rule.ID, rule.Name, rule)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DriftIgnoreRule excludes attributes from drift detection
+type DriftIgnoreRule struct {
+	// Kind restricts the rule to resources of a kind, matching all kinds where empty
+	Kind string
+	// Field is the dotted path of an attribute, e.g. sync or sync.status, and also matches the
+	// attributes nested within it. Where empty, resources of Kind are ignored entirely
+	Field string
+}
+
+// String returns the rule in the form accepted by ParseDriftIgnoreRule
+func (r DriftIgnoreRule) String() string {
+	if r.Kind == "" {
+		return r.Field
+	}
+	return r.Kind + ":" + r.Field
+}
+
+func (r DriftIgnoreRule) matches(kind string, field string) bool {
+	if r.Kind != "" && r.Kind != kind {
+		return false
+	}
+	return r.Field == "" || field == r.Field || strings.HasPrefix(field, r.Field+".")
+}
+
+// ParseDriftIgnoreRule parses a rule in the form [kind:]field, e.g. updated_at or
+// instance:online. A rule of kind: ignores resources of the kind entirely
+func ParseDriftIgnoreRule(s string) (DriftIgnoreRule, error) {
+	var rule DriftIgnoreRule
+	if kind, field, ok := strings.Cut(s, ":"); ok {
+		rule = DriftIgnoreRule{Kind: kind, Field: field}
+	} else {
+		rule = DriftIgnoreRule{Field: s}
+	}
+	if rule.Kind == "" && rule.Field == "" {
+		return rule, fmt.Errorf("invalid drift ignore rule [%s]", s)
+	}
+	return rule, nil
+}
+
+// DefaultDriftIgnoreRules ignore attributes which change without user intervention
+var DefaultDriftIgnoreRules = []DriftIgnoreRule{
+	{Field: "updated_at"},
+	{Field: "sync"},
+	{Field: "task"},
+}
+
+// DriftOptions configures DiffVPCSnapshots and DetectVPCDrift
+type DriftOptions struct {
+	// Ignore lists rules applied in addition to DefaultDriftIgnoreRules
+	Ignore []DriftIgnoreRule
+	// DisableDefaultIgnore applies only the rules in Ignore
+	DisableDefaultIgnore bool
+	// Clock is used to timestamp the live snapshot taken by DetectVPCDrift
+	Clock Clock
+}
+
+func (o DriftOptions) ignored(kind string, field string) bool {
+	rules := o.Ignore
+	if !o.DisableDefaultIgnore {
+		rules = append(append([]DriftIgnoreRule{}, DefaultDriftIgnoreRules...), rules...)
+	}
+	for _, rule := range rules {
+		if rule.matches(kind, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// DriftType is the kind of difference reported by a ResourceDrift or FieldDrift
+type DriftType string
+
+func (t DriftType) String() string {
+	return string(t)
+}
+
+const (
+	DriftTypeAdded   DriftType = "added"
+	DriftTypeRemoved DriftType = "removed"
+	DriftTypeChanged DriftType = "changed"
+)
+
+func (t DriftType) symbol() string {
+	switch t {
+	case DriftTypeAdded:
+		return "+"
+	case DriftTypeRemoved:
+		return "-"
+	}
+	return "~"
+}
+
+// FieldDrift is an attribute of a resource which differs from the snapshot. Before is unset for
+// added attributes, and After for removed attributes
+type FieldDrift struct {
+	Field  string      `json:"field"`
+	Type   DriftType   `json:"type"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// ResourceDrift is a resource which was added or removed since the snapshot, or whose attributes
+// changed
+type ResourceDrift struct {
+	Kind   string       `json:"kind"`
+	ID     string       `json:"id"`
+	Name   string       `json:"name"`
+	Type   DriftType    `json:"type"`
+	Fields []FieldDrift `json:"fields,omitempty"`
+}
+
+// DriftReport lists the differences between a saved snapshot and the live state of a VPC, ordered
+// by resource kind and ID
+type DriftReport struct {
+	VPCID           string          `json:"vpc_id"`
+	SnapshotTakenAt time.Time       `json:"snapshot_taken_at"`
+	CheckedAt       time.Time       `json:"checked_at"`
+	Resources       []ResourceDrift `json:"resources"`
+}
+
+// Empty returns true if no drift was detected
+func (r *DriftReport) Empty() bool {
+	return len(r.Resources) == 0
+}
+
+// Write writes a human-readable summary of the report, marking added resources and attributes with
+// +, removed with - and changed with ~
+func (r *DriftReport) Write(w io.Writer) error {
+	for _, resource := range r.Resources {
+		_, err := fmt.Fprintf(w, "%s %s/%s (%s)\n", resource.Type.symbol(), resource.Kind, resource.ID, resource.Name)
+		if err != nil {
+			return err
+		}
+		for _, field := range resource.Fields {
+			var line string
+			switch field.Type {
+			case DriftTypeAdded:
+				line = formatDriftValue(field.After)
+			case DriftTypeRemoved:
+				line = formatDriftValue(field.Before)
+			default:
+				line = formatDriftValue(field.Before) + " => " + formatDriftValue(field.After)
+			}
+			_, err := fmt.Fprintf(w, "    %s %s: %s\n", field.Type.symbol(), field.Field, line)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *DriftReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func formatDriftValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// DetectVPCDrift snapshots the live state of the VPC captured in saved, and compares it with saved
+func DetectVPCDrift(svc ECloudService, saved *VPCSnapshot, opts DriftOptions) (*DriftReport, error) {
+	live, err := GetVPCSnapshot(svc, saved.VPCID, VPCSnapshotOptions{Clock: opts.Clock})
+	if err != nil {
+		return nil, err
+	}
+	return DiffVPCSnapshots(saved, live, opts), nil
+}
+
+// DiffVPCSnapshots compares snapshot live against saved, reporting resources added and removed
+// since saved was taken, and the attributes changed on resources present in both. Nested attributes
+// are compared individually, while lists are compared as a whole
+func DiffVPCSnapshots(saved *VPCSnapshot, live *VPCSnapshot, opts DriftOptions) *DriftReport {
+	report := &DriftReport{VPCID: saved.VPCID, SnapshotTakenAt: saved.TakenAt, CheckedAt: live.TakenAt}
+
+	savedByKey := make(map[string]SnapshotResource)
+	for _, resource := range saved.Resources {
+		savedByKey[resource.key()] = resource
+	}
+	liveByKey := make(map[string]SnapshotResource)
+	for _, resource := range live.Resources {
+		liveByKey[resource.key()] = resource
+	}
+
+	for _, resource := range saved.Resources {
+		if opts.ignored(resource.Kind, "") {
+			continue
+		}
+		current, ok := liveByKey[resource.key()]
+		if !ok {
+			report.Resources = append(report.Resources, ResourceDrift{Kind: resource.Kind, ID: resource.ID, Name: resource.Name, Type: DriftTypeRemoved})
+			continue
+		}
+		fields := diffAttributes(resource.Kind, resource.Attributes, current.Attributes, opts)
+		if len(fields) > 0 {
+			report.Resources = append(report.Resources, ResourceDrift{Kind: resource.Kind, ID: resource.ID, Name: current.Name, Type: DriftTypeChanged, Fields: fields})
+		}
+	}
+	for _, resource := range live.Resources {
+		if _, ok := savedByKey[resource.key()]; ok || opts.ignored(resource.Kind, "") {
+			continue
+		}
+		report.Resources = append(report.Resources, ResourceDrift{Kind: resource.Kind, ID: resource.ID, Name: resource.Name, Type: DriftTypeAdded})
+	}
+
+	sort.SliceStable(report.Resources, func(i, j int) bool {
+		if report.Resources[i].Kind != report.Resources[j].Kind {
+			return report.Resources[i].Kind < report.Resources[j].Kind
+		}
+		return report.Resources[i].ID < report.Resources[j].ID
+	})
+	return report
+}
+
+func diffAttributes(kind string, before, after map[string]interface{}, opts DriftOptions) []FieldDrift {
+	beforeFields := make(map[string]interface{})
+	flattenAttributes("", before, beforeFields)
+	afterFields := make(map[string]interface{})
+	flattenAttributes("", after, afterFields)
+
+	var fields []FieldDrift
+	for field, beforeValue := range beforeFields {
+		if opts.ignored(kind, field) {
+			continue
+		}
+		afterValue, ok := afterFields[field]
+		if !ok {
+			fields = append(fields, FieldDrift{Field: field, Type: DriftTypeRemoved, Before: beforeValue})
+		} else if !reflect.DeepEqual(beforeValue, afterValue) {
+			fields = append(fields, FieldDrift{Field: field, Type: DriftTypeChanged, Before: beforeValue, After: afterValue})
+		}
+	}
+	for field, afterValue := range afterFields {
+		if _, ok := beforeFields[field]; ok || opts.ignored(kind, field) {
+			continue
+		}
+		fields = append(fields, FieldDrift{Field: field, Type: DriftTypeAdded, After: afterValue})
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+// flattenAttributes adds the attributes of v to fields keyed by dotted path
+func flattenAttributes(prefix string, v map[string]interface{}, fields map[string]interface{}) {
+	for key, value := range v {
+		field := key
+		if prefix != "" {
+			field = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenAttributes(field, nested, fields)
+			continue
+		}
+		fields[field] = value
+	}
+}
@@ -0,0 +1,260 @@
+package ecloud
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSnapshotService struct {
+	ECloudService
+
+	instances []Instance
+	volumes   []Volume
+	rules     []FirewallRule
+	vpcErr    error
+	filters   map[string]string
+}
+
+func newFakeSnapshotService() *fakeSnapshotService {
+	return &fakeSnapshotService{
+		instances: []Instance{
+			{ID: "i-00000001", Name: "web-01", VPCID: "vpc-abcdef12", RAMCapacity: 2048, Tags: []ResourceTag{{ID: "tag-00000001", Name: "web"}},
+				Sync: ResourceSync{Status: SyncStatusComplete}, UpdatedAt: "2024-01-01T00:00:00+00:00"},
+		},
+		volumes: []Volume{{ID: "vol-00000001", Name: "data", Capacity: 100}},
+		rules:   []FirewallRule{{ID: "fwr-00000001", Name: "https", FirewallPolicyID: "fwp-00000001", Action: FirewallRuleActionAllow}},
+		filters: make(map[string]string),
+	}
+}
+
+func (f *fakeSnapshotService) GetVPC(vpcID string) (VPC, error) {
+	if f.vpcErr != nil {
+		return VPC{}, f.vpcErr
+	}
+	return VPC{ID: vpcID, Name: "prod"}, nil
+}
+
+func (f *fakeSnapshotService) GetRouters(parameters connection.APIRequestParameters) ([]Router, error) {
+	f.filters["routers"] = filterValue(parameters, "vpc_id")
+	return []Router{{ID: "rtr-00000001", Name: "main"}}, nil
+}
+
+func (f *fakeSnapshotService) GetFirewallPolicies(parameters connection.APIRequestParameters) ([]FirewallPolicy, error) {
+	return []FirewallPolicy{{ID: "fwp-00000001", Name: "web", RouterID: "rtr-00000001"}}, nil
+}
+
+func (f *fakeSnapshotService) GetFirewallPolicyFirewallRules(policyID string, parameters connection.APIRequestParameters) ([]FirewallRule, error) {
+	return f.rules, nil
+}
+
+func (f *fakeSnapshotService) GetFirewallRuleFirewallRulePorts(firewallRuleID string, parameters connection.APIRequestParameters) ([]FirewallRulePort, error) {
+	return []FirewallRulePort{{ID: "fwrp-00000001", Name: "443", FirewallRuleID: firewallRuleID}}, nil
+}
+
+func (f *fakeSnapshotService) GetRouterNetworks(routerID string, parameters connection.APIRequestParameters) ([]Network, error) {
+	return []Network{{ID: "net-00000001", Name: "web", RouterID: routerID, Subnet: "10.0.0.0/24"}}, nil
+}
+
+func (f *fakeSnapshotService) GetNetworkNICs(networkID string, parameters connection.APIRequestParameters) ([]NIC, error) {
+	return []NIC{{ID: "nic-00000001", InstanceID: "i-00000001", NetworkID: networkID}}, nil
+}
+
+func (f *fakeSnapshotService) GetNATOverloadRules(parameters connection.APIRequestParameters) ([]NATOverloadRule, error) {
+	f.filters["nat_overload_rules"] = filterValue(parameters, "network_id")
+	return []NATOverloadRule{{ID: "nor-00000001", Name: "outbound", Subnet: "10.0.0.0/24"}}, nil
+}
+
+func (f *fakeSnapshotService) GetNetworkPolicies(parameters connection.APIRequestParameters) ([]NetworkPolicy, error) {
+	return nil, nil
+}
+
+func (f *fakeSnapshotService) GetVPCInstances(vpcID string, parameters connection.APIRequestParameters) ([]Instance, error) {
+	return f.instances, nil
+}
+
+func (f *fakeSnapshotService) GetVPCVolumes(vpcID string, parameters connection.APIRequestParameters) ([]Volume, error) {
+	return f.volumes, nil
+}
+
+func (f *fakeSnapshotService) GetFloatingIPs(parameters connection.APIRequestParameters) ([]FloatingIP, error) {
+	return nil, nil
+}
+
+func (f *fakeSnapshotService) GetLoadBalancers(parameters connection.APIRequestParameters) ([]LoadBalancer, error) {
+	return nil, nil
+}
+
+func (f *fakeSnapshotService) GetTags(parameters connection.APIRequestParameters) ([]Tag, error) {
+	return []Tag{{ID: "tag-00000001", Name: "web"}, {ID: "tag-00000002", Name: "unused"}}, nil
+}
+
+func TestGetVPCSnapshot(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		svc := newFakeSnapshotService()
+
+		snapshot, err := GetVPCSnapshot(svc, "vpc-abcdef12", VPCSnapshotOptions{Clock: newFakeClock()})
+
+		assert.Nil(t, err)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), snapshot.TakenAt)
+		var keys []string
+		for _, resource := range snapshot.Resources {
+			keys = append(keys, resource.key())
+		}
+		assert.Equal(t, []string{
+			"vpc/vpc-abcdef12",
+			"router/rtr-00000001",
+			"firewall_policy/fwp-00000001",
+			"firewall_rule/fwr-00000001",
+			"firewall_rule_port/fwrp-00000001",
+			"network/net-00000001",
+			"nic/nic-00000001",
+			"nat_overload_rule/nor-00000001",
+			"instance/i-00000001",
+			"volume/vol-00000001",
+			"tag/tag-00000001",
+		}, keys)
+		assert.Equal(t, map[string]string{"routers": "vpc-abcdef12", "nat_overload_rules": "net-00000001"}, svc.filters)
+		assert.Equal(t, float64(2048), snapshot.Resources[8].Attributes["ram_capacity"])
+	})
+
+	t.Run("GetVPCError_ReturnsError", func(t *testing.T) {
+		svc := newFakeSnapshotService()
+		svc.vpcErr = errors.New("test error")
+
+		_, err := GetVPCSnapshot(svc, "vpc-abcdef12", VPCSnapshotOptions{})
+
+		assert.Equal(t, "failed to retrieve vpc [vpc-abcdef12]: test error", err.Error())
+	})
+
+	t.Run("RoundTrips", func(t *testing.T) {
+		snapshot, err := GetVPCSnapshot(newFakeSnapshotService(), "vpc-abcdef12", VPCSnapshotOptions{Clock: newFakeClock()})
+		assert.Nil(t, err)
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, snapshot.WriteJSON(buf))
+		read, err := ReadVPCSnapshot(buf)
+
+		assert.Nil(t, err)
+		assert.Equal(t, snapshot, read)
+	})
+}
+
+func TestDetectVPCDrift(t *testing.T) {
+	clock := newFakeClock()
+	svc := newFakeSnapshotService()
+	saved, err := GetVPCSnapshot(svc, "vpc-abcdef12", VPCSnapshotOptions{Clock: clock})
+	assert.Nil(t, err)
+
+	t.Run("NoChanges_Empty", func(t *testing.T) {
+		report, err := DetectVPCDrift(svc, saved, DriftOptions{Clock: clock})
+
+		assert.Nil(t, err)
+		assert.True(t, report.Empty())
+	})
+
+	t.Run("Changes_ReportsDrift", func(t *testing.T) {
+		clock.now = clock.now.Add(time.Hour)
+		svc := newFakeSnapshotService()
+		svc.instances[0].RAMCapacity = 4096
+		svc.instances[0].Sync.Status = SyncStatusInProgress
+		svc.instances[0].UpdatedAt = "2024-01-01T01:00:00+00:00"
+		svc.volumes = append(svc.volumes, Volume{ID: "vol-00000002", Name: "logs"})
+		svc.rules = nil
+
+		report, err := DetectVPCDrift(svc, saved, DriftOptions{Clock: clock})
+
+		assert.Nil(t, err)
+		assert.Equal(t, time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), report.CheckedAt)
+		assert.Equal(t, []ResourceDrift{
+			{Kind: ResourceKindFirewallRule, ID: "fwr-00000001", Name: "https", Type: DriftTypeRemoved},
+			{Kind: ResourceKindFirewallRulePort, ID: "fwrp-00000001", Name: "443", Type: DriftTypeRemoved},
+			{Kind: ResourceKindInstance, ID: "i-00000001", Name: "web-01", Type: DriftTypeChanged, Fields: []FieldDrift{
+				{Field: "ram_capacity", Type: DriftTypeChanged, Before: float64(2048), After: float64(4096)},
+			}},
+			{Kind: ResourceKindVolume, ID: "vol-00000002", Name: "logs", Type: DriftTypeAdded},
+		}, report.Resources)
+
+		buf := new(bytes.Buffer)
+		assert.Nil(t, report.Write(buf))
+		assert.Equal(t, "- firewall_rule/fwr-00000001 (https)\n"+
+			"- firewall_rule_port/fwrp-00000001 (443)\n"+
+			"~ instance/i-00000001 (web-01)\n"+
+			"    ~ ram_capacity: 2048 => 4096\n"+
+			"+ volume/vol-00000002 (logs)\n", buf.String())
+	})
+}
+
+func TestDiffVPCSnapshots(t *testing.T) {
+	saved := &VPCSnapshot{VPCID: "vpc-abcdef12", Resources: []SnapshotResource{
+		{Kind: ResourceKindInstance, ID: "i-00000001", Name: "web-01", Attributes: map[string]interface{}{
+			"online":     true,
+			"sync":       map[string]interface{}{"status": "complete"},
+			"updated_at": "2024-01-01T00:00:00+00:00",
+			"legacy":     "x",
+		}},
+		{Kind: ResourceKindNIC, ID: "nic-00000001"},
+	}}
+	live := &VPCSnapshot{VPCID: "vpc-abcdef12", Resources: []SnapshotResource{
+		{Kind: ResourceKindInstance, ID: "i-00000001", Name: "web-01", Attributes: map[string]interface{}{
+			"online":     false,
+			"sync":       map[string]interface{}{"status": "in-progress"},
+			"updated_at": "2024-01-02T00:00:00+00:00",
+			"tags":       []interface{}{"web"},
+		}},
+	}}
+
+	t.Run("DefaultIgnore", func(t *testing.T) {
+		report := DiffVPCSnapshots(saved, live, DriftOptions{})
+
+		assert.Equal(t, []ResourceDrift{
+			{Kind: ResourceKindInstance, ID: "i-00000001", Name: "web-01", Type: DriftTypeChanged, Fields: []FieldDrift{
+				{Field: "legacy", Type: DriftTypeRemoved, Before: "x"},
+				{Field: "online", Type: DriftTypeChanged, Before: true, After: false},
+				{Field: "tags", Type: DriftTypeAdded, After: []interface{}{"web"}},
+			}},
+			{Kind: ResourceKindNIC, ID: "nic-00000001", Type: DriftTypeRemoved},
+		}, report.Resources)
+	})
+
+	t.Run("CustomIgnore", func(t *testing.T) {
+		var rules []DriftIgnoreRule
+		for _, s := range []string{"instance:online", "instance:legacy", "instance:tags", "nic:"} {
+			rule, err := ParseDriftIgnoreRule(s)
+			assert.Nil(t, err)
+			rules = append(rules, rule)
+		}
+
+		report := DiffVPCSnapshots(saved, live, DriftOptions{Ignore: rules})
+
+		assert.True(t, report.Empty())
+	})
+
+	t.Run("DisableDefaultIgnore", func(t *testing.T) {
+		report := DiffVPCSnapshots(saved, live, DriftOptions{DisableDefaultIgnore: true, Ignore: []DriftIgnoreRule{{Kind: ResourceKindNIC}}})
+
+		var fields []string
+		for _, field := range report.Resources[0].Fields {
+			fields = append(fields, field.Field)
+		}
+		assert.Equal(t, []string{"legacy", "online", "sync.status", "tags", "updated_at"}, fields)
+	})
+}
+
+func TestParseDriftIgnoreRule(t *testing.T) {
+	rule, err := ParseDriftIgnoreRule("updated_at")
+	assert.Nil(t, err)
+	assert.Equal(t, DriftIgnoreRule{Field: "updated_at"}, rule)
+
+	rule, err = ParseDriftIgnoreRule("instance:sync.status")
+	assert.Nil(t, err)
+	assert.Equal(t, DriftIgnoreRule{Kind: "instance", Field: "sync.status"}, rule)
+	assert.Equal(t, "instance:sync.status", rule.String())
+
+	_, err = ParseDriftIgnoreRule(":")
+	assert.Equal(t, "invalid drift ignore rule [:]", err.Error())
+}
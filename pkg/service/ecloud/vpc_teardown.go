@@ -0,0 +1,514 @@
+package ecloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+)
+
+// Resource kinds reported by TeardownVPC, in the order they are deleted
+const (
+	TeardownKindVPNSession     = ResourceKindVPNSession
+	TeardownKindVPNEndpoint    = ResourceKindVPNEndpoint
+	TeardownKindVPNService     = ResourceKindVPNService
+	TeardownKindVIP            = ResourceKindVIP
+	TeardownKindLoadBalancer   = ResourceKindLoadBalancer
+	TeardownKindFloatingIP     = ResourceKindFloatingIP
+	TeardownKindInstance       = ResourceKindInstance
+	TeardownKindNIC            = ResourceKindNIC
+	TeardownKindVolume         = ResourceKindVolume
+	TeardownKindFirewallPolicy = ResourceKindFirewallPolicy
+	TeardownKindNetworkPolicy  = ResourceKindNetworkPolicy
+	TeardownKindNetwork        = ResourceKindNetwork
+	TeardownKindRouter         = ResourceKindRouter
+	TeardownKindVPC            = ResourceKindVPC
+)
+
+var teardownStages = []string{
+	TeardownKindVPNSession,
+	TeardownKindVPNEndpoint,
+	TeardownKindVPNService,
+	TeardownKindVIP,
+	TeardownKindLoadBalancer,
+	TeardownKindFloatingIP,
+	TeardownKindInstance,
+	TeardownKindNIC,
+	TeardownKindVolume,
+	TeardownKindFirewallPolicy,
+	TeardownKindNetworkPolicy,
+	TeardownKindNetwork,
+	TeardownKindRouter,
+	TeardownKindVPC,
+}
+
+// TeardownResource identifies a resource discovered by TeardownVPC
+type TeardownResource struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (r TeardownResource) String() string {
+	return fmt.Sprintf("%s [%s]", r.Kind, r.ID)
+}
+
+// TeardownSkip records a resource which TeardownVPC did not attempt to delete
+type TeardownSkip struct {
+	Resource TeardownResource `json:"resource"`
+	Reason   string           `json:"reason"`
+}
+
+// TeardownFailure records a resource which TeardownVPC failed to delete
+type TeardownFailure struct {
+	Resource TeardownResource `json:"resource"`
+	Err      error            `json:"-"`
+}
+
+// VPCTeardownReport describes the outcome of TeardownVPC. Resources are listed in deletion order
+type VPCTeardownReport struct {
+	// Planned lists the resources which would be deleted when DryRun is set
+	Planned []TeardownResource `json:"planned,omitempty"`
+	Deleted []TeardownResource `json:"deleted,omitempty"`
+	Skipped []TeardownSkip     `json:"skipped,omitempty"`
+	Failed  []TeardownFailure  `json:"failed,omitempty"`
+}
+
+// Complete returns true if every discovered resource was deleted (or planned for deletion)
+func (r *VPCTeardownReport) Complete() bool {
+	return len(r.Skipped) == 0 && len(r.Failed) == 0
+}
+
+// VPCTeardownOptions configures TeardownVPC
+type VPCTeardownOptions struct {
+	// DryRun discovers resources and reports them as planned without deleting anything
+	DryRun bool
+	// Wait configures polling for deletion tasks and resource removal
+	Wait WaitOptions
+	// Progress is invoked after each resource is deleted (or planned for deletion), fails or is
+	// skipped. err is nil for successful and planned deletions. Progress may be invoked concurrently
+	Progress func(resource TeardownResource, err error)
+}
+
+type teardownNode struct {
+	resource TeardownResource
+	// dependents must be deleted before this node
+	dependents []*teardownNode
+	protected  string
+	remove     func(ctx context.Context) error
+
+	deleted    bool
+	skipReason string
+	err        error
+}
+
+// blocker returns the first dependent which wasn't deleted, if any
+func (n *teardownNode) blocker() *teardownNode {
+	for _, d := range n.dependents {
+		if !d.deleted {
+			return d
+		}
+	}
+	return nil
+}
+
+type teardownGraph struct {
+	svc   ECloudService
+	opts  VPCTeardownOptions
+	nodes map[string]*teardownNode
+	order []*teardownNode
+}
+
+func (g *teardownGraph) add(kind, id, name string, remove func(ctx context.Context) error) *teardownNode {
+	key := kind + "/" + id
+	if n, ok := g.nodes[key]; ok {
+		return n
+	}
+
+	n := &teardownNode{
+		resource: TeardownResource{Kind: kind, ID: id, Name: name},
+		remove:   remove,
+	}
+	g.nodes[key] = n
+	g.order = append(g.order, n)
+	return n
+}
+
+func (g *teardownGraph) get(kind, id string) *teardownNode {
+	return g.nodes[kind+"/"+id]
+}
+
+// depends records that n cannot be deleted until each of dependents has been deleted
+func (n *teardownNode) depends(dependents ...*teardownNode) {
+	for _, d := range dependents {
+		if d != nil {
+			n.dependents = append(n.dependents, d)
+		}
+	}
+}
+
+// TeardownVPC deletes the VPC with given ID along with all resources within it. Resources are
+// discovered using the existing list endpoints and deleted in dependency order, waiting for each
+// stage to complete before continuing. Locked instances are never deleted, and any resource
+// depending on a skipped or failed resource (e.g. the network and router of a locked instance,
+// and the VPC itself) is skipped. An error is returned only when discovery fails or ctx is done;
+// individual deletion failures are recorded in the report
+func TeardownVPC(ctx context.Context, svc ECloudService, vpcID string, opts VPCTeardownOptions) (*VPCTeardownReport, error) {
+	if vpcID == "" {
+		return nil, fmt.Errorf("invalid vpc id")
+	}
+
+	g := &teardownGraph{
+		svc:   svc,
+		opts:  opts,
+		nodes: make(map[string]*teardownNode),
+	}
+
+	err := g.discover(vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stage := range teardownStages {
+		err := g.runStage(ctx, stage)
+		if err != nil {
+			return g.report(), err
+		}
+	}
+
+	return g.report(), nil
+}
+
+func eqFilter(property string, value string) connection.APIRequestParameters {
+	return *connection.NewAPIRequestParameters().WithFilter(connection.APIRequestFiltering{
+		Property: property,
+		Operator: connection.EQOperator,
+		Value:    []string{value},
+	})
+}
+
+func (g *teardownGraph) discover(vpcID string) error {
+	vpc, err := g.svc.GetVPC(vpcID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve vpc [%s]: %w", vpcID, err)
+	}
+	vpcNode := g.add(TeardownKindVPC, vpc.ID, vpc.Name, g.pollDelete(vpc.ID, g.svc.DeleteVPC, func(id string) error {
+		_, err := g.svc.GetVPC(id)
+		return err
+	}, isNotFound[*VPCNotFoundError]))
+
+	vpcFilter := eqFilter("vpc_id", vpcID)
+
+	// VPN services, endpoints and sessions
+	services, err := g.svc.GetVPNServices(vpcFilter)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve vpn services: %w", err)
+	}
+	endpointsByFIP := make(map[string][]*teardownNode)
+	for _, service := range services {
+		serviceNode := g.add(TeardownKindVPNService, service.ID, service.Name, g.taskDelete(service.ID, g.svc.DeleteVPNService, isNotFound[*VPNServiceNotFoundError]))
+		vpcNode.depends(serviceNode)
+
+		serviceFilter := eqFilter("vpn_service_id", service.ID)
+		sessions, err := g.svc.GetVPNSessions(serviceFilter)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve vpn sessions for vpn service [%s]: %w", service.ID, err)
+		}
+		sessionsByEndpoint := make(map[string][]*teardownNode)
+		for _, session := range sessions {
+			sessionNode := g.add(TeardownKindVPNSession, session.ID, session.Name, g.taskDelete(session.ID, g.svc.DeleteVPNSession, isNotFound[*VPNSessionNotFoundError]))
+			serviceNode.depends(sessionNode)
+			sessionsByEndpoint[session.VPNEndpointID] = append(sessionsByEndpoint[session.VPNEndpointID], sessionNode)
+		}
+
+		endpoints, err := g.svc.GetVPNEndpoints(serviceFilter)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve vpn endpoints for vpn service [%s]: %w", service.ID, err)
+		}
+		for _, endpoint := range endpoints {
+			endpointNode := g.add(TeardownKindVPNEndpoint, endpoint.ID, endpoint.Name, g.taskDelete(endpoint.ID, g.svc.DeleteVPNEndpoint, isNotFound[*VPNEndpointNotFoundError]))
+			endpointNode.depends(sessionsByEndpoint[endpoint.ID]...)
+			serviceNode.depends(endpointNode)
+			if endpoint.FloatingIPID != "" {
+				endpointsByFIP[endpoint.FloatingIPID] = append(endpointsByFIP[endpoint.FloatingIPID], endpointNode)
+			}
+		}
+	}
+
+	// Load balancers and VIPs
+	lbs, err := g.svc.GetLoadBalancers(vpcFilter)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve load balancers: %w", err)
+	}
+	lbsByNetwork := make(map[string][]*teardownNode)
+	for _, lb := range lbs {
+		lbNode := g.add(TeardownKindLoadBalancer, lb.ID, lb.Name, g.taskDelete(lb.ID, g.svc.DeleteLoadBalancer, isNotFound[*LoadBalancerNotFoundError]))
+		vpcNode.depends(lbNode)
+		lbsByNetwork[lb.NetworkID] = append(lbsByNetwork[lb.NetworkID], lbNode)
+
+		vips, err := g.svc.GetVIPs(eqFilter("load_balancer_id", lb.ID))
+		if err != nil {
+			return fmt.Errorf("failed to retrieve vips for load balancer [%s]: %w", lb.ID, err)
+		}
+		for _, vip := range vips {
+			vipNode := g.add(TeardownKindVIP, vip.ID, vip.Name, g.taskDelete(vip.ID, g.svc.DeleteVIP, isNotFound[*VIPNotFoundError]))
+			lbNode.depends(vipNode)
+		}
+	}
+
+	// Instances, protecting locked instances along with their floating IPs. Attached volumes
+	// aren't deleted until their instance has been, so a failed or protected instance keeps them
+	instances, err := g.svc.GetVPCInstances(vpcID, connection.APIRequestParameters{})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve instances: %w", err)
+	}
+	volumeInstances := make(map[string]*teardownNode)
+	lockedFIPs := make(map[string]*teardownNode)
+	for _, instance := range instances {
+		instanceNode := g.add(TeardownKindInstance, instance.ID, instance.Name, g.pollDelete(instance.ID, g.svc.DeleteInstance, func(id string) error {
+			_, err := g.svc.GetInstance(id)
+			return err
+		}, isNotFound[*InstanceNotFoundError]))
+		vpcNode.depends(instanceNode)
+
+		volumes, err := g.svc.GetInstanceVolumes(instance.ID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve volumes for instance [%s]: %w", instance.ID, err)
+		}
+		for _, volume := range volumes {
+			volumeInstances[volume.ID] = instanceNode
+		}
+
+		if !instance.Locked {
+			continue
+		}
+		instanceNode.protected = "instance is locked"
+
+		fips, err := g.svc.GetInstanceFloatingIPs(instance.ID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve floating IPs for instance [%s]: %w", instance.ID, err)
+		}
+		for _, fip := range fips {
+			lockedFIPs[fip.ID] = instanceNode
+		}
+	}
+
+	// Floating IPs, unassigned prior to deletion
+	fips, err := g.svc.GetFloatingIPs(vpcFilter)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve floating IPs: %w", err)
+	}
+	for _, fip := range fips {
+		fipNode := g.add(TeardownKindFloatingIP, fip.ID, fip.Name, g.floatingIPDelete(fip))
+		fipNode.depends(endpointsByFIP[fip.ID]...)
+		fipNode.depends(lockedFIPs[fip.ID])
+		vpcNode.depends(fipNode)
+	}
+
+	// Volumes
+	volumes, err := g.svc.GetVPCVolumes(vpcID, connection.APIRequestParameters{})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve volumes: %w", err)
+	}
+	for _, volume := range volumes {
+		volumeNode := g.add(TeardownKindVolume, volume.ID, volume.Name, g.taskDelete(volume.ID, g.svc.DeleteVolume, isNotFound[*VolumeNotFoundError]))
+		volumeNode.depends(volumeInstances[volume.ID])
+		vpcNode.depends(volumeNode)
+	}
+
+	// Network policies
+	networkPolicies, err := g.svc.GetNetworkPolicies(vpcFilter)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve network policies: %w", err)
+	}
+	policiesByNetwork := make(map[string][]*teardownNode)
+	for _, policy := range networkPolicies {
+		policyNode := g.add(TeardownKindNetworkPolicy, policy.ID, policy.Name, g.taskDelete(policy.ID, g.svc.DeleteNetworkPolicy, isNotFound[*NetworkPolicyNotFoundError]))
+		policiesByNetwork[policy.NetworkID] = append(policiesByNetwork[policy.NetworkID], policyNode)
+		vpcNode.depends(policyNode)
+	}
+
+	// Routers, with their firewall policies, networks and NICs
+	routers, err := g.svc.GetRouters(vpcFilter)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve routers: %w", err)
+	}
+	for _, router := range routers {
+		routerNode := g.add(TeardownKindRouter, router.ID, router.Name, g.pollDelete(router.ID, g.svc.DeleteRouter, func(id string) error {
+			_, err := g.svc.GetRouter(id)
+			return err
+		}, isNotFound[*RouterNotFoundError]))
+		vpcNode.depends(routerNode)
+
+		for _, service := range services {
+			if service.RouterID == router.ID {
+				routerNode.depends(g.get(TeardownKindVPNService, service.ID))
+			}
+		}
+
+		firewallPolicies, err := g.svc.GetRouterFirewallPolicies(router.ID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve firewall policies for router [%s]: %w", router.ID, err)
+		}
+		for _, policy := range firewallPolicies {
+			policyNode := g.add(TeardownKindFirewallPolicy, policy.ID, policy.Name, g.taskDelete(policy.ID, g.svc.DeleteFirewallPolicy, isNotFound[*FirewallPolicyNotFoundError]))
+			routerNode.depends(policyNode)
+		}
+
+		networks, err := g.svc.GetRouterNetworks(router.ID, connection.APIRequestParameters{})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve networks for router [%s]: %w", router.ID, err)
+		}
+		for _, network := range networks {
+			networkNode := g.add(TeardownKindNetwork, network.ID, network.Name, g.pollDelete(network.ID, g.svc.DeleteNetwork, func(id string) error {
+				_, err := g.svc.GetNetwork(id)
+				return err
+			}, isNotFound[*NetworkNotFoundError]))
+			networkNode.depends(policiesByNetwork[network.ID]...)
+			networkNode.depends(lbsByNetwork[network.ID]...)
+			routerNode.depends(networkNode)
+
+			nics, err := g.svc.GetNetworkNICs(network.ID, connection.APIRequestParameters{})
+			if err != nil {
+				return fmt.Errorf("failed to retrieve NICs for network [%s]: %w", network.ID, err)
+			}
+			for _, nic := range nics {
+				nicNode := g.add(TeardownKindNIC, nic.ID, nic.Name, g.taskDelete(nic.ID, g.svc.DeleteNIC, isNotFound[*NICNotFoundError]))
+				if nic.InstanceID != "" {
+					nicNode.depends(g.get(TeardownKindInstance, nic.InstanceID))
+				}
+				networkNode.depends(nicNode)
+			}
+		}
+	}
+
+	return nil
+}
+
+// runStage deletes all resources of given kind concurrently, skipping those which are protected
+// or blocked by a dependent which wasn't deleted
+func (g *teardownGraph) runStage(ctx context.Context, kind string) error {
+	var wg sync.WaitGroup
+	for _, n := range g.order {
+		if n.resource.Kind != kind {
+			continue
+		}
+
+		if n.protected != "" {
+			n.skipReason = n.protected
+			g.progress(n)
+			continue
+		}
+		if b := n.blocker(); b != nil {
+			n.skipReason = fmt.Sprintf("depends on %s which was not deleted", b.resource)
+			g.progress(n)
+			continue
+		}
+		if g.opts.DryRun {
+			n.deleted = true
+			g.progress(n)
+			continue
+		}
+
+		wg.Add(1)
+		go func(n *teardownNode) {
+			defer wg.Done()
+			n.err = n.remove(ctx)
+			n.deleted = n.err == nil
+			g.progress(n)
+		}(n)
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func (g *teardownGraph) progress(n *teardownNode) {
+	if g.opts.Progress == nil {
+		return
+	}
+	if n.skipReason != "" {
+		g.opts.Progress(n.resource, errors.New(n.skipReason))
+		return
+	}
+	g.opts.Progress(n.resource, n.err)
+}
+
+func (g *teardownGraph) report() *VPCTeardownReport {
+	report := &VPCTeardownReport{}
+	for _, stage := range teardownStages {
+		for _, n := range g.order {
+			if n.resource.Kind != stage {
+				continue
+			}
+
+			switch {
+			case n.deleted && g.opts.DryRun:
+				report.Planned = append(report.Planned, n.resource)
+			case n.deleted:
+				report.Deleted = append(report.Deleted, n.resource)
+			case n.err != nil:
+				report.Failed = append(report.Failed, TeardownFailure{Resource: n.resource, Err: n.err})
+			case n.skipReason != "":
+				report.Skipped = append(report.Skipped, TeardownSkip{Resource: n.resource, Reason: n.skipReason})
+			}
+		}
+	}
+	return report
+}
+
+// taskDelete returns a remove func for resources whose deletion returns a task. Resources which
+// are already gone are treated as deleted
+func (g *teardownGraph) taskDelete(id string, del func(id string) (string, error), notFound func(error) bool) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		taskID, err := del(id)
+		if err != nil {
+			if notFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		_, err = WaitForTask(ctx, g.svc, taskID, TaskWaitOptions{WaitOptions: g.opts.Wait})
+		return err
+	}
+}
+
+// pollDelete returns a remove func for resources whose deletion doesn't return a task, polling
+// the resource with get until it is no longer found
+func (g *teardownGraph) pollDelete(id string, del func(id string) error, get func(id string) error, notFound func(error) bool) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		err := del(id)
+		if err != nil {
+			if notFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		return waitForRemoval(ctx, id, get, notFound, g.opts.Wait)
+	}
+}
+
+func (g *teardownGraph) floatingIPDelete(fip FloatingIP) func(ctx context.Context) error {
+	del := g.taskDelete(fip.ID, g.svc.DeleteFloatingIP, isNotFound[*FloatingIPNotFoundError])
+	if fip.ResourceID == "" {
+		return del
+	}
+
+	unassign := g.taskDelete(fip.ID, g.svc.UnassignFloatingIP, isNotFound[*FloatingIPNotFoundError])
+	return func(ctx context.Context) error {
+		err := unassign(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to unassign floating IP: %w", err)
+		}
+		return del(ctx)
+	}
+}
+
+func isNotFound[E error](err error) bool {
+	var target E
+	return errors.As(err, &target)
+}
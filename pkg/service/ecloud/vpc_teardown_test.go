@@ -0,0 +1,433 @@
+package ecloud
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeVPCService is an in-memory ECloudService covering the methods used by TeardownVPC
+type fakeVPCService struct {
+	fakeTaskService
+
+	vpc              VPC
+	routers          []Router
+	networks         []Network
+	firewallPolicies []FirewallPolicy
+	networkPolicies  []NetworkPolicy
+	instances        []Instance
+	instanceVolumes  map[string][]Volume
+	instanceFIPs     map[string][]FloatingIP
+	nics             []NIC
+	volumes          []Volume
+	fips             []FloatingIP
+	lbs              []LoadBalancer
+	vips             []VIP
+	vpnServices      []VPNService
+	vpnEndpoints     []VPNEndpoint
+	vpnSessions      []VPNSession
+	vpnGateways      []VPNGateway
+	ipAddresses      []IPAddress
+	nicIPAddresses   map[string][]IPAddress
+
+	// failDelete causes deletion of the given IDs to fail
+	failDelete map[string]error
+
+	deleted    []string
+	unassigned []string
+	mutex      sync.Mutex
+}
+
+func filterValue(parameters connection.APIRequestParameters, property string) string {
+	for _, f := range parameters.Filtering {
+		if f.Property == property && len(f.Value) > 0 {
+			return f.Value[0]
+		}
+	}
+	return ""
+}
+
+func (f *fakeVPCService) isDeleted(id string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, d := range f.deleted {
+		if d == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeVPCService) delete(id string) error {
+	if err := f.failDelete[id]; err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+func (f *fakeVPCService) deleteTask(id string) (string, error) {
+	return "task-" + id, f.delete(id)
+}
+
+func (f *fakeVPCService) GetVPC(vpcID string) (VPC, error) {
+	if vpcID != f.vpc.ID || f.isDeleted(vpcID) {
+		return VPC{}, &VPCNotFoundError{ID: vpcID}
+	}
+	return f.vpc, nil
+}
+
+func (f *fakeVPCService) DeleteVPC(vpcID string) error { return f.delete(vpcID) }
+
+func (f *fakeVPCService) GetRouters(parameters connection.APIRequestParameters) ([]Router, error) {
+	return f.routers, nil
+}
+
+func (f *fakeVPCService) GetRouter(routerID string) (Router, error) {
+	if f.isDeleted(routerID) {
+		return Router{}, &RouterNotFoundError{ID: routerID}
+	}
+	return Router{ID: routerID}, nil
+}
+
+func (f *fakeVPCService) DeleteRouter(routerID string) error { return f.delete(routerID) }
+
+func (f *fakeVPCService) GetRouterNetworks(routerID string, parameters connection.APIRequestParameters) ([]Network, error) {
+	var networks []Network
+	for _, n := range f.networks {
+		if n.RouterID == routerID {
+			networks = append(networks, n)
+		}
+	}
+	return networks, nil
+}
+
+func (f *fakeVPCService) GetRouterFirewallPolicies(routerID string, parameters connection.APIRequestParameters) ([]FirewallPolicy, error) {
+	var policies []FirewallPolicy
+	for _, p := range f.firewallPolicies {
+		if p.RouterID == routerID {
+			policies = append(policies, p)
+		}
+	}
+	return policies, nil
+}
+
+func (f *fakeVPCService) DeleteFirewallPolicy(policyID string) (string, error) {
+	return f.deleteTask(policyID)
+}
+
+func (f *fakeVPCService) GetNetwork(networkID string) (Network, error) {
+	if f.isDeleted(networkID) {
+		return Network{}, &NetworkNotFoundError{ID: networkID}
+	}
+	return Network{ID: networkID}, nil
+}
+
+func (f *fakeVPCService) DeleteNetwork(networkID string) error { return f.delete(networkID) }
+
+func (f *fakeVPCService) GetNetworkNICs(networkID string, parameters connection.APIRequestParameters) ([]NIC, error) {
+	var nics []NIC
+	for _, n := range f.nics {
+		if n.NetworkID == networkID {
+			nics = append(nics, n)
+		}
+	}
+	return nics, nil
+}
+
+func (f *fakeVPCService) DeleteNIC(nicID string) (string, error) {
+	if f.isDeleted(nicID) {
+		return "", &NICNotFoundError{ID: nicID}
+	}
+	return f.deleteTask(nicID)
+}
+
+func (f *fakeVPCService) GetNetworkPolicies(parameters connection.APIRequestParameters) ([]NetworkPolicy, error) {
+	return f.networkPolicies, nil
+}
+
+func (f *fakeVPCService) DeleteNetworkPolicy(policyID string) (string, error) {
+	return f.deleteTask(policyID)
+}
+
+func (f *fakeVPCService) GetVPCInstances(vpcID string, parameters connection.APIRequestParameters) ([]Instance, error) {
+	return f.instances, nil
+}
+
+func (f *fakeVPCService) GetInstance(instanceID string) (Instance, error) {
+	if f.isDeleted(instanceID) {
+		return Instance{}, &InstanceNotFoundError{ID: instanceID}
+	}
+	return Instance{ID: instanceID}, nil
+}
+
+func (f *fakeVPCService) DeleteInstance(instanceID string) error { return f.delete(instanceID) }
+
+func (f *fakeVPCService) GetInstanceVolumes(instanceID string, parameters connection.APIRequestParameters) ([]Volume, error) {
+	return f.instanceVolumes[instanceID], nil
+}
+
+func (f *fakeVPCService) GetInstanceFloatingIPs(instanceID string, parameters connection.APIRequestParameters) ([]FloatingIP, error) {
+	return f.instanceFIPs[instanceID], nil
+}
+
+func (f *fakeVPCService) GetVPCVolumes(vpcID string, parameters connection.APIRequestParameters) ([]Volume, error) {
+	return f.volumes, nil
+}
+
+func (f *fakeVPCService) DeleteVolume(volumeID string) (string, error) { return f.deleteTask(volumeID) }
+
+func (f *fakeVPCService) GetFloatingIPs(parameters connection.APIRequestParameters) ([]FloatingIP, error) {
+	return f.fips, nil
+}
+
+func (f *fakeVPCService) UnassignFloatingIP(fipID string) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.unassigned = append(f.unassigned, fipID)
+	return "task-unassign-" + fipID, nil
+}
+
+func (f *fakeVPCService) DeleteFloatingIP(fipID string) (string, error) { return f.deleteTask(fipID) }
+
+func (f *fakeVPCService) GetLoadBalancers(parameters connection.APIRequestParameters) ([]LoadBalancer, error) {
+	return f.lbs, nil
+}
+
+func (f *fakeVPCService) DeleteLoadBalancer(lbID string) (string, error) { return f.deleteTask(lbID) }
+
+func (f *fakeVPCService) GetVIPs(parameters connection.APIRequestParameters) ([]VIP, error) {
+	var vips []VIP
+	for _, v := range f.vips {
+		if v.LoadBalancerID == filterValue(parameters, "load_balancer_id") {
+			vips = append(vips, v)
+		}
+	}
+	return vips, nil
+}
+
+func (f *fakeVPCService) DeleteVIP(vipID string) (string, error) { return f.deleteTask(vipID) }
+
+func (f *fakeVPCService) GetVPNServices(parameters connection.APIRequestParameters) ([]VPNService, error) {
+	return f.vpnServices, nil
+}
+
+func (f *fakeVPCService) DeleteVPNService(serviceID string) (string, error) {
+	return f.deleteTask(serviceID)
+}
+
+func (f *fakeVPCService) GetVPNEndpoints(parameters connection.APIRequestParameters) ([]VPNEndpoint, error) {
+	var endpoints []VPNEndpoint
+	for _, e := range f.vpnEndpoints {
+		if e.VPNServiceID == filterValue(parameters, "vpn_service_id") {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints, nil
+}
+
+func (f *fakeVPCService) DeleteVPNEndpoint(endpointID string) (string, error) {
+	return f.deleteTask(endpointID)
+}
+
+func (f *fakeVPCService) GetVPNSessions(parameters connection.APIRequestParameters) ([]VPNSession, error) {
+	var sessions []VPNSession
+	for _, s := range f.vpnSessions {
+		if s.VPNServiceID == filterValue(parameters, "vpn_service_id") {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+func (f *fakeVPCService) DeleteVPNSession(sessionID string) (string, error) {
+	return f.deleteTask(sessionID)
+}
+
+func newFakeVPCService() *fakeVPCService {
+	return &fakeVPCService{
+		vpc:              VPC{ID: "vpc-abcdef12", Name: "prod"},
+		routers:          []Router{{ID: "rtr-abcdef12", VPCID: "vpc-abcdef12"}},
+		networks:         []Network{{ID: "net-abcdef12", RouterID: "rtr-abcdef12"}},
+		firewallPolicies: []FirewallPolicy{{ID: "fwp-abcdef12", RouterID: "rtr-abcdef12"}},
+		networkPolicies:  []NetworkPolicy{{ID: "np-abcdef12", NetworkID: "net-abcdef12"}},
+		instances:        []Instance{{ID: "i-abcdef12"}},
+		nics:             []NIC{{ID: "nic-abcdef12", InstanceID: "i-abcdef12", NetworkID: "net-abcdef12"}},
+		volumes:          []Volume{{ID: "vol-abcdef12"}},
+		fips:             []FloatingIP{{ID: "fip-abcdef12", ResourceID: "nic-abcdef12"}, {ID: "fip-abcdef34"}},
+		lbs:              []LoadBalancer{{ID: "lb-abcdef12", NetworkID: "net-abcdef12"}},
+		vips:             []VIP{{ID: "vip-abcdef12", LoadBalancerID: "lb-abcdef12"}},
+		vpnServices:      []VPNService{{ID: "vpn-abcdef12", RouterID: "rtr-abcdef12"}},
+		vpnEndpoints:     []VPNEndpoint{{ID: "vpne-abcdef12", VPNServiceID: "vpn-abcdef12", FloatingIPID: "fip-abcdef34"}},
+		vpnSessions:      []VPNSession{{ID: "vpns-abcdef12", VPNServiceID: "vpn-abcdef12", VPNEndpointID: "vpne-abcdef12"}},
+	}
+}
+
+func teardownIDs(resources []TeardownResource) []string {
+	var ids []string
+	for _, r := range resources {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+// assertTeardownOrder asserts resources were deleted in stage order, using the ID prefix to
+// determine the stage
+func assertTeardownOrder(t *testing.T, deleted []string) {
+	t.Helper()
+	stages := []string{"vpns-", "vpne-", "vpn-", "vip-", "lb-", "fip-", "i-", "nic-", "vol-", "fwp-", "np-", "net-", "rtr-", "vpc-"}
+	last := 0
+	for _, id := range deleted {
+		for i, prefix := range stages {
+			if strings.HasPrefix(id, prefix) {
+				assert.GreaterOrEqual(t, i, last, "%s deleted out of order", id)
+				last = i
+				break
+			}
+		}
+	}
+}
+
+func TestTeardownVPC(t *testing.T) {
+	opts := VPCTeardownOptions{Wait: WaitOptions{Interval: time.Second, Clock: newFakeClock()}}
+	expectedOrder := []string{
+		"vpns-abcdef12",
+		"vpne-abcdef12",
+		"vpn-abcdef12",
+		"vip-abcdef12",
+		"lb-abcdef12",
+		"fip-abcdef12",
+		"fip-abcdef34",
+		"i-abcdef12",
+		"nic-abcdef12",
+		"vol-abcdef12",
+		"fwp-abcdef12",
+		"np-abcdef12",
+		"net-abcdef12",
+		"rtr-abcdef12",
+		"vpc-abcdef12",
+	}
+
+	t.Run("DeletesInDependencyOrder", func(t *testing.T) {
+		svc := newFakeVPCService()
+
+		report, err := TeardownVPC(context.Background(), svc, "vpc-abcdef12", opts)
+
+		assert.Nil(t, err)
+		assert.True(t, report.Complete())
+		assert.Equal(t, expectedOrder, teardownIDs(report.Deleted))
+		assert.ElementsMatch(t, expectedOrder, svc.deleted)
+		assertTeardownOrder(t, svc.deleted)
+		assert.Equal(t, []string{"fip-abcdef12"}, svc.unassigned)
+	})
+
+	t.Run("DryRun_ReportsPlanWithoutDeleting", func(t *testing.T) {
+		svc := newFakeVPCService()
+
+		var progressed []string
+		report, err := TeardownVPC(context.Background(), svc, "vpc-abcdef12", VPCTeardownOptions{
+			DryRun: true,
+			Progress: func(resource TeardownResource, err error) {
+				assert.Nil(t, err)
+				progressed = append(progressed, resource.ID)
+			},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, expectedOrder, teardownIDs(report.Planned))
+		assert.Equal(t, expectedOrder, progressed)
+		assert.Empty(t, report.Deleted)
+		assert.Empty(t, svc.deleted)
+	})
+
+	t.Run("LockedInstance_ProtectsInstanceAndDependents", func(t *testing.T) {
+		svc := newFakeVPCService()
+		svc.instances[0].Locked = true
+		svc.instanceVolumes = map[string][]Volume{"i-abcdef12": {{ID: "vol-abcdef12"}}}
+		svc.instanceFIPs = map[string][]FloatingIP{"i-abcdef12": {{ID: "fip-abcdef12"}}}
+
+		report, err := TeardownVPC(context.Background(), svc, "vpc-abcdef12", opts)
+
+		assert.Nil(t, err)
+		assert.False(t, report.Complete())
+		assert.NotContains(t, svc.deleted, "i-abcdef12")
+		assert.Equal(t, []string{"vpns-abcdef12", "vpne-abcdef12", "vpn-abcdef12", "vip-abcdef12", "lb-abcdef12", "fip-abcdef34", "fwp-abcdef12", "np-abcdef12"}, svc.deleted)
+		assertTeardownOrder(t, svc.deleted)
+
+		var skipped []string
+		for _, s := range report.Skipped {
+			skipped = append(skipped, s.Resource.ID)
+		}
+		assert.Equal(t, []string{"fip-abcdef12", "i-abcdef12", "nic-abcdef12", "vol-abcdef12", "net-abcdef12", "rtr-abcdef12", "vpc-abcdef12"}, skipped)
+		assert.Equal(t, "instance is locked", report.Skipped[1].Reason)
+		assert.Equal(t, "depends on instance [i-abcdef12] which was not deleted", report.Skipped[2].Reason)
+	})
+
+	t.Run("DeleteFailure_RecordedAndDependentsSkipped", func(t *testing.T) {
+		svc := newFakeVPCService()
+		svc.failDelete = map[string]error{"lb-abcdef12": errors.New("test error 1")}
+
+		report, err := TeardownVPC(context.Background(), svc, "vpc-abcdef12", opts)
+
+		assert.Nil(t, err)
+		assert.Len(t, report.Failed, 1)
+		assert.Equal(t, "lb-abcdef12", report.Failed[0].Resource.ID)
+		assert.Equal(t, "test error 1", report.Failed[0].Err.Error())
+		assert.NotContains(t, svc.deleted, "net-abcdef12")
+		assert.NotContains(t, svc.deleted, "vpc-abcdef12")
+		assert.Contains(t, svc.deleted, "i-abcdef12")
+	})
+
+	t.Run("InstanceDeleteFailure_SkipsAttachedVolumes", func(t *testing.T) {
+		svc := newFakeVPCService()
+		svc.instanceVolumes = map[string][]Volume{"i-abcdef12": {{ID: "vol-abcdef12"}}}
+		svc.failDelete = map[string]error{"i-abcdef12": errors.New("test error 1")}
+
+		report, err := TeardownVPC(context.Background(), svc, "vpc-abcdef12", opts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "i-abcdef12", report.Failed[0].Resource.ID)
+		assert.NotContains(t, svc.deleted, "vol-abcdef12")
+
+		var skipped []string
+		for _, s := range report.Skipped {
+			skipped = append(skipped, s.Resource.ID)
+		}
+		assert.Contains(t, skipped, "vol-abcdef12")
+	})
+
+	t.Run("AlreadyDeleted_TreatedAsDeleted", func(t *testing.T) {
+		svc := newFakeVPCService()
+		svc.deleted = []string{"nic-abcdef12"}
+
+		report, err := TeardownVPC(context.Background(), svc, "vpc-abcdef12", opts)
+
+		assert.Nil(t, err)
+		assert.True(t, report.Complete())
+		assert.Contains(t, teardownIDs(report.Deleted), "nic-abcdef12")
+	})
+
+	t.Run("GetVPCError_ReturnsError", func(t *testing.T) {
+		svc := newFakeVPCService()
+
+		_, err := TeardownVPC(context.Background(), svc, "vpc-unknown", opts)
+
+		assert.NotNil(t, err)
+		assert.IsType(t, &VPCNotFoundError{}, errors.Unwrap(err))
+	})
+
+	t.Run("InvalidVPCID_ReturnsError", func(t *testing.T) {
+		_, err := TeardownVPC(context.Background(), newFakeVPCService(), "", opts)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid vpc id", err.Error())
+	})
+}
@@ -0,0 +1,310 @@
+package ecloud
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// VPNCryptoProfile holds the IKE and ESP settings for a VPN peer. The eCloud API doesn't expose the
+// crypto settings of a VPN profile group, so these are supplied locally
+type VPNCryptoProfile struct {
+	// IKEVersion is 1 or 2
+	IKEVersion int `json:"ike_version" yaml:"ike_version"`
+	// IKEProposals and ESPProposals are strongSwan proposal strings, e.g. aes256-sha256-modp2048
+	IKEProposals []string `json:"ike_proposals" yaml:"ike_proposals"`
+	ESPProposals []string `json:"esp_proposals" yaml:"esp_proposals"`
+	// IKELifetime and ChildLifetime are rekey intervals in seconds
+	IKELifetime   int `json:"ike_lifetime" yaml:"ike_lifetime"`
+	ChildLifetime int `json:"child_lifetime" yaml:"child_lifetime"`
+	// DPDDelay is the dead peer detection interval in seconds. Zero disables DPD
+	DPDDelay int `json:"dpd_delay" yaml:"dpd_delay"`
+}
+
+// DefaultVPNCryptoProfile is used for sessions whose profile group has no entry in
+// VPNPeerConfigOptions.Profiles
+var DefaultVPNCryptoProfile = VPNCryptoProfile{
+	IKEVersion:    2,
+	IKEProposals:  []string{"aes256-sha256-modp2048"},
+	ESPProposals:  []string{"aes256-sha256-modp2048"},
+	IKELifetime:   86400,
+	ChildLifetime: 3600,
+	DPDDelay:      30,
+}
+
+// VPNPeerConfig is the configuration for the customer side of an eCloud VPN session. Local refers
+// to the customer gateway and networks, Remote to the eCloud VPN endpoint and networks
+type VPNPeerConfig struct {
+	Name             string           `json:"name"`
+	SessionID        string           `json:"session_id"`
+	ProfileGroupID   string           `json:"profile_group_id"`
+	ProfileGroupName string           `json:"profile_group_name"`
+	LocalAddress     string           `json:"local_address"`
+	LocalSubnets     []string         `json:"local_subnets"`
+	RemoteAddress    string           `json:"remote_address"`
+	RemoteSubnets    []string         `json:"remote_subnets"`
+	PreSharedKey     string           `json:"pre_shared_key"`
+	Profile          VPNCryptoProfile `json:"profile"`
+}
+
+// VPNPeerConfigOptions configures GetVPNPeerConfig
+type VPNPeerConfigOptions struct {
+	// Profiles maps VPN profile group IDs or names to crypto settings
+	Profiles map[string]VPNCryptoProfile
+}
+
+// GetVPNPeerConfig assembles the peer configuration for VPN session with ID sessionID from the
+// session, its endpoint's floating IP, its profile group and its pre-shared key
+func GetVPNPeerConfig(svc ECloudService, sessionID string, opts VPNPeerConfigOptions) (*VPNPeerConfig, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("invalid vpn session id")
+	}
+
+	session, err := svc.GetVPNSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve vpn session [%s]: %w", sessionID, err)
+	}
+
+	endpoint, err := svc.GetVPNEndpoint(session.VPNEndpointID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve vpn endpoint [%s]: %w", session.VPNEndpointID, err)
+	}
+	fip, err := svc.GetFloatingIP(endpoint.FloatingIPID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve floating IP [%s] for vpn endpoint [%s]: %w", endpoint.FloatingIPID, endpoint.ID, err)
+	}
+
+	group, err := svc.GetVPNProfileGroup(session.VPNProfileGroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve vpn profile group [%s]: %w", session.VPNProfileGroupID, err)
+	}
+
+	psk, err := svc.GetVPNSessionPreSharedKey(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve pre-shared key for vpn session [%s]: %w", sessionID, err)
+	}
+
+	profile, ok := opts.Profiles[group.ID]
+	if !ok {
+		profile, ok = opts.Profiles[group.Name]
+	}
+	if !ok {
+		profile = DefaultVPNCryptoProfile
+	}
+
+	return &VPNPeerConfig{
+		Name:             session.Name,
+		SessionID:        session.ID,
+		ProfileGroupID:   group.ID,
+		ProfileGroupName: group.Name,
+		LocalAddress:     session.RemoteIP.String(),
+		LocalSubnets:     splitSubnets(session.RemoteNetworks),
+		RemoteAddress:    fip.IPAddress,
+		RemoteSubnets:    splitSubnets(session.LocalNetworks),
+		PreSharedKey:     psk.PSK,
+		Profile:          profile,
+	}, nil
+}
+
+func splitSubnets(s string) []string {
+	var subnets []string
+	for _, subnet := range strings.Split(s, ",") {
+		subnet = strings.TrimSpace(subnet)
+		if subnet != "" {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return subnets
+}
+
+var vpnPeerConnNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// connName returns a connection name safe for use in strongSwan configuration
+func (c *VPNPeerConfig) connName() string {
+	name := strings.Trim(vpnPeerConnNameRegexp.ReplaceAllString(c.Name, "-"), "-")
+	if name == "" {
+		return c.SessionID
+	}
+	return name
+}
+
+// vpnPeerChild is a pair of traffic selectors negotiated as a single child SA
+type vpnPeerChild struct {
+	name   string
+	local  []string
+	remote []string
+}
+
+// children returns the child SAs for the connection. IKEv2 negotiates multiple subnets per side in
+// a single child SA, whereas IKEv1 requires a child SA per pair of subnets
+func (c *VPNPeerConfig) children() []vpnPeerChild {
+	name := c.connName()
+	if c.Profile.IKEVersion != 1 || (len(c.LocalSubnets) <= 1 && len(c.RemoteSubnets) <= 1) {
+		return []vpnPeerChild{{name: name, local: c.LocalSubnets, remote: c.RemoteSubnets}}
+	}
+
+	var children []vpnPeerChild
+	for _, local := range c.LocalSubnets {
+		for _, remote := range c.RemoteSubnets {
+			children = append(children, vpnPeerChild{
+				name:   fmt.Sprintf("%s-%d", name, len(children)+1),
+				local:  []string{local},
+				remote: []string{remote},
+			})
+		}
+	}
+	return children
+}
+
+// secret returns the pre-shared key quoted for strongSwan configuration, falling back to base64
+// encoding for keys which can't be safely quoted
+func (c *VPNPeerConfig) secret() string {
+	if strings.ContainsAny(c.PreSharedKey, "\"\\\n\r") {
+		return "0s" + base64.StdEncoding.EncodeToString([]byte(c.PreSharedKey))
+	}
+	return `"` + c.PreSharedKey + `"`
+}
+
+func (c *VPNPeerConfig) validate() error {
+	if c.LocalAddress == "" || c.RemoteAddress == "" {
+		return fmt.Errorf("vpn peer config requires local and remote addresses")
+	}
+	if len(c.LocalSubnets) == 0 || len(c.RemoteSubnets) == 0 {
+		return fmt.Errorf("vpn peer config requires local and remote subnets")
+	}
+	if c.Profile.IKEVersion != 1 && c.Profile.IKEVersion != 2 {
+		return fmt.Errorf("invalid ike version [%d]", c.Profile.IKEVersion)
+	}
+	return nil
+}
+
+// WriteSwanctl writes the configuration to w in strongSwan swanctl.conf format, including the
+// pre-shared key in a secrets section
+func (c *VPNPeerConfig) WriteSwanctl(w io.Writer) error {
+	err := c.validate()
+	if err != nil {
+		return err
+	}
+
+	name := c.connName()
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "# eCloud VPN session %s (%s)\n", c.SessionID, c.Name)
+	fmt.Fprintf(b, "# Profile group %s (%s)\n", c.ProfileGroupID, c.ProfileGroupName)
+	fmt.Fprintf(b, "connections {\n")
+	fmt.Fprintf(b, "    %s {\n", name)
+	fmt.Fprintf(b, "        version = %d\n", c.Profile.IKEVersion)
+	fmt.Fprintf(b, "        local_addrs = %s\n", c.LocalAddress)
+	fmt.Fprintf(b, "        remote_addrs = %s\n", c.RemoteAddress)
+	fmt.Fprintf(b, "        proposals = %s\n", strings.Join(c.Profile.IKEProposals, ","))
+	fmt.Fprintf(b, "        rekey_time = %ds\n", c.Profile.IKELifetime)
+	if c.Profile.DPDDelay > 0 {
+		fmt.Fprintf(b, "        dpd_delay = %ds\n", c.Profile.DPDDelay)
+	}
+	fmt.Fprintf(b, "        local {\n            auth = psk\n            id = %s\n        }\n", c.LocalAddress)
+	fmt.Fprintf(b, "        remote {\n            auth = psk\n            id = %s\n        }\n", c.RemoteAddress)
+	fmt.Fprintf(b, "        children {\n")
+	for _, child := range c.children() {
+		fmt.Fprintf(b, "            %s {\n", child.name)
+		fmt.Fprintf(b, "                local_ts = %s\n", strings.Join(child.local, ","))
+		fmt.Fprintf(b, "                remote_ts = %s\n", strings.Join(child.remote, ","))
+		fmt.Fprintf(b, "                esp_proposals = %s\n", strings.Join(c.Profile.ESPProposals, ","))
+		fmt.Fprintf(b, "                rekey_time = %ds\n", c.Profile.ChildLifetime)
+		if c.Profile.DPDDelay > 0 {
+			fmt.Fprintf(b, "                dpd_action = restart\n")
+		}
+		fmt.Fprintf(b, "                start_action = start\n")
+		fmt.Fprintf(b, "            }\n")
+	}
+	fmt.Fprintf(b, "        }\n")
+	fmt.Fprintf(b, "    }\n")
+	fmt.Fprintf(b, "}\n\n")
+	fmt.Fprintf(b, "secrets {\n")
+	fmt.Fprintf(b, "    ike-%s {\n", name)
+	fmt.Fprintf(b, "        id-local = %s\n", c.LocalAddress)
+	fmt.Fprintf(b, "        id-remote = %s\n", c.RemoteAddress)
+	fmt.Fprintf(b, "        secret = %s\n", c.secret())
+	fmt.Fprintf(b, "    }\n")
+	fmt.Fprintf(b, "}\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// WriteIPsecConf writes the configuration to w in legacy strongSwan ipsec.conf format. The
+// pre-shared key is written separately by WriteIPsecSecrets
+func (c *VPNPeerConfig) WriteIPsecConf(w io.Writer) error {
+	err := c.validate()
+	if err != nil {
+		return err
+	}
+
+	name := c.connName()
+	children := c.children()
+	keyexchange := "ikev2"
+	if c.Profile.IKEVersion == 1 {
+		keyexchange = "ikev1"
+	}
+
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "# eCloud VPN session %s (%s)\n", c.SessionID, c.Name)
+	fmt.Fprintf(b, "# Profile group %s (%s)\n", c.ProfileGroupID, c.ProfileGroupName)
+
+	// With multiple child SAs, common settings are held in a base conn included by each child
+	base := name
+	if len(children) > 1 {
+		base = name + "-base"
+	}
+	fmt.Fprintf(b, "conn %s\n", base)
+	fmt.Fprintf(b, "    keyexchange=%s\n", keyexchange)
+	fmt.Fprintf(b, "    authby=psk\n")
+	fmt.Fprintf(b, "    left=%s\n", c.LocalAddress)
+	fmt.Fprintf(b, "    leftid=%s\n", c.LocalAddress)
+	fmt.Fprintf(b, "    right=%s\n", c.RemoteAddress)
+	fmt.Fprintf(b, "    rightid=%s\n", c.RemoteAddress)
+	fmt.Fprintf(b, "    ike=%s!\n", strings.Join(c.Profile.IKEProposals, ","))
+	fmt.Fprintf(b, "    esp=%s!\n", strings.Join(c.Profile.ESPProposals, ","))
+	fmt.Fprintf(b, "    ikelifetime=%ds\n", c.Profile.IKELifetime)
+	fmt.Fprintf(b, "    lifetime=%ds\n", c.Profile.ChildLifetime)
+	if c.Profile.DPDDelay > 0 {
+		fmt.Fprintf(b, "    dpddelay=%ds\n", c.Profile.DPDDelay)
+		fmt.Fprintf(b, "    dpdaction=restart\n")
+	}
+	if len(children) > 1 {
+		fmt.Fprintf(b, "    auto=ignore\n")
+		for _, child := range children {
+			fmt.Fprintf(b, "\nconn %s\n", child.name)
+			fmt.Fprintf(b, "    also=%s\n", base)
+			fmt.Fprintf(b, "    leftsubnet=%s\n", strings.Join(child.local, ","))
+			fmt.Fprintf(b, "    rightsubnet=%s\n", strings.Join(child.remote, ","))
+			fmt.Fprintf(b, "    auto=start\n")
+		}
+	} else {
+		fmt.Fprintf(b, "    leftsubnet=%s\n", strings.Join(children[0].local, ","))
+		fmt.Fprintf(b, "    rightsubnet=%s\n", strings.Join(children[0].remote, ","))
+		fmt.Fprintf(b, "    auto=start\n")
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// WriteIPsecSecrets writes the pre-shared key to w in legacy strongSwan ipsec.secrets format
+func (c *VPNPeerConfig) WriteIPsecSecrets(w io.Writer) error {
+	err := c.validate()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "# eCloud VPN session %s (%s)\n%s %s : PSK %s\n", c.SessionID, c.Name, c.LocalAddress, c.RemoteAddress, c.secret())
+	return err
+}
+
+// WriteJSON writes the configuration to w as indented JSON
+func (c *VPNPeerConfig) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
@@ -0,0 +1,162 @@
+package ecloud
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// assertGolden compares got with the golden file testdata/name, rewriting the file when -update is set
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.Nil(t, os.WriteFile(path, got, 0o644))
+	}
+
+	expected, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), string(got))
+}
+
+type fakeVPNPeerService struct {
+	ECloudService
+
+	session VPNSession
+	psk     string
+}
+
+func (f *fakeVPNPeerService) GetVPNSession(sessionID string) (VPNSession, error) {
+	if sessionID != f.session.ID {
+		return VPNSession{}, &VPNSessionNotFoundError{ID: sessionID}
+	}
+	return f.session, nil
+}
+
+func (f *fakeVPNPeerService) GetVPNEndpoint(endpointID string) (VPNEndpoint, error) {
+	return VPNEndpoint{ID: endpointID, FloatingIPID: "fip-abcdef12"}, nil
+}
+
+func (f *fakeVPNPeerService) GetFloatingIP(fipID string) (FloatingIP, error) {
+	return FloatingIP{ID: fipID, IPAddress: "203.0.113.10"}, nil
+}
+
+func (f *fakeVPNPeerService) GetVPNProfileGroup(groupID string) (VPNProfileGroup, error) {
+	return VPNProfileGroup{ID: groupID, Name: "legacy"}, nil
+}
+
+func (f *fakeVPNPeerService) GetVPNSessionPreSharedKey(sessionID string) (VPNSessionPreSharedKey, error) {
+	return VPNSessionPreSharedKey{PSK: f.psk}, nil
+}
+
+func newFakeVPNPeerService() *fakeVPNPeerService {
+	return &fakeVPNPeerService{
+		session: VPNSession{
+			ID:                "vpns-abcdef12",
+			Name:              "office link",
+			VPNProfileGroupID: "vpnpg-abcdef12",
+			VPNEndpointID:     "vpne-abcdef12",
+			RemoteIP:          "198.51.100.20",
+			RemoteNetworks:    "192.168.0.0/24, 192.168.10.0/24",
+			LocalNetworks:     "10.0.0.0/24,10.0.1.0/24",
+		},
+		psk: "s3cr3t",
+	}
+}
+
+func TestGetVPNPeerConfig(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		c, err := GetVPNPeerConfig(newFakeVPNPeerService(), "vpns-abcdef12", VPNPeerConfigOptions{})
+
+		assert.Nil(t, err)
+		assert.Equal(t, &VPNPeerConfig{
+			Name:             "office link",
+			SessionID:        "vpns-abcdef12",
+			ProfileGroupID:   "vpnpg-abcdef12",
+			ProfileGroupName: "legacy",
+			LocalAddress:     "198.51.100.20",
+			LocalSubnets:     []string{"192.168.0.0/24", "192.168.10.0/24"},
+			RemoteAddress:    "203.0.113.10",
+			RemoteSubnets:    []string{"10.0.0.0/24", "10.0.1.0/24"},
+			PreSharedKey:     "s3cr3t",
+			Profile:          DefaultVPNCryptoProfile,
+		}, c)
+	})
+
+	t.Run("ProfileByGroupName", func(t *testing.T) {
+		profile := VPNCryptoProfile{IKEVersion: 1}
+
+		c, err := GetVPNPeerConfig(newFakeVPNPeerService(), "vpns-abcdef12", VPNPeerConfigOptions{Profiles: map[string]VPNCryptoProfile{"legacy": profile}})
+
+		assert.Nil(t, err)
+		assert.Equal(t, profile, c.Profile)
+	})
+
+	t.Run("UnknownSession_ReturnsError", func(t *testing.T) {
+		_, err := GetVPNPeerConfig(newFakeVPNPeerService(), "vpns-unknown", VPNPeerConfigOptions{})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "failed to retrieve vpn session [vpns-unknown]: VPN session not found with ID [vpns-unknown]", err.Error())
+	})
+}
+
+func TestVPNPeerConfig_Write(t *testing.T) {
+	ikev1 := VPNCryptoProfile{
+		IKEVersion:    1,
+		IKEProposals:  []string{"aes128-sha1-modp1024"},
+		ESPProposals:  []string{"aes128-sha1"},
+		IKELifetime:   28800,
+		ChildLifetime: 3600,
+	}
+	opts := map[string]VPNPeerConfigOptions{
+		"ikev2": {},
+		"ikev1": {Profiles: map[string]VPNCryptoProfile{"vpnpg-abcdef12": ikev1}},
+	}
+	writers := map[string]func(c *VPNPeerConfig, w io.Writer) error{
+		"swanctl.conf":  (*VPNPeerConfig).WriteSwanctl,
+		"ipsec.conf":    (*VPNPeerConfig).WriteIPsecConf,
+		"ipsec.secrets": (*VPNPeerConfig).WriteIPsecSecrets,
+		"peer.json":     (*VPNPeerConfig).WriteJSON,
+	}
+
+	for version, opts := range opts {
+		for file, write := range writers {
+			t.Run(version+"/"+file, func(t *testing.T) {
+				c, err := GetVPNPeerConfig(newFakeVPNPeerService(), "vpns-abcdef12", opts)
+				assert.Nil(t, err)
+				buf := new(bytes.Buffer)
+
+				err = write(c, buf)
+
+				assert.Nil(t, err)
+				assertGolden(t, filepath.Join("vpn_peer", version, file), buf.Bytes())
+			})
+		}
+	}
+
+	t.Run("UnquotableSecret_Base64Encoded", func(t *testing.T) {
+		c := &VPNPeerConfig{LocalAddress: "198.51.100.20", LocalSubnets: []string{"192.168.0.0/24"}, RemoteAddress: "203.0.113.10", RemoteSubnets: []string{"10.0.0.0/24"}, PreSharedKey: `a"b`, Profile: DefaultVPNCryptoProfile, SessionID: "vpns-abcdef12"}
+		buf := new(bytes.Buffer)
+
+		err := c.WriteIPsecSecrets(buf)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "# eCloud VPN session vpns-abcdef12 ()\n198.51.100.20 203.0.113.10 : PSK 0sYSJi\n", buf.String())
+	})
+
+	t.Run("MissingSubnets_ReturnsError", func(t *testing.T) {
+		c := &VPNPeerConfig{LocalAddress: "198.51.100.20", RemoteAddress: "203.0.113.10", Profile: DefaultVPNCryptoProfile}
+
+		err := c.WriteSwanctl(new(bytes.Buffer))
+
+		assert.NotNil(t, err)
+	})
+}
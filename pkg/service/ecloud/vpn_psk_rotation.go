@@ -0,0 +1,215 @@
+package ecloud
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	vpnPreSharedKeyLength = 32
+	// vpnPreSharedKeyCharset is restricted to alphanumerics, which are accepted by the API and by
+	// third-party VPN devices without escaping
+	vpnPreSharedKeyCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// GenerateVPNPreSharedKey returns a random 32 character alphanumeric pre-shared key generated with
+// crypto/rand
+func GenerateVPNPreSharedKey() (string, error) {
+	max := big.NewInt(int64(len(vpnPreSharedKeyCharset)))
+	key := make([]byte, vpnPreSharedKeyLength)
+	for i := range key {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate pre-shared key: %w", err)
+		}
+		key[i] = vpnPreSharedKeyCharset[n.Int64()]
+	}
+	return string(key), nil
+}
+
+// VPNPreSharedKeyFingerprint returns the SHA256 fingerprint of psk, suitable for recording in
+// place of the key
+func VPNPreSharedKeyFingerprint(psk string) string {
+	sum := sha256.Sum256([]byte(psk))
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+type VPNPSKRotationOutcome string
+
+func (s VPNPSKRotationOutcome) String() string {
+	return string(s)
+}
+
+const (
+	VPNPSKRotationOutcomeRotated VPNPSKRotationOutcome = "rotated"
+	// VPNPSKRotationOutcomeFailed indicates the rotation failed before the key was changed
+	VPNPSKRotationOutcomeFailed VPNPSKRotationOutcome = "failed"
+	// VPNPSKRotationOutcomeRolledBack indicates the key was changed but the update task or
+	// verification failed, and the previous key was restored
+	VPNPSKRotationOutcomeRolledBack VPNPSKRotationOutcome = "rolled_back"
+	// VPNPSKRotationOutcomeRollbackFailed indicates the previous key couldn't be restored, leaving
+	// the session's key unknown
+	VPNPSKRotationOutcomeRollbackFailed VPNPSKRotationOutcome = "rollback_failed"
+)
+
+// VPNPSKAuditRecord records a pre-shared key rotation attempt. Keys are recorded as fingerprints only
+type VPNPSKAuditRecord struct {
+	SessionID           string                `json:"session_id"`
+	Timestamp           time.Time             `json:"timestamp"`
+	Outcome             VPNPSKRotationOutcome `json:"outcome"`
+	Fingerprint         string                `json:"fingerprint,omitempty"`
+	PreviousFingerprint string                `json:"previous_fingerprint,omitempty"`
+	Error               string                `json:"error,omitempty"`
+}
+
+// VPNPSKRotationOptions configures RotateVPNSessionPreSharedKey and RotateVPNServicePreSharedKeys
+type VPNPSKRotationOptions struct {
+	// Wait configures polling for update tasks. Wait.Clock is also used for audit timestamps
+	Wait WaitOptions
+	// Generate overrides key generation, defaulting to GenerateVPNPreSharedKey
+	Generate func() (string, error)
+	// Audit is invoked with a record of each rotation attempt, successful or not
+	Audit func(record VPNPSKAuditRecord)
+	// RollbackTimeout is the maximum time to wait for the previous key to be restored, defaulting
+	// to 5 minutes. Rollback ignores cancellation of the context, which may have caused the failure
+	RollbackTimeout time.Duration
+}
+
+const defaultVPNPSKRollbackTimeout = 5 * time.Minute
+
+// RotateVPNSessionPreSharedKey replaces the pre-shared key of VPN session with ID sessionID with a
+// newly generated key, waiting for the update task and verifying the key by reading it back. If the
+// update task fails or verification fails, the previous key is restored
+func RotateVPNSessionPreSharedKey(ctx context.Context, svc ECloudService, sessionID string, opts VPNPSKRotationOptions) (VPNPSKAuditRecord, error) {
+	record := VPNPSKAuditRecord{SessionID: sessionID, Outcome: VPNPSKRotationOutcomeFailed}
+	err := rotateVPNSessionPreSharedKey(ctx, svc, sessionID, opts, &record)
+	if err != nil {
+		record.Error = err.Error()
+	}
+	record.Timestamp = clockOrDefault(opts.Wait.Clock).Now().UTC()
+	if opts.Audit != nil {
+		opts.Audit(record)
+	}
+	return record, err
+}
+
+func rotateVPNSessionPreSharedKey(ctx context.Context, svc ECloudService, sessionID string, opts VPNPSKRotationOptions, record *VPNPSKAuditRecord) error {
+	if sessionID == "" {
+		return fmt.Errorf("invalid vpn session id")
+	}
+
+	previous, err := svc.GetVPNSessionPreSharedKey(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve pre-shared key for vpn session [%s]: %w", sessionID, err)
+	}
+	record.PreviousFingerprint = VPNPreSharedKeyFingerprint(previous.PSK)
+
+	generate := opts.Generate
+	if generate == nil {
+		generate = GenerateVPNPreSharedKey
+	}
+	psk, err := generate()
+	if err != nil {
+		return err
+	}
+	if psk == previous.PSK {
+		return fmt.Errorf("generated pre-shared key matches existing key")
+	}
+	record.Fingerprint = VPNPreSharedKeyFingerprint(psk)
+
+	err = updateVPNSessionPreSharedKey(ctx, svc, sessionID, psk, opts)
+	if err == nil {
+		err = verifyVPNSessionPreSharedKey(svc, sessionID, psk)
+	}
+	if err == nil {
+		record.Outcome = VPNPSKRotationOutcomeRotated
+		return nil
+	}
+
+	var rejected *vpnPSKUpdateRejectedError
+	if errors.As(err, &rejected) {
+		// The update wasn't accepted, so the previous key remains in place
+		return rejected.err
+	}
+
+	rollbackOpts := opts
+	rollbackOpts.Wait.Timeout = opts.RollbackTimeout
+	if rollbackOpts.Wait.Timeout <= 0 {
+		rollbackOpts.Wait.Timeout = defaultVPNPSKRollbackTimeout
+	}
+	rollbackErr := updateVPNSessionPreSharedKey(context.WithoutCancel(ctx), svc, sessionID, previous.PSK, rollbackOpts)
+	if rollbackErr == nil {
+		rollbackErr = verifyVPNSessionPreSharedKey(svc, sessionID, previous.PSK)
+	}
+	if rollbackErr != nil {
+		record.Outcome = VPNPSKRotationOutcomeRollbackFailed
+		return fmt.Errorf("%w, and failed to restore previous key: %s", err, rollbackErr)
+	}
+	record.Outcome = VPNPSKRotationOutcomeRolledBack
+	return err
+}
+
+// vpnPSKUpdateRejectedError indicates the API rejected a pre-shared key update without starting a task
+type vpnPSKUpdateRejectedError struct {
+	err error
+}
+
+func (e *vpnPSKUpdateRejectedError) Error() string {
+	return e.err.Error()
+}
+
+func updateVPNSessionPreSharedKey(ctx context.Context, svc ECloudService, sessionID string, psk string, opts VPNPSKRotationOptions) error {
+	task, err := svc.UpdateVPNSessionPreSharedKey(sessionID, UpdateVPNSessionPreSharedKeyRequest{PSK: psk})
+	if err != nil {
+		return &vpnPSKUpdateRejectedError{err: fmt.Errorf("failed to update pre-shared key for vpn session [%s]: %w", sessionID, err)}
+	}
+
+	_, err = WaitForTask(ctx, svc, task.TaskID, TaskWaitOptions{WaitOptions: opts.Wait})
+	return err
+}
+
+func verifyVPNSessionPreSharedKey(svc ECloudService, sessionID string, psk string) error {
+	current, err := svc.GetVPNSessionPreSharedKey(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to verify pre-shared key for vpn session [%s]: %w", sessionID, err)
+	}
+	if current.PSK != psk {
+		return fmt.Errorf("pre-shared key for vpn session [%s] does not match after update", sessionID)
+	}
+	return nil
+}
+
+// RotateVPNServicePreSharedKeys rotates the pre-shared key of each session of the VPN service with ID
+// serviceID in turn with RotateVPNSessionPreSharedKey, returning a record for each session attempted.
+// Rotation stops at the first failure, leaving the remaining sessions untouched
+func RotateVPNServicePreSharedKeys(ctx context.Context, svc ECloudService, serviceID string, opts VPNPSKRotationOptions) ([]VPNPSKAuditRecord, error) {
+	if serviceID == "" {
+		return nil, fmt.Errorf("invalid vpn service id")
+	}
+
+	sessions, err := svc.GetVPNSessions(eqFilter("vpn_service_id", serviceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve vpn sessions for vpn service [%s]: %w", serviceID, err)
+	}
+
+	var records []VPNPSKAuditRecord
+	for _, session := range sessions {
+		if ctx.Err() != nil {
+			return records, ctx.Err()
+		}
+
+		record, err := RotateVPNSessionPreSharedKey(ctx, svc, session.ID, opts)
+		records = append(records, record)
+		if err != nil {
+			return records, err
+		}
+	}
+
+	return records, nil
+}
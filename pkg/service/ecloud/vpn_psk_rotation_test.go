@@ -0,0 +1,220 @@
+package ecloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePSKService struct {
+	ECloudService
+
+	sessions []VPNSession
+	psks     map[string]string
+	// failTasks fails the update task for the given sessions after applying the key
+	failTasks map[string]bool
+	// ignoreUpdates accepts updates for the given sessions without applying them
+	ignoreUpdates map[string]bool
+	rejectUpdates map[string]bool
+	// rejectRollback rejects any update after the first
+	rejectRollback bool
+	// pendingPolls reports the given tasks as in progress for a number of polls
+	pendingPolls map[string]int
+
+	updates []string
+}
+
+func (f *fakePSKService) GetVPNSessions(parameters connection.APIRequestParameters) ([]VPNSession, error) {
+	return f.sessions, nil
+}
+
+func (f *fakePSKService) GetVPNSessionPreSharedKey(sessionID string) (VPNSessionPreSharedKey, error) {
+	return VPNSessionPreSharedKey{PSK: f.psks[sessionID]}, nil
+}
+
+func (f *fakePSKService) UpdateVPNSessionPreSharedKey(sessionID string, req UpdateVPNSessionPreSharedKeyRequest) (TaskReference, error) {
+	if f.rejectUpdates[sessionID] || (f.rejectRollback && len(f.updates) > 0) {
+		return TaskReference{}, errors.New("test error")
+	}
+	f.updates = append(f.updates, fmt.Sprintf("%s=%s", sessionID, req.PSK))
+	if !f.ignoreUpdates[sessionID] {
+		f.psks[sessionID] = req.PSK
+	}
+	taskID := fmt.Sprintf("task-%d", len(f.updates))
+	if f.failTasks[sessionID] && len(f.updates) == 1 {
+		taskID = "task-failed"
+	}
+	return TaskReference{TaskID: taskID}, nil
+}
+
+func (f *fakePSKService) GetTask(taskID string) (Task, error) {
+	if taskID == "task-failed" {
+		return Task{ID: taskID, Status: TaskStatusFailed}, nil
+	}
+	if f.pendingPolls[taskID] > 0 {
+		f.pendingPolls[taskID]--
+		return Task{ID: taskID, Status: TaskStatusInProgress}, nil
+	}
+	return Task{ID: taskID, Status: TaskStatusComplete}, nil
+}
+
+func newFakePSKService() *fakePSKService {
+	return &fakePSKService{
+		sessions: []VPNSession{{ID: "vpns-00000001"}, {ID: "vpns-00000002"}},
+		psks:     map[string]string{"vpns-00000001": "old1", "vpns-00000002": "old2"},
+	}
+}
+
+func TestGenerateVPNPreSharedKey(t *testing.T) {
+	a, err := GenerateVPNPreSharedKey()
+	assert.Nil(t, err)
+	b, err := GenerateVPNPreSharedKey()
+	assert.Nil(t, err)
+
+	assert.Regexp(t, regexp.MustCompile(`^[A-Za-z0-9]{32}$`), a)
+	assert.NotEqual(t, a, b)
+}
+
+func TestVPNPreSharedKeyFingerprint(t *testing.T) {
+	assert.Equal(t, "SHA256:LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ", VPNPreSharedKeyFingerprint("hello"))
+}
+
+func TestRotateVPNSessionPreSharedKey(t *testing.T) {
+	clock := newFakeClock()
+	opts := VPNPSKRotationOptions{
+		Wait:     WaitOptions{Interval: time.Second, Clock: clock},
+		Generate: func() (string, error) { return "new", nil },
+	}
+
+	t.Run("Valid_RotatesKey", func(t *testing.T) {
+		svc := newFakePSKService()
+		var audited []VPNPSKAuditRecord
+		opts := opts
+		opts.Audit = func(record VPNPSKAuditRecord) { audited = append(audited, record) }
+
+		record, err := RotateVPNSessionPreSharedKey(context.Background(), svc, "vpns-00000001", opts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, VPNPSKAuditRecord{
+			SessionID:           "vpns-00000001",
+			Timestamp:           clock.Now().UTC(),
+			Outcome:             VPNPSKRotationOutcomeRotated,
+			Fingerprint:         VPNPreSharedKeyFingerprint("new"),
+			PreviousFingerprint: VPNPreSharedKeyFingerprint("old1"),
+		}, record)
+		assert.Equal(t, []VPNPSKAuditRecord{record}, audited)
+		assert.Equal(t, "new", svc.psks["vpns-00000001"])
+	})
+
+	t.Run("TaskFailed_RollsBack", func(t *testing.T) {
+		svc := newFakePSKService()
+		svc.failTasks = map[string]bool{"vpns-00000001": true}
+
+		record, err := RotateVPNSessionPreSharedKey(context.Background(), svc, "vpns-00000001", opts)
+
+		assert.IsType(t, &TaskFailedError{}, err)
+		assert.Equal(t, VPNPSKRotationOutcomeRolledBack, record.Outcome)
+		assert.Equal(t, []string{"vpns-00000001=new", "vpns-00000001=old1"}, svc.updates)
+		assert.Equal(t, "old1", svc.psks["vpns-00000001"])
+	})
+
+	t.Run("ContextCancelled_RollsBack", func(t *testing.T) {
+		svc := newFakePSKService()
+		svc.pendingPolls = map[string]int{"task-1": 100, "task-2": 10}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		opts := opts
+		opts.Wait.Timeout = 3 * time.Second
+
+		record, err := RotateVPNSessionPreSharedKey(ctx, svc, "vpns-00000001", opts)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, VPNPSKRotationOutcomeRolledBack, record.Outcome)
+		assert.Equal(t, []string{"vpns-00000001=new", "vpns-00000001=old1"}, svc.updates)
+		assert.Equal(t, "old1", svc.psks["vpns-00000001"])
+	})
+
+	t.Run("VerificationFailed_RollsBack", func(t *testing.T) {
+		svc := newFakePSKService()
+		svc.ignoreUpdates = map[string]bool{"vpns-00000001": true}
+
+		record, err := RotateVPNSessionPreSharedKey(context.Background(), svc, "vpns-00000001", opts)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, VPNPSKRotationOutcomeRolledBack, record.Outcome)
+		assert.Equal(t, "pre-shared key for vpn session [vpns-00000001] does not match after update", record.Error)
+	})
+
+	t.Run("RollbackRejected_RollbackFailed", func(t *testing.T) {
+		svc := newFakePSKService()
+		svc.failTasks = map[string]bool{"vpns-00000001": true}
+		svc.rejectRollback = true
+
+		record, err := RotateVPNSessionPreSharedKey(context.Background(), svc, "vpns-00000001", opts)
+
+		assert.IsType(t, &TaskFailedError{}, errors.Unwrap(err))
+		assert.Equal(t, VPNPSKRotationOutcomeRollbackFailed, record.Outcome)
+		assert.Equal(t, "new", svc.psks["vpns-00000001"])
+	})
+
+	t.Run("UpdateRejected_Failed", func(t *testing.T) {
+		svc := newFakePSKService()
+		svc.rejectUpdates = map[string]bool{"vpns-00000001": true}
+
+		record, err := RotateVPNSessionPreSharedKey(context.Background(), svc, "vpns-00000001", opts)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, VPNPSKRotationOutcomeFailed, record.Outcome)
+		assert.Empty(t, svc.updates)
+	})
+
+	t.Run("GeneratedKeyMatchesExisting_ReturnsError", func(t *testing.T) {
+		svc := newFakePSKService()
+		svc.psks["vpns-00000001"] = "new"
+
+		record, err := RotateVPNSessionPreSharedKey(context.Background(), svc, "vpns-00000001", opts)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, VPNPSKRotationOutcomeFailed, record.Outcome)
+		assert.Empty(t, svc.updates)
+	})
+}
+
+func TestRotateVPNServicePreSharedKeys(t *testing.T) {
+	opts := VPNPSKRotationOptions{Wait: WaitOptions{Interval: time.Second, Clock: newFakeClock()}}
+
+	t.Run("RotatesEachSession", func(t *testing.T) {
+		svc := newFakePSKService()
+
+		records, err := RotateVPNServicePreSharedKeys(context.Background(), svc, "vpn-abcdef12", opts)
+
+		assert.Nil(t, err)
+		assert.Len(t, records, 2)
+		assert.NotEqual(t, "old1", svc.psks["vpns-00000001"])
+		assert.NotEqual(t, "old2", svc.psks["vpns-00000002"])
+		assert.NotEqual(t, svc.psks["vpns-00000001"], svc.psks["vpns-00000002"])
+	})
+
+	t.Run("StopsAtFirstFailure", func(t *testing.T) {
+		svc := newFakePSKService()
+		svc.rejectUpdates = map[string]bool{"vpns-00000001": true}
+
+		records, err := RotateVPNServicePreSharedKeys(context.Background(), svc, "vpn-abcdef12", opts)
+
+		assert.NotNil(t, err)
+		assert.Len(t, records, 1)
+		assert.Equal(t, "old2", svc.psks["vpns-00000002"])
+	})
+
+	t.Run("InvalidServiceID_ReturnsError", func(t *testing.T) {
+		_, err := RotateVPNServicePreSharedKeys(context.Background(), newFakePSKService(), "", opts)
+
+		assert.NotNil(t, err)
+	})
+}
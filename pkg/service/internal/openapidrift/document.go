@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document represents the subset of an OpenAPI 3 document used for drift detection
+type Document struct {
+	OpenAPI    string     `json:"openapi" yaml:"openapi"`
+	Components Components `json:"components" yaml:"components"`
+}
+
+// Components represents the components of an OpenAPI 3 document
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas" yaml:"schemas"`
+}
+
+// Schema represents an OpenAPI 3 schema object
+type Schema struct {
+	Ref        string             `json:"$ref" yaml:"$ref"`
+	Type       string             `json:"type" yaml:"type"`
+	Format     string             `json:"format" yaml:"format"`
+	Nullable   bool               `json:"nullable" yaml:"nullable"`
+	Properties map[string]*Schema `json:"properties" yaml:"properties"`
+	Items      *Schema            `json:"items" yaml:"items"`
+	AllOf      []*Schema          `json:"allOf" yaml:"allOf"`
+	Required   []string           `json:"required" yaml:"required"`
+}
+
+// Optional returns true if property name of s is nullable or isn't required
+func (s *Schema) Optional(name string) bool {
+	if prop := s.Properties[name]; prop != nil && prop.Nullable {
+		return true
+	}
+	for _, required := range s.Required {
+		if required == name {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadDocument reads an OpenAPI 3 document in JSON or YAML format from given path
+func LoadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, doc)
+	default:
+		err = yaml.Unmarshal(data, doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version [%s]", doc.OpenAPI)
+	}
+
+	return doc, nil
+}
+
+// RefName returns the schema name referenced by ref, e.g. Instance for #/components/schemas/Instance
+func RefName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// Resolve returns the schema referenced by s, or s where it isn't a reference. Properties of
+// allOf schemas are merged into a single schema
+func (d *Document) Resolve(s *Schema) *Schema {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		return d.Resolve(d.Components.Schemas[RefName(s.Ref)])
+	}
+	if len(s.AllOf) == 0 {
+		return s
+	}
+
+	merged := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, part := range s.AllOf {
+		resolved := d.Resolve(part)
+		if resolved == nil {
+			continue
+		}
+		for name, prop := range resolved.Properties {
+			merged.Properties[name] = prop
+		}
+		merged.Required = append(merged.Required, resolved.Required...)
+	}
+	for name, prop := range s.Properties {
+		merged.Properties[name] = prop
+	}
+	return merged
+}
+
+// Kind returns the basic kind of s, one of object, array, string, integer, number or boolean
+func (d *Document) Kind(s *Schema) string {
+	resolved := d.Resolve(s)
+	if resolved == nil {
+		return ""
+	}
+	if resolved.Type == "" && len(resolved.Properties) > 0 {
+		return "object"
+	}
+	return resolved.Type
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+)
+
+// DriftKind represents the kind of a drift finding
+type DriftKind string
+
+const (
+	DriftKindMissingType   DriftKind = "missing-type"
+	DriftKindMissingField  DriftKind = "missing-field"
+	DriftKindExtraField    DriftKind = "extra-field"
+	DriftKindTypeMismatch  DriftKind = "type-mismatch"
+	DriftKindMissingSchema DriftKind = "missing-schema"
+)
+
+// Drift represents a single difference between an OpenAPI schema and a Go type
+type Drift struct {
+	Kind   DriftKind `json:"kind"`
+	Schema string    `json:"schema"`
+	Type   string    `json:"type"`
+	// Path is the dotted JSON path of the field within the type, e.g. server.port
+	Path     string `json:"path,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	// Optional is true for a missing field whose property is nullable or not required
+	Optional bool `json:"optional,omitempty"`
+}
+
+func (d Drift) String() string {
+	switch d.Kind {
+	case DriftKindMissingType:
+		return fmt.Sprintf("%s: schema [%s] has no Go type [%s]", d.Kind, d.Schema, d.Type)
+	case DriftKindMissingSchema:
+		return fmt.Sprintf("%s: Go type [%s] has no schema [%s]", d.Kind, d.Type, d.Schema)
+	case DriftKindMissingField:
+		return fmt.Sprintf("%s: %s.%s (%s) missing from Go type", d.Kind, d.Type, d.Path, d.Expected)
+	case DriftKindExtraField:
+		return fmt.Sprintf("%s: %s.%s not present in schema [%s]", d.Kind, d.Type, d.Path, d.Schema)
+	default:
+		return fmt.Sprintf("%s: %s.%s expected %s, got %s", d.Kind, d.Type, d.Path, d.Expected, d.Actual)
+	}
+}
+
+// Comparer compares OpenAPI schemas against Go types
+type Comparer struct {
+	Document *Document
+	Package  *Package
+	// Mapping maps schema names to Go type names. Schemas without a mapping are compared
+	// against the Go type of the same name
+	Mapping map[string]string
+}
+
+// typeName returns the Go type name for given schema name
+func (c *Comparer) typeName(schema string) string {
+	if name, ok := c.Mapping[schema]; ok {
+		return name
+	}
+	return schema
+}
+
+// Compare compares all schemas within the document, returning drift findings sorted by type and path
+func (c *Comparer) Compare() []Drift {
+	var drifts []Drift
+	for schemaName, schema := range c.Document.Components.Schemas {
+		if c.Document.Kind(schema) != "object" {
+			continue
+		}
+
+		typeName := c.typeName(schemaName)
+		st, ok := c.Package.Structs[typeName]
+		if !ok {
+			drifts = append(drifts, Drift{Kind: DriftKindMissingType, Schema: schemaName, Type: typeName})
+			continue
+		}
+
+		drifts = append(drifts, c.compareStruct(schemaName, typeName, "", c.Document.Resolve(schema), st)...)
+	}
+
+	for schemaName, typeName := range c.Mapping {
+		if _, ok := c.Document.Components.Schemas[schemaName]; !ok {
+			drifts = append(drifts, Drift{Kind: DriftKindMissingSchema, Schema: schemaName, Type: typeName})
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].Type != drifts[j].Type {
+			return drifts[i].Type < drifts[j].Type
+		}
+		if drifts[i].Path != drifts[j].Path {
+			return drifts[i].Path < drifts[j].Path
+		}
+		return drifts[i].Kind < drifts[j].Kind
+	})
+
+	return drifts
+}
+
+func (c *Comparer) compareStruct(schemaName string, typeName string, prefix string, schema *Schema, st *ast.StructType) []Drift {
+	var drifts []Drift
+	fields := c.Package.Fields(st)
+
+	for propName, prop := range schema.Properties {
+		path := prefix + propName
+		field, ok := fields[propName]
+		if !ok {
+			drifts = append(drifts, Drift{
+				Kind:     DriftKindMissingField,
+				Schema:   schemaName,
+				Type:     typeName,
+				Path:     path,
+				Expected: GoType(c.Document, prop),
+				Optional: schema.Optional(propName),
+			})
+			continue
+		}
+
+		expected := c.Document.Kind(prop)
+		actual := c.Package.Kind(field.Type)
+		if expected == "" || actual == "" {
+			continue
+		}
+		if expected != actual && !(expected == "number" && actual == "integer") {
+			drifts = append(drifts, Drift{
+				Kind:     DriftKindTypeMismatch,
+				Schema:   schemaName,
+				Type:     typeName,
+				Path:     path,
+				Expected: expected,
+				Actual:   TypeString(field.Type),
+			})
+			continue
+		}
+
+		// Recurse into inline structs only, as named structs are compared against their own schema
+		if nested, ok := unwrapStruct(field.Type); ok && expected == "object" {
+			drifts = append(drifts, c.compareStruct(schemaName, typeName, path+".", c.Document.Resolve(prop), nested)...)
+		}
+	}
+
+	for jsonName := range fields {
+		if _, ok := schema.Properties[jsonName]; !ok {
+			drifts = append(drifts, Drift{Kind: DriftKindExtraField, Schema: schemaName, Type: typeName, Path: prefix + jsonName})
+		}
+	}
+
+	return drifts
+}
+
+func unwrapStruct(expr ast.Expr) (*ast.StructType, bool) {
+	switch t := expr.(type) {
+	case *ast.StructType:
+		return t, true
+	case *ast.StarExpr:
+		return unwrapStruct(t.X)
+	}
+	return nil, false
+}
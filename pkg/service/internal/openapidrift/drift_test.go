@@ -0,0 +1,191 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadTestComparer(t *testing.T) *Comparer {
+	doc, err := LoadDocument("testdata/openapi.json")
+	assert.Nil(t, err)
+
+	pkg, err := LoadPackage("testdata/models")
+	assert.Nil(t, err)
+
+	return &Comparer{
+		Document: doc,
+		Package:  pkg,
+		Mapping:  map[string]string{"CreateInstance": "CreateInstanceRequest"},
+	}
+}
+
+func TestComparer_Compare(t *testing.T) {
+	drifts := loadTestComparer(t).Compare()
+
+	var strs []string
+	for _, d := range drifts {
+		strs = append(strs, d.String())
+	}
+
+	assert.Equal(t, []string{
+		"missing-field: CreateInstanceRequest.ssh_key_pair_ids ([]string) missing from Go type",
+		"missing-field: Instance.backup_enabled (*bool) missing from Go type",
+		"extra-field: Instance.legacy_field not present in schema [Instance]",
+		"missing-field: Instance.ram_capacity (int) missing from Go type",
+		"missing-field: Instance.vpc_id (string) missing from Go type",
+		"missing-type: schema [Region] has no Go type [Region]",
+		"type-mismatch: Report.server.port expected integer, got string",
+	}, strs)
+}
+
+func TestComparer_Compare_MissingSchema(t *testing.T) {
+	c := loadTestComparer(t)
+	c.Mapping["Unknown"] = "Instance"
+
+	drifts := c.Compare()
+
+	assert.Contains(t, drifts, Drift{Kind: DriftKindMissingSchema, Schema: "Unknown", Type: "Instance"})
+}
+
+func TestLoadDocument(t *testing.T) {
+	t.Run("UnsupportedVersion_ReturnsError", func(t *testing.T) {
+		path := t.TempDir() + "/swagger.yaml"
+		err := os.WriteFile(path, []byte("swagger: \"2.0\"\n"), 0644)
+		assert.Nil(t, err)
+
+		_, err = LoadDocument(path)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "unsupported openapi version []", err.Error())
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		path := t.TempDir() + "/openapi.yaml"
+		err := os.WriteFile(path, []byte("openapi: 3.1.0\ncomponents:\n  schemas:\n    Foo:\n      type: object\n"), 0644)
+		assert.Nil(t, err)
+
+		doc, err := LoadDocument(path)
+
+		assert.Nil(t, err)
+		assert.Contains(t, doc.Components.Schemas, "Foo")
+	})
+}
+
+func TestFieldName(t *testing.T) {
+	assert.Equal(t, "VPCID", FieldName("vpc_id"))
+	assert.Equal(t, "RAMCapacity", FieldName("ram_capacity"))
+	assert.Equal(t, "SSHKeyPairIDs", FieldName("ssh_key_pair_ids"))
+	assert.Equal(t, "FloatingIPs", FieldName("floating_ips"))
+	assert.Equal(t, "Name", FieldName("name"))
+}
+
+func TestAddFields(t *testing.T) {
+	c := loadTestComparer(t)
+	src, err := os.ReadFile("testdata/models/model.go")
+	assert.Nil(t, err)
+
+	out, _, err := AddFields(src, c.Compare())
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(out), "RAMCapacity   int                 `json:\"ram_capacity\"`")
+	assert.Contains(t, string(out), "BackupEnabled *bool               `json:\"backup_enabled,omitempty\"`")
+	assert.Contains(t, string(out), "VPCID         string              `json:\"vpc_id,omitempty\"`")
+	assert.Contains(t, string(out), "SSHKeyPairIDs []string `json:\"ssh_key_pair_ids,omitempty\"`")
+
+	_, err = parser.ParseFile(token.NewFileSet(), "", out, 0)
+	assert.Nil(t, err)
+
+	// Re-comparing against the updated source should leave no missing fields
+	pkg, err := LoadPackage(writeTestPackage(t, out))
+	assert.Nil(t, err)
+	c.Package = pkg
+	for _, d := range c.Compare() {
+		assert.NotEqual(t, DriftKindMissingField, d.Kind, d.String())
+	}
+}
+
+func TestAddFields_ImportsConnectionAndSkipsNested(t *testing.T) {
+	src := []byte("package models\n\n// Report represents a report\ntype Report struct {\n\tServer struct {\n\t\tIP string `json:\"ip\"`\n\t} `json:\"server\"`\n}\n")
+	drifts := []Drift{
+		{Kind: DriftKindMissingField, Schema: "Report", Type: "Report", Path: "created_at", Expected: "connection.DateTime"},
+		{Kind: DriftKindMissingField, Schema: "Report", Type: "Report", Path: "server.port", Expected: "int"},
+		{Kind: DriftKindMissingField, Schema: "Unknown", Type: "Unknown", Path: "name", Expected: "string"},
+	}
+
+	out, skipped, err := AddFields(src, drifts)
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(out), "import \"github.com/ans-group/sdk-go/pkg/connection\"\n")
+	assert.Contains(t, string(out), "CreatedAt connection.DateTime `json:\"created_at\"`")
+	assert.Equal(t, drifts[1:], skipped)
+
+	_, err = LoadPackage(writeTestPackage(t, out))
+	assert.Nil(t, err)
+}
+
+func TestAddFields_RequestTypesOmitEmpty(t *testing.T) {
+	src := []byte("package models\n\n// PatchReportRequest represents a request to patch a report\ntype PatchReportRequest struct {\n\tName string `json:\"name,omitempty\"`\n}\n")
+	drifts := []Drift{
+		{Kind: DriftKindMissingField, Schema: "PatchReport", Type: "PatchReportRequest", Path: "enabled", Expected: "bool"},
+	}
+
+	out, _, err := AddFields(src, drifts)
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(out), "Enabled bool   `json:\"enabled,omitempty\"`")
+}
+
+func TestAddFields_AddsConnectionToExistingImports(t *testing.T) {
+	drifts := []Drift{
+		{Kind: DriftKindMissingField, Schema: "Report", Type: "Report", Path: "created_at", Expected: "connection.DateTime"},
+	}
+	tests := []struct {
+		name    string
+		imports string
+	}{
+		{name: "Single", imports: "import \"time\""},
+		{name: "Grouped", imports: "import (\n\t\"fmt\"\n\t\"time\"\n)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := []byte("package models\n\n" + tt.imports + "\n\n// Report represents a report\ntype Report struct {\n\tAge time.Duration `json:\"age\"`\n}\n")
+
+			out, _, err := AddFields(src, drifts)
+
+			assert.Nil(t, err)
+			assert.Equal(t, 1, strings.Count(string(out), "import"))
+			assert.Contains(t, string(out), "\t\"github.com/ans-group/sdk-go/pkg/connection\"\n\t\"time\"\n)")
+		})
+	}
+}
+
+func TestGenerateTypes(t *testing.T) {
+	c := loadTestComparer(t)
+
+	out, err := GenerateTypes("models", c.Document, c.Compare())
+
+	assert.Nil(t, err)
+	assert.Equal(t, `package models
+
+import "github.com/ans-group/sdk-go/pkg/connection"
+
+// Region represents a Region
+type Region struct {
+	ID        string              `+"`json:\"id\"`"+`
+	Name      string              `+"`json:\"name\"`"+`
+	UpdatedAt connection.DateTime `+"`json:\"updated_at,omitempty\"`"+`
+}
+`, string(out))
+}
+
+func writeTestPackage(t *testing.T, src []byte) string {
+	dir := t.TempDir()
+	err := os.WriteFile(dir+"/model.go", src, 0644)
+	assert.Nil(t, err)
+	return dir
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// connectionImport is the import path of the package declaring DateTime, Date and IPAddress
+const connectionImport = "github.com/ans-group/sdk-go/pkg/connection"
+
+// initialisms are upper cased when converting JSON names to Go field names
+var initialisms = map[string]bool{
+	"id": true, "ip": true, "url": true, "uri": true, "vpc": true, "vpn": true, "nic": true,
+	"cpu": true, "ram": true, "ssh": true, "ssl": true, "tls": true, "dns": true, "api": true,
+	"http": true, "https": true, "vip": true, "nat": true, "az": true, "iops": true, "ha": true,
+}
+
+// FieldName converts a JSON property name to a Go field name, e.g. vpc_id => VPCID
+func FieldName(jsonName string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(jsonName, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		lower := strings.ToLower(part)
+		if initialisms[lower] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		// Pluralised initialisms, e.g. ids => IDs
+		if singular := strings.TrimSuffix(lower, "s"); singular != lower && initialisms[singular] {
+			b.WriteString(strings.ToUpper(singular) + "s")
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// GoType returns the Go type used to represent schema s
+func GoType(doc *Document, s *Schema) string {
+	if s == nil {
+		return "interface{}"
+	}
+	if s.Ref != "" {
+		return RefName(s.Ref)
+	}
+
+	var t string
+	switch doc.Kind(s) {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			t = "connection.DateTime"
+		case "date":
+			t = "connection.Date"
+		case "ipv4", "ipv6":
+			t = "connection.IPAddress"
+		default:
+			t = "string"
+		}
+	case "integer":
+		t = "int"
+	case "number":
+		t = "float64"
+	case "boolean":
+		t = "bool"
+	case "array":
+		return "[]" + GoType(doc, s.Items)
+	case "object":
+		return "map[string]interface{}"
+	default:
+		return "interface{}"
+	}
+
+	if s.Nullable {
+		return "*" + t
+	}
+	return t
+}
+
+// jsonTag returns the struct tag of a field for JSON property name. Fields of request types, and
+// of optional properties, are tagged omitempty so that e.g. a patch request leaves attributes it
+// doesn't set unchanged
+func jsonTag(typeName string, name string, optional bool) string {
+	if optional || strings.HasSuffix(typeName, "Request") {
+		return fmt.Sprintf("`json:\"%s,omitempty\"`", name)
+	}
+	return fmt.Sprintf("`json:\"%s\"`", name)
+}
+
+// GenerateTypes returns Go source declaring the types reported as missing by drifts
+func GenerateTypes(pkgName string, doc *Document, drifts []Drift) ([]byte, error) {
+	missing := make(map[string]string)
+	for _, d := range drifts {
+		if d.Kind == DriftKindMissingType {
+			missing[d.Type] = d.Schema
+		}
+	}
+
+	body := &bytes.Buffer{}
+	for _, typeName := range sortedKeys(missing) {
+		schema := doc.Resolve(doc.Components.Schemas[missing[typeName]])
+		fmt.Fprintf(body, "\n// %s represents a %s\ntype %s struct {\n", typeName, missing[typeName], typeName)
+		for _, propName := range sortedKeys(schema.Properties) {
+			fmt.Fprintf(body, "\t%s %s %s\n", FieldName(propName), GoType(doc, schema.Properties[propName]), jsonTag(typeName, propName, schema.Optional(propName)))
+		}
+		body.WriteString("}\n")
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "package %s\n", pkgName)
+	if strings.Contains(body.String(), "connection.") {
+		fmt.Fprintf(buf, "\nimport %q\n", connectionImport)
+	}
+	buf.Write(body.Bytes())
+
+	return format.Source(buf.Bytes())
+}
+
+// AddFields returns src with the top-level fields reported as missing by drifts appended to
+// their struct declarations, importing the connection package where an added field requires it.
+// Missing fields of inline nested structs, or of types not declared in src, can't be added and are
+// returned as skipped
+func AddFields(src []byte, drifts []Drift) ([]byte, []Drift, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var skipped []Drift
+	missing := make(map[string][]Drift)
+	for _, d := range drifts {
+		if d.Kind != DriftKindMissingField {
+			continue
+		}
+		if strings.Contains(d.Path, ".") {
+			skipped = append(skipped, d)
+			continue
+		}
+		missing[d.Type] = append(missing[d.Type], d)
+	}
+
+	// insertion replaces the bytes of src between offset and end with text
+	type insertion struct {
+		offset int
+		end    int
+		text   string
+	}
+	var insertions []insertion
+	needsConnection := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		typeSpec, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		st, ok := typeSpec.Type.(*ast.StructType)
+		if !ok || len(missing[typeSpec.Name.Name]) == 0 {
+			return true
+		}
+
+		fields := &bytes.Buffer{}
+		for _, d := range missing[typeSpec.Name.Name] {
+			fmt.Fprintf(fields, "\t%s %s %s\n", FieldName(d.Path), d.Expected, jsonTag(d.Type, d.Path, d.Optional))
+		}
+		if strings.Contains(fields.String(), "connection.") {
+			needsConnection = true
+		}
+		offset := fset.Position(st.Fields.Closing).Offset
+		insertions = append(insertions, insertion{offset: offset, end: offset, text: fields.String()})
+		delete(missing, typeSpec.Name.Name)
+		return true
+	})
+	for _, typeName := range sortedKeys(missing) {
+		skipped = append(skipped, missing[typeName]...)
+	}
+
+	// The import is added to the file's first import declaration, which is given parentheses if
+	// it has none, leaving format.Source to sort the specs
+	if needsConnection && !importsPath(file, connectionImport) {
+		spec := fmt.Sprintf("%q", connectionImport)
+		ins := insertion{offset: fset.Position(file.Name.End()).Offset, text: "\n\nimport " + spec}
+		ins.end = ins.offset
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.IMPORT {
+				continue
+			}
+			if gen.Lparen.IsValid() {
+				ins.offset = fset.Position(gen.Rparen).Offset
+				ins.end = ins.offset
+				ins.text = "\t" + spec + "\n"
+			} else {
+				ins.offset = fset.Position(gen.Pos()).Offset
+				ins.end = fset.Position(gen.End()).Offset
+				existing := src[fset.Position(gen.Specs[0].Pos()).Offset:ins.end]
+				ins.text = fmt.Sprintf("import (\n\t%s\n\t%s\n)", existing, spec)
+			}
+			break
+		}
+		insertions = append(insertions, ins)
+	}
+
+	sort.Slice(insertions, func(i, j int) bool { return insertions[i].offset > insertions[j].offset })
+	out := append([]byte{}, src...)
+	for _, ins := range insertions {
+		out = append(out[:ins.offset], append([]byte(ins.text), out[ins.end:]...)...)
+	}
+
+	formatted, err := format.Source(out)
+	if err != nil {
+		return nil, nil, err
+	}
+	return formatted, skipped, nil
+}
+
+// importsPath returns true if file imports the package with given path
+func importsPath(file *ast.File, path string) bool {
+	for _, imp := range file.Imports {
+		if strings.Trim(imp.Path.Value, `"`) == path {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
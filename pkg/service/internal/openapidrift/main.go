@@ -0,0 +1,151 @@
+// Command openapidrift compares an OpenAPI 3 document against the model and request types of a
+// service package, reporting drift by JSON tag and optionally generating missing fields and types.
+// It operates entirely offline against a local document, e.g.
+//
+//	go run ./pkg/service/internal/openapidrift -spec ecloud.json -package ./pkg/service/ecloud -map CreateInstance=CreateInstanceRequest
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mappingFlag collects repeated schema=type flags
+type mappingFlag map[string]string
+
+func (m mappingFlag) String() string {
+	var pairs []string
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m mappingFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("expected schema=type, got [%s]", value)
+	}
+	m[parts[0]] = parts[1]
+	return nil
+}
+
+type options struct {
+	specPath   string
+	packageDir string
+	mapping    mappingFlag
+	outputJSON bool
+	generate   bool
+	typesFile  string
+	failOnDiff bool
+}
+
+func main() {
+	opts := options{mapping: make(mappingFlag)}
+	flag.StringVar(&opts.specPath, "spec", "", "path to OpenAPI 3 document (JSON or YAML)")
+	flag.StringVar(&opts.packageDir, "package", ".", "directory of Go package to compare")
+	flag.Var(opts.mapping, "map", "maps a schema to a Go type, in the form schema=type. May be repeated")
+	flag.BoolVar(&opts.outputJSON, "json", false, "output drift report as JSON")
+	flag.BoolVar(&opts.generate, "generate", false, "add missing fields to existing types and generate missing types")
+	flag.StringVar(&opts.typesFile, "types-file", "model_openapi.go", "file within package to write generated types to")
+	flag.BoolVar(&opts.failOnDiff, "fail", false, "exit with non-zero status when drift is detected")
+	flag.Parse()
+
+	drifts, err := run(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "openapidrift: %s\n", err)
+		os.Exit(1)
+	}
+
+	if opts.failOnDiff && len(drifts) > 0 {
+		os.Exit(2)
+	}
+}
+
+func run(opts options) ([]Drift, error) {
+	if opts.specPath == "" {
+		return nil, fmt.Errorf("spec is required")
+	}
+
+	doc, err := LoadDocument(opts.specPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg, err := LoadPackage(opts.packageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	comparer := &Comparer{Document: doc, Package: pkg, Mapping: opts.mapping}
+	drifts := comparer.Compare()
+
+	if opts.outputJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(drifts)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for _, d := range drifts {
+			fmt.Println(d.String())
+		}
+	}
+
+	if opts.generate {
+		err = apply(opts, doc, pkg, drifts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return drifts, nil
+}
+
+// apply writes missing fields to the files declaring their types, and missing types to the
+// configured types file
+func apply(opts options, doc *Document, pkg *Package, drifts []Drift) error {
+	byFile := make(map[string][]Drift)
+	hasMissingTypes := false
+	for _, d := range drifts {
+		switch d.Kind {
+		case DriftKindMissingField:
+			byFile[pkg.Files[d.Type]] = append(byFile[pkg.Files[d.Type]], d)
+		case DriftKindMissingType:
+			hasMissingTypes = true
+		}
+	}
+
+	for path, fileDrifts := range byFile {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		out, skipped, err := AddFields(src, fileDrifts)
+		if err != nil {
+			return fmt.Errorf("failed to add fields to %s: %w", path, err)
+		}
+		for _, d := range skipped {
+			fmt.Fprintf(os.Stderr, "openapidrift: not generated, add by hand: %s\n", d)
+		}
+		err = os.WriteFile(path, out, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !hasMissingTypes {
+		return nil
+	}
+
+	out, err := GenerateTypes(pkg.Name, doc, drifts)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(opts.packageDir, opts.typesFile), out, 0644)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Package represents the struct and named types declared within a Go package
+type Package struct {
+	Name    string
+	Structs map[string]*ast.StructType
+	Named   map[string]ast.Expr
+	// Files maps type names to the path of the file declaring them
+	Files map[string]string
+}
+
+// knownTypes maps qualified types from outside of the package to their basic kind
+var knownTypes = map[string]string{
+	"connection.DateTime":  "string",
+	"connection.Date":      "string",
+	"connection.IPAddress": "string",
+}
+
+// LoadPackage parses the non-test Go files within dir
+func LoadPackage(dir string) (*Package, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Package{
+		Structs: make(map[string]*ast.StructType),
+		Named:   make(map[string]ast.Expr),
+		Files:   make(map[string]string),
+	}
+	for _, pkg := range pkgs {
+		p.Name = pkg.Name
+		for path, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					if st, ok := typeSpec.Type.(*ast.StructType); ok {
+						p.Structs[typeSpec.Name.Name] = st
+					}
+					p.Named[typeSpec.Name.Name] = typeSpec.Type
+					p.Files[typeSpec.Name.Name] = path
+				}
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// Field represents a JSON-serialised struct field
+type Field struct {
+	Name string
+	Type ast.Expr
+}
+
+// Fields returns the fields of st keyed by JSON name, including those of embedded structs
+func (p *Package) Fields(st *ast.StructType) map[string]Field {
+	fields := make(map[string]Field)
+	for _, f := range st.Fields.List {
+		tag := ""
+		if f.Tag != nil {
+			tag = reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Get("json")
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if len(f.Names) == 0 {
+			if embedded := p.structOf(f.Type); embedded != nil && name == "" {
+				for k, v := range p.Fields(embedded) {
+					fields[k] = v
+				}
+			}
+			continue
+		}
+
+		for _, ident := range f.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			key := name
+			if key == "" {
+				key = ident.Name
+			}
+			fields[key] = Field{Name: ident.Name, Type: f.Type}
+		}
+	}
+	return fields
+}
+
+// structOf returns the struct type for expr where expr is an inline or local named struct
+func (p *Package) structOf(expr ast.Expr) *ast.StructType {
+	switch t := expr.(type) {
+	case *ast.StructType:
+		return t
+	case *ast.StarExpr:
+		return p.structOf(t.X)
+	case *ast.Ident:
+		return p.Structs[t.Name]
+	}
+	return nil
+}
+
+// Kind returns the basic kind of expr, matching the kinds returned by Document.Kind. An empty
+// string is returned where the kind cannot be determined
+func (p *Package) Kind(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return p.Kind(t.X)
+	case *ast.ArrayType:
+		return "array"
+	case *ast.MapType, *ast.StructType:
+		return "object"
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			return knownTypes[x.Name+"."+t.Sel.Name]
+		}
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return "string"
+		case "bool":
+			return "boolean"
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+			return "integer"
+		case "float32", "float64":
+			return "number"
+		case "any":
+			return ""
+		}
+		if named, ok := p.Named[t.Name]; ok {
+			return p.Kind(named)
+		}
+	}
+	return ""
+}
+
+// TypeString returns the Go source representation of expr
+func TypeString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + TypeString(t.X)
+	case *ast.ArrayType:
+		return "[]" + TypeString(t.Elt)
+	case *ast.MapType:
+		return "map[" + TypeString(t.Key) + "]" + TypeString(t.Value)
+	case *ast.SelectorExpr:
+		return TypeString(t.X) + "." + t.Sel.Name
+	case *ast.StructType:
+		return "struct{...}"
+	case *ast.InterfaceType:
+		return "interface{}"
+	case *ast.IndexExpr:
+		return TypeString(t.X) + "[" + TypeString(t.Index) + "]"
+	}
+	return "?"
+}
@@ -0,0 +1,35 @@
+package models
+
+import "github.com/ans-group/sdk-go/pkg/connection"
+
+type SyncStatus string
+
+type ResourceSync struct {
+	Status SyncStatus `json:"status"`
+}
+
+// Instance represents an instance
+type Instance struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	VCPUCores   int                 `json:"vcpu_cores"`
+	Locked      bool                `json:"locked"`
+	Sync        ResourceSync        `json:"sync"`
+	LegacyField string              `json:"legacy_field"`
+	CreatedAt   connection.DateTime `json:"created_at"`
+}
+
+// Report represents an SSL report
+type Report struct {
+	Server struct {
+		IP   string `json:"ip"`
+		Port string `json:"port"`
+	} `json:"server"`
+	Findings []string `json:"findings"`
+}
+
+// CreateInstanceRequest represents a request to create an instance
+type CreateInstanceRequest struct {
+	Name      string `json:"name,omitempty"`
+	VCPUCores int    `json:"vcpu_cores"`
+}
@@ -0,0 +1,22 @@
+package resource
+
+import "fmt"
+
+// NoMatchError indicates that no items matched the filter given to FindOne
+type NoMatchError struct {
+	Name string
+}
+
+func (e *NoMatchError) Error() string {
+	return fmt.Sprintf("no %s found matching filter", e.Name)
+}
+
+// MultipleMatchError indicates that more than one item matched the filter given to FindOne
+type MultipleMatchError struct {
+	Name  string
+	Count int
+}
+
+func (e *MultipleMatchError) Error() string {
+	return fmt.Sprintf("expected single %s matching filter, found %d", e.Name, e.Count)
+}
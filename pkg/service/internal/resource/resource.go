@@ -1,12 +1,18 @@
 package resource
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/ans-group/sdk-go/pkg/connection"
 )
 
-// Resource encapsulates standard CRUD operations for a single top-level API resource.
+// errExistsNotFound is used internally by Exists to detect a 404 response
+var errExistsNotFound = errors.New("not found")
+
+// Resource encapsulates standard CRUD operations for an API resource, optionally nested beneath
+// a parent resource (see WithParent).
 // T is the model type; ID is the identifier type (int or string).
 type Resource[T any, ID comparable] struct {
 	conn       connection.Connection
@@ -15,6 +21,46 @@ type Resource[T any, ID comparable] struct {
 	identifier string // word after "invalid {name}" in validation errors, e.g. "id" or "name"
 	validate   func(ID) bool
 	notFound   func(ID) error
+	parent     *Parent
+	idFunc     func(T) ID
+}
+
+// Parent describes the parent item of a nested resource, e.g. the listener in
+// /loadbalancers/v2/listeners/{listenerID}/binds
+type Parent struct {
+	path       string
+	name       string
+	identifier string
+	valid      bool
+	notFound   error
+}
+
+// IntParent returns a Parent with an int ID, valid when > 0
+func IntParent(basePath string, name string, id int, notFound error) Parent {
+	return Parent{
+		path:       fmt.Sprintf("%s/%d", strings.TrimRight(basePath, "/"), id),
+		name:       name,
+		identifier: "id",
+		valid:      id > 0,
+		notFound:   notFound,
+	}
+}
+
+// StringParent returns a Parent with a string ID, valid when non-empty
+func StringParent(basePath string, name string, id string, notFound error) Parent {
+	return StringParentWithIdentifier(basePath, name, "id", id, notFound)
+}
+
+// StringParentWithIdentifier returns a Parent with a string ID where the identifier word in
+// validation errors differs from the default "id" (e.g. "name" for domain/zone resources)
+func StringParentWithIdentifier(basePath string, name string, identifier string, id string, notFound error) Parent {
+	return Parent{
+		path:       fmt.Sprintf("%s/%s", strings.TrimRight(basePath, "/"), id),
+		name:       name,
+		identifier: identifier,
+		valid:      id != "",
+		notFound:   notFound,
+	}
 }
 
 // NewIntResource constructs a Resource with int IDs, valid when > 0.
@@ -54,6 +100,65 @@ func NewStringResourceWithIdentifier[T any](conn connection.Connection, basePath
 	}
 }
 
+// WithParent returns a copy of the resource scoped beneath parent. The resource's basePath is
+// treated as relative to the parent item, e.g. "binds"
+func (r *Resource[T, ID]) WithParent(parent Parent) *Resource[T, ID] {
+	nested := *r
+	nested.basePath = fmt.Sprintf("%s/%s", parent.path, strings.Trim(r.basePath, "/"))
+	nested.parent = &parent
+	return &nested
+}
+
+// WithIDFunc returns a copy of the resource using idFunc to retrieve the ID of a model, which is
+// required by Upsert
+func (r *Resource[T, ID]) WithIDFunc(idFunc func(T) ID) *Resource[T, ID] {
+	withID := *r
+	withID.idFunc = idFunc
+	return &withID
+}
+
+// validateParent returns an error if the resource is nested and the parent ID is invalid
+func (r *Resource[T, ID]) validateParent() error {
+	if r.parent != nil && !r.parent.valid {
+		return fmt.Errorf("invalid %s %s", r.parent.name, r.parent.identifier)
+	}
+	return nil
+}
+
+// validateID returns an error if either the parent ID or given ID are invalid
+func (r *Resource[T, ID]) validateID(id ID) error {
+	err := r.validateParent()
+	if err != nil {
+		return err
+	}
+	if !r.validate(id) {
+		return fmt.Errorf("invalid %s %s", r.name, r.identifier)
+	}
+	return nil
+}
+
+// parentNotFoundHandler returns a handler for 404 responses from collection endpoints, which
+// indicate that the parent doesn't exist
+func (r *Resource[T, ID]) parentNotFoundHandler() connection.ResponseHandler {
+	if r.parent == nil || r.parent.notFound == nil {
+		return nil
+	}
+	return connection.NotFoundResponseHandler(r.parent.notFound)
+}
+
+// notFoundHandler returns a handler for 404 responses from item endpoints
+func (r *Resource[T, ID]) notFoundHandler(id ID) connection.ResponseHandler {
+	if r.notFound == nil {
+		return nil
+	}
+	return connection.NotFoundResponseHandler(r.notFound(id))
+}
+
+// itemPath returns the path for the item with given ID
+func (r *Resource[T, ID]) itemPath(id ID) string {
+	return fmt.Sprintf("%s/%v", r.basePath, id)
+}
+
 // List retrieves all items, fetching all pages automatically.
 func (r *Resource[T, ID]) List(params connection.APIRequestParameters) ([]T, error) {
 	return connection.InvokeRequestAll(r.ListPaginated, params)
@@ -61,41 +166,139 @@ func (r *Resource[T, ID]) List(params connection.APIRequestParameters) ([]T, err
 
 // ListPaginated retrieves a single page of items.
 func (r *Resource[T, ID]) ListPaginated(params connection.APIRequestParameters) (*connection.Paginated[T], error) {
-	body, err := connection.Get[[]T](r.conn, r.basePath, params)
+	err := r.validateParent()
+	if err != nil {
+		return nil, err
+	}
+	body, err := connection.Get[[]T](r.conn, r.basePath, params, r.parentNotFoundHandler())
 	return connection.NewPaginated(body, params, r.ListPaginated), err
 }
 
 // Get retrieves a single item by ID.
 func (r *Resource[T, ID]) Get(id ID) (T, error) {
 	var zero T
-	if !r.validate(id) {
-		return zero, fmt.Errorf("invalid %s %s", r.name, r.identifier)
+	err := r.validateID(id)
+	if err != nil {
+		return zero, err
 	}
-	body, err := connection.Get[T](r.conn, fmt.Sprintf("%s/%v", r.basePath, id), connection.APIRequestParameters{},
-		connection.NotFoundResponseHandler(r.notFound(id)))
+	body, err := connection.Get[T](r.conn, r.itemPath(id), connection.APIRequestParameters{},
+		r.notFoundHandler(id))
 	return body.Data, err
 }
 
+// Exists returns true if an item with given ID exists, or false if the API returns a 404.
+func (r *Resource[T, ID]) Exists(id ID) (bool, error) {
+	err := r.validateID(id)
+	if err != nil {
+		return false, err
+	}
+	_, err = connection.Get[T](r.conn, r.itemPath(id), connection.APIRequestParameters{},
+		connection.NotFoundResponseHandler(errExistsNotFound))
+	if errors.Is(err, errExistsNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// FindOne retrieves the single item matching params, returning a *NoMatchError if no items
+// match, or a *MultipleMatchError if more than one item matches.
+func (r *Resource[T, ID]) FindOne(params connection.APIRequestParameters) (T, error) {
+	var zero T
+	items, err := r.List(params)
+	if err != nil {
+		return zero, err
+	}
+	switch len(items) {
+	case 0:
+		return zero, &NoMatchError{Name: r.name}
+	case 1:
+		return items[0], nil
+	default:
+		return zero, &MultipleMatchError{Name: r.name, Count: len(items)}
+	}
+}
+
+// Upsert patches the single item matching match with patch, or creates a new item with create
+// when no items match, returning the resulting model and whether it was created. The resource
+// must have an ID func configured via WithIDFunc.
+func (r *Resource[T, ID]) Upsert(match connection.APIRequestParameters, create any, patch any) (T, bool, error) {
+	var zero T
+	if r.idFunc == nil {
+		return zero, false, fmt.Errorf("upsert requires an id func for %s", r.name)
+	}
+	existing, err := r.FindOne(match)
+	if err != nil {
+		var noMatch *NoMatchError
+		if !errors.As(err, &noMatch) {
+			return zero, false, err
+		}
+		created, err := r.Create(create)
+		return created, err == nil, err
+	}
+	id := r.idFunc(existing)
+	err = r.Patch(id, patch)
+	if err != nil {
+		return zero, false, err
+	}
+	updated, err := r.Get(id)
+	return updated, false, err
+}
+
 // Create posts a new item and returns the created model.
 func (r *Resource[T, ID]) Create(req any) (T, error) {
-	body, err := connection.Post[T](r.conn, r.basePath, req)
-	return body.Data, err
+	return CreateAs[T](r, req)
 }
 
 // Patch updates an existing item by ID.
 func (r *Resource[T, ID]) Patch(id ID, req any) error {
-	if !r.validate(id) {
-		return fmt.Errorf("invalid %s %s", r.name, r.identifier)
+	err := r.validateID(id)
+	if err != nil {
+		return err
 	}
-	return connection.PatchRaw(r.conn, fmt.Sprintf("%s/%v", r.basePath, id), req, &connection.APIResponseBody{},
-		connection.NotFoundResponseHandler(r.notFound(id)))
+	return connection.PatchRaw(r.conn, r.itemPath(id), req, &connection.APIResponseBody{},
+		r.notFoundHandler(id))
 }
 
 // Delete removes an item by ID.
 func (r *Resource[T, ID]) Delete(id ID) error {
-	if !r.validate(id) {
-		return fmt.Errorf("invalid %s %s", r.name, r.identifier)
+	err := r.validateID(id)
+	if err != nil {
+		return err
+	}
+	return connection.DeleteRaw(r.conn, r.itemPath(id), nil, &connection.APIResponseBody{},
+		r.notFoundHandler(id))
+}
+
+// CreateAs posts a new item to r, decoding the response data as R rather than the model type,
+// e.g. for endpoints returning a task reference.
+func CreateAs[R any, T any, ID comparable](r *Resource[T, ID], req any) (R, error) {
+	var zero R
+	err := r.validateParent()
+	if err != nil {
+		return zero, err
 	}
-	return connection.DeleteRaw(r.conn, fmt.Sprintf("%s/%v", r.basePath, id), nil, &connection.APIResponseBody{},
-		connection.NotFoundResponseHandler(r.notFound(id)))
+	body, err := connection.Post[R](r.conn, r.basePath, req, r.parentNotFoundHandler())
+	return body.Data, err
+}
+
+// PatchAs updates an existing item of r by ID, decoding the response data as R.
+func PatchAs[R any, T any, ID comparable](r *Resource[T, ID], id ID, req any) (R, error) {
+	var zero R
+	err := r.validateID(id)
+	if err != nil {
+		return zero, err
+	}
+	body, err := connection.Patch[R](r.conn, r.itemPath(id), req, r.notFoundHandler(id))
+	return body.Data, err
+}
+
+// DeleteAs removes an item of r by ID, decoding the response data as R.
+func DeleteAs[R any, T any, ID comparable](r *Resource[T, ID], id ID) (R, error) {
+	var zero R
+	err := r.validateID(id)
+	if err != nil {
+		return zero, err
+	}
+	body, err := connection.Delete[R](r.conn, r.itemPath(id), nil, r.notFoundHandler(id))
+	return body.Data, err
 }
@@ -349,3 +349,311 @@ func TestResource_Delete(t *testing.T) {
 		assert.IsType(t, &testNotFoundError{}, err)
 	})
 }
+
+// --- Nested ---
+
+type testParentNotFoundError struct {
+	ID int
+}
+
+func (e *testParentNotFoundError) Error() string {
+	return fmt.Sprintf("test parent not found with id [%d]", e.ID)
+}
+
+func newNestedResource(c connection.Connection, parentID int) *resource.Resource[testModel, int] {
+	return resource.NewIntResource[testModel](c, "children", "child",
+		func(id int) error { return &testNotFoundError{ID: id} }).
+		WithParent(resource.IntParent("/test/v1/models", "model", parentID, &testParentNotFoundError{ID: parentID}))
+}
+
+func TestResource_Nested(t *testing.T) {
+	t.Run("List_UsesParentPath", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Get("/test/v1/models/123/children", gomock.Any()).Return(
+			apiResponseJSON(200, `{"data":[{"id":1}],"meta":{"pagination":{"total_pages":1}}}`), nil).Times(1)
+
+		items, err := newNestedResource(c, 123).List(connection.APIRequestParameters{})
+
+		assert.Nil(t, err)
+		assert.Len(t, items, 1)
+	})
+
+	t.Run("List_ParentNotFound_ReturnsParentNotFoundError", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Get("/test/v1/models/123/children", gomock.Any()).Return(
+			apiResponseJSON(404, ""), nil).Times(1)
+
+		_, err := newNestedResource(c, 123).List(connection.APIRequestParameters{})
+
+		assert.NotNil(t, err)
+		assert.IsType(t, &testParentNotFoundError{}, err)
+	})
+
+	t.Run("Get_UsesItemPath", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Get("/test/v1/models/123/children/456", gomock.Any()).Return(
+			apiResponseJSON(200, `{"data":{"id":456}}`), nil).Times(1)
+
+		item, err := newNestedResource(c, 123).Get(456)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 456, item.ID)
+	})
+
+	t.Run("InvalidParentID_ReturnsError", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+
+		_, err := newNestedResource(c, 0).Get(456)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid model id", err.Error())
+	})
+
+	t.Run("InvalidParentID_List_ReturnsError", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+
+		_, err := newNestedResource(c, 0).List(connection.APIRequestParameters{})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid model id", err.Error())
+	})
+
+	t.Run("InvalidChildID_ReturnsError", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+
+		err := newNestedResource(c, 123).Delete(0)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid child id", err.Error())
+	})
+
+	t.Run("StringParentWithIdentifier_InvalidName_ReturnsError", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		r := resource.NewIntResource[testModel](c, "records", "record",
+			func(id int) error { return &testNotFoundError{ID: id} }).
+			WithParent(resource.StringParentWithIdentifier("/test/v1/zones", "zone", "name", "", nil))
+
+		_, err := r.Get(1)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid zone name", err.Error())
+	})
+}
+
+// --- Exists ---
+
+func TestResource_Exists(t *testing.T) {
+	t.Run("Exists_ReturnsTrue", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Get("/test/v1/models/123", gomock.Any()).Return(
+			apiResponseJSON(200, `{"data":{"id":123}}`), nil).Times(1)
+
+		exists, err := newIntResource(c).Exists(123)
+
+		assert.Nil(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("NotFound_ReturnsFalse", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Get("/test/v1/models/123", gomock.Any()).Return(
+			apiResponseJSON(404, ""), nil).Times(1)
+
+		exists, err := newIntResource(c).Exists(123)
+
+		assert.Nil(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("ServerError_ReturnsError", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Get("/test/v1/models/123", gomock.Any()).Return(
+			apiResponseJSON(500, ""), nil).Times(1)
+
+		exists, err := newIntResource(c).Exists(123)
+
+		assert.NotNil(t, err)
+		assert.False(t, exists)
+	})
+}
+
+// --- FindOne ---
+
+func TestResource_FindOne(t *testing.T) {
+	t.Run("SingleMatch_ReturnsItem", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Get("/test/v1/models", gomock.Any()).Return(
+			apiResponseJSON(200, `{"data":[{"id":1,"name":"foo"}],"meta":{"pagination":{"total_pages":1}}}`), nil).Times(1)
+
+		item, err := newIntResource(c).FindOne(*connection.NewAPIRequestParameters().WithFilter(connection.APIRequestFiltering{Property: "name", Operator: connection.EQOperator, Value: []string{"foo"}}))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, item.ID)
+	})
+
+	t.Run("NoMatch_ReturnsNoMatchError", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Get("/test/v1/models", gomock.Any()).Return(
+			apiResponseJSON(200, `{"data":[],"meta":{"pagination":{"total_pages":1}}}`), nil).Times(1)
+
+		_, err := newIntResource(c).FindOne(connection.APIRequestParameters{})
+
+		assert.NotNil(t, err)
+		assert.IsType(t, &resource.NoMatchError{}, err)
+	})
+
+	t.Run("MultipleMatches_ReturnsMultipleMatchError", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Get("/test/v1/models", gomock.Any()).Return(
+			apiResponseJSON(200, `{"data":[{"id":1},{"id":2}],"meta":{"pagination":{"total_pages":1}}}`), nil).Times(1)
+
+		_, err := newIntResource(c).FindOne(connection.APIRequestParameters{})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "expected single model matching filter, found 2", err.Error())
+	})
+}
+
+// --- Upsert ---
+
+func TestResource_Upsert(t *testing.T) {
+	idFunc := func(m testModel) int { return m.ID }
+
+	t.Run("NoMatch_Creates", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		gomock.InOrder(
+			c.EXPECT().Get("/test/v1/models", gomock.Any()).Return(
+				apiResponseJSON(200, `{"data":[],"meta":{"pagination":{"total_pages":1}}}`), nil),
+			c.EXPECT().Post("/test/v1/models", gomock.Any()).Return(
+				apiResponseJSON(201, `{"data":{"id":99}}`), nil),
+		)
+
+		item, created, err := newIntResource(c).WithIDFunc(idFunc).Upsert(connection.APIRequestParameters{}, struct{}{}, struct{}{})
+
+		assert.Nil(t, err)
+		assert.True(t, created)
+		assert.Equal(t, 99, item.ID)
+	})
+
+	t.Run("Match_PatchesAndRetrieves", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		gomock.InOrder(
+			c.EXPECT().Get("/test/v1/models", gomock.Any()).Return(
+				apiResponseJSON(200, `{"data":[{"id":5}],"meta":{"pagination":{"total_pages":1}}}`), nil),
+			c.EXPECT().Patch("/test/v1/models/5", gomock.Any()).Return(
+				apiResponseJSON(200, `{}`), nil),
+			c.EXPECT().Get("/test/v1/models/5", gomock.Any()).Return(
+				apiResponseJSON(200, `{"data":{"id":5,"name":"patched"}}`), nil),
+		)
+
+		item, created, err := newIntResource(c).WithIDFunc(idFunc).Upsert(connection.APIRequestParameters{}, struct{}{}, struct{}{})
+
+		assert.Nil(t, err)
+		assert.False(t, created)
+		assert.Equal(t, "patched", item.Name)
+	})
+
+	t.Run("NoIDFunc_ReturnsError", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+
+		_, _, err := newIntResource(c).Upsert(connection.APIRequestParameters{}, struct{}{}, struct{}{})
+
+		assert.NotNil(t, err)
+	})
+}
+
+// --- Task variants ---
+
+type testTaskReference struct {
+	TaskID     string `json:"task_id"`
+	ResourceID string `json:"id"`
+}
+
+func TestCreateAs(t *testing.T) {
+	t.Run("ReturnsTaskReference", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Post("/test/v1/models", gomock.Any()).Return(
+			apiResponseJSON(202, `{"data":{"task_id":"task-abc","id":"abc-123"}}`), nil).Times(1)
+
+		ref, err := resource.CreateAs[testTaskReference](newStringResource(c), struct{}{})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "task-abc", ref.TaskID)
+		assert.Equal(t, "abc-123", ref.ResourceID)
+	})
+}
+
+func TestPatchAs(t *testing.T) {
+	t.Run("InvalidID_ReturnsError", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+
+		_, err := resource.PatchAs[testTaskReference](newStringResource(c), "", struct{}{})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "invalid model id", err.Error())
+	})
+
+	t.Run("ReturnsTaskReference", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Patch("/test/v1/models/abc-123", gomock.Any()).Return(
+			apiResponseJSON(202, `{"data":{"task_id":"task-abc","id":"abc-123"}}`), nil).Times(1)
+
+		ref, err := resource.PatchAs[testTaskReference](newStringResource(c), "abc-123", struct{}{})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "task-abc", ref.TaskID)
+	})
+}
+
+func TestDeleteAs(t *testing.T) {
+	t.Run("NotFound_ReturnsNotFoundError", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		c := mocks.NewMockConnection(mockCtrl)
+		c.EXPECT().Delete("/test/v1/models/abc-123", gomock.Any()).Return(
+			apiResponseJSON(404, ""), nil).Times(1)
+
+		_, err := resource.DeleteAs[testTaskReference](newStringResource(c), "abc-123")
+
+		assert.NotNil(t, err)
+		assert.IsType(t, &testStringNotFoundError{}, err)
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+)
+
+const (
+	beginMarker = "// resourcegen:begin"
+	endMarker   = "// resourcegen:end"
+)
+
+// Generate returns the generated files for spec, keyed by file name
+func Generate(spec *Spec) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	for _, r := range spec.Resources {
+		data := struct {
+			Package  string
+			Resource Resource
+		}{
+			Package:  spec.Package,
+			Resource: r,
+		}
+
+		base := "service_" + strings.ToLower(r.Name)
+		for suffix, tmpl := range map[string]*template.Template{
+			"_gen.go":      serviceTemplate,
+			"_gen_test.go": testTemplate,
+		} {
+			content, err := render(tmpl, data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate %s for resource [%s]: %w", suffix, r.Name, err)
+			}
+			files[base+suffix] = content
+		}
+	}
+
+	for name, tmpl := range map[string]*template.Template{
+		"error_gen.go":            errorTemplate,
+		"resourcegen_gen_test.go": testHelperTemplate,
+	} {
+		content, err := render(tmpl, spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate %s: %w", name, err)
+		}
+		files[name] = content
+	}
+
+	return files, nil
+}
+
+// GenerateInterface replaces the content of the resourcegen region of each resource in src with
+// the resource's interface stanza, returning the updated source. Regions are delimited by
+// "// resourcegen:begin {Name}" and "// resourcegen:end" markers, allowing each stanza to sit
+// alongside the related stanzas of the interface
+func GenerateInterface(spec *Spec, src []byte) ([]byte, error) {
+	out := append([]byte{}, src...)
+	for _, r := range spec.Resources {
+		marker := []byte(beginMarker + " " + r.Name + "\n")
+		begin := bytes.Index(out, marker)
+		if begin == -1 {
+			return nil, fmt.Errorf("missing %q marker for resource [%s]", strings.TrimSpace(string(marker)), r.Name)
+		}
+		begin += len(marker)
+		end := bytes.Index(out[begin:], []byte(endMarker))
+		if end == -1 {
+			return nil, fmt.Errorf("missing %q marker for resource [%s]", endMarker, r.Name)
+		}
+		end += begin
+
+		stanza := &bytes.Buffer{}
+		err := interfaceTemplate.Execute(stanza, r)
+		if err != nil {
+			return nil, err
+		}
+		stanza.WriteString("\t")
+
+		out = append(out[:begin:begin], append(stanza.Bytes(), out[end:]...)...)
+	}
+
+	return format.Source(out)
+}
+
+func render(tmpl *template.Template, data interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%w\n%s", err, buf.String())
+	}
+
+	return formatted, nil
+}
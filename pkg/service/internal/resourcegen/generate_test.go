@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSpec = `
+package: test
+resources:
+  - name: VPNWidget
+    description: vpn widget
+    path: /test/v1/vpn-widgets
+    id_type: string
+    example_id: vw-abcdef12
+    operations: [list, get, create, patch, delete]
+    task: true
+    sub_collections:
+      - name: Tasks
+        model: Task
+        path: tasks
+        example_id: task-abcdef12
+  - name: Gadget
+    path: /test/v1/gadgets
+    id_type: int
+    example_id: "123"
+    operations: [get, create, delete]
+`
+
+func TestParseSpec(t *testing.T) {
+	t.Run("AppliesDefaults", func(t *testing.T) {
+		spec, err := ParseSpec([]byte(testSpec))
+
+		assert.Nil(t, err)
+		assert.Len(t, spec.Resources, 2)
+		assert.Equal(t, "VPNWidgets", spec.Resources[0].Plural)
+		assert.Equal(t, "CreateVPNWidgetRequest", spec.Resources[0].CreateRequest)
+		assert.Equal(t, "vpnWidget", spec.Resources[0].Var())
+		assert.Equal(t, "tasks", spec.Resources[0].SubCollections[0].Description)
+		assert.Equal(t, "gadget", spec.Resources[1].Description)
+		assert.Equal(t, "a", spec.Resources[1].Article)
+		assert.Equal(t, "gadget", spec.Resources[1].ErrorDescription)
+		assert.Equal(t, "VPN Widgets", spec.Resources[0].DisplayName)
+		assert.Equal(t, "vpnWidgetID", spec.Resources[0].IDParam)
+		assert.Equal(t, "req", spec.Resources[0].PatchParam)
+	})
+
+	t.Run("OverridesParameterNames", func(t *testing.T) {
+		spec, err := ParseSpec([]byte("package: test\nresources:\n  - name: AffinityRule\n    display_name: Affinity Rules\n    path: /foo\n    id_type: string\n    example_id: a\n    id_param: ruleID\n    patch_param: patch\n    operations: [patch]\n"))
+		assert.Nil(t, err)
+
+		files, err := Generate(spec)
+
+		assert.Nil(t, err)
+		assert.Contains(t, string(files["service_affinityrule_gen.go"]), "func (s *Service) PatchAffinityRule(ruleID string, patch PatchAffinityRuleRequest) error {")
+	})
+
+	t.Run("VowelDescription_DefaultsArticleToAn", func(t *testing.T) {
+		spec, err := ParseSpec([]byte("package: test\nresources:\n  - name: AffinityRule\n    description: affinity rule\n    error_description: Affinity Rule\n    path: /foo\n    id_type: string\n    example_id: a\n"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "an", spec.Resources[0].Article)
+		assert.Equal(t, "Affinity Rule", spec.Resources[0].ErrorDescription)
+	})
+
+	t.Run("InvalidIDType_ReturnsError", func(t *testing.T) {
+		_, err := ParseSpec([]byte("package: test\nresources:\n  - name: Foo\n    path: /foo\n    id_type: uuid\n    example_id: a\n"))
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "resource [Foo] id_type must be one of int, string", err.Error())
+	})
+
+	t.Run("InvalidOperation_ReturnsError", func(t *testing.T) {
+		_, err := ParseSpec([]byte("package: test\nresources:\n  - name: Foo\n    path: /foo\n    id_type: int\n    example_id: \"1\"\n    operations: [put]\n"))
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "resource [Foo] has invalid operation [put]", err.Error())
+	})
+
+	t.Run("MissingPackage_ReturnsError", func(t *testing.T) {
+		_, err := ParseSpec([]byte("resources: []\n"))
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestGenerate(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	assert.Nil(t, err)
+
+	files, err := Generate(spec)
+
+	assert.Nil(t, err)
+	assert.Contains(t, files, "service_vpnwidget_gen.go")
+	assert.Contains(t, files, "service_vpnwidget_gen_test.go")
+	assert.Contains(t, files, "service_gadget_gen.go")
+	assert.Contains(t, files, "error_gen.go")
+	assert.Contains(t, files, "resourcegen_gen_test.go")
+
+	widget := string(files["service_vpnwidget_gen.go"])
+	assert.Contains(t, widget, "func (s *Service) CreateVPNWidget(req CreateVPNWidgetRequest) (TaskReference, error)")
+	assert.Contains(t, widget, "func (s *Service) GetVPNWidgetTasks(vpnWidgetID string, parameters connection.APIRequestParameters) ([]Task, error)")
+
+	gadget := string(files["service_gadget_gen.go"])
+	assert.Contains(t, gadget, "func (s *Service) CreateGadget(req CreateGadgetRequest) (int, error)")
+	assert.Contains(t, gadget, "func (s *Service) DeleteGadget(gadgetID int) error")
+	assert.Contains(t, gadget, "// DeleteGadget deletes a gadget\n")
+	assert.NotContains(t, gadget, "GetGadgets")
+
+	assert.Contains(t, string(files["error_gen.go"]), `fmt.Sprintf("gadget not found with ID [%d]", e.ID)`)
+}
+
+func TestGenerateInterface(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	assert.Nil(t, err)
+
+	t.Run("ReplacesMarkedRegion", func(t *testing.T) {
+		src := "package test\n\ntype TestService interface {\n\t// resourcegen:begin Gadget\n\tStale() error\n\t// resourcegen:end\n\n\tExisting() error\n\n\t// resourcegen:begin VPNWidget\n\t// resourcegen:end\n}\n"
+
+		out, err := GenerateInterface(spec, []byte(src))
+
+		assert.Nil(t, err)
+		assert.NotContains(t, string(out), "Stale")
+		assert.Contains(t, string(out), "\t// resourcegen:begin Gadget\n\t// Gadgets\n\tGetGadget(gadgetID int) (Gadget, error)\n")
+		assert.Contains(t, string(out), "\t// resourcegen:begin VPNWidget\n\t// VPN Widgets\n")
+		assert.Contains(t, string(out), "\tDeleteVPNWidget(vpnWidgetID string) (string, error)\n\tGetVPNWidgetTasks(")
+		assert.True(t, strings.Index(string(out), "GetGadget(") < strings.Index(string(out), "Existing()"))
+		assert.True(t, strings.Index(string(out), "Existing()") < strings.Index(string(out), "GetVPNWidget("))
+	})
+
+	t.Run("MissingMarkers_ReturnsError", func(t *testing.T) {
+		_, err := GenerateInterface(spec, []byte("package test\n\ntype TestService interface {\n\t// resourcegen:begin VPNWidget\n\t// resourcegen:end\n}\n"))
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "missing \"// resourcegen:begin Gadget\" marker for resource [Gadget]", err.Error())
+	})
+}
@@ -0,0 +1,58 @@
+// Command resourcegen generates service methods, not found errors, interface stanzas and tests for
+// API resources from a declarative YAML spec. It is intended to be invoked via go generate from
+// within a service package, e.g.
+//
+//	//go:generate go run ../internal/resourcegen -spec resources.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	specPath := flag.String("spec", "resources.yaml", "path to resource spec")
+	dir := flag.String("dir", ".", "output directory")
+	interfaceFile := flag.String("interface", "service.go", "file containing the service interface, relative to output directory")
+	flag.Parse()
+
+	err := run(*specPath, *dir, *interfaceFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "resourcegen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(specPath string, dir string, interfaceFile string) error {
+	spec, err := LoadSpec(specPath)
+	if err != nil {
+		return err
+	}
+
+	files, err := Generate(spec)
+	if err != nil {
+		return err
+	}
+
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), content, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	interfacePath := filepath.Join(dir, interfaceFile)
+	existing, err := os.ReadFile(interfacePath)
+	if err != nil {
+		return err
+	}
+
+	updated, err := GenerateInterface(spec, existing)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", interfaceFile, err)
+	}
+
+	return os.WriteFile(interfacePath, updated, 0644)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec represents a resource spec file, describing the resources to generate for a single service package
+type Spec struct {
+	Package   string     `yaml:"package"`
+	Resources []Resource `yaml:"resources"`
+}
+
+// Resource represents a single top-level API resource
+type Resource struct {
+	// Name is the Go name of the resource, e.g. AffinityRule
+	Name string `yaml:"name"`
+	// Plural is the pluralised Go name of the resource, defaulting to Name with an "s" suffix
+	Plural string `yaml:"plural"`
+	// DisplayName is the heading of the resource's stanza within the service interface,
+	// defaulting to Plural with spaces between words, e.g. "Affinity Rules"
+	DisplayName string `yaml:"display_name"`
+	// Description is the human readable name of the resource used in comments and errors,
+	// e.g. "affinity rule"
+	Description string `yaml:"description"`
+	// Article is the indefinite article preceding Description in comments, defaulting to "an"
+	// where Description begins with a vowel and "a" otherwise
+	Article string `yaml:"article"`
+	// ErrorDescription is the name of the resource within not found error messages, defaulting
+	// to Description, e.g. "Affinity Rule"
+	ErrorDescription string `yaml:"error_description"`
+	// Model is the model type, defaulting to Name
+	Model string `yaml:"model"`
+	// Path is the collection path, e.g. /ecloud/v2/affinity-rules
+	Path string `yaml:"path"`
+	// IDType is the ID type of the resource, either "int" or "string"
+	IDType string `yaml:"id_type"`
+	// ExampleID is an ID used within generated tests, e.g. ar-abcdef12
+	ExampleID string `yaml:"example_id"`
+	// IDParam is the name of ID parameters, defaulting to the lower camel case Name with an
+	// "ID" suffix, e.g. affinityRuleID
+	IDParam string `yaml:"id_param"`
+	// PatchParam is the name of the patch request parameter, defaulting to req
+	PatchParam string `yaml:"patch_param"`
+	// Operations are the operations supported by the resource, any of list, get, create, patch and delete
+	Operations []string `yaml:"operations"`
+	// CreateRequest is the create request type, defaulting to Create{Name}Request
+	CreateRequest string `yaml:"create_request"`
+	// PatchRequest is the patch request type, defaulting to Patch{Name}Request
+	PatchRequest string `yaml:"patch_request"`
+	// Task indicates that mutations are asynchronous, with create and patch returning a
+	// TaskReference and delete returning a task ID
+	Task bool `yaml:"task"`
+	// SubCollections are read-only collections nested beneath the resource, e.g. tasks
+	SubCollections []SubCollection `yaml:"sub_collections"`
+}
+
+// SubCollection represents a read-only collection nested beneath a resource
+type SubCollection struct {
+	// Name is the Go name of the collection, e.g. Tasks
+	Name string `yaml:"name"`
+	// Description is the human readable name of the collection, e.g. "tasks"
+	Description string `yaml:"description"`
+	// Model is the model type of collection items, e.g. Task
+	Model string `yaml:"model"`
+	// Path is the collection path relative to the parent item, e.g. tasks
+	Path string `yaml:"path"`
+	// ExampleID is an ID of a collection item used within generated tests
+	ExampleID string `yaml:"example_id"`
+}
+
+// LoadSpec reads and validates the spec at given path
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSpec(data)
+}
+
+// ParseSpec parses and validates given spec data, applying defaults
+func ParseSpec(data []byte) (*Spec, error) {
+	spec := &Spec{}
+	err := yaml.Unmarshal(data, spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+
+	if spec.Package == "" {
+		return nil, fmt.Errorf("spec package is required")
+	}
+
+	for i := range spec.Resources {
+		err := spec.Resources[i].hydrate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return spec, nil
+}
+
+func (r *Resource) hydrate() error {
+	if r.Name == "" {
+		return fmt.Errorf("resource name is required")
+	}
+	if r.Path == "" {
+		return fmt.Errorf("resource [%s] path is required", r.Name)
+	}
+	if r.IDType != "int" && r.IDType != "string" {
+		return fmt.Errorf("resource [%s] id_type must be one of int, string", r.Name)
+	}
+	if r.ExampleID == "" {
+		return fmt.Errorf("resource [%s] example_id is required", r.Name)
+	}
+	if r.Plural == "" {
+		r.Plural = r.Name + "s"
+	}
+	if r.DisplayName == "" {
+		r.DisplayName = spaced(r.Plural)
+	}
+	if r.Description == "" {
+		r.Description = strings.ToLower(r.Name)
+	}
+	if r.Article == "" {
+		r.Article = "a"
+		if strings.ContainsRune("aeiou", unicode.ToLower([]rune(r.Description)[0])) {
+			r.Article = "an"
+		}
+	}
+	if r.ErrorDescription == "" {
+		r.ErrorDescription = r.Description
+	}
+	if r.Model == "" {
+		r.Model = r.Name
+	}
+	if r.CreateRequest == "" {
+		r.CreateRequest = fmt.Sprintf("Create%sRequest", r.Name)
+	}
+	if r.PatchRequest == "" {
+		r.PatchRequest = fmt.Sprintf("Patch%sRequest", r.Name)
+	}
+	if r.IDParam == "" {
+		r.IDParam = r.Var() + "ID"
+	}
+	if r.PatchParam == "" {
+		r.PatchParam = "req"
+	}
+	if len(r.Operations) == 0 {
+		r.Operations = []string{"list", "get"}
+	}
+	for _, op := range r.Operations {
+		switch op {
+		case "list", "get", "create", "patch", "delete":
+		default:
+			return fmt.Errorf("resource [%s] has invalid operation [%s]", r.Name, op)
+		}
+	}
+	for i, sub := range r.SubCollections {
+		if sub.Name == "" || sub.Model == "" || sub.Path == "" || sub.ExampleID == "" {
+			return fmt.Errorf("resource [%s] sub collection requires name, model, path and example_id", r.Name)
+		}
+		if sub.Description == "" {
+			r.SubCollections[i].Description = strings.ToLower(sub.Name)
+		}
+	}
+	return nil
+}
+
+// Has returns true if the resource supports given operation
+func (r Resource) Has(op string) bool {
+	for _, o := range r.Operations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Var returns the lower camel case name of the resource, e.g. affinityRule for AffinityRule
+// and vpc for VPC
+func (r Resource) Var() string {
+	return lowerCamel(r.Name)
+}
+
+// ExampleIDLiteral returns ExampleID as a Go literal
+func (r Resource) ExampleIDLiteral() string {
+	if r.IDType == "int" {
+		return r.ExampleID
+	}
+	return fmt.Sprintf("%q", r.ExampleID)
+}
+
+// ExampleIDJSON returns ExampleID as a JSON value
+func (r Resource) ExampleIDJSON() string {
+	return r.ExampleIDLiteral()
+}
+
+// InvalidIDLiteral returns a Go literal for an ID which fails validation
+func (r Resource) InvalidIDLiteral() string {
+	if r.IDType == "int" {
+		return "0"
+	}
+	return `""`
+}
+
+// Constructor returns the resource package constructor for the resource's ID type
+func (r Resource) Constructor() string {
+	if r.IDType == "int" {
+		return "NewIntResource"
+	}
+	return "NewStringResource"
+}
+
+// Parent returns the resource package parent constructor for the resource's ID type
+func (r Resource) Parent() string {
+	if r.IDType == "int" {
+		return "IntParent"
+	}
+	return "StringParent"
+}
+
+// IDFormat returns the format verb for the resource's ID type
+func (r Resource) IDFormat() string {
+	if r.IDType == "int" {
+		return "%d"
+	}
+	return "%s"
+}
+
+// lowerCamel lower cases the leading upper case run of s, keeping the final upper case
+// rune of the run where it begins the next word, e.g. VPNService => vpnService
+func lowerCamel(s string) string {
+	runes := []rune(s)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// spaced inserts a space before each word of s after the first, where words begin with an upper
+// case rune following a lower case rune, or ending an upper case run, e.g. VPNGateways => VPN Gateways
+func spaced(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// TestUsesConnection returns true if generated tests for the resource reference the connection package
+func (r Resource) TestUsesConnection() bool {
+	return r.Has("list") || len(r.SubCollections) > 0
+}
+
+// TestUsesAssert returns true if generated tests for the resource reference the assert package directly
+func (r Resource) TestUsesAssert() bool {
+	return r.Has("list") || r.Has("get") || r.Has("create") || len(r.SubCollections) > 0 ||
+		(r.Task && (r.Has("patch") || r.Has("delete")))
+}
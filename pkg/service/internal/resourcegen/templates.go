@@ -0,0 +1,416 @@
+package main
+
+import "text/template"
+
+const header = "// Code generated by resourcegen. DO NOT EDIT.\n\n"
+
+var serviceTemplate = template.Must(template.New("service").Parse(header + `package {{ .Package }}
+
+import (
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/ans-group/sdk-go/pkg/service/internal/resource"
+)
+{{ with .Resource }}
+func (s *Service) {{ .Var }}Res() *resource.Resource[{{ .Model }}, {{ .IDType }}] {
+	return resource.{{ .Constructor }}[{{ .Model }}](s.connection, "{{ .Path }}", "{{ .Description }}", func(id {{ .IDType }}) error {
+		return &{{ .Name }}NotFoundError{ID: id}
+	})
+}
+{{- if .Has "list" }}
+
+// Get{{ .Plural }} retrieves a list of {{ .Description }}s
+func (s *Service) Get{{ .Plural }}(parameters connection.APIRequestParameters) ([]{{ .Model }}, error) {
+	return s.{{ .Var }}Res().List(parameters)
+}
+
+// Get{{ .Plural }}Paginated retrieves a paginated list of {{ .Description }}s
+func (s *Service) Get{{ .Plural }}Paginated(parameters connection.APIRequestParameters) (*connection.Paginated[{{ .Model }}], error) {
+	return s.{{ .Var }}Res().ListPaginated(parameters)
+}
+{{- end }}
+{{- if .Has "get" }}
+
+// Get{{ .Name }} retrieves a single {{ .Description }} by id
+func (s *Service) Get{{ .Name }}({{ .IDParam }} {{ .IDType }}) ({{ .Model }}, error) {
+	return s.{{ .Var }}Res().Get({{ .IDParam }})
+}
+{{- end }}
+{{- if .Has "create" }}
+
+// Create{{ .Name }} creates a new {{ .Description }}
+{{- if .Task }}
+func (s *Service) Create{{ .Name }}(req {{ .CreateRequest }}) (TaskReference, error) {
+	return resource.CreateAs[TaskReference](s.{{ .Var }}Res(), &req)
+}
+{{- else }}
+func (s *Service) Create{{ .Name }}(req {{ .CreateRequest }}) ({{ .IDType }}, error) {
+	item, err := s.{{ .Var }}Res().Create(&req)
+	return item.ID, err
+}
+{{- end }}
+{{- end }}
+{{- if .Has "patch" }}
+
+// Patch{{ .Name }} patches {{ .Article }} {{ .Description }}
+{{- if .Task }}
+func (s *Service) Patch{{ .Name }}({{ .IDParam }} {{ .IDType }}, {{ .PatchParam }} {{ .PatchRequest }}) (TaskReference, error) {
+	return resource.PatchAs[TaskReference](s.{{ .Var }}Res(), {{ .IDParam }}, &{{ .PatchParam }})
+}
+{{- else }}
+func (s *Service) Patch{{ .Name }}({{ .IDParam }} {{ .IDType }}, {{ .PatchParam }} {{ .PatchRequest }}) error {
+	return s.{{ .Var }}Res().Patch({{ .IDParam }}, &{{ .PatchParam }})
+}
+{{- end }}
+{{- end }}
+{{- if .Has "delete" }}
+
+// Delete{{ .Name }} deletes {{ .Article }} {{ .Description }}
+{{- if .Task }}
+func (s *Service) Delete{{ .Name }}({{ .IDParam }} {{ .IDType }}) (string, error) {
+	task, err := resource.DeleteAs[TaskReference](s.{{ .Var }}Res(), {{ .IDParam }})
+	return task.TaskID, err
+}
+{{- else }}
+func (s *Service) Delete{{ .Name }}({{ .IDParam }} {{ .IDType }}) error {
+	return s.{{ .Var }}Res().Delete({{ .IDParam }})
+}
+{{- end }}
+{{- end }}
+{{- $r := . }}
+{{- range .SubCollections }}
+
+func (s *Service) {{ $r.Var }}{{ .Name }}Res({{ $r.IDParam }} {{ $r.IDType }}) *resource.Resource[{{ .Model }}, string] {
+	return resource.NewStringResource[{{ .Model }}](s.connection, "{{ .Path }}", "{{ .Description }}", nil).
+		WithParent(resource.{{ $r.Parent }}("{{ $r.Path }}", "{{ $r.Description }}", {{ $r.IDParam }}, &{{ $r.Name }}NotFoundError{ID: {{ $r.IDParam }}}))
+}
+
+// Get{{ $r.Name }}{{ .Name }} retrieves a list of {{ $r.Description }} {{ .Description }}
+func (s *Service) Get{{ $r.Name }}{{ .Name }}({{ $r.IDParam }} {{ $r.IDType }}, parameters connection.APIRequestParameters) ([]{{ .Model }}, error) {
+	return s.{{ $r.Var }}{{ .Name }}Res({{ $r.IDParam }}).List(parameters)
+}
+
+// Get{{ $r.Name }}{{ .Name }}Paginated retrieves a paginated list of {{ $r.Description }} {{ .Description }}
+func (s *Service) Get{{ $r.Name }}{{ .Name }}Paginated({{ $r.IDParam }} {{ $r.IDType }}, parameters connection.APIRequestParameters) (*connection.Paginated[{{ .Model }}], error) {
+	return s.{{ $r.Var }}{{ .Name }}Res({{ $r.IDParam }}).ListPaginated(parameters)
+}
+{{- end }}
+{{- end }}
+`))
+
+var errorTemplate = template.Must(template.New("error").Parse(header + `package {{ .Package }}
+
+import "fmt"
+{{ range .Resources }}
+// {{ .Name }}NotFoundError indicates {{ .Article }} {{ .Description }} was not found
+type {{ .Name }}NotFoundError struct {
+	ID {{ .IDType }}
+}
+
+func (e *{{ .Name }}NotFoundError) Error() string {
+	return fmt.Sprintf("{{ .ErrorDescription }} not found with ID [{{ .IDFormat }}]", e.ID)
+}
+{{ end }}`))
+
+var interfaceTemplate = template.Must(template.New("interface").Parse(`{{ $r := . }}	// {{ .DisplayName }}
+{{- if .Has "list" }}
+	Get{{ .Plural }}(parameters connection.APIRequestParameters) ([]{{ .Model }}, error)
+	Get{{ .Plural }}Paginated(parameters connection.APIRequestParameters) (*connection.Paginated[{{ .Model }}], error)
+{{- end }}
+{{- if .Has "get" }}
+	Get{{ .Name }}({{ .IDParam }} {{ .IDType }}) ({{ .Model }}, error)
+{{- end }}
+{{- if .Has "create" }}
+	Create{{ .Name }}(req {{ .CreateRequest }}) ({{ if .Task }}TaskReference{{ else }}{{ .IDType }}{{ end }}, error)
+{{- end }}
+{{- if .Has "patch" }}
+	Patch{{ .Name }}({{ .IDParam }} {{ .IDType }}, {{ .PatchParam }} {{ .PatchRequest }}) {{ if .Task }}(TaskReference, error){{ else }}error{{ end }}
+{{- end }}
+{{- if .Has "delete" }}
+	Delete{{ .Name }}({{ .IDParam }} {{ .IDType }}) {{ if .Task }}(string, error){{ else }}error{{ end }}
+{{- end }}
+{{- range .SubCollections }}
+	Get{{ $r.Name }}{{ .Name }}({{ $r.IDParam }} {{ $r.IDType }}, parameters connection.APIRequestParameters) ([]{{ .Model }}, error)
+	Get{{ $r.Name }}{{ .Name }}Paginated({{ $r.IDParam }} {{ $r.IDType }}, parameters connection.APIRequestParameters) (*connection.Paginated[{{ .Model }}], error)
+{{- end }}
+`))
+
+var testHelperTemplate = template.Must(template.New("testhelper").Parse(header + `package {{ .Package }}
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/ans-group/sdk-go/test/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// genTestCase represents a single generated test case
+type genTestCase struct {
+	name        string
+	invalidID   bool
+	call        bool
+	statusCode  int
+	body        string
+	connErr     error
+	wantErr     string
+	wantErrType error
+}
+
+// genExpect registers the expected call for tc against c
+func genExpect(c *mocks.MockConnection, method string, path string, tc genTestCase) {
+	if !tc.call {
+		return
+	}
+
+	resp := &connection.APIResponse{}
+	if tc.connErr == nil {
+		resp.Response = &http.Response{
+			Body:       io.NopCloser(bytes.NewReader([]byte(tc.body))),
+			StatusCode: tc.statusCode,
+		}
+	}
+
+	switch method {
+	case "GET":
+		c.EXPECT().Get(path, gomock.Any()).Return(resp, tc.connErr).Times(1)
+	case "POST":
+		c.EXPECT().Post(path, gomock.Any()).Return(resp, tc.connErr).Times(1)
+	case "PATCH":
+		c.EXPECT().Patch(path, gomock.Any()).Return(resp, tc.connErr).Times(1)
+	case "DELETE":
+		c.EXPECT().Delete(path, gomock.Any()).Return(resp, tc.connErr).Times(1)
+	}
+}
+
+// genAssertErr asserts err against the expectations of tc, returning true if no error was expected
+func genAssertErr(t *testing.T, tc genTestCase, err error) bool {
+	if tc.wantErr == "" && tc.wantErrType == nil {
+		assert.Nil(t, err)
+		return true
+	}
+
+	assert.NotNil(t, err)
+	if tc.wantErr != "" {
+		assert.Equal(t, tc.wantErr, err.Error())
+	}
+	if tc.wantErrType != nil {
+		assert.IsType(t, tc.wantErrType, err)
+	}
+	return false
+}
+`))
+
+var testTemplate = template.Must(template.New("test").Parse(header + `package {{ .Package }}
+
+import (
+	"errors"
+	"testing"
+{{ if .Resource.TestUsesConnection }}
+	"github.com/ans-group/sdk-go/pkg/connection"
+{{- end }}
+	"github.com/ans-group/sdk-go/test/mocks"
+	"github.com/golang/mock/gomock"
+{{- if .Resource.TestUsesAssert }}
+	"github.com/stretchr/testify/assert"
+{{- end }}
+)
+{{ with .Resource }}
+{{- $r := . }}
+{{- if .Has "list" }}
+func TestGet{{ .Plural }}_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+		{name: "Single", call: true, statusCode: 200, body: ` + "`" + `{"data":[{"id":{{ .ExampleIDJSON }}}],"meta":{"pagination":{"total_pages":1}}}` + "`" + `},
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "GET", "{{ .Path }}", tc)
+
+			items, err := s.Get{{ .Plural }}(connection.APIRequestParameters{})
+
+			if genAssertErr(t, tc, err) {
+				assert.Len(t, items, 1)
+				assert.Equal(t, {{ .ExampleIDLiteral }}, items[0].ID)
+			}
+		})
+	}
+}
+{{ end }}
+{{- if .Has "get" }}
+func TestGet{{ .Name }}_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+		{name: "Valid", call: true, statusCode: 200, body: ` + "`" + `{"data":{"id":{{ .ExampleIDJSON }}}}` + "`" + `},
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+		{name: "Invalid{{ .Name }}ID_ReturnsError", invalidID: true, wantErr: "invalid {{ .Description }} id"},
+		{name: "404_Returns{{ .Name }}NotFoundError", call: true, statusCode: 404, wantErrType: &{{ .Name }}NotFoundError{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "GET", "{{ .Path }}/{{ .ExampleID }}", tc)
+
+			{{ .IDParam }} := {{ .ExampleIDLiteral }}
+			if tc.invalidID {
+				{{ .IDParam }} = {{ .InvalidIDLiteral }}
+			}
+			item, err := s.Get{{ .Name }}({{ .IDParam }})
+
+			if genAssertErr(t, tc, err) {
+				assert.Equal(t, {{ .ExampleIDLiteral }}, item.ID)
+			}
+		})
+	}
+}
+{{ end }}
+{{- if .Has "create" }}
+func TestCreate{{ .Name }}_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+{{- if .Task }}
+		{name: "Valid", call: true, statusCode: 202, body: ` + "`" + `{"data":{"id":{{ .ExampleIDJSON }},"task_id":"task-abcdef12"}}` + "`" + `},
+{{- else }}
+		{name: "Valid", call: true, statusCode: 201, body: ` + "`" + `{"data":{"id":{{ .ExampleIDJSON }}}}` + "`" + `},
+{{- end }}
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "POST", "{{ .Path }}", tc)
+
+			result, err := s.Create{{ .Name }}({{ .CreateRequest }}{})
+
+			if genAssertErr(t, tc, err) {
+{{- if .Task }}
+				assert.Equal(t, {{ .ExampleIDLiteral }}, result.ResourceID)
+				assert.Equal(t, "task-abcdef12", result.TaskID)
+{{- else }}
+				assert.Equal(t, {{ .ExampleIDLiteral }}, result)
+{{- end }}
+			}
+		})
+	}
+}
+{{ end }}
+{{- if .Has "patch" }}
+func TestPatch{{ .Name }}_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+{{- if .Task }}
+		{name: "Valid", call: true, statusCode: 202, body: ` + "`" + `{"data":{"id":{{ .ExampleIDJSON }},"task_id":"task-abcdef12"}}` + "`" + `},
+{{- else }}
+		{name: "Valid", call: true, statusCode: 200, body: ` + "`" + `{}` + "`" + `},
+{{- end }}
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+		{name: "Invalid{{ .Name }}ID_ReturnsError", invalidID: true, wantErr: "invalid {{ .Description }} id"},
+		{name: "404_Returns{{ .Name }}NotFoundError", call: true, statusCode: 404, wantErrType: &{{ .Name }}NotFoundError{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "PATCH", "{{ .Path }}/{{ .ExampleID }}", tc)
+
+			{{ .IDParam }} := {{ .ExampleIDLiteral }}
+			if tc.invalidID {
+				{{ .IDParam }} = {{ .InvalidIDLiteral }}
+			}
+{{- if .Task }}
+			task, err := s.Patch{{ .Name }}({{ .IDParam }}, {{ .PatchRequest }}{})
+
+			if genAssertErr(t, tc, err) {
+				assert.Equal(t, "task-abcdef12", task.TaskID)
+			}
+{{- else }}
+			err := s.Patch{{ .Name }}({{ .IDParam }}, {{ .PatchRequest }}{})
+
+			genAssertErr(t, tc, err)
+{{- end }}
+		})
+	}
+}
+{{ end }}
+{{- if .Has "delete" }}
+func TestDelete{{ .Name }}_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+{{- if .Task }}
+		{name: "Valid", call: true, statusCode: 202, body: ` + "`" + `{"data":{"task_id":"task-abcdef12"}}` + "`" + `},
+{{- else }}
+		{name: "Valid", call: true, statusCode: 204},
+{{- end }}
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+		{name: "Invalid{{ .Name }}ID_ReturnsError", invalidID: true, wantErr: "invalid {{ .Description }} id"},
+		{name: "404_Returns{{ .Name }}NotFoundError", call: true, statusCode: 404, wantErrType: &{{ .Name }}NotFoundError{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "DELETE", "{{ .Path }}/{{ .ExampleID }}", tc)
+
+			{{ .IDParam }} := {{ .ExampleIDLiteral }}
+			if tc.invalidID {
+				{{ .IDParam }} = {{ .InvalidIDLiteral }}
+			}
+{{- if .Task }}
+			taskID, err := s.Delete{{ .Name }}({{ .IDParam }})
+
+			if genAssertErr(t, tc, err) {
+				assert.Equal(t, "task-abcdef12", taskID)
+			}
+{{- else }}
+			err := s.Delete{{ .Name }}({{ .IDParam }})
+
+			genAssertErr(t, tc, err)
+{{- end }}
+		})
+	}
+}
+{{ end }}
+{{- range .SubCollections }}
+func TestGet{{ $r.Name }}{{ .Name }}_Generated(t *testing.T) {
+	for _, tc := range []genTestCase{
+		{name: "Single", call: true, statusCode: 200, body: ` + "`" + `{"data":[{"id":"{{ .ExampleID }}"}],"meta":{"pagination":{"total_pages":1}}}` + "`" + `},
+		{name: "ConnectionError_ReturnsError", call: true, connErr: errors.New("test error 1"), wantErr: "test error 1"},
+		{name: "Invalid{{ $r.Name }}ID_ReturnsError", invalidID: true, wantErr: "invalid {{ $r.Description }} id"},
+		{name: "404_Returns{{ $r.Name }}NotFoundError", call: true, statusCode: 404, wantErrType: &{{ $r.Name }}NotFoundError{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			c := mocks.NewMockConnection(mockCtrl)
+			s := Service{connection: c}
+			genExpect(c, "GET", "{{ $r.Path }}/{{ $r.ExampleID }}/{{ .Path }}", tc)
+
+			{{ $r.IDParam }} := {{ $r.ExampleIDLiteral }}
+			if tc.invalidID {
+				{{ $r.IDParam }} = {{ $r.InvalidIDLiteral }}
+			}
+			items, err := s.Get{{ $r.Name }}{{ .Name }}({{ $r.IDParam }}, connection.APIRequestParameters{})
+
+			if genAssertErr(t, tc, err) {
+				assert.Len(t, items, 1)
+				assert.Equal(t, "{{ .ExampleID }}", items[0].ID)
+			}
+		})
+	}
+}
+{{ end }}
+{{- end }}`))
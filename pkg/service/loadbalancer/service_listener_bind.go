@@ -1,68 +1,45 @@
 package loadbalancer
 
 import (
-	"fmt"
-
 	"github.com/ans-group/sdk-go/pkg/connection"
+	"github.com/ans-group/sdk-go/pkg/service/internal/resource"
 )
 
+// listenerBindRes returns the binds nested beneath the given listener. A 404 from the binds
+// collection has always been reported as a BindNotFoundError, so the parent keeps that error
+func (s *Service) listenerBindRes(listenerID int) *resource.Resource[Bind, int] {
+	return resource.NewIntResource[Bind](s.connection, "binds", "bind",
+		func(id int) error { return &BindNotFoundError{ID: id} }).
+		WithParent(resource.IntParent("/loadbalancers/v2/listeners", "listener", listenerID, &BindNotFoundError{ID: listenerID}))
+}
+
 // GetListenerBinds retrieves a list of binds
 func (s *Service) GetListenerBinds(listenerID int, parameters connection.APIRequestParameters) ([]Bind, error) {
-	return connection.InvokeRequestAll(func(p connection.APIRequestParameters) (*connection.Paginated[Bind], error) {
-		return s.GetListenerBindsPaginated(listenerID, p)
-	}, parameters)
+	return s.listenerBindRes(listenerID).List(parameters)
 }
 
 // GetListenerBindsPaginated retrieves a paginated list of binds
 func (s *Service) GetListenerBindsPaginated(listenerID int, parameters connection.APIRequestParameters) (*connection.Paginated[Bind], error) {
-	if listenerID < 1 {
-		return nil, fmt.Errorf("invalid listener id")
-	}
-	body, err := connection.Get[[]Bind](s.connection, fmt.Sprintf("/loadbalancers/v2/listeners/%d/binds", listenerID), parameters)
-	return connection.NewPaginated(body, parameters, func(p connection.APIRequestParameters) (*connection.Paginated[Bind], error) {
-		return s.GetListenerBindsPaginated(listenerID, p)
-	}), err
+	return s.listenerBindRes(listenerID).ListPaginated(parameters)
 }
 
 // GetListenerBind retrieves a single bind by id
 func (s *Service) GetListenerBind(listenerID int, bindID int) (Bind, error) {
-	if listenerID < 1 {
-		return Bind{}, fmt.Errorf("invalid listener id")
-	}
-	if bindID < 1 {
-		return Bind{}, fmt.Errorf("invalid bind id")
-	}
-	body, err := connection.Get[Bind](s.connection, fmt.Sprintf("/loadbalancers/v2/listeners/%d/binds/%d", listenerID, bindID), connection.APIRequestParameters{}, connection.NotFoundResponseHandler(&BindNotFoundError{ID: listenerID}))
-	return body.Data, err
+	return s.listenerBindRes(listenerID).Get(bindID)
 }
 
-// CreateListenerBind creates an bind
+// CreateListenerBind creates a bind
 func (s *Service) CreateListenerBind(listenerID int, req CreateBindRequest) (int, error) {
-	if listenerID < 1 {
-		return 0, fmt.Errorf("invalid listener id")
-	}
-	body, err := connection.Post[Bind](s.connection, fmt.Sprintf("/loadbalancers/v2/listeners/%d/binds", listenerID), &req, connection.NotFoundResponseHandler(&BindNotFoundError{ID: listenerID}))
-	return body.Data.ID, err
+	bind, err := s.listenerBindRes(listenerID).Create(&req)
+	return bind.ID, err
 }
 
-// PatchListenerBind patches an bind
+// PatchListenerBind patches a bind
 func (s *Service) PatchListenerBind(listenerID int, bindID int, req PatchBindRequest) error {
-	if listenerID < 1 {
-		return fmt.Errorf("invalid listener id")
-	}
-	if bindID < 1 {
-		return fmt.Errorf("invalid bind id")
-	}
-	return connection.PatchRaw(s.connection, fmt.Sprintf("/loadbalancers/v2/listeners/%d/binds/%d", listenerID, bindID), &req, &connection.APIResponseBody{}, connection.NotFoundResponseHandler(&BindNotFoundError{ID: listenerID}))
+	return s.listenerBindRes(listenerID).Patch(bindID, &req)
 }
 
 // DeleteListenerBind deletes a bind
 func (s *Service) DeleteListenerBind(listenerID int, bindID int) error {
-	if listenerID < 1 {
-		return fmt.Errorf("invalid listener id")
-	}
-	if bindID < 1 {
-		return fmt.Errorf("invalid bind id")
-	}
-	return connection.DeleteRaw(s.connection, fmt.Sprintf("/loadbalancers/v2/listeners/%d/binds/%d", listenerID, bindID), nil, &connection.APIResponseBody{}, connection.NotFoundResponseHandler(&BindNotFoundError{ID: listenerID}))
+	return s.listenerBindRes(listenerID).Delete(bindID)
 }
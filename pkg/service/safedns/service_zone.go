@@ -45,43 +45,31 @@ func (s *Service) DeleteZone(zoneName string) error {
 	return s.zoneRes().Delete(zoneName)
 }
 
+func (s *Service) zoneRecordRes(zoneName string) *resource.Resource[Record, int] {
+	return resource.NewIntResource[Record](s.connection, "records", "record",
+		func(id int) error { return &ZoneRecordNotFoundError{ZoneName: zoneName, RecordID: id} }).
+		WithParent(resource.StringParentWithIdentifier("/safedns/v1/zones", "zone", "name", zoneName, &ZoneNotFoundError{ZoneName: zoneName}))
+}
+
 // GetZoneRecords retrieves a list of records
 func (s *Service) GetZoneRecords(zoneName string, parameters connection.APIRequestParameters) ([]Record, error) {
-	return connection.InvokeRequestAll(func(p connection.APIRequestParameters) (*connection.Paginated[Record], error) {
-		return s.GetZoneRecordsPaginated(zoneName, p)
-	}, parameters)
+	return s.zoneRecordRes(zoneName).List(parameters)
 }
 
 // GetZoneRecordsPaginated retrieves a paginated list of zones
 func (s *Service) GetZoneRecordsPaginated(zoneName string, parameters connection.APIRequestParameters) (*connection.Paginated[Record], error) {
-	if zoneName == "" {
-		return nil, fmt.Errorf("invalid zone name")
-	}
-	body, err := connection.Get[[]Record](s.connection, fmt.Sprintf("/safedns/v1/zones/%s/records", zoneName), parameters, connection.NotFoundResponseHandler(&ZoneNotFoundError{ZoneName: zoneName}))
-	return connection.NewPaginated(body, parameters, func(p connection.APIRequestParameters) (*connection.Paginated[Record], error) {
-		return s.GetZoneRecordsPaginated(zoneName, p)
-	}), err
+	return s.zoneRecordRes(zoneName).ListPaginated(parameters)
 }
 
 // GetZoneRecord retrieves a single zone record by ID
 func (s *Service) GetZoneRecord(zoneName string, recordID int) (Record, error) {
-	if zoneName == "" {
-		return Record{}, fmt.Errorf("invalid zone name")
-	}
-	if recordID < 1 {
-		return Record{}, fmt.Errorf("invalid record id")
-	}
-	body, err := connection.Get[Record](s.connection, fmt.Sprintf("/safedns/v1/zones/%s/records/%d", zoneName, recordID), connection.APIRequestParameters{}, connection.NotFoundResponseHandler(&ZoneRecordNotFoundError{ZoneName: zoneName, RecordID: recordID}))
-	return body.Data, err
+	return s.zoneRecordRes(zoneName).Get(recordID)
 }
 
 // CreateZoneRecord creates a new SafeDNS zone record
 func (s *Service) CreateZoneRecord(zoneName string, req CreateRecordRequest) (int, error) {
-	if zoneName == "" {
-		return 0, fmt.Errorf("invalid zone name")
-	}
-	body, err := connection.Post[Record](s.connection, fmt.Sprintf("/safedns/v1/zones/%s/records", zoneName), &req, connection.NotFoundResponseHandler(&ZoneNotFoundError{ZoneName: zoneName}))
-	return body.Data.ID, err
+	record, err := s.zoneRecordRes(zoneName).Create(&req)
+	return record.ID, err
 }
 
 // UpdateZoneRecord updates a SafeDNS zone record
@@ -110,13 +98,7 @@ func (s *Service) PatchZoneRecord(zoneName string, recordID int, patch PatchReco
 
 // DeleteZoneRecord removes a SafeDNS zone record
 func (s *Service) DeleteZoneRecord(zoneName string, recordID int) error {
-	if zoneName == "" {
-		return fmt.Errorf("invalid zone name")
-	}
-	if recordID < 1 {
-		return fmt.Errorf("invalid record id")
-	}
-	return connection.DeleteRaw(s.connection, fmt.Sprintf("/safedns/v1/zones/%s/records/%d", zoneName, recordID), nil, &connection.APIResponseBody{}, connection.NotFoundResponseHandler(&ZoneRecordNotFoundError{ZoneName: zoneName, RecordID: recordID}))
+	return s.zoneRecordRes(zoneName).Delete(recordID)
 }
 
 // GetZoneNotes retrieves a list of notes